@@ -2,10 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/igorganapolsky/trading/adk_trading/internal/agents"
 	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
@@ -16,11 +25,27 @@ import (
 	"google.golang.org/adk/session"
 )
 
+// selfTestTimeout bounds the selftest subcommand, including the model round trip.
+const selfTestTimeout = 2 * time.Minute
+
 type config struct {
 	modelName string
 	dataDir   string
 	logPath   string
 	appName   string
+	mode      string
+	keyFile   string
+	parallel  bool
+	syslog    string
+	dbPath    string
+	dbOnly    bool
+	allowed   string
+	blocked   string
+	overrides string
+	profiles  string
+	schema    string
+	aliases   string
+	evaluate  string
 }
 
 func main() {
@@ -28,32 +53,217 @@ func main() {
 
 	var cfg config
 	flag.StringVar(&cfg.modelName, "model", envOrDefault("ADK_MODEL", "gemini-2.5-flash"), "Gemini model name to use for all agents.")
-	flag.StringVar(&cfg.dataDir, "data_dir", envOrDefault("ADK_DATA_DIR", defaultDataDir()), "Path to the trading data directory.")
+	flag.StringVar(&cfg.dataDir, "data_dir", envOrDefault("ADK_DATA_DIR", defaultDataDir()), "Path to the trading data directory; separate several with ':' to search them in order.")
 	flag.StringVar(&cfg.logPath, "log_path", envOrDefault("ADK_LOG_PATH", defaultLogPath()), "Destination JSONL log file for execution plans.")
 	flag.StringVar(&cfg.appName, "app", envOrDefault("ADK_APP_NAME", "trading_orchestrator"), "App name to register with the ADK runtime.")
+	flag.StringVar(&cfg.mode, "mode", envOrDefault("ADK_MODE", agents.ModePaper), "Trading mode tagged on every decision: paper or live.")
+	flag.StringVar(&cfg.keyFile, "api_key_file", "", "File containing the Google API key; takes precedence over GOOGLE_API_KEY_FILE and GOOGLE_API_KEY.")
+	flag.BoolVar(&cfg.parallel, "parallel_research", os.Getenv("ADK_PARALLEL_RESEARCH") == "true", "Run research and sentiment agents concurrently before the signal stage.")
+	flag.StringVar(&cfg.syslog, "syslog_addr", os.Getenv("ADK_SYSLOG_ADDR"), "Also send audit log entries to syslog: udp://host:514, tcp://host:601, unix:///dev/log or local.")
+	flag.StringVar(&cfg.dbPath, "decision_db", os.Getenv("ADK_DECISION_DB"), "Also insert logged decisions into this SQLite database.")
+	flag.BoolVar(&cfg.dbOnly, "decision_db_only", os.Getenv("ADK_DECISION_DB_ONLY") == "true", "Log decisions only to -decision_db instead of the JSONL file.")
+	flag.StringVar(&cfg.allowed, "allowed_symbols", os.Getenv("ADK_ALLOWED_SYMBOLS"), "Comma-separated symbols the risk check may approve; empty allows all.")
+	flag.StringVar(&cfg.blocked, "blocked_symbols", os.Getenv("ADK_BLOCKED_SYMBOLS"), "Comma-separated restricted symbols the risk check always rejects.")
+	flag.StringVar(&cfg.overrides, "risk_overrides", os.Getenv("ADK_RISK_OVERRIDES"), "Optional JSON file mapping symbols to maxRiskBps, rejectVolatility and maxPositionFraction overrides.")
+	flag.StringVar(&cfg.profiles, "risk_profiles", os.Getenv("ADK_RISK_PROFILES"), "Optional JSON file mapping risk profile names to maxRiskBps, rejectVolatility and maxPositionFraction thresholds.")
+	flag.StringVar(&cfg.schema, "csv_schema", os.Getenv("ADK_CSV_SCHEMA"), "Optional JSON descriptor mapping date, open, high, low, close, volume and adjClose to CSV column labels or indices.")
+	flag.StringVar(&cfg.aliases, "symbol_aliases", os.Getenv("ADK_SYMBOL_ALIASES"), "Optional JSON file mapping symbol variants such as BRK.B and BRKB to the canonical symbol the data is filed under.")
+	flag.StringVar(&cfg.evaluate, "evaluate_addr", os.Getenv("ADK_EVALUATE_ADDR"), "Optional address for the plain REST POST /evaluate endpoint, e.g. :8092; empty disables it.")
 	flag.Parse()
 
+	logger, err := newLogger(os.Getenv("ADK_LOG_LEVEL"), os.Getenv("ADK_LOG_FORMAT"))
+	if err != nil {
+		fatal("invalid logging configuration", "error", err)
+	}
+	slog.SetDefault(logger)
+
+	// snapshot prints one symbol's market data snapshot without the agent stack.
+	if flag.Arg(0) == "snapshot" {
+		if err := runSnapshot(ctx, cfg.dataDir, cfg.schema, flag.Args()[1:], os.Stdout, os.Stderr); err != nil {
+			if !errors.Is(err, errNoSnapshot) && !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, "snapshot:", err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	overridesReload, err := time.ParseDuration(envOrDefault("ADK_RISK_OVERRIDES_RELOAD", "5m"))
+	if err != nil {
+		fatal("invalid ADK_RISK_OVERRIDES_RELOAD", "error", err)
+	}
+	evaluateTimeout, err := time.ParseDuration(envOrDefault("ADK_EVALUATE_TIMEOUT", agents.DefaultEvaluateTimeout.String()))
+	if err != nil {
+		fatal("invalid ADK_EVALUATE_TIMEOUT", "error", err)
+	}
+	logRotateBytes, err := strconv.ParseInt(envOrDefault("ADK_LOG_ROTATE_BYTES", "0"), 10, 64)
+	if err != nil {
+		fatal("invalid ADK_LOG_ROTATE_BYTES", "error", err)
+	}
+	logRotateKeep, err := strconv.Atoi(envOrDefault("ADK_LOG_ROTATE_KEEP", "0"))
+	if err != nil {
+		fatal("invalid ADK_LOG_ROTATE_KEEP", "error", err)
+	}
+	logSizeTolerance, err := strconv.ParseFloat(envOrDefault("ADK_LOG_SIZE_TOLERANCE", "0"), 64)
+	if err != nil {
+		fatal("invalid ADK_LOG_SIZE_TOLERANCE", "error", err)
+	}
+	logMinConfidence, err := strconv.ParseFloat(envOrDefault("ADK_LOG_MIN_CONFIDENCE", "0"), 64)
+	if err != nil {
+		fatal("invalid ADK_LOG_MIN_CONFIDENCE", "error", err)
+	}
+	approvalThreshold, err := strconv.ParseFloat(envOrDefault("ADK_APPROVAL_THRESHOLD", "0"), 64)
+	if err != nil {
+		fatal("invalid ADK_APPROVAL_THRESHOLD", "error", err)
+	}
+	approvalTimeout, err := time.ParseDuration(envOrDefault("ADK_APPROVAL_TIMEOUT", "0s"))
+	if err != nil {
+		fatal("invalid ADK_APPROVAL_TIMEOUT", "error", err)
+	}
+	betaReviewThreshold, err := strconv.ParseFloat(envOrDefault("ADK_BETA_REVIEW_THRESHOLD", "0"), 64)
+	if err != nil {
+		fatal("invalid ADK_BETA_REVIEW_THRESHOLD", "error", err)
+	}
+	biasRefresh, err := time.ParseDuration(envOrDefault("ADK_BIAS_REFRESH", "0s"))
+	if err != nil {
+		fatal("invalid ADK_BIAS_REFRESH", "error", err)
+	}
+	scoreWeights, err := parseScoreWeights(os.Getenv("ADK_SCORE_WEIGHTS"))
+	if err != nil {
+		fatal("invalid ADK_SCORE_WEIGHTS", "error", err)
+	}
+	toolRateLimit, err := strconv.ParseFloat(envOrDefault("ADK_TOOL_RATE_LIMIT", "0"), 64)
+	if err != nil {
+		fatal("invalid ADK_TOOL_RATE_LIMIT", "error", err)
+	}
+	toolRateBurst, err := strconv.Atoi(envOrDefault("ADK_TOOL_RATE_BURST", "0"))
+	if err != nil {
+		fatal("invalid ADK_TOOL_RATE_BURST", "error", err)
+	}
+	orchestratorCfg := agents.Config{
+		AppName:              cfg.appName,
+		ModelName:            cfg.modelName,
+		APIKeyFile:           cfg.keyFile,
+		Mode:                 cfg.mode,
+		DataDir:              cfg.dataDir,
+		LogPath:              cfg.logPath,
+		ParallelResearch:     cfg.parallel,
+		ToolRateLimit:        toolRateLimit,
+		ToolRateBurst:        toolRateBurst,
+		ToolRatePerTool:      os.Getenv("ADK_TOOL_RATE_PER_TOOL") == "true",
+		SyslogAddr:           cfg.syslog,
+		DecisionDBPath:       cfg.dbPath,
+		DecisionDBOnly:       cfg.dbOnly,
+		AllowedSymbols:       splitList(cfg.allowed),
+		BlockedSymbols:       splitList(cfg.blocked),
+		RiskOverridesPath:    cfg.overrides,
+		RiskOverridesReload:  overridesReload,
+		RiskProfilesPath:     cfg.profiles,
+		CSVSchemaPath:        cfg.schema,
+		SymbolAliasesPath:    cfg.aliases,
+		ApprovalURL:          os.Getenv("ADK_APPROVAL_URL"),
+		ApprovalThreshold:    approvalThreshold,
+		ApprovalTimeout:      approvalTimeout,
+		BetaReviewThreshold:  betaReviewThreshold,
+		BiasRefreshInterval:  biasRefresh,
+		LogRotateBytes:       logRotateBytes,
+		LogRotateKeep:        logRotateKeep,
+		LogCompressRotations: os.Getenv("ADK_LOG_COMPRESS") == "true",
+		LogSizeTolerance:     logSizeTolerance,
+		LogMinConfidence:     logMinConfidence,
+		ScoreTrendWeight:     scoreWeights[0],
+		ScoreRSIWeight:       scoreWeights[1],
+		ScoreVolumeWeight:    scoreWeights[2],
+	}
+
+	// selftest is a readiness gate for CI and deploys: it never serves anything.
+	if flag.Arg(0) == "selftest" {
+		selfTestCtx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+		report := agents.SelfTest(selfTestCtx, orchestratorCfg)
+		cancel()
+		fmt.Print(report)
+		if report.Failed() > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	healthAddr := envOrDefault("ADK_HEALTH_ADDR", ":8091")
-	obsRecorder := observability.NewRecorder(healthAddr)
+	circuitThreshold, err := strconv.Atoi(envOrDefault("ADK_CIRCUIT_THRESHOLD", "5"))
+	if err != nil {
+		fatal("invalid ADK_CIRCUIT_THRESHOLD", "error", err)
+	}
+	circuitWindow, err := time.ParseDuration(envOrDefault("ADK_CIRCUIT_WINDOW", "30m"))
+	if err != nil {
+		fatal("invalid ADK_CIRCUIT_WINDOW", "error", err)
+	}
+	heartbeat, err := time.ParseDuration(envOrDefault("ADK_HEARTBEAT_INTERVAL", observability.DefaultHeartbeatInterval.String()))
+	if err != nil {
+		fatal("invalid ADK_HEARTBEAT_INTERVAL", "error", err)
+	}
+	// Without ADK_ADMIN_TOKEN, POST /reset, /reopen and /evaluate only answer local callers.
+	adminToken := os.Getenv("ADK_ADMIN_TOKEN")
+	obsOpts := []observability.Option{
+		observability.WithApp(cfg.appName),
+		observability.WithMode(cfg.mode),
+		observability.WithCircuitBreaker(circuitThreshold, circuitWindow),
+		observability.WithHeartbeat(heartbeat),
+		observability.WithAdminToken(adminToken),
+	}
+	obsRecorder := observability.NewRecorder(healthAddr, obsOpts...)
 	obsCtx, obsCancel := context.WithCancel(ctx)
 	defer obsCancel()
-	obsRecorder.Start(obsCtx)
+	if err := obsRecorder.Start(obsCtx); err != nil {
+		if strings.HasPrefix(healthAddr, "unix:") {
+			// Falling back to TCP would open a port the deployment chose not to expose.
+			fatal("failed to start observability server", "error", err)
+		}
+		slog.Warn("observability address unavailable; falling back to a random port", "error", err)
+		obsRecorder = observability.NewRecorder(":0", obsOpts...)
+		if err := obsRecorder.Start(obsCtx); err != nil {
+			fatal("failed to start observability server", "error", err)
+		}
+	}
+	slog.Info("observability endpoints listening", "addr", obsRecorder.Addr())
 	defer obsRecorder.Shutdown(context.Background())
 
-	rootAgent, subAgents, err := agents.BuildTradingOrchestrator(ctx, agents.Config{
-		AppName:               cfg.appName,
-		ModelName:             cfg.modelName,
-		DataDir:               cfg.dataDir,
-		LogPath:               cfg.logPath,
-		ObservabilityRecorder: obsRecorder,
-	})
+	orchestratorCfg.ObservabilityRecorder = obsRecorder
+	rootAgent, subAgents, err := agents.BuildTradingOrchestrator(ctx, orchestratorCfg)
 	if err != nil {
-		log.Fatalf("failed to initialize trading orchestrator: %v", err)
+		if errors.Is(err, agents.ErrMissingAPIKey) {
+			fatal("failed to initialize trading orchestrator", "error", err, "hint", "set -api_key_file, GOOGLE_API_KEY_FILE or GOOGLE_API_KEY")
+		}
+		fatal("failed to initialize trading orchestrator", "error", err)
+	}
+
+	// SIGHUP reopens the audit log so external rotation (logrotate without
+	// copytruncate) takes effect; an authorised POST /reopen on the health address
+	// does the same.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			_ = obsRecorder.ReopenLogs()
+		}
+	}()
+
+	if cfg.evaluate != "" {
+		evaluator, err := agents.NewEvaluator(cfg.appName, rootAgent, evaluateTimeout)
+		if err != nil {
+			fatal("failed to create evaluator", "error", err)
+		}
+		evaluateServer, err := serveEvaluate(cfg.evaluate, evaluator, adminToken, evaluateTimeout)
+		if err != nil {
+			fatal("failed to start evaluate endpoint", "error", err)
+		}
+		slog.Info("evaluate endpoint listening", "addr", cfg.evaluate)
+		defer evaluateServer.Shutdown(context.Background())
 	}
 
 	agentLoader, err := services.NewMultiAgentLoader(rootAgent, subAgents...)
 	if err != nil {
-		log.Fatalf("failed to create agent loader: %v", err)
+		fatal("failed to create agent loader", "error", err)
 	}
 
 	cfgADK := &adk.Config{
@@ -71,8 +281,90 @@ func main() {
 	}
 
 	if err := launcher.Execute(ctx, cfgADK, args); err != nil {
-		log.Fatalf("run failed: %v\n\n%s", err, launcher.CommandLineSyntax())
+		fmt.Fprintln(os.Stderr, launcher.CommandLineSyntax())
+		fatal("run failed", "error", err)
+	}
+}
+
+// serveEvaluate serves evaluator at POST /evaluate on addr in the background,
+// guarded by adminToken as the recorder's admin endpoints are, since every call
+// runs the models and can log decisions. The write timeout leaves room for an
+// orchestration that runs up to timeout.
+func serveEvaluate(addr string, evaluator *agents.Evaluator, adminToken string, timeout time.Duration) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/evaluate", observability.AdminOnly(adminToken, evaluator.ServeHTTP))
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      timeout + 10*time.Second,
+	}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("evaluate endpoint stopped", "error", err)
+		}
+	}()
+	return server, nil
+}
+
+// newLogger builds the process logger from ADK_LOG_LEVEL (debug, info, warn or
+// error; default info) and ADK_LOG_FORMAT (json or text; default text).
+func newLogger(level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if strings.TrimSpace(level) != "" {
+		if err := lvl.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+			return nil, fmt.Errorf("ADK_LOG_LEVEL: %w", err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
+	return nil, fmt.Errorf("ADK_LOG_FORMAT must be json or text, got %q", format)
+}
+
+// fatal logs msg at error level and exits non-zero, like log.Fatalf.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseScoreWeights parses the composite score's "trend,rsi,volume" weights, e.g.
+// "0.5,0.3,0.2". An empty value leaves them zero, keeping the marketdata defaults.
+func parseScoreWeights(value string) ([3]float64, error) {
+	var weights [3]float64
+	parts := splitList(value)
+	if len(parts) == 0 {
+		return weights, nil
+	}
+	if len(parts) != len(weights) {
+		return weights, fmt.Errorf("want trend,rsi,volume weights, got %q", value)
+	}
+	for i, part := range parts {
+		w, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return weights, err
+		}
+		weights[i] = w
 	}
+	return weights, nil
 }
 
 func envOrDefault(key, fallback string) string {
@@ -93,7 +385,7 @@ func defaultLogPath() string {
 func projectRoot() string {
 	wd, err := os.Getwd()
 	if err != nil {
-		log.Printf("warning: unable to determine working directory: %v", err)
+		slog.Warn("unable to determine working directory", "error", err)
 		return "."
 	}
 	root := wd
@@ -110,6 +402,6 @@ func projectRoot() string {
 	if modRoot := os.Getenv("TRADING_PROJECT_ROOT"); modRoot != "" {
 		return modRoot
 	}
-	log.Println("warning: falling back to current working directory for project root discovery")
+	slog.Warn("falling back to current working directory for project root discovery")
 	return wd
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+)
+
+// errNoSnapshot reports a snapshot that was printed but carries no data.
+var errNoSnapshot = errors.New("snapshot has no data")
+
+// runSnapshot implements the snapshot subcommand: it computes the market data
+// snapshot for one symbol from the data directories and prints it to stdout as
+// indented JSON, without building any agents. The snapshot is printed even when
+// it has no data, in which case errNoSnapshot is returned. A non-empty schemaPath
+// names the CSV schema descriptor to parse the data with.
+func runSnapshot(ctx context.Context, dataDir, schemaPath string, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var input marketdata.Input
+	fs.StringVar(&dataDir, "data_dir", dataDir, "Path to the trading data directory; separate several with ':' to search them in order.")
+	fs.StringVar(&schemaPath, "csv_schema", schemaPath, "Optional JSON descriptor mapping OHLCV fields to CSV columns.")
+	fs.StringVar(&input.Symbol, "symbol", "", "Symbol to snapshot (required).")
+	fs.IntVar(&input.Window, "window", 0, "Number of bars to load; zero keeps the tool default.")
+	fs.StringVar(&input.PriceField, "price_field", "", "Price field to compute stats on: close (default) or adjClose.")
+	fs.StringVar(&input.TrendMethod, "trend_method", "", "Trend method: sma (default) or ema.")
+	fs.StringVar(&input.Action, "action", "", "BUY or SELL, to orient the suggested stops.")
+	fs.StringVar(&input.Resample, "resample", "", "Aggregate daily bars into weekly or monthly bars.")
+	fs.StringVar(&input.ReturnType, "return_type", "", "Return type: simple (default) or log.")
+	fs.StringVar(&input.VolatilityModel, "volatility_model", "", "Volatility model: stddev (default) or ewma.")
+	fs.Float64Var(&input.PeriodsPerYear, "periods_per_year", 0, "Periods used to annualise volatility; zero keeps the default.")
+	fs.BoolVar(&input.IncludeRaw, "raw", false, "Include the loaded rows.")
+	fs.BoolVar(&input.IncludeSeries, "series", false, "Include per-bar indicator series.")
+	fs.BoolVar(&input.IncludeVolumeProfile, "volume_profile", false, "Include a volume-by-price profile.")
+	fs.BoolVar(&input.IncludeSeasonality, "seasonality", false, "Include the average return by weekday.")
+	fs.StringVar(&input.AsOfDate, "as_of", "", "Reproduce the snapshot as of this YYYY-MM-DD date, ignoring later rows.")
+	fs.StringVar(&input.Benchmark, "benchmark", "", "Benchmark symbol, e.g. SPY, to compute beta against.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if strings.TrimSpace(input.Symbol) == "" {
+		return errors.New("-symbol is required")
+	}
+
+	var opts []marketdata.Option
+	if schemaPath != "" {
+		schema, err := marketdata.ReadSchema(schemaPath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, marketdata.WithSchema(schema))
+	}
+	loader, err := marketdata.NewLoader(dataDir, opts...)
+	if err != nil {
+		return err
+	}
+	out := loader.Snapshot(ctx, input)
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	if !out.HasData {
+		return errNoSnapshot
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunSnapshot_PrintsOutput(t *testing.T) {
+	dataDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dataDir, "historical"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	csv := "Date,Close,High,Low,Open,Volume\n2025-01-01,450.00,452.00,448.00,449.00,1000000\n2025-01-02,451.00,452.00,448.00,449.00,1000000\n2025-01-03,453.00,454.00,450.00,451.00,1000000\n"
+	if err := os.WriteFile(filepath.Join(dataDir, "historical", "SPY_2025-01-01.csv"), []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if err := runSnapshot(context.Background(), dataDir, "", []string{"-symbol", "spy", "-window", "2"}, &stdout, io.Discard); err != nil {
+		t.Fatalf("runSnapshot: %v", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", stdout.String(), err)
+	}
+	if out["hasData"] != true || out["close"] != 453.0 {
+		t.Errorf("Expected the SPY snapshot, got %v", out)
+	}
+
+	stdout.Reset()
+	err := runSnapshot(context.Background(), dataDir, "", []string{"-symbol", "QQQ"}, &stdout, io.Discard)
+	if !errors.Is(err, errNoSnapshot) || stdout.Len() == 0 {
+		t.Errorf("Expected a printed empty snapshot and errNoSnapshot, got %v", err)
+	}
+}
+
+func TestRunSnapshot_RejectsBadArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-window", "10"},
+		{"-symbol", "SPY", "extra"},
+		{"-symbol", "SPY", "-window", "ten"},
+	} {
+		if err := runSnapshot(context.Background(), t.TempDir(), "", args, io.Discard, io.Discard); err == nil {
+			t.Errorf("Expected an error for %q", args)
+		}
+	}
+}
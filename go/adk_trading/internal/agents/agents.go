@@ -4,14 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+	"github.com/igorganapolsky/trading/adk_trading/internal/symbols"
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/backtest"
 	"github.com/igorganapolsky/trading/adk_trading/internal/tools/bias"
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/correlation"
 	"github.com/igorganapolsky/trading/adk_trading/internal/tools/logging"
 	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/netting"
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/pnl"
 	"github.com/igorganapolsky/trading/adk_trading/internal/tools/risk"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
@@ -22,12 +29,68 @@ import (
 	"google.golang.org/genai"
 )
 
+// Sentinel errors returned (wrapped) by BuildTradingOrchestrator so callers can branch with errors.Is.
+var (
+	ErrMissingAPIKey      = errors.New("missing Google API key")
+	ErrModelInit          = errors.New("gemini model initialization failed")
+	ErrDataDirUnavailable = errors.New("data directory unavailable")
+)
+
+// Trading modes accepted by Config.Mode.
+const (
+	ModePaper = "paper"
+	ModeLive  = "live"
+)
+
 type Config struct {
 	AppName               string
 	ModelName             string
-	DataDir               string
+	APIKeyFile            string  // path to a file holding the Gemini API key; overrides GOOGLE_API_KEY_FILE and GOOGLE_API_KEY
+	Mode                  string  // ModePaper (default) or ModeLive
+	PaperPositionCap      float64 // optional tighter single-position cap applied in paper mode
+	DataDir               string  // one or more directories separated by the OS path list separator, searched in order
 	LogPath               string
+	ResultsDir            string // directory for daily orchestration result files; empty uses LogPath's directory
 	PortfolioValue        float64
+	ModelInitAttempts     int           // attempts at creating the Gemini model; zero uses the default of 3
+	ModelInitBaseDelay    time.Duration // delay before the first retry, doubled after each transient failure
+	MarketDataCacheSize   int           // zero keeps the marketdata default, negative disables caching
+	CSVSchemaPath         string        // optional JSON descriptor mapping CSV columns to OHLCV fields; empty detects them from headers
+	SymbolAliasesPath     string        // optional JSON file mapping symbol variants (BRK.B, BRKB) to the canonical symbol files use
+	MinAverageDailyVolume float64       // zero keeps the marketdata default illiquidity threshold
+	MaxDataAge            time.Duration // zero keeps the marketdata default age beyond which snapshots are stale
+	MinWindow             int           // fewest rows a market snapshot is computed from; zero keeps the marketdata default of 2
+	MinSeasonalitySamples int           // fewest returns a weekday needs in snapshot seasonality; zero keeps the marketdata default
+	ScoreTrendWeight      float64       // trend weight of the snapshot composite score; all three zero keep the marketdata defaults
+	ScoreRSIWeight        float64       // RSI weight of the snapshot composite score
+	ScoreVolumeWeight     float64       // volume weight of the snapshot composite score
+	MaxPositionFraction   float64       // zero keeps the risk default 10% single-position cap
+	SectorCap             float64       // zero keeps the risk default sector cap
+	AllowedSymbols        []string      // when non-empty, risk_budget_check rejects any other symbol
+	BlockedSymbols        []string      // symbols risk_budget_check always rejects as restricted
+	RiskOverridesPath     string        // optional JSON file of per-symbol risk thresholds
+	RiskOverridesReload   time.Duration // how often the overrides file is re-read; zero reads it once
+	RiskProfilesPath      string        // optional JSON file of named risk profiles selectable per request
+	ApprovalURL           string        // POST approved positions above ApprovalThreshold here for human sign-off; empty disables
+	ApprovalThreshold     float64       // position size, in dollars, above which ApprovalURL must approve
+	ApprovalTimeout       time.Duration // how long to wait for the approver; zero keeps the risk default
+	ShortCap              float64       // zero keeps the risk default short exposure cap
+	ReversalCooldown      time.Duration // zero keeps the risk default window for flagging flip-flopping trades
+	BetaReviewThreshold   float64       // when positive, risk sends approvals of market-driven names with a higher beta to REVIEW
+	ParallelResearch      bool          // run research_agent and sentiment_agent concurrently before the signal stage
+	BiasRefreshInterval   time.Duration // serve bias snapshots from memory, reloading the store this often; zero reads it per call
+	LogRotateBytes        int64         // rotate the audit log before it grows past this size; zero never rotates
+	LogRotateKeep         int           // rotations kept; zero keeps the logging default
+	LogCompressRotations  bool          // gzip rotations older than the first in the background
+	LogDedupWindow        time.Duration // skip identical consecutive log_trade_decision entries within this window; zero disables
+	LogSizeTolerance      float64       // relative executed vs approved size difference flagged as a mismatch; zero keeps the logging default
+	LogMinConfidence      float64       // skip logging decisions below this confidence, and HOLDs once it is above zero; zero logs everything
+	DecisionDBPath        string        // also insert logged decisions into this SQLite database; empty disables
+	DecisionDBOnly        bool          // write decisions only to DecisionDBPath, not the LogPath JSONL file
+	ToolRateLimit         float64       // tool calls a second allowed before calls return "throttled" instead of running; zero disables
+	ToolRateBurst         int           // tool calls allowed in a burst above ToolRateLimit; zero allows one second's worth
+	ToolRatePerTool       bool          // give each tool its own ToolRateLimit instead of one budget shared by every tool
+	SyslogAddr            string        // also send audit entries to syslog: "udp://host:514", "tcp://host:601", "unix:///dev/log" or "local"; empty disables
 	ObservabilityRecorder *observability.Recorder
 }
 
@@ -39,91 +102,454 @@ func (c Config) validate() error {
 		return errors.New("model name is required")
 	}
 	if strings.TrimSpace(c.DataDir) == "" {
-		return errors.New("data directory is required")
+		return fmt.Errorf("%w: data directory is required", ErrDataDirUnavailable)
 	}
-	if _, err := os.Stat(c.DataDir); err != nil {
-		return fmt.Errorf("data directory not available: %w", err)
+	for _, dir := range filepath.SplitList(c.DataDir) {
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("%w: %w", ErrDataDirUnavailable, err)
+		}
 	}
 	if strings.TrimSpace(c.LogPath) == "" {
 		return errors.New("log path is required")
 	}
+	switch c.Mode {
+	case "", ModePaper, ModeLive:
+	default:
+		return fmt.Errorf("mode must be %q or %q, got %q", ModePaper, ModeLive, c.Mode)
+	}
 	return nil
 }
 
 func BuildTradingOrchestrator(ctx context.Context, cfg Config) (agent.Agent, []agent.Agent, error) {
+	root, subAgents, _, err := buildTradingOrchestrator(ctx, cfg)
+	return root, subAgents, err
+}
+
+// buildTradingOrchestrator is BuildTradingOrchestrator that also returns the
+// agents' tools, so a caller that is not going to serve can close their sinks.
+func buildTradingOrchestrator(ctx context.Context, cfg Config) (agent.Agent, []agent.Agent, *toolset, error) {
 	if err := cfg.validate(); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if cfg.PortfolioValue <= 0 {
 		cfg.PortfolioValue = 1_000_000
 	}
-
-	apiKey := strings.TrimSpace(os.Getenv("GOOGLE_API_KEY"))
-	if apiKey == "" {
-		return nil, nil, errors.New("GOOGLE_API_KEY environment variable is required for ADK agents")
+	if cfg.Mode == "" {
+		cfg.Mode = ModePaper
 	}
 
-	geminiModel, err := gemini.NewModel(ctx, cfg.ModelName, &genai.ClientConfig{
-		APIKey: apiKey,
-	})
+	apiKey, err := resolveAPIKey(cfg.APIKeyFile)
 	if err != nil {
-		return nil, nil, fmt.Errorf("create gemini model: %w", err)
+		return nil, nil, nil, err
 	}
 
-	marketTool, err := marketdata.New(cfg.DataDir)
+	geminiModel, err := newModel(ctx, cfg, apiKey)
 	if err != nil {
-		return nil, nil, fmt.Errorf("market data tool: %w", err)
+		return nil, nil, nil, err
 	}
 
 	biasDir := os.Getenv("BIAS_DATA_DIR")
 	if strings.TrimSpace(biasDir) == "" {
-		biasDir = filepath.Join(cfg.DataDir, "bias")
+		biasDir = filepath.Join(filepath.SplitList(cfg.DataDir)[0], "bias")
+	}
+	// A bias service URL takes precedence over the file-based store.
+	if biasURL := strings.TrimSpace(os.Getenv("BIAS_URL")); biasURL != "" {
+		biasDir = biasURL
+	}
+	biasErr := bias.Validate(biasDir)
+	if biasErr != nil {
+		slog.Warn("bias store unavailable; get_bias_snapshot will return empty snapshots", "error", biasErr)
+	}
+	if cfg.ObservabilityRecorder != nil {
+		cfg.ObservabilityRecorder.SetBiasStoreHealth(biasErr)
+	}
+	tools, err := newToolset(ctx, cfg, biasDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	beforeTool, err := toolCallbacks(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	afterTool := afterToolCallbacks(cfg)
+
+	researchAgent, err := newResearchAgent(geminiModel, tools.market, tools.peers, tools.bias, beforeTool, afterTool)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The first stage is research alone, or research fanned out alongside an
+	// independent sentiment pass when ParallelResearch is set.
+	firstStage := researchAgent
+	if cfg.ParallelResearch {
+		sentimentAgent, err := newSentimentAgent(geminiModel, tools.bias, beforeTool, afterTool)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		firstStage, err = newFanoutAgent(
+			"research_fanout",
+			"Runs research_agent and sentiment_agent concurrently and returns both findings.",
+			fanoutMember{agent: researchAgent, outputKey: researchOutputKey},
+			fanoutMember{agent: sentimentAgent, outputKey: sentimentOutputKey},
+		)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("research fan-out: %w", err)
+		}
+	}
+
+	signalAgent, err := newSignalAgent(geminiModel, tools.market, tools.bias, tools.backtest, beforeTool, afterTool)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	riskAgent, err := newRiskAgent(geminiModel, tools.risk, tools.correlation, tools.pnl, beforeTool, afterTool)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	executionAgent, err := newExecutionAgent(geminiModel, tools.log, tools.netting, tools.entry, beforeTool, afterTool)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	subAgents := []agent.Agent{firstStage, signalAgent, riskAgent, executionAgent}
+	rootAgent, err := newRootAgent(cfg, geminiModel, []tool.Tool{tools.consensus, tools.breadth, tools.explain, tools.results}, beforeTool, afterTool, subAgents...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return rootAgent, subAgents, tools, nil
+}
+
+// toolset holds every tool the orchestrator's agents are given.
+type toolset struct {
+	market, breadth, peers, backtest, correlation, pnl tool.Tool
+	entry                                              tool.Tool
+	bias, consensus                                    tool.Tool
+	log, explain, results, netting                     tool.Tool
+	risk                                               tool.Tool
+	// sinks are the log tool's audit sinks, for closing when the tools are done.
+	sinks []logging.Sink
+}
+
+// newModel creates the Gemini model, retrying transient failures with backoff.
+func newModel(ctx context.Context, cfg Config, apiKey string) (model.LLM, error) {
+	attempts := cfg.ModelInitAttempts
+	if attempts <= 0 {
+		attempts = defaultModelInitAttempts
+	}
+	baseDelay := cfg.ModelInitBaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultModelInitBaseDelay
+	}
+	var geminiModel model.LLM
+	err := retryWithBackoff(ctx, attempts, baseDelay, func() error {
+		var err error
+		geminiModel, err = newGeminiModel(ctx, cfg.ModelName, &genai.ClientConfig{
+			APIKey: apiKey,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: create gemini model %s: %w", ErrModelInit, cfg.ModelName, err)
+	}
+	return geminiModel, nil
+}
+
+// newToolset builds the tools over cfg.DataDir, cfg.LogPath and the bias store in
+// biasDir. Background work started for the tools stops when ctx is done.
+func newToolset(ctx context.Context, cfg Config, biasDir string) (*toolset, error) {
+	var aliases symbols.Aliases
+	if strings.TrimSpace(cfg.SymbolAliasesPath) != "" {
+		var err error
+		if aliases, err = symbols.ReadAliases(cfg.SymbolAliasesPath); err != nil {
+			return nil, err
+		}
+	}
+	marketOpts := []marketdata.Option{marketdata.WithSymbolAliases(aliases)}
+	if cfg.MarketDataCacheSize != 0 {
+		marketOpts = append(marketOpts, marketdata.WithCacheSize(cfg.MarketDataCacheSize))
+	}
+	if cfg.MinAverageDailyVolume > 0 {
+		marketOpts = append(marketOpts, marketdata.WithMinAverageDailyVolume(cfg.MinAverageDailyVolume))
+	}
+	if cfg.MaxDataAge > 0 {
+		marketOpts = append(marketOpts, marketdata.WithMaxDataAge(cfg.MaxDataAge))
+	}
+	if cfg.MinWindow != 0 {
+		marketOpts = append(marketOpts, marketdata.WithMinWindow(cfg.MinWindow))
+	}
+	if cfg.MinSeasonalitySamples != 0 {
+		marketOpts = append(marketOpts, marketdata.WithMinSeasonalitySamples(cfg.MinSeasonalitySamples))
+	}
+	if cfg.ScoreTrendWeight != 0 || cfg.ScoreRSIWeight != 0 || cfg.ScoreVolumeWeight != 0 {
+		marketOpts = append(marketOpts, marketdata.WithCompositeWeights(cfg.ScoreTrendWeight, cfg.ScoreRSIWeight, cfg.ScoreVolumeWeight))
+	}
+	if strings.TrimSpace(cfg.CSVSchemaPath) != "" {
+		schema, err := marketdata.ReadSchema(cfg.CSVSchemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("market data loader: %w", err)
+		}
+		marketOpts = append(marketOpts, marketdata.WithSchema(schema))
+	}
+	marketLoader, err := marketdata.NewLoader(cfg.DataDir, marketOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("market data loader: %w", err)
+	}
+	marketTool, err := marketdata.NewTool(marketLoader)
+	if err != nil {
+		return nil, fmt.Errorf("market data tool: %w", err)
+	}
+	breadthTool, err := marketdata.NewBreadthTool(marketLoader)
+	if err != nil {
+		return nil, fmt.Errorf("market breadth tool: %w", err)
+	}
+
+	entryTool, err := marketdata.NewEntryWindowTool(marketLoader)
+	if err != nil {
+		return nil, fmt.Errorf("entry window tool: %w", err)
+	}
+	peersTool, err := marketdata.NewPeersTool(marketLoader)
+	if err != nil {
+		return nil, fmt.Errorf("peer relative strength tool: %w", err)
+	}
+
+	backtestTool, err := backtest.New(marketLoader, cfg.PortfolioValue)
+	if err != nil {
+		return nil, fmt.Errorf("backtest tool: %w", err)
 	}
-	biasTool, err := bias.New(biasDir)
+
+	correlationTool, err := correlation.New(marketLoader)
 	if err != nil {
-		return nil, nil, fmt.Errorf("bias tool: %w", err)
+		return nil, fmt.Errorf("correlation tool: %w", err)
 	}
 
-	logTool, err := logging.New(cfg.LogPath, cfg.ObservabilityRecorder)
+	pnlTool, err := pnl.New(marketLoader)
 	if err != nil {
-		return nil, nil, fmt.Errorf("logging tool: %w", err)
+		return nil, fmt.Errorf("pnl tool: %w", err)
 	}
 
-	riskTool, err := risk.New(cfg.PortfolioValue)
+	nettingTool, err := netting.New(marketLoader)
 	if err != nil {
-		return nil, nil, fmt.Errorf("risk tool: %w", err)
+		return nil, fmt.Errorf("netting tool: %w", err)
 	}
 
-	researchAgent, err := newResearchAgent(geminiModel, marketTool, biasTool)
+	biasOpts := []bias.Option{bias.WithSymbolAliases(aliases)}
+	if cfg.BiasRefreshInterval > 0 {
+		biasOpts = append(biasOpts, bias.WithRefresh(ctx, cfg.BiasRefreshInterval))
+	}
+	// Both bias tools read one store, so a refresh interval starts one refresher.
+	biasStore := bias.NewStore(biasDir, biasOpts...)
+	biasTool, err := bias.New(biasStore, cfg.ObservabilityRecorder)
+	if err != nil {
+		return nil, fmt.Errorf("bias tool: %w", err)
+	}
+	consensusTool, err := bias.NewConsensus(biasStore)
 	if err != nil {
-		return nil, nil, err
+		return nil, fmt.Errorf("bias consensus tool: %w", err)
 	}
 
-	signalAgent, err := newSignalAgent(geminiModel, marketTool, biasTool)
+	logOpts := []logging.Option{logging.WithMode(cfg.Mode)}
+	if cfg.LogDedupWindow > 0 {
+		logOpts = append(logOpts, logging.WithDedupWindow(cfg.LogDedupWindow))
+	}
+	if cfg.LogSizeTolerance != 0 {
+		logOpts = append(logOpts, logging.WithSizeTolerance(cfg.LogSizeTolerance))
+	}
+	if cfg.LogMinConfidence != 0 {
+		logOpts = append(logOpts, logging.WithMinConfidenceToLog(cfg.LogMinConfidence))
+	}
+	logSinks, err := newLogSinks(cfg)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if cfg.ObservabilityRecorder != nil {
+		cfg.ObservabilityRecorder.SetLogReopener(func() error { return logging.ReopenSinks(logSinks) })
+		cfg.ObservabilityRecorder.SetLogCloser(func() error { return logging.CloseSinks(logSinks) })
+	}
+	logTool, err := logging.New(logSinks, cfg.ObservabilityRecorder, logOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("logging tool: %w", err)
+	}
+	explainTool, err := newExplainTool(cfg, logSinks)
+	if err != nil {
+		return nil, fmt.Errorf("explain tool: %w", err)
 	}
 
-	riskAgent, err := newRiskAgent(geminiModel, riskTool)
+	resultsDir := cfg.ResultsDir
+	if strings.TrimSpace(resultsDir) == "" {
+		resultsDir = filepath.Dir(cfg.LogPath)
+	}
+	resultsTool, err := logging.NewResults(resultsDir, logging.WithMode(cfg.Mode))
 	if err != nil {
-		return nil, nil, err
+		return nil, fmt.Errorf("results tool: %w", err)
 	}
 
-	executionAgent, err := newExecutionAgent(geminiModel, logTool)
+	var riskOpts []risk.Option
+	if cfg.MaxPositionFraction > 0 {
+		riskOpts = append(riskOpts, risk.WithMaxPositionFraction(cfg.MaxPositionFraction))
+	}
+	if cfg.SectorCap > 0 {
+		riskOpts = append(riskOpts, risk.WithSectorCap(cfg.SectorCap))
+	}
+	if cfg.ShortCap > 0 {
+		riskOpts = append(riskOpts, risk.WithShortCap(cfg.ShortCap))
+	}
+	if cfg.ReversalCooldown > 0 {
+		riskOpts = append(riskOpts, risk.WithReversalCooldown(cfg.ReversalCooldown))
+	}
+	if cfg.BetaReviewThreshold > 0 {
+		riskOpts = append(riskOpts, risk.WithBetaReview(cfg.BetaReviewThreshold))
+	}
+	if len(cfg.AllowedSymbols) > 0 || len(cfg.BlockedSymbols) > 0 {
+		riskOpts = append(riskOpts, risk.WithSymbolRestrictions(cfg.AllowedSymbols, cfg.BlockedSymbols))
+	}
+	if strings.TrimSpace(cfg.RiskOverridesPath) != "" {
+		riskOpts = append(riskOpts, risk.WithSymbolOverrides(cfg.RiskOverridesPath, cfg.RiskOverridesReload))
+	}
+	if strings.TrimSpace(cfg.RiskProfilesPath) != "" {
+		profiles, err := risk.ReadProfiles(cfg.RiskProfilesPath)
+		if err != nil {
+			return nil, fmt.Errorf("risk tool: %w", err)
+		}
+		riskOpts = append(riskOpts, risk.WithProfiles(profiles))
+	}
+	if cfg.ObservabilityRecorder != nil {
+		riskOpts = append(riskOpts, risk.WithCircuitBreaker(cfg.ObservabilityRecorder))
+	}
+	if strings.TrimSpace(cfg.ApprovalURL) != "" {
+		riskOpts = append(riskOpts, risk.WithApproval(cfg.ApprovalURL, cfg.ApprovalThreshold, cfg.ApprovalTimeout))
+	}
+	if cfg.Mode == ModePaper && cfg.PaperPositionCap > 0 {
+		riskOpts = append(riskOpts, risk.WithPaperPositionCap(cfg.PaperPositionCap))
+	}
+	riskTool, err := risk.New(cfg.PortfolioValue, riskOpts...)
 	if err != nil {
-		return nil, nil, err
+		return nil, fmt.Errorf("risk tool: %w", err)
 	}
 
-	rootAgent, err := newRootAgent(cfg, geminiModel, researchAgent, signalAgent, riskAgent, executionAgent)
+	return &toolset{
+		market:      marketTool,
+		breadth:     breadthTool,
+		peers:       peersTool,
+		entry:       entryTool,
+		backtest:    backtestTool,
+		correlation: correlationTool,
+		pnl:         pnlTool,
+		bias:        biasTool,
+		consensus:   consensusTool,
+		log:         logTool,
+		netting:     nettingTool,
+		explain:     explainTool,
+		results:     resultsTool,
+		risk:        riskTool,
+		sinks:       logSinks,
+	}, nil
+}
+
+// newLogSinks returns the JSONL file sink for LogPath, unless DecisionDBOnly is set,
+// plus a SQLite sink when DecisionDBPath is set and a syslog sink when SyslogAddr is.
+func newLogSinks(cfg Config) ([]logging.Sink, error) {
+	dbPath := strings.TrimSpace(cfg.DecisionDBPath)
+	if cfg.DecisionDBOnly && dbPath == "" {
+		return nil, errors.New("decision database path is required to log decisions only to it")
+	}
+	// The decision log is hash chained so tampering shows up in VerifyChain.
+	fileOpts := []logging.FileSinkOption{logging.WithHashChain()}
+	if cfg.LogRotateBytes != 0 || cfg.LogRotateKeep != 0 {
+		fileOpts = append(fileOpts, logging.WithRotation(cfg.LogRotateBytes, cfg.LogRotateKeep))
+	}
+	if cfg.LogCompressRotations {
+		fileOpts = append(fileOpts, logging.WithCompressedRotations())
+	}
+	var sinks []logging.Sink
+	if !cfg.DecisionDBOnly {
+		fileSink, err := logging.NewFileSink(cfg.LogPath, fileOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("log file sink: %w", err)
+		}
+		sinks = append(sinks, fileSink)
+	}
+	if dbPath != "" {
+		dbSink, err := logging.NewSQLiteSink(dbPath)
+		if err != nil {
+			logging.CloseSinks(sinks)
+			return nil, fmt.Errorf("sqlite sink: %w", err)
+		}
+		sinks = append(sinks, dbSink)
+	}
+	addr := strings.TrimSpace(cfg.SyslogAddr)
+	if addr == "" {
+		return sinks, nil
+	}
+	var network, raddr string
+	if addr != "local" {
+		var ok bool
+		network, raddr, ok = strings.Cut(addr, "://")
+		if !ok || raddr == "" {
+			logging.CloseSinks(sinks)
+			return nil, fmt.Errorf("syslog address %q must look like udp://host:port, tcp://host:port, unix:///path or local", addr)
+		}
+	}
+	syslogSink, err := logging.NewSyslogSink(network, raddr, sanitizeName(cfg.AppName))
 	if err != nil {
-		return nil, nil, err
+		logging.CloseSinks(sinks)
+		return nil, fmt.Errorf("syslog sink: %w", err)
 	}
+	return append(sinks, syslogSink), nil
+}
 
-	return rootAgent, []agent.Agent{researchAgent, signalAgent, riskAgent, executionAgent}, nil
+// newExplainTool reads decisions back from the LogPath JSONL file, or from the
+// SQLite database when decisions are written only to it.
+func newExplainTool(cfg Config, sinks []logging.Sink) (tool.Tool, error) {
+	if !cfg.DecisionDBOnly {
+		return logging.NewExplain(cfg.LogPath)
+	}
+	for _, sink := range sinks {
+		if db, ok := sink.(*logging.SQLiteSink); ok {
+			return logging.NewSQLiteExplain(db)
+		}
+	}
+	return nil, errors.New("no decision database to explain decisions from")
 }
 
-func newResearchAgent(llm model.LLM, market tool.Tool, bias tool.Tool) (agent.Agent, error) {
-	tools := []tool.Tool{market}
+// resolveAPIKey returns the Gemini API key, preferring an explicit key file, then
+// GOOGLE_API_KEY_FILE, then GOOGLE_API_KEY. The key itself never appears in errors.
+func resolveAPIKey(keyFile string) (string, error) {
+	source := "APIKeyFile"
+	if strings.TrimSpace(keyFile) == "" {
+		keyFile = os.Getenv("GOOGLE_API_KEY_FILE")
+		source = "GOOGLE_API_KEY_FILE"
+	}
+	if strings.TrimSpace(keyFile) != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("%w: read API key file from %s: %w", ErrMissingAPIKey, source, err)
+		}
+		apiKey := strings.TrimSpace(string(data))
+		if apiKey == "" {
+			return "", fmt.Errorf("%w: API key file %s is empty", ErrMissingAPIKey, keyFile)
+		}
+		return apiKey, nil
+	}
+	apiKey := strings.TrimSpace(os.Getenv("GOOGLE_API_KEY"))
+	if apiKey == "" {
+		return "", fmt.Errorf("%w: set GOOGLE_API_KEY or GOOGLE_API_KEY_FILE for ADK agents", ErrMissingAPIKey)
+	}
+	return apiKey, nil
+}
+
+// Session state keys the research-stage agents write their final replies to.
+const (
+	researchOutputKey  = "research_output"
+	sentimentOutputKey = "sentiment_output"
+)
+
+// newGeminiModel is swapped out in tests to simulate model initialization failures.
+var newGeminiModel = gemini.NewModel
+
+func newResearchAgent(llm model.LLM, market, peers, bias tool.Tool, beforeTool []llmagent.BeforeToolCallback, afterTool []llmagent.AfterToolCallback) (agent.Agent, error) {
+	tools := []tool.Tool{market, peers}
 	if bias != nil {
 		tools = append(tools, bias)
 	}
@@ -134,22 +560,62 @@ func newResearchAgent(llm model.LLM, market tool.Tool, bias tool.Tool) (agent.Ag
 		Instruction: strings.TrimSpace(`
 You synthesize recent market structure for the target symbol.
 Always call the get_market_snapshot tool before drafting conclusions to inspect quantitative features.
+If the snapshot reports hasData=false, do not infer a market regime from its zeroed metrics; report the error field instead.
+If it also lists suggestions and one is clearly the intended ticker, retry get_market_snapshot with it and say so.
+If the snapshot reports stale=true, do not draw conclusions from it: set market_regime to "unknown" and state the dataAgeDays so the data feed gets fixed.
+Use volatilityRegime (low, normal, elevated, extreme) rather than the raw volatility number when describing risk conditions.
+When quoting volatility, name its volatilityModel: "ewma" weights recent returns more heavily than the equal-weighted "stddev".
+When quoting sharpeRatio, pass the prevailing annualised riskFreeRate (e.g. 0.05) if known and cite the rate used.
+Pass benchmark "SPY" (or the symbol's index) and include beta and benchmarkVolatility in supporting_metrics.
+Call get_peer_relative_strength with 3-6 close peers (same sector or index) and state where the symbol's trailing return ranks, its relativeVolatility and relativeTrendStrength; name any skipped peers.
 If get_bias_snapshot is available, compare its score with your findings.
 Return a concise JSON object with keys:
   - symbol
-  - market_regime (bullish, bearish, range-bound)
+  - market_regime (bullish, bearish, range-bound, or unknown when data is stale)
   - narrative (two sentences max)
   - supporting_metrics (map of indicator -> value)
 `),
-		Tools: tools,
+		Tools:               tools,
+		BeforeToolCallbacks: beforeTool,
+		AfterToolCallbacks:  afterTool,
+		OutputKey:           researchOutputKey,
+	})
+}
+
+func newSentimentAgent(llm model.LLM, bias tool.Tool, beforeTool []llmagent.BeforeToolCallback, afterTool []llmagent.AfterToolCallback) (agent.Agent, error) {
+	var tools []tool.Tool
+	if bias != nil {
+		tools = append(tools, bias)
+	}
+	return llmagent.New(llmagent.Config{
+		Name:        "sentiment_agent",
+		Model:       llm,
+		Description: "Specialist that summarizes stored news and sentiment bias for a symbol.",
+		Instruction: strings.TrimSpace(`
+You summarize prevailing sentiment for the target symbol.
+If get_bias_snapshot is available, call it and report its direction, conviction and freshness; treat stale snapshots as low signal.
+Do not fetch price data; research_agent covers market structure in parallel.
+Return a concise JSON object with keys:
+  - symbol
+  - sentiment (bullish, bearish, neutral, unknown)
+  - conviction (0-1)
+  - narrative (two sentences max)
+`),
+		Tools:               tools,
+		BeforeToolCallbacks: beforeTool,
+		AfterToolCallbacks:  afterTool,
+		OutputKey:           sentimentOutputKey,
 	})
 }
 
-func newSignalAgent(llm model.LLM, market tool.Tool, bias tool.Tool) (agent.Agent, error) {
+func newSignalAgent(llm model.LLM, market tool.Tool, bias tool.Tool, backtest tool.Tool, beforeTool []llmagent.BeforeToolCallback, afterTool []llmagent.AfterToolCallback) (agent.Agent, error) {
 	tools := []tool.Tool{market}
 	if bias != nil {
 		tools = append(tools, bias)
 	}
+	if backtest != nil {
+		tools = append(tools, backtest)
+	}
 	return llmagent.New(llmagent.Config{
 		Name:        "signal_agent",
 		Model:       llm,
@@ -157,80 +623,129 @@ func newSignalAgent(llm model.LLM, market tool.Tool, bias tool.Tool) (agent.Agen
 		Instruction: strings.TrimSpace(`
 Leverage research_agent findings and get_market_snapshot as needed to produce a trading signal.
 If get_bias_snapshot is available, explicitly state whether you are aligned or deliberately fading it.
+Anchor conviction on the snapshot's compositeScore (-1 bearish to 1 bullish); if your action disagrees with its sign, say why.
+Base exit_plan stops on the snapshot's suggestedStops (pass action to get_market_snapshot for short-side levels) rather than round numbers.
+Use the snapshot's pivots (pivot, r1/r2 resistance, s1/s2 support) as level references for entry_window and targets.
+If backtest_rule is available, sanity-check the entry rule behind your signal (e.g. "buy when ma20>ma50") and cite its hit rate and max drawdown.
 Provide JSON with fields:
   - action (BUY, SELL, HOLD)
   - conviction (0-1)
   - entry_window (price range)
   - exit_plan (targets and stop)
 `),
-		Tools: tools,
+		Tools:               tools,
+		BeforeToolCallbacks: beforeTool,
+		AfterToolCallbacks:  afterTool,
 	})
 }
 
-func newRiskAgent(llm model.LLM, riskTool tool.Tool) (agent.Agent, error) {
+func newRiskAgent(llm model.LLM, riskTool tool.Tool, correlationTool tool.Tool, pnlTool tool.Tool, beforeTool []llmagent.BeforeToolCallback, afterTool []llmagent.AfterToolCallback) (agent.Agent, error) {
+	tools := []tool.Tool{riskTool}
+	if correlationTool != nil {
+		tools = append(tools, correlationTool)
+	}
+	if pnlTool != nil {
+		tools = append(tools, pnlTool)
+	}
 	return llmagent.New(llmagent.Config{
 		Name:        "risk_agent",
 		Model:       llm,
 		Description: "Applies portfolio risk guardrails and position sizing heuristics.",
 		Instruction: strings.TrimSpace(`
 Use the risk_budget_check tool to validate the signal.
+When the request names a risk profile or mandate (e.g. "conservative"), pass it as profile; mention any unknown_profile reason.
+Pass the symbol's sector and current sector exposures when known, and cite the returned sectorExposure against sectorLimit.
+Pass current gross leverage and short exposure when known so sizing respects leverage limits.
+Pass recentActions (symbol, action, timestamp) for trades decided earlier today so flip-flopping is caught.
+Pass the snapshot volatility as volatility and mention its volatilityModel in the rationale when it is "ewma".
+Pass beta and benchmarkVolatility from research when reported; if a market_beta reason comes back, say the trade is mostly market exposure.
+Pass currentDrawdown (e.g. -0.07) when the book is below its peak so sizing is throttled during losing streaks.
+When other positions are in play, call get_correlation_matrix on the basket and flag stacking of highly correlated names.
+When existing positions are known, call mark_positions_to_market and cite the aggregate unrealizedPnl; name any unpriced symbols.
+Pass sharePrice (the snapshot close) and lotSize when known so the size is rounded to tradeable shares.
+For trend-following signals, set sizingMethod to "atr" with entryPrice (the snapshot close) and atr (the snapshot averageTrueRange), and report unitsOfRisk.
+Set sizingUnit to "shares" when the order will be submitted as a share quantity, and report the returned shares; otherwise report positionSize in dollars.
+Set scenarios=true and present the conservative/base/aggressive sizes with their VaR so the PM can choose.
+Pass confidenceSweep (e.g. [0.3, 0.5, 0.7, 0.9]) and present the returned sweep as a table of decision and size by confidence.
+Set explain=true and build the rationale from the returned explanation steps instead of re-deriving the sizing math.
 If the risk decision is not APPROVE, justify what should change.
+If approval is "pending", say the trade is awaiting human approval; if "rejected", quote the approver's reason.
 Respond in JSON:
   - decision (APPROVE, REVIEW, REJECT)
   - position_size
   - rationale
 `),
-		Tools: []tool.Tool{riskTool},
+		Tools:               tools,
+		BeforeToolCallbacks: beforeTool,
+		AfterToolCallbacks:  afterTool,
 	})
 }
 
-func newExecutionAgent(llm model.LLM, logTool tool.Tool) (agent.Agent, error) {
+func newExecutionAgent(llm model.LLM, logTool, nettingTool, entryTool tool.Tool, beforeTool []llmagent.BeforeToolCallback, afterTool []llmagent.AfterToolCallback) (agent.Agent, error) {
 	return llmagent.New(llmagent.Config{
 		Name:        "execution_agent",
 		Model:       llm,
 		Description: "Prepares execution checklist and records the plan.",
 		Instruction: strings.TrimSpace(`
+Call check_entry_window with the signal's entry_window low and high. If the close is above or below the window, prefer a limit order at the window or waiting over chasing, citing distanceAtr in timing_notes.
 Summarize the execution approach, then call log_trade_decision to persist the plan.
+When the session proposes several decisions, call net_trade_decisions on them first and log the netted decisions it returns; mention any offsets it flags in timing_notes.
+Include the risk decision and its approved position_size under metadata.risk, and the size you are executing as metadata.executed_size.
+If log_trade_decision returns sizeMismatch=true, state the divergence in timing_notes.
+If you call log_trade_decision again for the same decision, reuse the same idempotencyKey so the retry is not logged twice.
 Return JSON with:
   - venue_preference
   - order_type
   - timing_notes
   - logging_status
 `),
-		Tools: []tool.Tool{logTool},
+		Tools:               []tool.Tool{entryTool, nettingTool, logTool},
+		BeforeToolCallbacks: beforeTool,
+		AfterToolCallbacks:  afterTool,
 	})
 }
 
-func newRootAgent(cfg Config, llm model.LLM, subAgents ...agent.Agent) (agent.Agent, error) {
-	tools := make([]tool.Tool, 0, len(subAgents))
+func newRootAgent(cfg Config, llm model.LLM, rootTools []tool.Tool, beforeTool []llmagent.BeforeToolCallback, afterTool []llmagent.AfterToolCallback, subAgents ...agent.Agent) (agent.Agent, error) {
+	tools := append(make([]tool.Tool, 0, len(rootTools)+len(subAgents)), rootTools...)
 	for _, sub := range subAgents {
 		tools = append(tools, agenttool.New(sub, nil))
 	}
 
+	researchStep := "Delegate to research_agent to understand symbol state."
+	if cfg.ParallelResearch {
+		researchStep = "Delegate to research_fanout, which runs research_agent and sentiment_agent concurrently, to understand symbol state and sentiment."
+	}
+
 	instruction := strings.TrimSpace(fmt.Sprintf(`
 You are the primary orchestrator for %s.
 Process flow:
-  1. Delegate to research_agent to understand symbol state.
-  2. Delegate to signal_agent to draft the trade idea.
-  3. Delegate to risk_agent to validate risk parameters.
-  4. Delegate to execution_agent to log the plan.
+  1. Call get_bias_consensus for market-wide risk-on/risk-off context and get_market_breadth on the symbol's universe (or a basket of index ETFs), then state how the symbol fits both.
+  2. %s
+  3. Delegate to signal_agent to draft the trade idea.
+  4. Delegate to risk_agent to validate risk parameters.
+  5. Delegate to execution_agent to log the plan.
 Only approve trades when risk_agent returns decision "APPROVE".
+If risk_agent reports "circuit open", stop immediately: skip execution and report that trading is halted pending a reset.
 Final reply must be JSON with keys:
   - symbol
   - trade_summary
   - risk
   - execution
   - next_steps
+Before replying, call persist_orchestration_result with that same JSON; if it reports missing keys, fix them and call it again.
 Ensure the narrative references quantitative metrics retrieved from tools.
-`, cfg.AppName))
+When asked why an earlier trade was placed, call explain_trade_decision with its invocation ID, or its symbol and timestamp, instead of running the process flow again.
+`, cfg.AppName, researchStep))
 
 	return llmagent.New(llmagent.Config{
-		Name:        fmt.Sprintf("%s_root_agent", sanitizeName(cfg.AppName)),
-		Model:       llm,
-		Description: "Coordinates multi-agent trading evaluation loop.",
-		Instruction: instruction,
-		Tools:       tools,
-		SubAgents:   subAgents,
+		Name:                fmt.Sprintf("%s_root_agent", sanitizeName(cfg.AppName)),
+		Model:               llm,
+		Description:         "Coordinates multi-agent trading evaluation loop.",
+		Instruction:         instruction,
+		Tools:               tools,
+		BeforeToolCallbacks: beforeTool,
+		AfterToolCallbacks:  afterTool,
+		SubAgents:           subAgents,
 	})
 }
 
@@ -2,9 +2,12 @@ package agents
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/logging"
 )
 
 func TestConfig_Validate(t *testing.T) {
@@ -66,6 +69,28 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "live mode",
+			config: Config{
+				AppName:   "test_app",
+				ModelName: "gemini-2.5-flash",
+				Mode:      ModeLive,
+				DataDir:   tempDir,
+				LogPath:   logPath,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown mode",
+			config: Config{
+				AppName:   "test_app",
+				ModelName: "gemini-2.5-flash",
+				Mode:      "backtest",
+				DataDir:   tempDir,
+				LogPath:   logPath,
+			},
+			wantErr: true,
+		},
 		{
 			name: "non-existent data dir",
 			config: Config{
@@ -167,7 +192,100 @@ func TestBuildTradingOrchestrator_MissingAPIKey(t *testing.T) {
 	ctx := context.Background()
 	_, _, err := BuildTradingOrchestrator(ctx, config)
 
-	if err == nil {
-		t.Error("Expected error when GOOGLE_API_KEY is not set")
+	if !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("Expected ErrMissingAPIKey when GOOGLE_API_KEY is not set, got %v", err)
+	}
+}
+
+func TestConfig_ValidateDataDirSentinel(t *testing.T) {
+	config := Config{
+		AppName:   "test_app",
+		ModelName: "gemini-2.5-flash",
+		DataDir:   "/nonexistent/path",
+		LogPath:   filepath.Join(t.TempDir(), "test.log"),
+	}
+
+	if err := config.validate(); !errors.Is(err, ErrDataDirUnavailable) {
+		t.Errorf("Expected ErrDataDirUnavailable, got %v", err)
+	}
+}
+
+func TestResolveAPIKey_Precedence(t *testing.T) {
+	tempDir := t.TempDir()
+	flagFile := filepath.Join(tempDir, "flag.key")
+	envFile := filepath.Join(tempDir, "env.key")
+	if err := os.WriteFile(flagFile, []byte("flag-key\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(envFile, []byte("  env-file-key  "), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Setenv("GOOGLE_API_KEY", "env-key")
+	t.Setenv("GOOGLE_API_KEY_FILE", envFile)
+
+	if key, err := resolveAPIKey(flagFile); err != nil || key != "flag-key" {
+		t.Errorf("Expected explicit key file to win, got %q (%v)", key, err)
+	}
+	if key, err := resolveAPIKey(""); err != nil || key != "env-file-key" {
+		t.Errorf("Expected GOOGLE_API_KEY_FILE to win over GOOGLE_API_KEY, got %q (%v)", key, err)
+	}
+	t.Setenv("GOOGLE_API_KEY_FILE", "")
+	if key, err := resolveAPIKey(""); err != nil || key != "env-key" {
+		t.Errorf("Expected GOOGLE_API_KEY fallback, got %q (%v)", key, err)
+	}
+}
+
+func TestResolveAPIKey_UnreadableFile(t *testing.T) {
+	t.Setenv("GOOGLE_API_KEY", "env-key")
+
+	_, err := resolveAPIKey(filepath.Join(t.TempDir(), "missing.key"))
+
+	if !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("Expected ErrMissingAPIKey for an unreadable key file, got %v", err)
+	}
+}
+
+func TestNewLogSinks(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "test.log")
+
+	sinks, err := newLogSinks(Config{AppName: "test_app", LogPath: logPath})
+	if err != nil || len(sinks) != 1 {
+		t.Fatalf("Expected only the file sink without a syslog address, got %v, %v", sinks, err)
+	}
+
+	sinks, err = newLogSinks(Config{AppName: "test_app", LogPath: logPath, SyslogAddr: "udp://127.0.0.1:514"})
+	if err != nil || len(sinks) != 2 {
+		t.Fatalf("Expected file and syslog sinks, got %v, %v", sinks, err)
+	}
+	if sinks[1].Name() != "syslog:udp://127.0.0.1:514" {
+		t.Errorf("Expected the syslog sink named by its address, got %q", sinks[1].Name())
+	}
+	if err := logging.CloseSinks(sinks); err != nil {
+		t.Fatalf("Expected the syslog sink closed with the others, got %v", err)
+	}
+
+	if _, err := newLogSinks(Config{AppName: "test_app", LogPath: logPath, SyslogAddr: "siem:514"}); err == nil {
+		t.Error("Expected an error for a syslog address without a network")
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "decisions.sqlite")
+	sinks, err = newLogSinks(Config{AppName: "test_app", LogPath: logPath, DecisionDBPath: dbPath})
+	if err != nil || len(sinks) != 2 {
+		t.Fatalf("Expected file and SQLite sinks, got %v, %v", sinks, err)
+	}
+	if err := logging.CloseSinks(sinks); err != nil {
+		t.Fatalf("CloseSinks: %v", err)
+	}
+	dbOnly := Config{AppName: "test_app", LogPath: logPath, DecisionDBPath: dbPath, DecisionDBOnly: true}
+	sinks, err = newLogSinks(dbOnly)
+	if err != nil || len(sinks) != 1 || sinks[0].Name() != "sqlite:"+dbPath {
+		t.Fatalf("Expected only the SQLite sink, got %v, %v", sinks, err)
+	}
+	defer logging.CloseSinks(sinks)
+	if explain, err := newExplainTool(dbOnly, sinks); err != nil || explain == nil {
+		t.Errorf("Expected decisions explained from the database, got %v", err)
+	}
+	if _, err := newLogSinks(Config{AppName: "test_app", LogPath: logPath, DecisionDBOnly: true}); err == nil {
+		t.Error("Expected an error logging only to a database without a path")
 	}
 }
@@ -0,0 +1,176 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/symbols"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// DefaultEvaluateTimeout bounds one orchestration served by an Evaluator.
+const DefaultEvaluateTimeout = 2 * time.Minute
+
+// evaluateUser is the session user every evaluation runs as.
+const evaluateUser = "evaluate"
+
+// maxEvaluateBody caps the request body; a symbol needs far less.
+const maxEvaluateBody = 1 << 16
+
+// ErrNoResult is returned when an orchestration finishes without a final reply.
+var ErrNoResult = errors.New("orchestration produced no final reply")
+
+// ErrInvalidSymbol is returned for a symbol that does not look like a ticker,
+// which is refused before it reaches the prompt.
+var ErrInvalidSymbol = errors.New("symbol is not a valid ticker")
+
+// Evaluator runs single orchestrations of the root agent on demand, each in a
+// fresh in-memory session, so callers need not speak the ADK server protocol.
+type Evaluator struct {
+	appName  string
+	runner   *runner.Runner
+	sessions session.Service
+	timeout  time.Duration
+}
+
+// NewEvaluator returns an Evaluator for root. A non-positive timeout uses
+// DefaultEvaluateTimeout.
+func NewEvaluator(appName string, root agent.Agent, timeout time.Duration) (*Evaluator, error) {
+	if root == nil {
+		return nil, errors.New("root agent is required")
+	}
+	if strings.TrimSpace(appName) == "" {
+		appName = "trading_orchestrator"
+	}
+	if timeout <= 0 {
+		timeout = DefaultEvaluateTimeout
+	}
+	sessions := session.InMemoryService()
+	r, err := runner.New(runner.Config{AppName: appName, Agent: root, SessionService: sessions})
+	if err != nil {
+		return nil, fmt.Errorf("create evaluation runner: %w", err)
+	}
+	return &Evaluator{appName: appName, runner: r, sessions: sessions, timeout: timeout}, nil
+}
+
+// Evaluate runs one orchestration for symbol and returns the root agent's final
+// reply text. It gives up with the context's error once the timeout elapses.
+func (e *Evaluator) Evaluate(ctx context.Context, symbol string) (string, error) {
+	if !symbols.Valid(symbol) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSymbol, symbol)
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	created, err := e.sessions.Create(ctx, &session.CreateRequest{AppName: e.appName, UserID: evaluateUser})
+	if err != nil {
+		return "", fmt.Errorf("create session: %w", err)
+	}
+	sessionID := created.Session.ID()
+	defer e.sessions.Delete(context.Background(), &session.DeleteRequest{AppName: e.appName, UserID: evaluateUser, SessionID: sessionID})
+
+	prompt := genai.NewContentFromText(fmt.Sprintf("Evaluate a trade for %s.", symbol), genai.RoleUser)
+	var reply string
+	for event, err := range e.runner.Run(ctx, evaluateUser, sessionID, prompt, agent.RunConfig{}) {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		if err != nil {
+			return "", err
+		}
+		if text := eventText(event); text != "" && !event.Partial {
+			reply = text
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if reply == "" {
+		return "", ErrNoResult
+	}
+	return reply, nil
+}
+
+// ServeHTTP handles POST {"symbol": "SPY"} by running one orchestration and
+// writing its final JSON result. It answers 400 when the symbol is not a ticker,
+// 504 when the timeout elapses and 502 when the final reply is missing or not JSON.
+func (e *Evaluator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "evaluate requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxEvaluateBody)).Decode(&body); err != nil {
+		http.Error(w, "body must be a JSON object with a symbol", http.StatusBadRequest)
+		return
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(body.Symbol))
+	if symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+
+	reply, err := e.Evaluate(req.Context(), symbol)
+	switch {
+	case errors.Is(err, ErrInvalidSymbol):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, fmt.Sprintf("evaluation of %s timed out after %s", symbol, e.timeout), http.StatusGatewayTimeout)
+		return
+	case errors.Is(err, ErrNoResult):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := json.RawMessage(stripCodeFence(reply))
+	if !json.Valid(result) {
+		// The reply can be long and is model output; it goes to the log, not the caller.
+		slog.Warn("evaluate reply is not JSON", "symbol", symbol, "reply", reply)
+		http.Error(w, "final reply is not JSON", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(append(result, '\n'))
+}
+
+// eventText joins the text parts of event's content.
+func eventText(event *session.Event) string {
+	if event == nil || event.Content == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range event.Content.Parts {
+		if part != nil {
+			b.WriteString(part.Text)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// stripCodeFence removes the ```json fence models often wrap JSON replies in.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
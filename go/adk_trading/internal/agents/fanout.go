@@ -0,0 +1,67 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/workflowagents/parallelagent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// fanoutMember is a sub-agent dispatched by a fan-out together with the session
+// state key its llmagent.Config.OutputKey writes its final reply to.
+type fanoutMember struct {
+	agent     agent.Agent
+	outputKey string
+}
+
+// newFanoutAgent runs independent sub-agents concurrently, one goroutine each, and
+// once every member has finished emits a single reply that joins their outputs in
+// declaration order, so callers see the same merged result however the runs interleave.
+func newFanoutAgent(name, description string, members ...fanoutMember) (agent.Agent, error) {
+	if len(members) == 0 {
+		return nil, errors.New("fan-out requires at least one sub-agent")
+	}
+	subAgents := make([]agent.Agent, 0, len(members))
+	for _, m := range members {
+		if m.outputKey == "" {
+			return nil, fmt.Errorf("fan-out member %s has no output key", m.agent.Name())
+		}
+		subAgents = append(subAgents, m.agent)
+	}
+	return parallelagent.New(parallelagent.Config{
+		AgentConfig: agent.Config{
+			Name:                name,
+			Description:         description,
+			SubAgents:           subAgents,
+			AfterAgentCallbacks: []agent.AfterAgentCallback{mergeFanoutOutputs(members)},
+		},
+	})
+}
+
+// mergeFanoutOutputs reads each member's output from session state and joins them,
+// labelled by agent name, in the order the members were declared.
+func mergeFanoutOutputs(members []fanoutMember) agent.AfterAgentCallback {
+	return func(ctx agent.CallbackContext) (*genai.Content, error) {
+		var b strings.Builder
+		for _, m := range members {
+			value, err := ctx.State().Get(m.outputKey)
+			if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
+				return nil, fmt.Errorf("read %s output: %w", m.agent.Name(), err)
+			}
+			if b.Len() > 0 {
+				b.WriteString("\n\n")
+			}
+			fmt.Fprintf(&b, "## %s\n", m.agent.Name())
+			if value == nil {
+				b.WriteString("(no output)")
+				continue
+			}
+			fmt.Fprintf(&b, "%v", value)
+		}
+		return genai.NewContentFromText(b.String(), genai.RoleModel), nil
+	}
+}
@@ -0,0 +1,123 @@
+package agents
+
+import (
+	"context"
+	"iter"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// replyLLM answers every request with a fixed text after an optional delay.
+type replyLLM struct {
+	text  string
+	delay time.Duration
+}
+
+func (r replyLLM) Name() string { return "reply" }
+
+func (r replyLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		time.Sleep(r.delay)
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(r.text, genai.RoleModel)}, nil)
+	}
+}
+
+func newReplyAgent(t *testing.T, name, outputKey string, llm model.LLM) agent.Agent {
+	t.Helper()
+	a, err := llmagent.New(llmagent.Config{Name: name, Model: llm, OutputKey: outputKey})
+	if err != nil {
+		t.Fatalf("llmagent.New: %v", err)
+	}
+	return a
+}
+
+func TestFanoutAgent_MergesInDeclarationOrder(t *testing.T) {
+	// The first member finishes last, so a merge in completion order would be reversed.
+	slow := newReplyAgent(t, "slow_agent", "slow_output", replyLLM{text: "slow findings", delay: 20 * time.Millisecond})
+	fast := newReplyAgent(t, "fast_agent", "fast_output", replyLLM{text: "fast findings"})
+	fanout, err := newFanoutAgent("fanout", "test fan-out",
+		fanoutMember{agent: slow, outputKey: "slow_output"},
+		fanoutMember{agent: fast, outputKey: "fast_output"},
+	)
+	if err != nil {
+		t.Fatalf("newFanoutAgent: %v", err)
+	}
+
+	ctx := context.Background()
+	sessions := session.InMemoryService()
+	if _, err := sessions.Create(ctx, &session.CreateRequest{AppName: "test", UserID: "u", SessionID: "s"}); err != nil {
+		t.Fatalf("Create session: %v", err)
+	}
+	r, err := runner.New(runner.Config{AppName: "test", Agent: fanout, SessionService: sessions})
+	if err != nil {
+		t.Fatalf("runner.New: %v", err)
+	}
+
+	var last *session.Event
+	for event, err := range r.Run(ctx, "u", "s", genai.NewContentFromText("go", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		last = event
+	}
+
+	if last == nil || last.Author != "fanout" || last.Content == nil || len(last.Content.Parts) == 0 {
+		t.Fatalf("Expected a merged reply authored by the fan-out, got %+v", last)
+	}
+	merged := last.Content.Parts[0].Text
+	slowAt, fastAt := strings.Index(merged, "slow findings"), strings.Index(merged, "fast findings")
+	if slowAt < 0 || fastAt < 0 || slowAt > fastAt {
+		t.Errorf("Expected both outputs in declaration order, got %q", merged)
+	}
+}
+
+func TestNewFanoutAgent_RequiresOutputKeys(t *testing.T) {
+	a := newReplyAgent(t, "a", "", replyLLM{})
+	if _, err := newFanoutAgent("fanout", "", fanoutMember{agent: a}); err == nil {
+		t.Error("Expected an error for a member without an output key")
+	}
+	if _, err := newFanoutAgent("fanout", ""); err == nil {
+		t.Error("Expected an error for an empty fan-out")
+	}
+}
+
+func TestBuildTradingOrchestrator_ParallelResearch(t *testing.T) {
+	t.Setenv("GOOGLE_API_KEY", "test-key")
+	original := newGeminiModel
+	defer func() { newGeminiModel = original }()
+	newGeminiModel = func(ctx context.Context, name string, cfg *genai.ClientConfig) (model.LLM, error) {
+		return fakeLLM{}, nil
+	}
+
+	tempDir := t.TempDir()
+	_, subAgents, err := BuildTradingOrchestrator(context.Background(), Config{
+		AppName:          "test_app",
+		ModelName:        "gemini-2.5-flash",
+		DataDir:          tempDir,
+		LogPath:          filepath.Join(tempDir, "test.log"),
+		ParallelResearch: true,
+	})
+	if err != nil {
+		t.Fatalf("BuildTradingOrchestrator: %v", err)
+	}
+
+	if subAgents[0].Name() != "research_fanout" {
+		t.Fatalf("Expected research_fanout as the first stage, got %s", subAgents[0].Name())
+	}
+	var members []string
+	for _, sub := range subAgents[0].SubAgents() {
+		members = append(members, sub.Name())
+	}
+	if strings.Join(members, ",") != "research_agent,sentiment_agent" {
+		t.Errorf("Expected research and sentiment members, got %v", members)
+	}
+}
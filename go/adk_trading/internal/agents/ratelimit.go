@@ -0,0 +1,111 @@
+package agents
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/tool"
+)
+
+// sharedBucket keys the single bucket every tool draws from unless limits are per tool.
+const sharedBucket = ""
+
+// toolLimiter is a token bucket throttling tool calls, so a runaway agent loop
+// cannot exhaust the model quota or hammer the disk. The bucket refills at rate
+// tokens a second up to burst, and each call takes one token.
+type toolLimiter struct {
+	rate     float64
+	burst    float64
+	perTool  bool
+	now      func() time.Time
+	recorder *observability.Recorder
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newToolLimiter returns a limiter allowing rate calls a second with bursts of
+// burst, shared by every tool or, when perTool is set, for each tool separately.
+// A zero burst allows one second's worth of calls, at least one.
+func newToolLimiter(rate float64, burst int, perTool bool, recorder *observability.Recorder) (*toolLimiter, error) {
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return nil, fmt.Errorf("tool rate limit must be positive, got %g", rate)
+	}
+	if burst < 0 {
+		return nil, fmt.Errorf("tool rate burst must not be negative, got %d", burst)
+	}
+	if burst == 0 {
+		burst = max(1, int(math.Ceil(rate)))
+	}
+	return &toolLimiter{
+		rate:     rate,
+		burst:    float64(burst),
+		perTool:  perTool,
+		now:      time.Now,
+		recorder: recorder,
+		buckets:  map[string]*tokenBucket{},
+	}, nil
+}
+
+// allow takes a token for a call of the named tool, reporting false when its
+// bucket is empty.
+func (l *toolLimiter) allow(name string) bool {
+	key := sharedBucket
+	if l.perTool {
+		key = name
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// beforeTool is an llmagent.BeforeToolCallback that answers a throttled call with
+// a "throttled" result instead of running the tool.
+func (l *toolLimiter) beforeTool(ctx tool.Context, t tool.Tool, _ map[string]any) (map[string]any, error) {
+	if l.allow(t.Name()) {
+		return nil, nil
+	}
+	if l.recorder != nil {
+		l.recorder.RecordThrottle(t.Name(), ctx.InvocationID())
+	}
+	return map[string]any{
+		"status": "throttled",
+		"error":  fmt.Sprintf("%s was not run: tool calls are limited to %g per second; wait before retrying and avoid repeating calls", t.Name(), l.rate),
+	}, nil
+}
+
+// toolCallbacks returns the callbacks every agent runs before its tools: the rate
+// limiter's, when cfg sets a limit.
+func toolCallbacks(cfg Config) ([]llmagent.BeforeToolCallback, error) {
+	if cfg.ToolRateLimit == 0 {
+		return nil, nil
+	}
+	limiter, err := newToolLimiter(cfg.ToolRateLimit, cfg.ToolRateBurst, cfg.ToolRatePerTool, cfg.ObservabilityRecorder)
+	if err != nil {
+		return nil, err
+	}
+	return []llmagent.BeforeToolCallback{limiter.beforeTool}, nil
+}
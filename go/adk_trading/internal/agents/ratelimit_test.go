@@ -0,0 +1,77 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+	"google.golang.org/adk/tool"
+)
+
+// namedTool is a tool.Tool with only a name, enough for the limiter.
+type namedTool struct {
+	tool.Tool
+	name string
+}
+
+func (t namedTool) Name() string { return t.name }
+
+// invocationContext is a tool.Context reporting only an invocation ID.
+type invocationContext struct {
+	tool.Context
+}
+
+func (invocationContext) InvocationID() string { return "inv-1" }
+
+func TestToolLimiter_ThrottlesBeyondBurst(t *testing.T) {
+	recorder := observability.NewRecorder(":0")
+	limiter, err := newToolLimiter(2, 3, false, recorder)
+	if err != nil {
+		t.Fatalf("newToolLimiter: %v", err)
+	}
+	now := time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	market, logTool := namedTool{name: "get_market_snapshot"}, namedTool{name: "log_trade_decision"}
+
+	for i := 0; i < 3; i++ {
+		if out, err := limiter.beforeTool(invocationContext{}, market, nil); out != nil || err != nil {
+			t.Fatalf("Expected call %d within the burst to run, got %v %v", i, out, err)
+		}
+	}
+	// The bucket is shared, so another tool is throttled too.
+	out, err := limiter.beforeTool(invocationContext{}, logTool, nil)
+	if err != nil || out["status"] != "throttled" || !strings.Contains(out["error"].(string), "log_trade_decision") {
+		t.Fatalf("Expected a throttled result naming the tool, got %v %v", out, err)
+	}
+
+	// Half a second refills one token at two a second.
+	now = now.Add(500 * time.Millisecond)
+	if !limiter.allow("get_market_snapshot") || limiter.allow("get_market_snapshot") {
+		t.Error("Expected exactly one call after refilling one token")
+	}
+}
+
+func TestToolLimiter_PerTool(t *testing.T) {
+	limiter, err := newToolLimiter(1, 0, true, nil)
+	if err != nil {
+		t.Fatalf("newToolLimiter: %v", err)
+	}
+	limiter.now = func() time.Time { return time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC) }
+	if !limiter.allow("a") || !limiter.allow("b") {
+		t.Error("Expected each tool to have its own bucket")
+	}
+	if limiter.allow("a") {
+		t.Error("Expected a's one-call burst to be spent")
+	}
+
+	if _, err := newToolLimiter(-1, 0, false, nil); err == nil {
+		t.Error("Expected an error for a negative rate")
+	}
+	if _, err := newToolLimiter(1, -1, false, nil); err == nil {
+		t.Error("Expected an error for a negative burst")
+	}
+	if callbacks, err := toolCallbacks(Config{}); err != nil || callbacks != nil {
+		t.Errorf("Expected no callbacks without a limit, got %v %v", callbacks, err)
+	}
+}
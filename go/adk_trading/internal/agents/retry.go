@@ -0,0 +1,64 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	defaultModelInitAttempts  = 3
+	defaultModelInitBaseDelay = 500 * time.Millisecond
+	maxModelInitDelay         = 30 * time.Second
+)
+
+// retryWithBackoff calls fn up to attempts times, doubling the delay after each
+// transient failure. It stops early on non-transient errors and when ctx is done.
+func retryWithBackoff(ctx context.Context, attempts int, baseDelay time.Duration, fn func() error) error {
+	if attempts <= 0 {
+		attempts = 1
+	}
+	delay := baseDelay
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == attempts || !isTransient(err) {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		delay *= 2
+		if delay > maxModelInitDelay {
+			delay = maxModelInitDelay
+		}
+	}
+	return err
+}
+
+// isTransient reports whether err is worth retrying: network failures, timeouts,
+// throttling and server errors. Authentication and other client errors are not.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
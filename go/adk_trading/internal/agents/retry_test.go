@@ -0,0 +1,129 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestRetryWithBackoff_RetriesTransientErrors(t *testing.T) {
+	calls := 0
+	err := retryWithBackoff(context.Background(), 3, time.Millisecond, func() error {
+		calls++
+		if calls < 3 {
+			return &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected success on the third attempt, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryWithBackoff_DoesNotRetryAuthFailures(t *testing.T) {
+	calls := 0
+	authErr := genai.APIError{Code: 403, Message: "permission denied"}
+	err := retryWithBackoff(context.Background(), 5, time.Millisecond, func() error {
+		calls++
+		return authErr
+	})
+
+	if calls != 1 {
+		t.Errorf("Expected a single attempt for an auth failure, got %d", calls)
+	}
+	if !errors.As(err, &genai.APIError{}) {
+		t.Errorf("Expected the auth error to be returned, got %v", err)
+	}
+}
+
+func TestRetryWithBackoff_StopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := retryWithBackoff(ctx, 5, time.Hour, func() error {
+		calls++
+		cancel()
+		return genai.APIError{Code: 503}
+	})
+
+	if calls != 1 {
+		t.Errorf("Expected cancellation to stop retries after 1 call, got %d", calls)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled in the returned error, got %v", err)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limited", genai.APIError{Code: 429}, true},
+		{"server error", genai.APIError{Code: 503}, true},
+		{"unauthorized", genai.APIError{Code: 401}, false},
+		{"bad request", genai.APIError{Code: 400}, false},
+		{"network", &net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		{"plain", errors.New("invalid api key"), false},
+		{"canceled", context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeLLM struct{}
+
+func (fakeLLM) Name() string { return "fake" }
+
+func (fakeLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {}
+}
+
+func TestBuildTradingOrchestrator_RetriesModelInit(t *testing.T) {
+	t.Setenv("GOOGLE_API_KEY", "test-key")
+	original := newGeminiModel
+	defer func() { newGeminiModel = original }()
+
+	calls := 0
+	newGeminiModel = func(ctx context.Context, name string, cfg *genai.ClientConfig) (model.LLM, error) {
+		calls++
+		if calls < 3 {
+			return nil, genai.APIError{Code: 503, Message: "unavailable"}
+		}
+		return fakeLLM{}, nil
+	}
+
+	tempDir := t.TempDir()
+	_, _, err := BuildTradingOrchestrator(context.Background(), Config{
+		AppName:            "test_app",
+		ModelName:          "gemini-2.5-flash",
+		DataDir:            tempDir,
+		LogPath:            filepath.Join(tempDir, "test.log"),
+		ModelInitAttempts:  3,
+		ModelInitBaseDelay: time.Millisecond,
+	})
+
+	if err != nil {
+		t.Fatalf("Expected orchestrator to build after transient failures, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 model init attempts, got %d", calls)
+	}
+}
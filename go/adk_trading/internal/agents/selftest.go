@@ -0,0 +1,296 @@
+package agents
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/bias"
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/logging"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// selfTestSymbols are the fixture tickers written for the tool checks.
+var selfTestSymbols = []string{"SELFTEST", "SELFTESTB"}
+
+// selfTestBars is how many daily bars each fixture holds: enough for ma100.
+const selfTestBars = 120
+
+// SelfTestResult is the outcome of one self-test check.
+type SelfTestResult struct {
+	Name    string
+	Err     error
+	Elapsed time.Duration
+}
+
+// SelfTestReport lists every check run by SelfTest, in order.
+type SelfTestReport []SelfTestResult
+
+// Failed counts the checks that did not pass.
+func (r SelfTestReport) Failed() int {
+	failed := 0
+	for _, result := range r {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// String renders one line per check followed by a pass/fail total.
+func (r SelfTestReport) String() string {
+	var b strings.Builder
+	for _, result := range r {
+		status := "ok  "
+		if result.Err != nil {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "%s %-26s %8s", status, result.Name, result.Elapsed.Round(time.Millisecond))
+		if result.Err != nil {
+			fmt.Fprintf(&b, "  %v", result.Err)
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%d/%d checks passed\n", len(r)-r.Failed(), len(r))
+	return b.String()
+}
+
+// SelfTest checks the orchestrator is ready to serve: it builds the orchestrator
+// from cfg, asks the model for a reply, and runs every tool once against synthetic
+// fixture data in a scratch directory so cfg's data and audit log are untouched.
+// Nothing is served.
+func SelfTest(ctx context.Context, cfg Config) SelfTestReport {
+	var report SelfTestReport
+	check := func(name string, fn func() error) {
+		start := time.Now()
+		err := func() (err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					err = fmt.Errorf("panic: %v", p)
+				}
+			}()
+			return fn()
+		}()
+		report = append(report, SelfTestResult{Name: name, Err: err, Elapsed: time.Since(start)})
+	}
+
+	check("build_orchestrator", func() error {
+		_, _, tools, err := buildTradingOrchestrator(ctx, cfg)
+		if tools != nil {
+			logging.CloseSinks(tools.sinks)
+		}
+		return err
+	})
+
+	check("model_response", func() error {
+		apiKey, err := resolveAPIKey(cfg.APIKeyFile)
+		if err != nil {
+			return err
+		}
+		llm, err := newModel(ctx, cfg, apiKey)
+		if err != nil {
+			return err
+		}
+		return pingModel(ctx, llm, cfg.ModelName)
+	})
+
+	scratch, err := os.MkdirTemp("", "adk-selftest-")
+	if err != nil {
+		check("fixture_data", func() error { return err })
+		return report
+	}
+	defer os.RemoveAll(scratch)
+
+	var tools *toolset
+	check("fixture_tools", func() error {
+		if err := writeSelfTestFixtures(scratch, time.Now().UTC()); err != nil {
+			return err
+		}
+		tools, err = newToolset(ctx, selfTestConfig(cfg, scratch), filepath.Join(scratch, "bias"))
+		return err
+	})
+	if tools == nil {
+		return report
+	}
+	defer logging.CloseSinks(tools.sinks)
+
+	symbol := selfTestSymbols[0]
+	calls := []struct {
+		tool tool.Tool
+		args map[string]any
+	}{
+		{tools.market, map[string]any{"symbol": symbol}},
+		{tools.breadth, map[string]any{"symbols": selfTestSymbols}},
+		{tools.peers, map[string]any{"symbol": symbol, "peers": selfTestSymbols[1:]}},
+		{tools.entry, map[string]any{"symbol": symbol, "low": 1.0, "high": 1_000_000.0}},
+		{tools.backtest, map[string]any{"symbol": symbol, "rule": "buy when ma20>ma50"}},
+		{tools.correlation, map[string]any{"symbols": selfTestSymbols}},
+		{tools.pnl, map[string]any{"positions": []any{map[string]any{"symbol": symbol, "entryPrice": 100.0, "shares": 10.0, "side": "LONG"}}}},
+		{tools.bias, map[string]any{"symbol": symbol}},
+		{tools.consensus, map[string]any{}},
+		{tools.risk, map[string]any{"symbol": symbol, "action": "BUY", "confidence": 0.7, "volatility": 0.2, "portfolioValue": 1_000_000.0}},
+		{tools.netting, map[string]any{"decisions": []any{map[string]any{"symbol": symbol, "action": "HOLD", "confidence": 0.5}}}},
+		{tools.log, map[string]any{"symbol": symbol, "action": "HOLD", "confidence": 0.5, "notes": "selftest"}},
+		{tools.explain, map[string]any{"invocation": selfTestInvocation}},
+		{tools.results, map[string]any{"symbol": symbol, "trade_summary": "selftest", "risk": "APPROVE", "execution": "none", "next_steps": "none"}},
+	}
+	for _, call := range calls {
+		check(call.tool.Name(), func() error {
+			return runSelfTestTool(ctx, call.tool, call.args)
+		})
+	}
+	return report
+}
+
+// selfTestConfig is the config the fixture tools are built from: cfg's app and
+// model settings, with everything else at its default and every path in scratch.
+// Settings that reach outside the process, such as the decision database, the
+// approval webhook and syslog, stay unset, as do the log confidence floor and
+// dedup window, which would make the log check skip the entry explain looks for.
+func selfTestConfig(cfg Config, scratch string) Config {
+	return Config{
+		AppName:            cfg.AppName,
+		ModelName:          cfg.ModelName,
+		APIKeyFile:         cfg.APIKeyFile,
+		Mode:               cfg.Mode,
+		ModelInitAttempts:  cfg.ModelInitAttempts,
+		ModelInitBaseDelay: cfg.ModelInitBaseDelay,
+		DataDir:            scratch,
+		LogPath:            filepath.Join(scratch, "logs", "selftest.jsonl"),
+		PortfolioValue:     1_000_000,
+	}
+}
+
+// pingModel asks llm for a one-word reply and fails unless some text comes back.
+func pingModel(ctx context.Context, llm model.LLM, modelName string) error {
+	req := &model.LLMRequest{
+		Model:    modelName,
+		Contents: []*genai.Content{genai.NewContentFromText("Reply with the single word OK.", genai.RoleUser)},
+	}
+	for resp, err := range llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return fmt.Errorf("generate content: %w", err)
+		}
+		if resp != nil && resp.Content != nil {
+			for _, part := range resp.Content.Parts {
+				if part != nil && strings.TrimSpace(part.Text) != "" {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("model %s returned no text", modelName)
+}
+
+// selfTestInvocation is the invocation ID tools see during the self-test, so the
+// explain check can find the entry the log check wrote.
+const selfTestInvocation = "selftest"
+
+// selfTestContext satisfies tool.Context for the methods the tools call.
+type selfTestContext struct {
+	tool.Context
+	ctx context.Context
+}
+
+func (c selfTestContext) AgentName() string           { return "selftest" }
+func (c selfTestContext) InvocationID() string        { return selfTestInvocation }
+func (c selfTestContext) Deadline() (time.Time, bool) { return c.ctx.Deadline() }
+func (c selfTestContext) Done() <-chan struct{}       { return c.ctx.Done() }
+func (c selfTestContext) Err() error                  { return c.ctx.Err() }
+func (c selfTestContext) Value(key any) any           { return c.ctx.Value(key) }
+
+// runSelfTestTool runs t once and fails on a run error or on an error reported in
+// its output.
+func runSelfTestTool(ctx context.Context, t tool.Tool, args map[string]any) error {
+	runner, ok := t.(interface {
+		Run(tool.Context, any) (map[string]any, error)
+	})
+	if !ok {
+		return fmt.Errorf("tool %T cannot be run directly", t)
+	}
+	out, err := runner.Run(selfTestContext{ctx: ctx}, args)
+	if err != nil {
+		return err
+	}
+	if msg, _ := out["error"].(string); msg != "" {
+		return fmt.Errorf("tool reported: %s", msg)
+	}
+	if status, _ := out["status"].(string); status == "error" {
+		return fmt.Errorf("tool reported status %q", status)
+	}
+	if found, ok := out["found"].(bool); ok && !found {
+		return fmt.Errorf("no matching entry found")
+	}
+	return nil
+}
+
+// writeSelfTestFixtures writes synthetic daily bars ending at now for every
+// fixture symbol, plus a fresh bias store.
+func writeSelfTestFixtures(dir string, now time.Time) error {
+	historical := filepath.Join(dir, "historical")
+	if err := os.MkdirAll(historical, 0o755); err != nil {
+		return fmt.Errorf("create fixture directory: %w", err)
+	}
+	for i, symbol := range selfTestSymbols {
+		if err := writeSelfTestBars(filepath.Join(historical, symbol+".csv"), now, float64(i+1)); err != nil {
+			return err
+		}
+	}
+
+	biasDir := filepath.Join(dir, "bias")
+	if err := os.MkdirAll(biasDir, 0o755); err != nil {
+		return fmt.Errorf("create fixture bias directory: %w", err)
+	}
+	store := map[string]any{}
+	for _, symbol := range selfTestSymbols {
+		store[symbol] = map[string]any{
+			"symbol":     symbol,
+			"score":      0.2,
+			"direction":  "bullish",
+			"conviction": 0.5,
+			"reason":     "selftest fixture",
+			"created_at": now.Format(time.RFC3339),
+			"expires_at": now.Add(time.Hour).Format(time.RFC3339),
+		}
+	}
+	data, err := json.Marshal(store)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(biasDir, "latest_biases.json"), data, 0o644); err != nil {
+		return fmt.Errorf("write fixture bias store: %w", err)
+	}
+	return bias.Validate(biasDir)
+}
+
+// writeSelfTestBars writes a gently trending, oscillating price series whose phase
+// depends on seed, so fixture symbols are related but not identical.
+func writeSelfTestBars(path string, end time.Time, seed float64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create fixture bars: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"Date", "Close", "High", "Low", "Open", "Volume"})
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	for i := 0; i < selfTestBars; i++ {
+		day := end.AddDate(0, 0, i-selfTestBars+1)
+		close := 100 + 0.1*float64(i) + 2*math.Sin(float64(i)/5+seed)
+		_ = w.Write([]string{day.Format("2006-01-02"), format(close), format(close + 1), format(close - 1), format(close - 0.2), "1000000"})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("write fixture bars: %w", err)
+	}
+	return f.Close()
+}
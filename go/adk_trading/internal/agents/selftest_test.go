@@ -0,0 +1,90 @@
+package agents
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/logging"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestSelfTest_PassesWithAResponsiveModel(t *testing.T) {
+	t.Setenv("GOOGLE_API_KEY", "test-key")
+	original := newGeminiModel
+	defer func() { newGeminiModel = original }()
+	newGeminiModel = func(ctx context.Context, name string, cfg *genai.ClientConfig) (model.LLM, error) {
+		return replyLLM{text: "OK"}, nil
+	}
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "audit.jsonl")
+	dbPath := filepath.Join(tempDir, "decisions.sqlite")
+	approver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected the approval webhook left alone, got %s %s", r.Method, r.URL)
+	}))
+	defer approver.Close()
+
+	// Production settings that would touch the outside world or skip the logged
+	// HOLD must not reach the fixture tools.
+	report := SelfTest(context.Background(), Config{
+		AppName:          "test_app",
+		ModelName:        "gemini-2.5-flash",
+		DataDir:          tempDir,
+		LogPath:          logPath,
+		BlockedSymbols:   []string{"SELFTEST"},
+		DecisionDBPath:   dbPath,
+		DecisionDBOnly:   true,
+		ApprovalURL:      approver.URL,
+		LogMinConfidence: 0.6,
+	})
+
+	if report.Failed() != 0 {
+		t.Fatalf("Expected every check to pass:\n%s", report)
+	}
+	var names []string
+	for _, result := range report {
+		names = append(names, result.Name)
+	}
+	for _, want := range []string{"build_orchestrator", "model_response", "get_market_snapshot", "risk_budget_check", "log_trade_decision", "explain_trade_decision"} {
+		if !strings.Contains(strings.Join(names, ","), want) {
+			t.Errorf("Expected a %s check, got %v", want, names)
+		}
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the configured audit log to be left untouched, stat err=%v", err)
+	}
+	db, err := logging.NewSQLiteSink(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteSink: %v", err)
+	}
+	defer db.Close()
+	if entries, err := db.Entries(); err != nil || len(entries) != 0 {
+		t.Errorf("Expected the configured decision database left empty, got %v (%v)", entries, err)
+	}
+}
+
+func TestSelfTest_FailsWhenTheModelIsSilent(t *testing.T) {
+	t.Setenv("GOOGLE_API_KEY", "test-key")
+	original := newGeminiModel
+	defer func() { newGeminiModel = original }()
+	newGeminiModel = func(ctx context.Context, name string, cfg *genai.ClientConfig) (model.LLM, error) {
+		return fakeLLM{}, nil
+	}
+	tempDir := t.TempDir()
+
+	report := SelfTest(context.Background(), Config{
+		AppName:   "test_app",
+		ModelName: "gemini-2.5-flash",
+		DataDir:   tempDir,
+		LogPath:   filepath.Join(tempDir, "audit.jsonl"),
+	})
+
+	if report.Failed() != 1 || !strings.Contains(report.String(), "FAIL model_response") {
+		t.Errorf("Expected only the model check to fail:\n%s", report)
+	}
+}
@@ -0,0 +1,66 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/tool"
+)
+
+// afterToolCallbacks returns the callbacks every agent runs after its tools: the
+// tool call counter, when cfg has a recorder.
+func afterToolCallbacks(cfg Config) []llmagent.AfterToolCallback {
+	if cfg.ObservabilityRecorder == nil {
+		return nil
+	}
+	return []llmagent.AfterToolCallback{countToolCall(cfg.ObservabilityRecorder)}
+}
+
+// countToolCall returns an llmagent.AfterToolCallback counting every tool call on
+// recorder, so each tool is instrumented without wiring the recorder into it.
+// Throttled calls never ran and are counted by the limiter instead.
+func countToolCall(recorder *observability.Recorder) llmagent.AfterToolCallback {
+	return func(ctx tool.Context, t tool.Tool, _ map[string]any, result map[string]any, err error) (map[string]any, error) {
+		if result["status"] == "throttled" {
+			return nil, nil
+		}
+		if err == nil {
+			err = resultError(result)
+		}
+		recorder.RecordToolCall(t.Name(), ctx.InvocationID(), err)
+		return nil, nil
+	}
+}
+
+// resultError is the failure a tool reported in its result: a "status" of
+// "error", or a non-empty "error". Other statuses, such as a partial write, are
+// successful calls.
+func resultError(result map[string]any) error {
+	switch msg := result["error"].(type) {
+	case string:
+		if msg != "" {
+			return errors.New(msg)
+		}
+	case error:
+		return msg
+	}
+	if result["status"] != "error" {
+		return nil
+	}
+	var parts []string
+	switch msgs := result["errors"].(type) {
+	case []string:
+		parts = msgs
+	case []any:
+		for _, msg := range msgs {
+			parts = append(parts, fmt.Sprint(msg))
+		}
+	}
+	if len(parts) == 0 {
+		return errors.New("tool reported an error")
+	}
+	return errors.New(strings.Join(parts, "; "))
+}
@@ -0,0 +1,64 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+)
+
+func TestCountToolCall(t *testing.T) {
+	recorder := observability.NewRecorder("127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := recorder.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	count := countToolCall(recorder)
+	calls := []struct {
+		tool   string
+		result map[string]any
+		err    error
+	}{
+		{"get_market_snapshot", map[string]any{"symbol": "SPY", "hasData": true}, nil},
+		{"get_market_snapshot", map[string]any{"symbol": "ZZZZ", "error": "no data for ZZZZ"}, nil},
+		{"check_risk", map[string]any{"decision": "APPROVE"}, nil},
+		{"check_risk", nil, errors.New("bad arguments")},
+		{"log_trade_decision", map[string]any{"status": "partial", "errors": []any{"syslog: down"}}, nil},
+		{"log_trade_decision", map[string]any{"status": "error", "errors": []any{"disk full"}}, nil},
+		{"get_bias_snapshot", map[string]any{"status": "throttled", "error": "not run"}, nil},
+	}
+	for _, call := range calls {
+		if out, err := count(invocationContext{}, namedTool{name: call.tool}, nil, call.result, call.err); out != nil || err != nil {
+			t.Fatalf("Expected the counter to leave %s's result alone, got %v %v", call.tool, out, err)
+		}
+	}
+
+	resp, err := http.Get("http://" + recorder.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	want := `adk_tool_calls_total{tool="check_risk",result="error"} 1
+adk_tool_calls_total{tool="check_risk",result="ok"} 1
+adk_tool_calls_total{tool="get_market_snapshot",result="error"} 1
+adk_tool_calls_total{tool="get_market_snapshot",result="ok"} 1
+adk_tool_calls_total{tool="log_trade_decision",result="error"} 1
+adk_tool_calls_total{tool="log_trade_decision",result="ok"} 1
+`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("Expected every tool counted by result, got:\n%s", body)
+	}
+	if strings.Contains(string(body), `tool="get_bias_snapshot"`) {
+		t.Errorf("Expected throttled calls left to the limiter, got:\n%s", body)
+	}
+
+	if callbacks := afterToolCallbacks(Config{}); callbacks != nil {
+		t.Errorf("Expected no callbacks without a recorder, got %v", callbacks)
+	}
+}
@@ -0,0 +1,22 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixed(t *testing.T) {
+	at := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	c := Fixed(at)
+	if !c.Now().Equal(at) || !c.Now().Equal(at) {
+		t.Errorf("Expected Fixed to always report %s, got %s", at, c.Now())
+	}
+}
+
+func TestSystem(t *testing.T) {
+	before := time.Now()
+	now := System.Now()
+	if now.Before(before) || now.After(time.Now()) {
+		t.Errorf("Expected System to report the wall clock, got %s", now)
+	}
+}
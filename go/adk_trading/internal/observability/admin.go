@@ -0,0 +1,55 @@
+package observability
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// WithAdminToken requires the admin endpoints, which change what the process
+// does, to be called with "Authorization: Bearer <token>". Without a token they
+// only answer callers on the loopback interface or the Unix socket.
+func WithAdminToken(token string) Option {
+	return func(r *Recorder) {
+		r.adminToken = token
+	}
+}
+
+// adminOnly guards an admin endpoint with the recorder's admin token.
+func (r *Recorder) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return AdminOnly(r.adminToken, next)
+}
+
+// AdminOnly guards an endpoint that changes what the process does: the bearer
+// token must match when token is set, and the caller must be local otherwise.
+func AdminOnly(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if token == "" {
+			if !localRequest(req) {
+				http.Error(w, "admin endpoints are only served locally without an admin token", http.StatusForbidden)
+				return
+			}
+			next(w, req)
+			return
+		}
+		got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "admin token required", http.StatusUnauthorized)
+			return
+		}
+		next(w, req)
+	}
+}
+
+// localRequest reports whether req came over the Unix socket, whose peers have
+// no network address, or from a loopback address.
+func localRequest(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr == "" || req.RemoteAddr == "@"
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,73 @@
+package observability
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminOnly(t *testing.T) {
+	calls := 0
+	ok := func(w http.ResponseWriter, req *http.Request) { calls++ }
+
+	post := func(r *Recorder, remote, auth string) int {
+		req := httptest.NewRequest(http.MethodPost, "/reset", nil)
+		req.RemoteAddr = remote
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		r.adminOnly(ok)(rec, req)
+		return rec.Code
+	}
+
+	local := NewRecorder(":0")
+	if code := post(local, "203.0.113.7:40000", ""); code != http.StatusForbidden || calls != 0 {
+		t.Errorf("Expected a remote caller refused without a token, got %d (%d calls)", code, calls)
+	}
+	for _, remote := range []string{"127.0.0.1:40000", "[::1]:40000", "@"} {
+		if code := post(local, remote, ""); code != http.StatusOK {
+			t.Errorf("Expected local caller %q served, got %d", remote, code)
+		}
+	}
+
+	calls = 0
+	guarded := NewRecorder(":0", WithAdminToken("s3cret"))
+	for _, auth := range []string{"", "Bearer wrong", "s3cret"} {
+		if code := post(guarded, "127.0.0.1:40000", auth); code != http.StatusUnauthorized {
+			t.Errorf("Expected %q refused, got %d", auth, code)
+		}
+	}
+	if code := post(guarded, "203.0.113.7:40000", "Bearer s3cret"); code != http.StatusOK || calls != 1 {
+		t.Errorf("Expected the token accepted from anywhere, got %d (%d calls)", code, calls)
+	}
+}
+
+func TestRecorder_AdminEndpointsNeedToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewRecorder("127.0.0.1:0", WithAdminToken("s3cret"), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	reopened := 0
+	r.SetLogReopener(func() error { reopened++; return nil })
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer r.Shutdown(context.Background())
+
+	for _, path := range []string{"/reset", "/reopen"} {
+		resp, err := http.Post("http://"+r.Addr()+path, "", nil)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected POST %s refused without the token, got %d", path, resp.StatusCode)
+		}
+	}
+	if reopened != 0 {
+		t.Errorf("Expected no reopen without the token, got %d", reopened)
+	}
+}
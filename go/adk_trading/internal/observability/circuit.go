@@ -0,0 +1,94 @@
+package observability
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// circuitBreaker trips after threshold consecutive non-APPROVE decisions that all
+// fall within window, and stays open until reset.
+type circuitBreaker struct {
+	threshold int
+	window    time.Duration
+	streak    []time.Time // times of the current run of failed decisions
+	open      bool
+	openedAt  time.Time
+}
+
+// WithCircuitBreaker opens the circuit after threshold consecutive failed or
+// non-APPROVE decisions recorded within window; a non-positive window counts the
+// whole run. A threshold below one disables the breaker.
+func WithCircuitBreaker(threshold int, window time.Duration) Option {
+	return func(r *Recorder) {
+		r.circuit.threshold = threshold
+		r.circuit.window = window
+	}
+}
+
+// observe folds a decision into the current streak and reports whether it tripped the circuit.
+func (c *circuitBreaker) observe(failed bool, now time.Time) bool {
+	if c.threshold < 1 || c.open {
+		return false
+	}
+	if !failed {
+		c.streak = c.streak[:0]
+		return false
+	}
+	c.streak = append(c.streak, now)
+	if c.window > 0 {
+		cutoff := now.Add(-c.window)
+		drop := 0
+		for drop < len(c.streak) && c.streak[drop].Before(cutoff) {
+			drop++
+		}
+		c.streak = c.streak[drop:]
+	}
+	if len(c.streak) < c.threshold {
+		return false
+	}
+	c.open = true
+	c.openedAt = now
+	c.streak = nil
+	return true
+}
+
+// CircuitOpen reports whether the circuit breaker has tripped and not yet been reset.
+func (r *Recorder) CircuitOpen() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.circuit.open
+}
+
+// ResetCircuit closes the circuit and clears the failure streak.
+func (r *Recorder) ResetCircuit() {
+	r.mu.Lock()
+	wasOpen := r.circuit.open
+	r.circuit.open = false
+	r.circuit.openedAt = time.Time{}
+	r.circuit.streak = nil
+	r.mu.Unlock()
+	if wasOpen {
+		r.logger.Info("circuit breaker reset")
+	}
+}
+
+func (r *Recorder) handleReset(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "reset requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ResetCircuit()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"circuit_open": false}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
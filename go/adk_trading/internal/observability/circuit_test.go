@@ -0,0 +1,82 @@
+package observability
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_TripsOnConsecutiveFailures(t *testing.T) {
+	r := NewRecorder(":0", WithCircuitBreaker(3, time.Hour))
+	r.Record(DecisionEvent{Symbol: "SPY", RiskDecision: "REJECT"})
+	r.Record(DecisionEvent{Symbol: "SPY", RiskDecision: "REVIEW"})
+	r.Record(DecisionEvent{Symbol: "SPY", RiskDecision: "APPROVE"})
+	r.Record(DecisionEvent{Symbol: "QQQ", RiskDecision: "REJECT"})
+	r.Record(DecisionEvent{Symbol: "QQQ", Error: "write failed"})
+	if r.CircuitOpen() {
+		t.Fatal("Expected an APPROVE to break the streak")
+	}
+	r.Record(DecisionEvent{Symbol: "IWM", RiskDecision: "REJECT"})
+	if !r.CircuitOpen() {
+		t.Fatal("Expected the circuit to open after 3 consecutive non-APPROVE decisions")
+	}
+
+	health := httptest.NewRecorder()
+	r.handleHealth(health, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var payload map[string]any
+	if err := json.Unmarshal(health.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode /healthz: %v", err)
+	}
+	if payload["circuit_open"] != true {
+		t.Errorf("Expected circuit_open true on /healthz, got %v", payload["circuit_open"])
+	}
+	metrics := httptest.NewRecorder()
+	r.handleMetrics(metrics, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(metrics.Body.String(), "adk_circuit_open 1\n") {
+		t.Errorf("Expected adk_circuit_open 1, got:\n%s", metrics.Body.String())
+	}
+}
+
+func TestCircuitBreaker_WindowExpiresOldFailures(t *testing.T) {
+	c := circuitBreaker{threshold: 2, window: time.Minute}
+	start := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	if c.observe(true, start) || c.observe(true, start.Add(2*time.Minute)) {
+		t.Fatal("Expected failures further apart than the window not to trip")
+	}
+	if !c.observe(true, start.Add(150*time.Second)) {
+		t.Error("Expected two failures inside the window to trip")
+	}
+}
+
+func TestCircuitBreaker_DisabledByDefault(t *testing.T) {
+	r := NewRecorder(":0")
+	for i := 0; i < 10; i++ {
+		r.Record(DecisionEvent{RiskDecision: "REJECT"})
+	}
+	if r.CircuitOpen() {
+		t.Error("Expected no circuit breaker without WithCircuitBreaker")
+	}
+}
+
+func TestRecorder_ResetEndpoint(t *testing.T) {
+	r := NewRecorder(":0", WithCircuitBreaker(1, 0))
+	r.Record(DecisionEvent{RiskDecision: "REJECT"})
+	if !r.CircuitOpen() {
+		t.Fatal("Expected the circuit to open")
+	}
+
+	get := httptest.NewRecorder()
+	r.handleReset(get, httptest.NewRequest(http.MethodGet, "/reset", nil))
+	if get.Code != http.StatusMethodNotAllowed || !r.CircuitOpen() {
+		t.Errorf("Expected GET to be refused without resetting, got %d", get.Code)
+	}
+
+	post := httptest.NewRecorder()
+	r.handleReset(post, httptest.NewRequest(http.MethodPost, "/reset", nil))
+	if post.Code != http.StatusOK || r.CircuitOpen() {
+		t.Errorf("Expected POST to close the circuit, got %d (open=%v)", post.Code, r.CircuitOpen())
+	}
+}
@@ -0,0 +1,39 @@
+package observability
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultHeartbeatInterval is how often a started Recorder refreshes its heartbeat.
+const DefaultHeartbeatInterval = 30 * time.Second
+
+// WithHeartbeat sets how often the recorder stamps last_heartbeat while it serves,
+// so alerting can tell an idle process from a dead one during quiet periods. A
+// non-positive interval disables the heartbeat.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(r *Recorder) {
+		r.heartbeat = interval
+	}
+}
+
+// beat stamps the heartbeat at now.
+func (r *Recorder) beat(now time.Time) {
+	r.mu.Lock()
+	r.lastBeat = now.UTC()
+	r.mu.Unlock()
+}
+
+// runHeartbeat beats every interval until ctx is done.
+func (r *Recorder) runHeartbeat(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.beat(now)
+		}
+	}
+}
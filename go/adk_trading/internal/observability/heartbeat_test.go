@@ -0,0 +1,81 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (r *Recorder) lastHeartbeat() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastBeat
+}
+
+func TestRecorder_HeartbeatWithoutDecisions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewRecorder("127.0.0.1:0", WithHeartbeat(10*time.Millisecond), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer r.Shutdown(context.Background())
+
+	first := r.lastHeartbeat()
+	if first.IsZero() {
+		t.Fatal("Expected Start to beat immediately")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !r.lastHeartbeat().After(first) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the heartbeat to advance while idle")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	rec := httptest.NewRecorder()
+	r.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var health map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if health["status"] != "cold" || health["last_heartbeat"] == nil {
+		t.Errorf("Expected a cold but beating recorder, got %v", health)
+	}
+	rec = httptest.NewRecorder()
+	r.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "adk_last_heartbeat_timestamp ") {
+		t.Errorf("Expected the heartbeat on /metrics, got:\n%s", rec.Body.String())
+	}
+
+	// Cancelling the context stops the ticker.
+	cancel()
+	time.Sleep(30 * time.Millisecond)
+	stopped := r.lastHeartbeat()
+	time.Sleep(50 * time.Millisecond)
+	if !r.lastHeartbeat().Equal(stopped) {
+		t.Error("Expected the heartbeat to stop once the context is done")
+	}
+}
+
+func TestRecorder_HeartbeatDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewRecorder("127.0.0.1:0", WithHeartbeat(0), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer r.Shutdown(context.Background())
+
+	rec := httptest.NewRecorder()
+	r.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(rec.Body.String(), "adk_last_heartbeat_timestamp") {
+		t.Errorf("Expected no heartbeat when disabled, got:\n%s", rec.Body.String())
+	}
+}
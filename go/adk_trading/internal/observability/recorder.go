@@ -3,92 +3,449 @@ package observability
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// SchemaVersion identifies the shape of DecisionEvent and of the JSONL audit entries
+// written by log_trade_decision. Bump it whenever either shape changes.
+const SchemaVersion = 7
+
+// unixPrefix marks a recorder address as a Unix domain socket path.
+const unixPrefix = "unix:"
+
+// DefaultDecisionBufferSize is how many recent decisions /decisions retains.
+const DefaultDecisionBufferSize = 50
+
 // DecisionEvent captures the salient facts about a trade decision emitted from the ADK stack.
 type DecisionEvent struct {
-	Timestamp    time.Time      `json:"timestamp"`
-	Symbol       string         `json:"symbol"`
-	Action       string         `json:"action"`
-	Confidence   float64        `json:"confidence"`
-	PositionSize float64        `json:"position_size"`
-	RiskDecision string         `json:"risk_decision"`
-	Error        string         `json:"error,omitempty"`
-	Metadata     map[string]any `json:"metadata,omitempty"`
-	Raw          map[string]any `json:"raw,omitempty"`
+	SchemaVersion int       `json:"schema_version"`
+	Timestamp     time.Time `json:"timestamp"`
+	Mode          string    `json:"mode,omitempty"`
+	Symbol        string    `json:"symbol"`
+	Action        string    `json:"action"`
+	Confidence    float64   `json:"confidence"`
+	PositionSize  float64   `json:"position_size"`
+	RiskDecision  string    `json:"risk_decision"`
+	Error         string    `json:"error,omitempty"`
+	// SizeMismatch marks a decision whose executed size diverged from the
+	// position size risk approved.
+	SizeMismatch bool `json:"size_mismatch,omitempty"`
+	// TraceID ties the event to its originating OpenTelemetry trace, and is
+	// surfaced as a /metrics exemplar; it is empty when no span was active.
+	TraceID string `json:"trace_id,omitempty"`
+	// Invocation is the ADK invocation ID of the orchestration that made the
+	// decision, shared by every tool call in that run.
+	Invocation string         `json:"invocation,omitempty"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+	Raw        map[string]any `json:"raw,omitempty"`
 }
 
 // Recorder exposes health and metrics endpoints while tracking decision statistics.
 type Recorder struct {
 	addr       string
+	app        string
+	mode       string
+	logger     *slog.Logger
 	server     *http.Server
+	boundAddr  string
 	mu         sync.RWMutex
 	total      uint64
 	failures   uint64
 	lastUpdate time.Time
 	lastEvent  DecisionEvent
+	lastFailed DecisionEvent
+	recent     []DecisionEvent     // ring buffer of the last len(recent) decisions
+	next       int                 // ring slot the next decision is written to
+	filled     int                 // number of ring slots holding a decision
+	biasStale  uint64              // stale bias snapshots served
+	sinkFails  map[string]uint64   // audit log sink write failures by sink
+	sizeSkews  uint64              // decisions executed at a size risk did not approve
+	skipped    uint64              // decisions below the minimum confidence, not logged
+	toolCalls  map[toolCall]uint64 // tool calls by tool and result
+	throttled  map[string]uint64   // tool calls refused by the rate limiter, by tool
+	biasErr    error               // last bias store health check result
+	biasCheck  bool                // whether a bias store health check has been reported
+	reopen     func() error        // reopens the audit log files, set once the sinks exist
+	closeLogs  func() error        // closes the audit log sinks on Shutdown
+	circuit    circuitBreaker
+	adminToken string        // bearer token the admin endpoints require; empty restricts them to local callers
+	heartbeat  time.Duration // interval between heartbeats, non-positive when disabled
+	lastBeat   time.Time     // when the heartbeat last fired
 }
 
-// NewRecorder initialises a Recorder bound to the provided address (e.g. ":8091").
-func NewRecorder(addr string) *Recorder {
-	return &Recorder{addr: addr}
+// Option customises a Recorder.
+type Option func(*Recorder)
+
+// WithApp labels every /metrics series with app, so instances of different apps
+// scraped into one Prometheus stay distinct.
+func WithApp(app string) Option {
+	return func(r *Recorder) {
+		r.app = app
+	}
 }
 
-// Start launches the HTTP server asynchronously.
-func (r *Recorder) Start(ctx context.Context) {
+// WithMode reports the trading mode (e.g. "paper" or "live") on /healthz and
+// labels every /metrics series with it.
+func WithMode(mode string) Option {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithLogger sets the structured logger for server lifecycle and decision records.
+// A nil logger keeps slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Recorder) {
+		if logger != nil {
+			r.logger = logger
+		}
+	}
+}
+
+// WithDecisionBuffer sets how many recent decisions /decisions retains. Values below
+// one keep DefaultDecisionBufferSize.
+func WithDecisionBuffer(size int) Option {
+	return func(r *Recorder) {
+		if size > 0 {
+			r.recent = make([]DecisionEvent, size)
+		}
+	}
+}
+
+// NewRecorder initialises a Recorder bound to the provided address: a TCP address
+// such as ":8091", or "unix:/path/to.sock" to serve over a Unix domain socket.
+func NewRecorder(addr string, opts ...Option) *Recorder {
+	r := &Recorder{addr: addr, logger: slog.Default(), heartbeat: DefaultHeartbeatInterval}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.recent == nil {
+		r.recent = make([]DecisionEvent, DefaultDecisionBufferSize)
+	}
+	return r
+}
+
+// Start binds the listener synchronously, returning an error if the address is
+// unavailable, then serves, and beats the heartbeat, in the background until ctx
+// is done.
+func (r *Recorder) Start(ctx context.Context) error {
+	ln, err := listen(r.addr)
+	if err != nil {
+		return fmt.Errorf("observability listen on %s: %w", r.addr, err)
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", r.handleHealth)
 	mux.HandleFunc("/metrics", r.handleMetrics)
+	mux.HandleFunc("/decisions", r.handleDecisions)
+	mux.HandleFunc("/reset", r.adminOnly(r.handleReset))
+	mux.HandleFunc("/reopen", r.adminOnly(r.handleReopen))
 
-	r.server = &http.Server{
+	server := &http.Server{
 		Addr:              r.addr,
 		Handler:           mux,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
+	bound := ln.Addr().String()
+	if ln.Addr().Network() == "unix" {
+		bound = unixPrefix + bound
+	}
+	r.mu.Lock()
+	r.server = server
+	r.boundAddr = bound
+	r.mu.Unlock()
 
+	r.logger.Info("observability server listening", "addr", bound)
 	go func() {
-		if err := r.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			fmt.Printf("observability server error: %v\n", err)
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			r.logger.Error("observability server failed", "addr", bound, "error", err)
 		}
 	}()
 
+	if r.heartbeat > 0 {
+		r.beat(time.Now())
+		go r.runHeartbeat(ctx, r.heartbeat)
+	}
+
 	go func() {
 		<-ctx.Done()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		if r.server != nil {
-			_ = r.server.Shutdown(shutdownCtx)
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			r.logger.Warn("observability server shutdown failed", "addr", bound, "error", err)
+			return
 		}
+		r.logger.Info("observability server stopped", "addr", bound)
 	}()
+	return nil
+}
+
+// listen opens a Unix socket for "unix:" addresses, first removing a stale socket
+// left behind by an earlier run, and a TCP listener otherwise.
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if path == "" {
+		return nil, errors.New("unix socket path is empty")
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// Addr returns the address the server is bound to, which differs from the
+// configured one when that used port 0. It is empty until Start succeeds.
+func (r *Recorder) Addr() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.boundAddr
 }
 
-// Shutdown gracefully stops the server.
+// Shutdown gracefully stops the server, then closes the audit log sinks through
+// the registered closer, if any.
 func (r *Recorder) Shutdown(ctx context.Context) error {
-	if r.server == nil {
-		return nil
+	r.mu.RLock()
+	server := r.server
+	closeLogs := r.closeLogs
+	r.mu.RUnlock()
+	var errs []error
+	if server != nil {
+		errs = append(errs, server.Shutdown(ctx))
+	}
+	if closeLogs != nil {
+		if err := closeLogs(); err != nil {
+			r.logger.Error("audit log close failed", "error", err)
+			errs = append(errs, err)
+		}
 	}
-	return r.server.Shutdown(ctx)
+	return errors.Join(errs...)
 }
 
 // Record stores a new decision event.
 func (r *Recorder) Record(event DecisionEvent) {
+	if event.SchemaVersion == 0 {
+		event.SchemaVersion = SchemaVersion
+	}
 	event.Timestamp = event.Timestamp.UTC()
 	riskDecision := strings.ToUpper(event.RiskDecision)
 
+	r.logger.Debug("decision recorded",
+		"symbol", event.Symbol,
+		"action", event.Action,
+		"risk_decision", event.RiskDecision,
+		"position_size", event.PositionSize,
+		"mode", event.Mode,
+		"invocation", event.Invocation,
+		"error", event.Error,
+	)
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	r.total++
-	if event.Error != "" || (riskDecision != "" && riskDecision != "APPROVE") {
+	failed := event.Error != "" || (riskDecision != "" && riskDecision != "APPROVE")
+	if failed {
 		r.failures++
+		r.lastFailed = event
 	}
 	r.lastUpdate = time.Now().UTC()
+	if r.circuit.observe(failed, r.lastUpdate) {
+		r.logger.Error("circuit breaker open: halting trades until reset",
+			"consecutive_failures", r.circuit.threshold,
+			"window", r.circuit.window,
+			"symbol", event.Symbol,
+		)
+	}
 	r.lastEvent = event
+	if len(r.recent) == 0 {
+		return
+	}
+	r.recent[r.next] = event
+	r.next = (r.next + 1) % len(r.recent)
+	if r.filled < len(r.recent) {
+		r.filled++
+	}
+}
+
+// RecordBiasStale counts a stale bias snapshot served to an agent during invocation.
+func (r *Recorder) RecordBiasStale(symbol, invocation string) {
+	r.logger.Debug("stale bias snapshot served", "symbol", symbol, "invocation", invocation)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.biasStale++
+}
+
+// RecordSinkFailure counts a failed write to an audit log sink during invocation,
+// exposed per sink as adk_log_sink_failures_total.
+func (r *Recorder) RecordSinkFailure(sink, invocation string, err error) {
+	r.logger.Warn("audit log sink write failed", "sink", sink, "invocation", invocation, "error", err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sinkFails == nil {
+		r.sinkFails = map[string]uint64{}
+	}
+	r.sinkFails[sink]++
+}
+
+// RecordSizeMismatch warns that the decision logged for symbol during invocation
+// executes a size other than the one risk approved, counted as adk_size_mismatch_total.
+func (r *Recorder) RecordSizeMismatch(symbol, invocation string, approved, executed float64) {
+	r.logger.Warn("executed size differs from risk-approved size",
+		"symbol", symbol,
+		"invocation", invocation,
+		"approved_size", approved,
+		"executed_size", executed,
+	)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sizeSkews++
+}
+
+// RecordSkip counts a decision for symbol during invocation that was not logged
+// because it fell below the minimum confidence to log, exposed as
+// adk_decisions_skipped_total.
+func (r *Recorder) RecordSkip(symbol, action, invocation string, confidence float64) {
+	r.logger.Debug("decision below the minimum confidence not logged",
+		"symbol", symbol,
+		"action", action,
+		"confidence", confidence,
+		"invocation", invocation,
+	)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped++
+}
+
+// toolCall labels adk_tool_calls_total: the tool name and "ok" or "error".
+type toolCall struct {
+	tool   string
+	result string
+}
+
+// RecordToolCall counts a call of the named tool during invocation, as an error
+// when err is non-nil, exposed per tool and result as adk_tool_calls_total.
+func (r *Recorder) RecordToolCall(tool, invocation string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+		r.logger.Debug("tool call failed", "tool", tool, "invocation", invocation, "error", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.toolCalls == nil {
+		r.toolCalls = map[toolCall]uint64{}
+	}
+	r.toolCalls[toolCall{tool: tool, result: result}]++
+}
+
+// RecordThrottle counts a call of the named tool refused by the rate limiter
+// during invocation, exposed per tool as adk_tool_throttled_total.
+func (r *Recorder) RecordThrottle(tool, invocation string) {
+	r.logger.Warn("tool call throttled", "tool", tool, "invocation", invocation)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.throttled == nil {
+		r.throttled = map[string]uint64{}
+	}
+	r.throttled[tool]++
+}
+
+// SetBiasStoreHealth records the outcome of a bias store health check, surfaced as
+// bias_store_ok (and bias_store_error when unhealthy) on /healthz.
+func (r *Recorder) SetBiasStoreHealth(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.biasCheck = true
+	r.biasErr = err
+}
+
+// SetLogReopener registers the function ReopenLogs and POST /reopen call to reopen
+// the audit log files after external rotation.
+func (r *Recorder) SetLogReopener(reopen func() error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reopen = reopen
+}
+
+// SetLogCloser registers the function Shutdown calls to close the audit log sinks.
+func (r *Recorder) SetLogCloser(close func() error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeLogs = close
+}
+
+// ReopenLogs reopens the audit log files through the registered reopener, if any.
+func (r *Recorder) ReopenLogs() error {
+	r.mu.RLock()
+	reopen := r.reopen
+	r.mu.RUnlock()
+	if reopen == nil {
+		return nil
+	}
+	if err := reopen(); err != nil {
+		r.logger.Error("audit log reopen failed", "error", err)
+		return err
+	}
+	r.logger.Info("audit logs reopened")
+	return nil
+}
+
+func (r *Recorder) handleReopen(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "reopen requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ReopenLogs(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"reopened": true}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Recent returns up to limit of the most recently recorded decisions, newest first.
+// A limit of zero or below, or above the buffer size, returns everything retained.
+func (r *Recorder) Recent(limit int) []DecisionEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if limit <= 0 || limit > r.filled {
+		limit = r.filled
+	}
+	events := make([]DecisionEvent, 0, limit)
+	for i := 1; i <= limit; i++ {
+		idx := (r.next - i + len(r.recent)) % len(r.recent)
+		events = append(events, r.recent[idx])
+	}
+	return events
 }
 
 func (r *Recorder) handleHealth(w http.ResponseWriter, req *http.Request) {
@@ -97,14 +454,25 @@ func (r *Recorder) handleHealth(w http.ResponseWriter, req *http.Request) {
 
 	payload := map[string]any{
 		"status":        "ok",
+		"mode":          r.mode,
 		"total":         r.total,
 		"failures":      r.failures,
 		"last_update":   r.lastUpdate,
 		"last_decision": r.lastEvent,
+		"circuit_open":  r.circuit.open,
+	}
+	if !r.lastBeat.IsZero() {
+		payload["last_heartbeat"] = r.lastBeat
 	}
 	if r.total == 0 {
 		payload["status"] = "cold"
 	}
+	if r.biasCheck {
+		payload["bias_store_ok"] = r.biasErr == nil
+		if r.biasErr != nil {
+			payload["bias_store_error"] = r.biasErr.Error()
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
@@ -116,10 +484,181 @@ func (r *Recorder) handleMetrics(w http.ResponseWriter, req *http.Request) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
-	fmt.Fprintf(w, "adk_decisions_total %d\n", r.total)
-	fmt.Fprintf(w, "adk_decisions_failures_total %d\n", r.failures)
+	if !acceptsOpenMetrics(req) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		labels := r.labels()
+		fmt.Fprintf(w, "adk_decisions_total%s %d\n", labels, r.total)
+		fmt.Fprintf(w, "adk_decisions_failures_total%s %d\n", labels, r.failures)
+		fmt.Fprintf(w, "adk_bias_stale_total%s %d\n", labels, r.biasStale)
+		fmt.Fprintf(w, "adk_size_mismatch_total%s %d\n", labels, r.sizeSkews)
+		fmt.Fprintf(w, "adk_decisions_skipped_total%s %d\n", labels, r.skipped)
+		r.writeSinkFailures(w)
+		r.writeToolCalls(w)
+		r.writeThrottles(w)
+		fmt.Fprintf(w, "adk_circuit_open%s %d\n", labels, boolToInt(r.circuit.open))
+		if !r.lastUpdate.IsZero() {
+			fmt.Fprintf(w, "adk_last_decision_timestamp%s %d\n", labels, r.lastUpdate.Unix())
+		}
+		if !r.lastBeat.IsZero() {
+			fmt.Fprintf(w, "adk_last_heartbeat_timestamp%s %d\n", labels, r.lastBeat.Unix())
+		}
+		return
+	}
+
+	// OpenMetrics scrapers also get exemplars linking the counters to the trace
+	// of the most recent decision (or failure) that incremented them.
+	w.Header().Set("Content-Type", openMetricsContentType)
+	labels := r.labels()
+	fmt.Fprint(w, "# TYPE adk_decisions counter\n")
+	fmt.Fprintf(w, "adk_decisions_total%s %d%s\n", labels, r.total, exemplar(r.lastEvent))
+	fmt.Fprint(w, "# TYPE adk_decisions_failures counter\n")
+	fmt.Fprintf(w, "adk_decisions_failures_total%s %d%s\n", labels, r.failures, exemplar(r.lastFailed))
+	fmt.Fprint(w, "# TYPE adk_bias_stale counter\n")
+	fmt.Fprintf(w, "adk_bias_stale_total%s %d\n", labels, r.biasStale)
+	fmt.Fprint(w, "# TYPE adk_size_mismatch counter\n")
+	fmt.Fprintf(w, "adk_size_mismatch_total%s %d\n", labels, r.sizeSkews)
+	fmt.Fprint(w, "# TYPE adk_decisions_skipped counter\n")
+	fmt.Fprintf(w, "adk_decisions_skipped_total%s %d\n", labels, r.skipped)
+	if len(r.sinkFails) > 0 {
+		fmt.Fprint(w, "# TYPE adk_log_sink_failures counter\n")
+		r.writeSinkFailures(w)
+	}
+	if len(r.toolCalls) > 0 {
+		fmt.Fprint(w, "# TYPE adk_tool_calls counter\n")
+		r.writeToolCalls(w)
+	}
+	if len(r.throttled) > 0 {
+		fmt.Fprint(w, "# TYPE adk_tool_throttled counter\n")
+		r.writeThrottles(w)
+	}
+	fmt.Fprint(w, "# TYPE adk_circuit_open gauge\n")
+	fmt.Fprintf(w, "adk_circuit_open%s %d\n", labels, boolToInt(r.circuit.open))
 	if !r.lastUpdate.IsZero() {
-		fmt.Fprintf(w, "adk_last_decision_timestamp %d\n", r.lastUpdate.Unix())
+		fmt.Fprint(w, "# TYPE adk_last_decision_timestamp gauge\n")
+		fmt.Fprintf(w, "adk_last_decision_timestamp%s %d\n", labels, r.lastUpdate.Unix())
+	}
+	if !r.lastBeat.IsZero() {
+		fmt.Fprint(w, "# TYPE adk_last_heartbeat_timestamp gauge\n")
+		fmt.Fprintf(w, "adk_last_heartbeat_timestamp%s %d\n", labels, r.lastBeat.Unix())
+	}
+	fmt.Fprint(w, "# EOF\n")
+}
+
+// writeSinkFailures emits one adk_log_sink_failures_total sample per sink, in
+// sink order. Callers hold r.mu.
+func (r *Recorder) writeSinkFailures(w io.Writer) {
+	sinks := make([]string, 0, len(r.sinkFails))
+	for sink := range r.sinkFails {
+		sinks = append(sinks, sink)
+	}
+	sort.Strings(sinks)
+	for _, sink := range sinks {
+		fmt.Fprintf(w, "adk_log_sink_failures_total%s %d\n", r.labels("sink", sink), r.sinkFails[sink])
+	}
+}
+
+// writeToolCalls emits one adk_tool_calls_total sample per tool and result, in
+// tool order. Callers hold r.mu.
+func (r *Recorder) writeToolCalls(w io.Writer) {
+	calls := make([]toolCall, 0, len(r.toolCalls))
+	for call := range r.toolCalls {
+		calls = append(calls, call)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].tool != calls[j].tool {
+			return calls[i].tool < calls[j].tool
+		}
+		return calls[i].result < calls[j].result
+	})
+	for _, call := range calls {
+		fmt.Fprintf(w, "adk_tool_calls_total%s %d\n", r.labels("tool", call.tool, "result", call.result), r.toolCalls[call])
+	}
+}
+
+// writeThrottles emits one adk_tool_throttled_total sample per tool, in tool
+// order. Callers hold r.mu.
+func (r *Recorder) writeThrottles(w io.Writer) {
+	tools := make([]string, 0, len(r.throttled))
+	for tool := range r.throttled {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	for _, tool := range tools {
+		fmt.Fprintf(w, "adk_tool_throttled_total%s %d\n", r.labels("tool", tool), r.throttled[tool])
+	}
+}
+
+// labelEscaper escapes a label value as the Prometheus text formats require.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels renders the label set of a series: app and mode when set, then the
+// extra name/value pairs. Empty values are omitted, and with no labels at all
+// the series is left bare.
+func (r *Recorder) labels(extra ...string) string {
+	pairs := append([]string{"app", r.app, "mode", r.mode}, extra...)
+	var rendered []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			rendered = append(rendered, pairs[i]+`="`+labelEscaper.Replace(pairs[i+1])+`"`)
+		}
+	}
+	if len(rendered) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(rendered, ",") + "}"
+}
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// acceptsOpenMetrics reports whether the scraper's Accept header asks for the
+// OpenMetrics text format, which is the only one that carries exemplars.
+func acceptsOpenMetrics(req *http.Request) bool {
+	for _, accept := range req.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, _ := strings.Cut(part, ";")
+			if strings.TrimSpace(mediaType) == "application/openmetrics-text" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exemplar renders the OpenMetrics exemplar suffix for event, or nothing when it
+// carries no trace ID.
+func exemplar(event DecisionEvent) string {
+	if event.TraceID == "" {
+		return ""
+	}
+	return fmt.Sprintf(" # {trace_id=%q} 1 %.3f", event.TraceID, float64(event.Timestamp.UnixMilli())/1000)
+}
+
+func (r *Recorder) handleDecisions(w http.ResponseWriter, req *http.Request) {
+	limit := 0
+	if raw := req.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	events := r.Recent(0)
+	if invocation := req.URL.Query().Get("invocation"); invocation != "" {
+		matched := events[:0]
+		for _, event := range events {
+			if event.Invocation == invocation {
+				matched = append(matched, event)
+			}
+		}
+		events = matched
+	}
+	if limit > 0 && limit < len(events) {
+		events = events[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,441 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder_RecordStampsSchemaVersion(t *testing.T) {
+	// Version 6 added the prev_hash and hash chain fields and 7 idempotency_key; a
+	// new shape must bump it.
+	if SchemaVersion != 7 {
+		t.Errorf("Expected schema version 7, got %d", SchemaVersion)
+	}
+	r := NewRecorder(":0")
+
+	r.Record(DecisionEvent{Timestamp: time.Now(), Symbol: "SPY", Action: "BUY", RiskDecision: "APPROVE"})
+
+	if r.lastEvent.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", SchemaVersion, r.lastEvent.SchemaVersion)
+	}
+	if r.total != 1 || r.failures != 0 {
+		t.Errorf("Expected 1 total and 0 failures, got %d and %d", r.total, r.failures)
+	}
+}
+
+func TestRecorder_RecentIsBoundedAndNewestFirst(t *testing.T) {
+	r := NewRecorder(":0", WithDecisionBuffer(3))
+	for _, symbol := range []string{"A", "B", "C", "D", "E"} {
+		r.Record(DecisionEvent{Symbol: symbol, RiskDecision: "APPROVE"})
+	}
+
+	got := r.Recent(0)
+
+	if len(got) != 3 {
+		t.Fatalf("Expected the buffer to hold 3 decisions, got %d", len(got))
+	}
+	for i, want := range []string{"E", "D", "C"} {
+		if got[i].Symbol != want {
+			t.Errorf("Expected decision %d to be %s, got %s", i, want, got[i].Symbol)
+		}
+	}
+}
+
+func TestRecorder_DecisionsEndpointClampsLimit(t *testing.T) {
+	r := NewRecorder(":0", WithDecisionBuffer(2))
+	r.Record(DecisionEvent{Symbol: "SPY"})
+	r.Record(DecisionEvent{Symbol: "QQQ"})
+	r.Record(DecisionEvent{Symbol: "IWM"})
+
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"", 2},
+		{"?limit=1", 1},
+		{"?limit=100", 2},
+	} {
+		rec := httptest.NewRecorder()
+		r.handleDecisions(rec, httptest.NewRequest(http.MethodGet, "/decisions"+tc.query, nil))
+
+		var events []DecisionEvent
+		if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+			t.Fatalf("%q: decode: %v", tc.query, err)
+		}
+		if len(events) != tc.want {
+			t.Errorf("%q: expected %d events, got %d", tc.query, tc.want, len(events))
+		}
+		if len(events) > 0 && events[0].Symbol != "IWM" {
+			t.Errorf("%q: expected newest decision first, got %s", tc.query, events[0].Symbol)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.handleDecisions(rec, httptest.NewRequest(http.MethodGet, "/decisions?limit=abc", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed limit, got %d", rec.Code)
+	}
+}
+
+func TestRecorder_DecisionsEndpointFiltersByInvocation(t *testing.T) {
+	r := NewRecorder(":0")
+	r.Record(DecisionEvent{Symbol: "SPY", Invocation: "inv-1"})
+	r.Record(DecisionEvent{Symbol: "QQQ", Invocation: "inv-2"})
+	r.Record(DecisionEvent{Symbol: "IWM", Invocation: "inv-1"})
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"?invocation=inv-1", []string{"IWM", "SPY"}},
+		{"?invocation=inv-1&limit=1", []string{"IWM"}},
+		{"?invocation=inv-3", []string{}},
+	} {
+		rec := httptest.NewRecorder()
+		r.handleDecisions(rec, httptest.NewRequest(http.MethodGet, "/decisions"+tc.query, nil))
+
+		var events []DecisionEvent
+		if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+			t.Fatalf("%q: decode: %v", tc.query, err)
+		}
+		got := []string{}
+		for _, event := range events {
+			got = append(got, event.Symbol)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%q: expected %v, got %v", tc.query, tc.want, got)
+		}
+	}
+}
+
+func TestRecorder_HealthReportsBiasStore(t *testing.T) {
+	r := NewRecorder(":0")
+
+	health := func() map[string]any {
+		rec := httptest.NewRecorder()
+		r.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		var payload map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return payload
+	}
+
+	if _, ok := health()["bias_store_ok"]; ok {
+		t.Error("Expected no bias_store_ok before a check is reported")
+	}
+	r.SetBiasStoreHealth(errors.New("corrupt"))
+	if payload := health(); payload["bias_store_ok"] != false || payload["bias_store_error"] != "corrupt" {
+		t.Errorf("Expected an unhealthy bias store, got %v", payload)
+	}
+	r.SetBiasStoreHealth(nil)
+	if payload := health(); payload["bias_store_ok"] != true {
+		t.Errorf("Expected a healthy bias store, got %v", payload)
+	}
+}
+
+func TestRecorder_LogsDecisionsAtDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r := NewRecorder(":0", WithLogger(logger))
+
+	r.Record(DecisionEvent{Symbol: "SPY", Action: "BUY", RiskDecision: "APPROVE"})
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected one JSON log record, got %q: %v", buf.String(), err)
+	}
+	if record["level"] != "DEBUG" || record["msg"] != "decision recorded" || record["symbol"] != "SPY" {
+		t.Errorf("Unexpected log record: %v", record)
+	}
+}
+
+func TestRecorder_MetricsCountStaleBias(t *testing.T) {
+	r := NewRecorder(":0")
+	r.RecordBiasStale("SPY", "inv-1")
+	r.RecordBiasStale("QQQ", "inv-2")
+
+	rec := httptest.NewRecorder()
+	r.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.Contains(rec.Body.String(), "adk_bias_stale_total 2\n") {
+		t.Errorf("Expected adk_bias_stale_total 2, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestRecorder_MetricsCountSizeMismatches(t *testing.T) {
+	r := NewRecorder(":0")
+	r.RecordSizeMismatch("SPY", "inv-1", 10000, 15000)
+
+	rec := httptest.NewRecorder()
+	r.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.Contains(rec.Body.String(), "adk_size_mismatch_total 1\n") {
+		t.Errorf("Expected adk_size_mismatch_total 1, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestRecorder_MetricsCountSkippedDecisions(t *testing.T) {
+	r := NewRecorder(":0")
+	r.RecordSkip("SPY", "HOLD", "inv-1", 0.9)
+	r.RecordSkip("QQQ", "BUY", "inv-2", 0.2)
+
+	rec := httptest.NewRecorder()
+	r.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.Contains(rec.Body.String(), "adk_decisions_skipped_total 2\n") {
+		t.Errorf("Expected adk_decisions_skipped_total 2, got:\n%s", rec.Body.String())
+	}
+	if recent := r.Recent(10); len(recent) != 0 {
+		t.Errorf("Expected skipped decisions kept out of the recent buffer, got %v", recent)
+	}
+}
+
+func TestRecorder_MetricsCountToolCalls(t *testing.T) {
+	r := NewRecorder(":0")
+	r.RecordToolCall("get_market_snapshot", "inv-1", nil)
+	r.RecordToolCall("get_market_snapshot", "inv-1", errors.New("no data for ZZZZ"))
+	r.RecordToolCall("get_bias_snapshot", "inv-1", errors.New("no bias snapshot for SPY"))
+	r.RecordToolCall("get_market_snapshot", "inv-2", nil)
+
+	for _, accept := range []string{"", "application/openmetrics-text"} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		r.handleMetrics(rec, req)
+
+		want := `adk_tool_calls_total{tool="get_bias_snapshot",result="error"} 1
+adk_tool_calls_total{tool="get_market_snapshot",result="error"} 1
+adk_tool_calls_total{tool="get_market_snapshot",result="ok"} 2
+`
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("Expected tool calls by tool and result (Accept %q), got:\n%s", accept, rec.Body.String())
+		}
+	}
+}
+
+func TestRecorder_MetricsCountThrottles(t *testing.T) {
+	r := NewRecorder(":0")
+	r.RecordThrottle("get_market_snapshot", "inv-1")
+	r.RecordThrottle("get_market_snapshot", "inv-1")
+	r.RecordThrottle("log_trade_decision", "inv-2")
+
+	for _, accept := range []string{"", "application/openmetrics-text"} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		r.handleMetrics(rec, req)
+
+		want := `adk_tool_throttled_total{tool="get_market_snapshot"} 2
+adk_tool_throttled_total{tool="log_trade_decision"} 1
+`
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("Expected throttles by tool (Accept %q), got:\n%s", accept, rec.Body.String())
+		}
+	}
+}
+
+func TestRecorder_MetricsCarryAppAndModeLabels(t *testing.T) {
+	r := NewRecorder(":0", WithApp(`trading "east"`), WithMode("paper"))
+	r.Record(DecisionEvent{Symbol: "SPY"})
+	r.RecordSinkFailure(`file:a\b.jsonl`, "inv-1", errors.New("disk full"))
+
+	for _, accept := range []string{"", "application/openmetrics-text"} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		r.handleMetrics(rec, req)
+
+		body := rec.Body.String()
+		for _, want := range []string{
+			`adk_decisions_total{app="trading \"east\"",mode="paper"} 1`,
+			`adk_circuit_open{app="trading \"east\"",mode="paper"} 0`,
+			`adk_log_sink_failures_total{app="trading \"east\"",mode="paper",sink="file:a\\b.jsonl"} 1`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Accept %q: expected %s, got:\n%s", accept, want, body)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewRecorder(":0", WithMode("live")).handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `adk_decisions_total{mode="live"} 0`) {
+		t.Errorf("Expected an unset app label to be omitted, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestRecorder_StartReportsBoundAddress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewRecorder("127.0.0.1:0", WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer r.Shutdown(context.Background())
+
+	addr := r.Addr()
+	if addr == "" || strings.HasSuffix(addr, ":0") {
+		t.Fatalf("Expected a concrete bound address, got %q", addr)
+	}
+	resp, err := http.Get("http://" + addr + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 from /healthz, got %d", resp.StatusCode)
+	}
+}
+
+func TestRecorder_StartFailsOnPortConflict(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	r := NewRecorder(ln.Addr().String(), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err := r.Start(context.Background()); err == nil {
+		r.Shutdown(context.Background())
+		t.Fatal("Expected Start to fail when the port is already taken")
+	}
+	if r.Addr() != "" {
+		t.Errorf("Expected no bound address after a failed start, got %q", r.Addr())
+	}
+}
+
+func TestRecorder_StartServesOverUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "obs.sock")
+	// A socket file left by a previous run must not block the new listener.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewRecorder("unix:"+path, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer r.Shutdown(context.Background())
+	if r.Addr() != "unix:"+path {
+		t.Errorf("Expected bound address unix:%s, got %q", path, r.Addr())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://recorder/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz over unix socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 from /healthz, got %d", resp.StatusCode)
+	}
+}
+
+func TestRecorder_StartRefusesToReplaceNonSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "obs.sock")
+	if err := os.WriteFile(path, []byte("keep"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	r := NewRecorder("unix:"+path, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err := r.Start(context.Background()); err == nil {
+		r.Shutdown(context.Background())
+		t.Fatal("Expected Start to refuse a regular file at the socket path")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "keep" {
+		t.Errorf("Expected the regular file to be left alone, got %q", data)
+	}
+}
+
+func TestRecorder_MetricsExemplarsNeedOpenMetrics(t *testing.T) {
+	r := NewRecorder(":0")
+	r.Record(DecisionEvent{Symbol: "SPY", Action: "BUY", RiskDecision: "REJECT", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"})
+
+	plain := httptest.NewRecorder()
+	r.handleMetrics(plain, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(plain.Body.String(), "trace_id") {
+		t.Errorf("Expected no exemplars for a plain Prometheus scrape, got:\n%s", plain.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5")
+	om := httptest.NewRecorder()
+	r.handleMetrics(om, req)
+
+	body := om.Body.String()
+	if !strings.HasPrefix(om.Header().Get("Content-Type"), "application/openmetrics-text") {
+		t.Errorf("Expected an OpenMetrics content type, got %q", om.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		`adk_decisions_total 1 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 1 `,
+		`adk_decisions_failures_total 1 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 1 `,
+		"# EOF\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in OpenMetrics output, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestRecorder_ReopenEndpoint(t *testing.T) {
+	r := NewRecorder(":0")
+	calls := 0
+	var fail error
+	r.SetLogReopener(func() error {
+		calls++
+		return fail
+	})
+
+	get := httptest.NewRecorder()
+	r.handleReopen(get, httptest.NewRequest(http.MethodGet, "/reopen", nil))
+	if get.Code != http.StatusMethodNotAllowed || calls != 0 {
+		t.Errorf("Expected GET to be refused without reopening, got %d (%d calls)", get.Code, calls)
+	}
+
+	post := httptest.NewRecorder()
+	r.handleReopen(post, httptest.NewRequest(http.MethodPost, "/reopen", nil))
+	if post.Code != http.StatusOK || calls != 1 {
+		t.Errorf("Expected POST to reopen the logs, got %d (%d calls)", post.Code, calls)
+	}
+
+	fail = errors.New("permission denied")
+	failed := httptest.NewRecorder()
+	r.handleReopen(failed, httptest.NewRequest(http.MethodPost, "/reopen", nil))
+	if failed.Code != http.StatusInternalServerError || !strings.Contains(failed.Body.String(), "permission denied") {
+		t.Errorf("Expected a failed reopen to report 500, got %d: %s", failed.Code, failed.Body.String())
+	}
+}
+
+func TestRecorder_ShutdownClosesLogs(t *testing.T) {
+	r := NewRecorder(":0")
+	closed := 0
+	r.SetLogCloser(func() error {
+		closed++
+		return errors.New("sync failed")
+	})
+
+	if err := r.Shutdown(context.Background()); err == nil || closed != 1 {
+		t.Errorf("Expected Shutdown to close the logs and report the failure, got %v (%d closes)", err, closed)
+	}
+}
@@ -0,0 +1,69 @@
+// Package symbols normalises the ticker variants users type, such as BRK.B, BRK-B
+// and BRKB, to the one form the data is filed under.
+package symbols
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// tickerPattern matches the symbols the tools accept once upper-cased: letters and
+// digits with the ".", "-" and "=" of share classes and futures, and a leading
+// "^" for indices, up to 16 characters.
+var tickerPattern = regexp.MustCompile(`^\^?[A-Z0-9][A-Z0-9.=-]{0,15}$`)
+
+// Aliases maps upper-cased symbol variants to their canonical form. A nil Aliases
+// maps nothing.
+type Aliases map[string]string
+
+// NewAliases returns aliases keyed and valued by upper-cased symbols, rejecting
+// blank entries.
+func NewAliases(aliases map[string]string) (Aliases, error) {
+	out := make(Aliases, len(aliases))
+	for alias, canonical := range aliases {
+		key := normalize(alias)
+		value := normalize(canonical)
+		if key == "" || value == "" {
+			return nil, fmt.Errorf("symbol alias %q -> %q must not be blank", alias, canonical)
+		}
+		out[key] = value
+	}
+	return out, nil
+}
+
+// ReadAliases reads a JSON file mapping variants to canonical symbols, e.g.
+// {"BRK.B": "BRK-B", "BRKB": "BRK-B"}.
+func ReadAliases(path string) (Aliases, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read symbol aliases: %w", err)
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse symbol aliases %s: %w", path, err)
+	}
+	return NewAliases(raw)
+}
+
+// Canonical upper-cases and trims symbol and maps it through the aliases; an
+// unmapped symbol is returned upper-cased.
+func (a Aliases) Canonical(symbol string) string {
+	symbol = normalize(symbol)
+	if canonical, ok := a[symbol]; ok {
+		return canonical
+	}
+	return symbol
+}
+
+// Valid reports whether symbol, trimmed and upper-cased, looks like a ticker, so
+// callers can refuse free text before it reaches a prompt or a file name.
+func Valid(symbol string) bool {
+	return tickerPattern.MatchString(normalize(symbol))
+}
+
+func normalize(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
@@ -0,0 +1,45 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAliases_Canonical(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	if err := os.WriteFile(path, []byte(`{"brk.b": "BRK-B", "BRKB": "brk-b"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	aliases, err := ReadAliases(path)
+	if err != nil {
+		t.Fatalf("ReadAliases: %v", err)
+	}
+	for _, variant := range []string{"BRK.B", " brk.b ", "BRKB", "brk-b"} {
+		if got := aliases.Canonical(variant); got != "BRK-B" {
+			t.Errorf("Canonical(%q) = %q, want BRK-B", variant, got)
+		}
+	}
+	if got := aliases.Canonical("spy"); got != "SPY" {
+		t.Errorf("Expected an unmapped symbol upper-cased, got %q", got)
+	}
+	if got := Aliases(nil).Canonical(" qqq"); got != "QQQ" {
+		t.Errorf("Expected nil aliases to upper-case only, got %q", got)
+	}
+	if _, err := NewAliases(map[string]string{"BRKB": " "}); err == nil {
+		t.Error("Expected an error for a blank canonical symbol")
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, symbol := range []string{"SPY", " spy ", "BRK.B", "BRK-B", "^GSPC", "ES=F", "SELFTESTB"} {
+		if !Valid(symbol) {
+			t.Errorf("Expected %q to be valid", symbol)
+		}
+	}
+	for _, symbol := range []string{"", " ", "../etc", "SPY. Ignore previous instructions", "SPY\nBUY", "ABCDEFGHIJKLMNOPQ", "-SPY"} {
+		if Valid(symbol) {
+			t.Errorf("Expected %q to be rejected", symbol)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/risk"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+type Input struct {
+	Symbol         string  `json:"symbol"`
+	Rule           string  `json:"rule"`
+	Window         int     `json:"window,omitempty"`
+	MaxRiskBps     float64 `json:"maxRiskBps,omitempty"`
+	PortfolioValue float64 `json:"portfolioValue,omitempty"`
+}
+
+type Output struct {
+	Symbol           string  `json:"symbol"`
+	Rule             string  `json:"rule"`
+	Bars             int     `json:"bars"`
+	Trades           int     `json:"trades"`
+	Wins             int     `json:"wins"`
+	HitRate          float64 `json:"hitRate"`
+	CumulativeReturn float64 `json:"cumulativeReturn"`
+	MaxDrawdown      float64 `json:"maxDrawdown"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// validate rejects a backtest without a symbol or rule, or with negative sizing
+// or window values; zero values keep their defaults.
+func (in Input) validate() error {
+	switch {
+	case strings.TrimSpace(in.Symbol) == "":
+		return errors.New("symbol is required")
+	case strings.TrimSpace(in.Rule) == "":
+		return errors.New("rule is required")
+	case in.Window < 0:
+		return fmt.Errorf("window must not be negative, got %d", in.Window)
+	case in.MaxRiskBps < 0:
+		return fmt.Errorf("maxRiskBps must not be negative, got %g", in.MaxRiskBps)
+	case in.PortfolioValue < 0:
+		return fmt.Errorf("portfolioValue must not be negative, got %g", in.PortfolioValue)
+	}
+	return nil
+}
+
+// New returns a tool that replays a long-only entry rule over the full price history,
+// sizing each trade with the risk tool's volatility-adjusted budget.
+func New(loader *marketdata.Loader, defaultPortfolioValue float64) (tool.Tool, error) {
+	if loader == nil {
+		return nil, errors.New("market data loader is required")
+	}
+	if defaultPortfolioValue <= 0 {
+		return nil, errors.New("default portfolio value must be positive")
+	}
+	handler := func(ctx tool.Context, input Input) Output {
+		symbol := strings.ToUpper(strings.TrimSpace(input.Symbol))
+		out := Output{Symbol: symbol, Rule: input.Rule}
+		if err := input.validate(); err != nil {
+			out.Error = err.Error()
+			return out
+		}
+		cond, err := parseRule(input.Rule)
+		if err != nil {
+			out.Error = err.Error()
+			return out
+		}
+		rows, err := loader.Load(ctx, symbol, 0)
+		if err != nil {
+			out.Error = err.Error()
+			return out
+		}
+		portfolioValue := input.PortfolioValue
+		if portfolioValue <= 0 {
+			portfolioValue = defaultPortfolioValue
+		}
+		result := run(rows, cond, input.Window, portfolioValue, input.MaxRiskBps)
+		result.Symbol = symbol
+		result.Rule = input.Rule
+		return result
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "backtest_rule",
+		Description: "Replay a simple long-only rule such as \"buy when ma20>ma50\" over a symbol's history and report trades, hit rate, cumulative return and max drawdown.",
+	}, handler)
+}
+
+// run walks rows day by day, evaluating cond on the trailing window exactly as the
+// snapshot tool would have seen it, entering at the close when cond turns true and
+// exiting at the close when it turns false.
+func run(rows []marketdata.Row, cond condition, window int, portfolioValue, maxRiskBps float64) Output {
+	if window <= 0 {
+		window = 60
+	}
+	out := Output{Bars: len(rows)}
+	if len(rows) < 2 {
+		return out
+	}
+
+	start := window - 1
+	if start >= len(rows) || start < 1 {
+		start = 1
+	}
+
+	cash := portfolioValue
+	peak := portfolioValue
+	var shares, entryPrice float64
+	closeTrade := func(price float64) {
+		cash += shares * price
+		out.Trades++
+		if price > entryPrice {
+			out.Wins++
+		}
+		shares = 0
+	}
+
+	for i := start; i < len(rows); i++ {
+		lo := i + 1 - window
+		if lo < 0 {
+			lo = 0
+		}
+		stats := marketdata.ComputeStats(rows[lo:i+1], marketdata.StatsOptions{})
+		price := rows[i].Close
+		signal := cond.eval(stats)
+
+		switch {
+		case signal && shares == 0 && price > 0:
+			size, _ := risk.PositionSize(cash, maxRiskBps, stats.Volatility)
+			shares = size / price
+			entryPrice = price
+			cash -= shares * price
+		case !signal && shares > 0:
+			closeTrade(price)
+		}
+
+		equity := cash + shares*price
+		if equity > peak {
+			peak = equity
+		}
+		if peak > 0 {
+			if dd := (peak - equity) / peak; dd > out.MaxDrawdown {
+				out.MaxDrawdown = dd
+			}
+		}
+	}
+	if shares > 0 {
+		closeTrade(rows[len(rows)-1].Close)
+	}
+
+	if out.Trades > 0 {
+		out.HitRate = float64(out.Wins) / float64(out.Trades)
+	}
+	out.CumulativeReturn = cash/portfolioValue - 1
+	return out
+}
+
+type condition struct {
+	left  operand
+	op    string
+	right operand
+}
+
+type operand struct {
+	field string
+	value float64
+}
+
+func (c condition) eval(stats marketdata.Summary) bool {
+	l, r := c.left.resolve(stats), c.right.resolve(stats)
+	switch c.op {
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	}
+	return false
+}
+
+func (o operand) resolve(stats marketdata.Summary) float64 {
+	switch o.field {
+	case "":
+		return o.value
+	case "close":
+		return stats.Close
+	case "open":
+		return stats.Open
+	case "high":
+		return stats.High
+	case "low":
+		return stats.Low
+	case "volume":
+		return stats.Volume
+	case "volatility":
+		return stats.Volatility
+	case "atr":
+		return stats.AverageTrueRange
+	case "volumeratio":
+		return stats.VolumeRatio
+	case "trendstrength":
+		return stats.TrendStrength
+	}
+	return stats.MovingAverages[o.field]
+}
+
+var knownFields = map[string]bool{
+	"close": true, "open": true, "high": true, "low": true, "volume": true,
+	"volatility": true, "atr": true, "volumeratio": true, "trendstrength": true,
+	"ma20": true, "ma50": true, "ma100": true,
+}
+
+// parseRule accepts "<lhs> <op> <rhs>" with an optional "buy when" prefix, where each
+// side is a snapshot field (close, ma20, trendStrength, ...) or a number.
+func parseRule(rule string) (condition, error) {
+	text := strings.ToLower(strings.TrimSpace(rule))
+	text = strings.TrimSpace(strings.TrimPrefix(text, "buy when"))
+	if text == "" {
+		return condition{}, errors.New("rule is required")
+	}
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		idx := strings.Index(text, op)
+		if idx < 0 {
+			continue
+		}
+		left, err := parseOperand(text[:idx])
+		if err != nil {
+			return condition{}, err
+		}
+		right, err := parseOperand(text[idx+len(op):])
+		if err != nil {
+			return condition{}, err
+		}
+		return condition{left: left, op: op, right: right}, nil
+	}
+	return condition{}, fmt.Errorf("rule %q has no comparison operator", rule)
+}
+
+func parseOperand(text string) (operand, error) {
+	text = strings.TrimSpace(text)
+	if v, err := strconv.ParseFloat(text, 64); err == nil {
+		return operand{value: v}, nil
+	}
+	if !knownFields[text] {
+		return operand{}, fmt.Errorf("unknown rule field %q", text)
+	}
+	return operand{field: text}, nil
+}
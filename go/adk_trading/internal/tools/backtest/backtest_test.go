@@ -0,0 +1,81 @@
+package backtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr bool
+	}{
+		{"ma crossover", "buy when ma20>ma50", false},
+		{"field vs number", "trendStrength >= 0.01", false},
+		{"close vs ma", "close < ma20", false},
+		{"empty", "", true},
+		{"no operator", "ma20 ma50", true},
+		{"unknown field", "rsi > 70", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseRule(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRun_UptrendIsProfitable(t *testing.T) {
+	rows := make([]marketdata.Row, 0, 80)
+	for i := 0; i < 80; i++ {
+		price := 100.0 + float64(i)
+		rows = append(rows, marketdata.Row{
+			Date:   fmt.Sprintf("2025-01-%02d", i%28+1),
+			Close:  price,
+			High:   price + 1,
+			Low:    price - 1,
+			Open:   price,
+			Volume: 1_000_000,
+		})
+	}
+	cond, err := parseRule("close > ma20")
+	if err != nil {
+		t.Fatalf("parseRule: %v", err)
+	}
+
+	out := run(rows, cond, 30, 1_000_000, 50)
+
+	if out.Trades != 1 {
+		t.Errorf("Expected a single held trade in a steady uptrend, got %d", out.Trades)
+	}
+	if out.HitRate != 1 {
+		t.Errorf("Expected hit rate 1, got %f", out.HitRate)
+	}
+	if out.CumulativeReturn <= 0 {
+		t.Errorf("Expected positive cumulative return, got %f", out.CumulativeReturn)
+	}
+	if out.MaxDrawdown != 0 {
+		t.Errorf("Expected no drawdown in a monotonic uptrend, got %f", out.MaxDrawdown)
+	}
+}
+
+func TestRun_NoSignalNoTrades(t *testing.T) {
+	rows := []marketdata.Row{
+		{Date: "2025-01-01", Close: 100},
+		{Date: "2025-01-02", Close: 99},
+		{Date: "2025-01-03", Close: 98},
+	}
+	cond, _ := parseRule("close > 1000")
+
+	out := run(rows, cond, 2, 1_000_000, 50)
+
+	if out.Trades != 0 || out.CumulativeReturn != 0 {
+		t.Errorf("Expected no trades and flat return, got %d trades, %f return", out.Trades, out.CumulativeReturn)
+	}
+}
@@ -1,13 +1,19 @@
 package bias
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+	"github.com/igorganapolsky/trading/adk_trading/internal/symbols"
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
 )
@@ -29,6 +35,17 @@ type Output struct {
 	AgeMinutes   float64   `json:"ageMinutes"`
 	Fresh        bool      `json:"fresh"`
 	MetadataNote string    `json:"metadataNote,omitempty"`
+	// RefreshedAt is when the in-memory store was last reloaded, when refreshing in the background.
+	RefreshedAt time.Time `json:"refreshedAt,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// validate rejects a lookup without a symbol.
+func (in Input) validate() error {
+	if strings.TrimSpace(in.Symbol) == "" {
+		return errors.New("symbol is required")
+	}
+	return nil
 }
 
 type snapshot struct {
@@ -57,27 +74,103 @@ type rawSnapshot struct {
 	Metadata   map[string]interface{} `json:"metadata"`
 }
 
-// New returns an ADK tool that surfaces bias snapshots published by the slow analyst loop.
-func New(biasDir string) (tool.Tool, error) {
-	if strings.TrimSpace(biasDir) == "" {
-		biasDir = "data/bias"
+// latestFile is the bias store file published by the slow analyst loop.
+const latestFile = "latest_biases.json"
+
+// Validate confirms that the latest bias file in biasDir, or the full store served
+// by a bias service URL, exists and parses, so a missing or corrupt store is
+// reported at startup instead of silently yielding empty snapshots.
+func Validate(biasDir string) error {
+	load, name := newConfig().source(biasDir)
+	if _, err := load(context.Background(), ""); err != nil {
+		return fmt.Errorf("bias store %s is unhealthy: %w", name, err)
 	}
-	handler := func(ctx tool.Context, input Input) Output {
-		symbol := strings.ToUpper(strings.TrimSpace(input.Symbol))
-		if symbol == "" {
-			return Output{}
+	return nil
+}
+
+type config struct {
+	clock           clock.Clock
+	refreshCtx      context.Context
+	refreshInterval time.Duration
+	httpTimeout     time.Duration
+	aliases         symbols.Aliases
+}
+
+// Option customises a bias Store and the tools built on it.
+type Option func(*config)
+
+// WithClock sets the clock freshness and age are measured against.
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) {
+		if c != nil {
+			cfg.clock = c
 		}
-		latestPath := filepath.Join(biasDir, "latest_biases.json")
-		snapshot, err := loadSnapshot(latestPath, symbol)
+	}
+}
+
+// WithSymbolAliases maps the symbol variants users type to the canonical symbol
+// snapshots are published under; unmapped symbols are only upper-cased.
+func WithSymbolAliases(aliases symbols.Aliases) Option {
+	return func(cfg *config) {
+		cfg.aliases = aliases
+	}
+}
+
+func newConfig(opts ...Option) config {
+	cfg := config{clock: clock.System, httpTimeout: DefaultHTTPTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Store is the bias store the bias tools read. Tools built on one Store share its
+// background refresh, if any, rather than each reloading the store.
+type Store struct {
+	cfg  config
+	read func(context.Context, string) (map[string]*snapshot, time.Time, error)
+}
+
+// NewStore returns the bias store at biasDir, the directory holding it or, when it
+// is an http(s) URL, the bias service to query. With WithRefresh its single
+// refresher starts here.
+func NewStore(biasDir string, opts ...Option) *Store {
+	cfg := newConfig(opts...)
+	return &Store{cfg: cfg, read: cfg.newStore(cfg.source(biasDir))}
+}
+
+// New returns an ADK tool that surfaces bias snapshots published by the slow analyst
+// loop from store. When recorder is non-nil every stale snapshot served is counted
+// on it. A lookup that serves no snapshot says why in Output.Error.
+func New(store *Store, recorder *observability.Recorder) (tool.Tool, error) {
+	cfg := store.cfg
+	lookup := func(ctx tool.Context, input Input) (Output, error) {
+		if err := input.validate(); err != nil {
+			return Output{Error: err.Error()}, err
+		}
+		symbol := cfg.aliases.Canonical(input.Symbol)
+		payloads, refreshedAt, err := store.read(ctx, symbol)
 		if err != nil {
-			return Output{Symbol: symbol}
+			if ctx.Err() != nil {
+				return Output{Symbol: symbol, MetadataNote: ctx.Err().Error()}, ctx.Err()
+			}
+			return Output{Symbol: symbol, RefreshedAt: refreshedAt}, err
+		}
+		snapshot, ok := payloads[symbol]
+		if !ok {
+			slog.Debug("no bias snapshot", "invocation", ctx.InvocationID(), "symbol", symbol)
+			return Output{Symbol: symbol, RefreshedAt: refreshedAt}, fmt.Errorf("no bias snapshot for %s", symbol)
 		}
-		now := time.Now().UTC()
+		now := cfg.clock.Now().UTC()
 		ageMinutes := now.Sub(snapshot.CreatedAt).Minutes()
-		fresh := now.Before(snapshot.ExpiresAt) && ageMinutes <= 24*60
+		fresh := snapshot.fresh(now)
+		slog.Debug("bias snapshot served", "invocation", ctx.InvocationID(), "symbol", symbol, "score", snapshot.Score, "fresh", fresh)
 		metaNote := ""
 		if !fresh {
 			metaNote = "stale_bias"
+			if recorder != nil {
+				recorder.RecordBiasStale(symbol, ctx.InvocationID())
+			}
 		}
 		return Output{
 			Symbol:       symbol,
@@ -92,7 +185,15 @@ func New(biasDir string) (tool.Tool, error) {
 			AgeMinutes:   ageMinutes,
 			Fresh:        fresh,
 			MetadataNote: metaNote,
+			RefreshedAt:  refreshedAt,
+		}, nil
+	}
+	handler := func(ctx tool.Context, input Input) Output {
+		out, err := lookup(ctx, input)
+		if err != nil && out.Error == "" {
+			out.Error = err.Error()
 		}
+		return out
 	}
 
 	return functiontool.New(functiontool.Config{
@@ -101,23 +202,72 @@ func New(biasDir string) (tool.Tool, error) {
 	}, handler)
 }
 
-func loadSnapshot(path string, symbol string) (*snapshot, error) {
-	payloads, err := readLatest(path)
-	if err != nil {
-		return nil, err
+// fresh reports whether the snapshot is unexpired and no more than a day old at now.
+func (s *snapshot) fresh(now time.Time) bool {
+	return now.Before(s.ExpiresAt) && now.Sub(s.CreatedAt) <= 24*time.Hour
+}
+
+// loadFunc reads the snapshot for symbol, or the whole bias store when symbol is
+// empty. A source may return more snapshots than asked for.
+type loadFunc func(ctx context.Context, symbol string) (map[string]*snapshot, error)
+
+// source returns how to read the bias store at location, a directory or an
+// http(s) bias service URL, and the name to report it by.
+func (c config) source(location string) (loadFunc, string) {
+	location = strings.TrimSpace(location)
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return newHTTPSource(location, c.httpTimeout).load, location
+	}
+	if location == "" {
+		location = "data/bias"
+	}
+	path := filepath.Join(location, latestFile)
+	return func(ctx context.Context, _ string) (map[string]*snapshot, error) {
+		return readLatest(ctx, path)
+	}, path
+}
+
+// newStore returns how the tools read the bias store: from memory when a
+// background refresh is configured, reporting the last refresh time, or from the
+// source on every call.
+func (c config) newStore(load loadFunc, name string) func(context.Context, string) (map[string]*snapshot, time.Time, error) {
+	if c.refreshInterval <= 0 || c.refreshCtx == nil {
+		return func(ctx context.Context, symbol string) (map[string]*snapshot, time.Time, error) {
+			payloads, err := load(ctx, symbol)
+			return payloads, time.Time{}, err
+		}
 	}
-	entry, ok := payloads[strings.ToUpper(symbol)]
-	if !ok {
-		return nil, errors.New("symbol_not_found")
+	r := startRefresher(c.refreshCtx, load, name, c.refreshInterval, c.clock)
+	return func(ctx context.Context, _ string) (map[string]*snapshot, time.Time, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, time.Time{}, err
+		}
+		view := r.view()
+		if view == nil {
+			return nil, time.Time{}, errors.New("bias store not loaded yet")
+		}
+		return view.payloads, view.refreshedAt, nil
 	}
-	return entry, nil
 }
 
-func readLatest(path string) (map[string]*snapshot, error) {
+// readLatest reads and parses the bias store, giving up if ctx is done before or
+// after the read.
+func readLatest(ctx context.Context, path string) (map[string]*snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parseStore(data)
+}
+
+// parseStore parses a bias store document: a JSON object of snapshots keyed by symbol.
+func parseStore(data []byte) (map[string]*snapshot, error) {
 	var blob map[string]rawSnapshot
 	if err := json.Unmarshal(data, &blob); err != nil {
 		return nil, err
@@ -125,23 +275,26 @@ func readLatest(path string) (map[string]*snapshot, error) {
 	out := make(map[string]*snapshot, len(blob))
 	for sym, snap := range blob {
 		sym = strings.ToUpper(sym)
-		parsed := snapshot{
-			Symbol:     sym,
-			Score:      snap.Score,
-			Direction:  snap.Direction,
-			Conviction: snap.Conviction,
-			Reason:     snap.Reason,
-			Model:      snap.Model,
-			Sources:    append([]string(nil), snap.Sources...),
-			CreatedAt:  parseTime(snap.CreatedAt),
-			ExpiresAt:  parseTime(snap.ExpiresAt),
-			Metadata:   snap.Metadata,
-		}
-		out[sym] = &parsed
+		out[sym] = snap.parse(sym)
 	}
 	return out, nil
 }
 
+func (snap rawSnapshot) parse(symbol string) *snapshot {
+	return &snapshot{
+		Symbol:     symbol,
+		Score:      snap.Score,
+		Direction:  snap.Direction,
+		Conviction: snap.Conviction,
+		Reason:     snap.Reason,
+		Model:      snap.Model,
+		Sources:    append([]string(nil), snap.Sources...),
+		CreatedAt:  parseTime(snap.CreatedAt),
+		ExpiresAt:  parseTime(snap.ExpiresAt),
+		Metadata:   snap.Metadata,
+	}
+}
+
 func parseTime(value string) time.Time {
 	formats := []string{
 		time.RFC3339,
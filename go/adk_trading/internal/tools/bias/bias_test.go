@@ -0,0 +1,142 @@
+package bias
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+	"github.com/igorganapolsky/trading/adk_trading/internal/symbols"
+	"google.golang.org/adk/tool"
+)
+
+func TestValidate(t *testing.T) {
+	healthy := t.TempDir()
+	if err := os.WriteFile(filepath.Join(healthy, latestFile), []byte(`{"spy": {"score": 0.4, "direction": "bullish"}}`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	corrupt := t.TempDir()
+	if err := os.WriteFile(filepath.Join(corrupt, latestFile), []byte(`{"spy": `), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := Validate(healthy); err != nil {
+		t.Errorf("Expected a healthy store to validate, got %v", err)
+	}
+	if err := Validate(corrupt); err == nil {
+		t.Error("Expected a corrupt store to fail validation")
+	}
+	if err := Validate(t.TempDir()); err == nil {
+		t.Error("Expected a missing store to fail validation")
+	}
+}
+
+// fakeContext satisfies tool.Context with a background context.
+type fakeContext struct {
+	tool.Context
+}
+
+func (fakeContext) Deadline() (time.Time, bool) { return context.Background().Deadline() }
+func (fakeContext) Done() <-chan struct{}       { return context.Background().Done() }
+func (fakeContext) Err() error                  { return context.Background().Err() }
+func (fakeContext) Value(key any) any           { return context.Background().Value(key) }
+func (fakeContext) InvocationID() string        { return "inv-1" }
+
+type runnable interface {
+	Run(tool.Context, any) (map[string]any, error)
+}
+
+func TestBiasTool_FlagsStaleSnapshots(t *testing.T) {
+	biasDir := t.TempDir()
+	stale := `{"SPY": {"score": 0.4, "direction": "bullish", "created_at": "2020-01-01T00:00:00Z", "expires_at": "2020-01-02T00:00:00Z"}}`
+	if err := os.WriteFile(filepath.Join(biasDir, latestFile), []byte(stale), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	recorder := observability.NewRecorder("127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := recorder.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	tl, err := New(NewStore(biasDir), recorder)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	out, err := tl.(runnable).Run(fakeContext{}, map[string]any{"symbol": "spy"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if out["fresh"] != false || out["metadataNote"] != "stale_bias" {
+		t.Errorf("Expected a stale snapshot, got %v", out)
+	}
+	resp, err := http.Get("http://" + recorder.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "adk_bias_stale_total 1\n") {
+		t.Errorf("Expected the stale snapshot counted on /metrics, got:\n%s", body)
+	}
+}
+
+func TestBiasTool_FreshnessAcrossTheDayBoundary(t *testing.T) {
+	biasDir := t.TempDir()
+	payload := `{"SPY": {"score": 0.4, "direction": "bullish", "created_at": "2025-01-02T14:30:00Z", "expires_at": "2025-01-05T00:00:00Z"}}`
+	if err := os.WriteFile(filepath.Join(biasDir, latestFile), []byte(payload), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	created := time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantAge   float64
+		wantFresh bool
+	}{
+		{"one minute before a day", created.Add(24*time.Hour - time.Minute), 1439, true},
+		{"exactly a day", created.Add(24 * time.Hour), 1440, true},
+		{"one minute past a day", created.Add(24*time.Hour + time.Minute), 1441, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl, err := New(NewStore(biasDir, WithClock(clock.Fixed(tt.now))), nil)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			out, err := tl.(runnable).Run(fakeContext{}, map[string]any{"symbol": "SPY"})
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if out["ageMinutes"] != tt.wantAge || out["fresh"] != tt.wantFresh {
+				t.Errorf("Expected ageMinutes %v fresh %v, got %v %v", tt.wantAge, tt.wantFresh, out["ageMinutes"], out["fresh"])
+			}
+		})
+	}
+}
+
+func TestBiasTool_SymbolAliases(t *testing.T) {
+	biasDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(biasDir, latestFile), []byte(`{"BRK-B": {"score": 0.2, "direction": "bullish"}}`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	aliases, _ := symbols.NewAliases(map[string]string{"BRK.B": "BRK-B", "BRKB": "BRK-B"})
+	tl, err := New(NewStore(biasDir, WithSymbolAliases(aliases)), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, variant := range []string{"brk.b", "BRK-B", "BRKB"} {
+		out, err := tl.(runnable).Run(fakeContext{}, map[string]any{"symbol": variant})
+		if err != nil || out["symbol"] != "BRK-B" || out["direction"] != "bullish" {
+			t.Errorf("Expected %q to find the BRK-B snapshot, got %v (%v)", variant, out, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package bias
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+type ConsensusInput struct {
+	TopN int `json:"topN,omitempty"`
+}
+
+// validate rejects a negative topN; zero keeps the default.
+func (in ConsensusInput) validate() error {
+	if in.TopN < 0 {
+		return fmt.Errorf("topN must not be negative, got %d", in.TopN)
+	}
+	return nil
+}
+
+type ConsensusOutput struct {
+	Bullish    int      `json:"bullish"`
+	Bearish    int      `json:"bearish"`
+	Neutral    int      `json:"neutral"`
+	Stale      int      `json:"stale"`
+	NetScore   float64  `json:"netScore"`
+	TopBullish []Leader `json:"topBullish"`
+	TopBearish []Leader `json:"topBearish"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Leader is a high-conviction name on one side of the consensus.
+type Leader struct {
+	Symbol     string  `json:"symbol"`
+	Score      float64 `json:"score"`
+	Conviction float64 `json:"conviction"`
+}
+
+// NewConsensus returns a tool that aggregates every fresh entry in store into a
+// market-wide risk-on/risk-off gauge.
+func NewConsensus(store *Store) (tool.Tool, error) {
+	cfg := store.cfg
+	handler := func(ctx tool.Context, input ConsensusInput) ConsensusOutput {
+		if err := input.validate(); err != nil {
+			return ConsensusOutput{TopBullish: []Leader{}, TopBearish: []Leader{}, Error: err.Error()}
+		}
+		payloads, _, err := store.read(ctx, "")
+		if err != nil {
+			return ConsensusOutput{Error: err.Error()}
+		}
+		return consensus(payloads, cfg.clock.Now().UTC(), input.TopN)
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "get_bias_consensus",
+		Description: "Aggregate fresh bias snapshots across all symbols into bullish/bearish counts, a conviction-weighted net direction score in [-1, 1] and the highest-conviction names per side.",
+	}, handler)
+}
+
+// consensus tallies fresh snapshots by direction. NetScore is the conviction-weighted
+// mean direction, +1 when every weighted vote is bullish and -1 when all are bearish.
+func consensus(payloads map[string]*snapshot, now time.Time, topN int) ConsensusOutput {
+	if topN <= 0 {
+		topN = 5
+	}
+	out := ConsensusOutput{TopBullish: []Leader{}, TopBearish: []Leader{}}
+	var weighted, totalConviction float64
+	for _, snap := range payloads {
+		if !snap.fresh(now) {
+			out.Stale++
+			continue
+		}
+		leader := Leader{Symbol: snap.Symbol, Score: snap.Score, Conviction: snap.Conviction}
+		switch direction(snap) {
+		case 1:
+			out.Bullish++
+			out.TopBullish = append(out.TopBullish, leader)
+		case -1:
+			out.Bearish++
+			out.TopBearish = append(out.TopBearish, leader)
+		default:
+			out.Neutral++
+		}
+		weighted += float64(direction(snap)) * snap.Conviction
+		totalConviction += snap.Conviction
+	}
+	if totalConviction > 0 {
+		out.NetScore = weighted / totalConviction
+	}
+	out.TopBullish = topByConviction(out.TopBullish, topN)
+	out.TopBearish = topByConviction(out.TopBearish, topN)
+	return out
+}
+
+// direction maps a snapshot to +1 (bullish), -1 (bearish) or 0, falling back to the
+// sign of the score when the direction label is missing or unrecognised.
+func direction(snap *snapshot) int {
+	switch strings.ToLower(strings.TrimSpace(snap.Direction)) {
+	case "bullish", "long", "buy":
+		return 1
+	case "bearish", "short", "sell":
+		return -1
+	case "neutral":
+		return 0
+	}
+	switch {
+	case snap.Score > 0:
+		return 1
+	case snap.Score < 0:
+		return -1
+	}
+	return 0
+}
+
+func topByConviction(leaders []Leader, n int) []Leader {
+	sort.Slice(leaders, func(i, j int) bool {
+		if leaders[i].Conviction != leaders[j].Conviction {
+			return leaders[i].Conviction > leaders[j].Conviction
+		}
+		return leaders[i].Symbol < leaders[j].Symbol
+	})
+	if len(leaders) > n {
+		leaders = leaders[:n]
+	}
+	return leaders
+}
@@ -0,0 +1,43 @@
+package bias
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConsensus(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	fresh := func(symbol, direction string, conviction float64) *snapshot {
+		return &snapshot{
+			Symbol:     symbol,
+			Direction:  direction,
+			Conviction: conviction,
+			CreatedAt:  now.Add(-time.Hour),
+			ExpiresAt:  now.Add(time.Hour),
+		}
+	}
+	payloads := map[string]*snapshot{
+		"SPY": fresh("SPY", "bullish", 0.9),
+		"QQQ": fresh("QQQ", "bullish", 0.5),
+		"IWM": fresh("IWM", "bearish", 0.6),
+		"TLT": fresh("TLT", "neutral", 0.4),
+		"XLE": {Symbol: "XLE", Direction: "bearish", Conviction: 1, CreatedAt: now.Add(-48 * time.Hour), ExpiresAt: now.Add(time.Hour)},
+	}
+
+	out := consensus(payloads, now, 1)
+
+	if out.Bullish != 2 || out.Bearish != 1 || out.Neutral != 1 || out.Stale != 1 {
+		t.Errorf("Unexpected tallies: %+v", out)
+	}
+	want := (0.9 + 0.5 - 0.6) / (0.9 + 0.5 + 0.6 + 0.4)
+	if math.Abs(out.NetScore-want) > 1e-12 {
+		t.Errorf("Expected net score %f, got %f", want, out.NetScore)
+	}
+	if len(out.TopBullish) != 1 || out.TopBullish[0].Symbol != "SPY" {
+		t.Errorf("Expected SPY as the top bullish name, got %+v", out.TopBullish)
+	}
+	if len(out.TopBearish) != 1 || out.TopBearish[0].Symbol != "IWM" {
+		t.Errorf("Expected stale XLE to be excluded from the bearish leaders, got %+v", out.TopBearish)
+	}
+}
@@ -0,0 +1,132 @@
+package bias
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHTTPTimeout bounds each request to a bias service.
+const DefaultHTTPTimeout = 3 * time.Second
+
+// maxHTTPBody caps a bias service response.
+const maxHTTPBody = 8 << 20
+
+// WithHTTPTimeout sets how long a request to a bias service URL may take. Values
+// below one keep DefaultHTTPTimeout.
+func WithHTTPTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		if d > 0 {
+			cfg.httpTimeout = d
+		}
+	}
+}
+
+// httpSource reads snapshots from a bias service: GET {base}/biases/{symbol} for
+// one symbol and GET {base}/biases for the whole store, each in the bias file's
+// schema. The last good response for each path is kept and served instead when
+// the service fails transiently.
+type httpSource struct {
+	base   string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]map[string]*snapshot
+}
+
+func newHTTPSource(base string, timeout time.Duration) *httpSource {
+	return &httpSource{
+		base:   strings.TrimRight(base, "/"),
+		client: &http.Client{Timeout: timeout},
+		cache:  map[string]map[string]*snapshot{},
+	}
+}
+
+// errStatus is a non-success response from the bias service.
+type errStatus struct {
+	url  string
+	code int
+}
+
+func (e errStatus) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.url, e.code, http.StatusText(e.code))
+}
+
+// transient reports whether a retry might succeed: a network failure, a timeout,
+// throttling or a server error. A caller's own cancellation is not transient.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var status errStatus
+	if errors.As(err, &status) {
+		return status.code == http.StatusTooManyRequests || status.code >= 500
+	}
+	return true
+}
+
+func (s *httpSource) load(ctx context.Context, symbol string) (map[string]*snapshot, error) {
+	path := "/biases"
+	if symbol != "" {
+		path += "/" + url.PathEscape(symbol)
+	}
+	payloads, err := s.fetch(ctx, path, symbol)
+	var status errStatus
+	switch {
+	case err == nil:
+		s.mu.Lock()
+		s.cache[path] = payloads
+		s.mu.Unlock()
+		return payloads, nil
+	case symbol != "" && errors.As(err, &status) && status.code == http.StatusNotFound:
+		// The service has no snapshot for the symbol.
+		return map[string]*snapshot{}, nil
+	case transient(ctx, err):
+		s.mu.Lock()
+		cached, ok := s.cache[path]
+		s.mu.Unlock()
+		if ok {
+			slog.Warn("bias service unavailable; serving the last good response", "url", s.base+path, "error", err)
+			return cached, nil
+		}
+	}
+	return nil, err
+}
+
+// fetch GETs path and parses a single snapshot for symbol, or the whole store
+// when symbol is empty.
+func (s *httpSource) fetch(ctx context.Context, path, symbol string) (map[string]*snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errStatus{url: s.base + path, code: resp.StatusCode}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBody))
+	if err != nil {
+		return nil, err
+	}
+	if symbol == "" {
+		return parseStore(data)
+	}
+	var raw rawSnapshot
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return map[string]*snapshot{symbol: raw.parse(symbol)}, nil
+}
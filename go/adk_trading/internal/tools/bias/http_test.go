@@ -0,0 +1,115 @@
+package bias
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
+)
+
+// biasService serves the bias service endpoints; failing makes every request 503.
+func biasService(t *testing.T, failing *atomic.Bool) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/biases/SPY", func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"score": 0.4, "direction": "bullish", "created_at": "2025-01-02T14:00:00Z", "expires_at": "2025-01-03T14:00:00Z"}`))
+	})
+	mux.HandleFunc("/biases", func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"SPY": {"score": 0.4, "direction": "bullish", "conviction": 0.8, "created_at": "2025-01-02T14:00:00Z", "expires_at": "2025-01-03T14:00:00Z"}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBiasTool_HTTPSource(t *testing.T) {
+	var failing atomic.Bool
+	server := biasService(t, &failing)
+	tl, err := New(NewStore(server.URL+"/", WithClock(clock.Fixed(time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)))), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	run := func(symbol string) map[string]any {
+		t.Helper()
+		out, err := tl.(runnable).Run(fakeContext{}, map[string]any{"symbol": symbol})
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return out
+	}
+
+	if out := run("spy"); out["score"] != 0.4 || out["fresh"] != true {
+		t.Errorf("Expected the served snapshot, got %v", out)
+	}
+	if out := run("QQQ"); out["score"] != 0.0 || out["direction"] != "" {
+		t.Errorf("Expected an empty snapshot for an unknown symbol, got %v", out)
+	}
+
+	failing.Store(true)
+	if out := run("SPY"); out["score"] != 0.4 {
+		t.Errorf("Expected the cached snapshot while the service is down, got %v", out)
+	}
+	if out := run("IWM"); out["score"] != 0.0 {
+		t.Errorf("Expected nothing for a symbol never fetched, got %v", out)
+	}
+}
+
+func TestBiasConsensus_HTTPSource(t *testing.T) {
+	var failing atomic.Bool
+	server := biasService(t, &failing)
+	if err := Validate(server.URL); err != nil {
+		t.Errorf("Expected the service to validate, got %v", err)
+	}
+	tl, err := NewConsensus(NewStore(server.URL, WithClock(clock.Fixed(time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)))))
+	if err != nil {
+		t.Fatalf("NewConsensus: %v", err)
+	}
+
+	out, err := tl.(runnable).Run(fakeContext{}, map[string]any{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out["bullish"] != 1.0 {
+		t.Errorf("Expected one bullish name, got %v", out)
+	}
+
+	failing.Store(true)
+	if err := Validate(server.URL); err == nil {
+		t.Error("Expected a failing service without a cached response to be unhealthy")
+	}
+}
+
+func TestHTTPSource_TimeoutFallsBackToCache(t *testing.T) {
+	var slow atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(`{"score": -0.3}`))
+	}))
+	defer server.Close()
+	source := newHTTPSource(server.URL, 20*time.Millisecond)
+	ctx := fakeContext{}
+
+	if payloads, err := source.load(ctx, "SPY"); err != nil || payloads["SPY"].Score != -0.3 {
+		t.Fatalf("Expected a snapshot, got %v (%v)", payloads, err)
+	}
+	slow.Store(true)
+	if payloads, err := source.load(ctx, "SPY"); err != nil || payloads["SPY"].Score != -0.3 {
+		t.Errorf("Expected the cached snapshot after a timeout, got %v (%v)", payloads, err)
+	}
+	if _, err := source.load(ctx, "QQQ"); err == nil {
+		t.Error("Expected a timeout without a cached response to fail")
+	}
+}
@@ -0,0 +1,72 @@
+package bias
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
+)
+
+// storeView is one successfully parsed bias store.
+type storeView struct {
+	payloads    map[string]*snapshot
+	refreshedAt time.Time
+}
+
+// refresher keeps the latest bias store in memory, re-reading it every interval
+// and swapping the new view in only when it parses, so a torn write never reaches
+// callers.
+type refresher struct {
+	load    loadFunc
+	name    string
+	clock   clock.Clock
+	current atomic.Pointer[storeView]
+}
+
+// WithRefresh serves snapshots from memory, reloading the bias store every interval
+// in the background until ctx is done. A reload that fails keeps the last good
+// store. A non-positive interval reads the file on every call, as by default.
+func WithRefresh(ctx context.Context, interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.refreshCtx = ctx
+		cfg.refreshInterval = interval
+	}
+}
+
+// startRefresher loads the whole store once and then reloads it every interval
+// until ctx is done. name identifies the store in warnings.
+func startRefresher(ctx context.Context, load loadFunc, name string, interval time.Duration, c clock.Clock) *refresher {
+	r := &refresher{load: load, name: name, clock: c}
+	r.refresh(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.refresh(ctx)
+			}
+		}
+	}()
+	return r
+}
+
+func (r *refresher) refresh(ctx context.Context) {
+	payloads, err := r.load(ctx, "")
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("bias store refresh failed; serving the last good snapshot", "store", r.name, "error", err)
+		}
+		return
+	}
+	r.current.Store(&storeView{payloads: payloads, refreshedAt: r.clock.Now().UTC()})
+}
+
+// view returns the last good store, or nil before the first successful load.
+func (r *refresher) view() *storeView {
+	return r.current.Load()
+}
@@ -0,0 +1,101 @@
+package bias
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
+)
+
+func TestBiasTool_BackgroundRefresh(t *testing.T) {
+	biasDir := t.TempDir()
+	path := filepath.Join(biasDir, latestFile)
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	write(`{"SPY": {"score": 0.4, "direction": "bullish"}}`)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	refreshed := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	tl, err := New(NewStore(biasDir, WithClock(clock.Fixed(refreshed)), WithRefresh(ctx, 5*time.Millisecond)), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	score := func() any {
+		t.Helper()
+		out, err := tl.(runnable).Run(fakeContext{}, map[string]any{"symbol": "SPY"})
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return out["score"]
+	}
+
+	out, err := tl.(runnable).Run(fakeContext{}, map[string]any{"symbol": "SPY"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out["score"] != 0.4 || out["refreshedAt"] != refreshed.Format(time.RFC3339) {
+		t.Fatalf("Expected the initial load served with its refresh time, got %v", out)
+	}
+
+	write(`{"SPY": {"score": `)
+	time.Sleep(30 * time.Millisecond)
+	if got := score(); got != 0.4 {
+		t.Errorf("Expected a torn write to keep the last good snapshot, got %v", got)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := score(); got != 0.4 {
+		t.Errorf("Expected calls to be served from memory, got %v", got)
+	}
+
+	write(`{"SPY": {"score": -0.2, "direction": "bearish"}}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for score() != -0.2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the refreshed snapshot to be served")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStore_SharesOneRefresher(t *testing.T) {
+	var loads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loads.Add(1)
+		w.Write([]byte(`{"SPY": {"score": 0.4, "direction": "bullish", "conviction": 0.8, "created_at": "2025-01-02T14:00:00Z", "expires_at": "2025-01-03T14:00:00Z"}}`))
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewStore(server.URL, WithClock(clock.Fixed(time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC))), WithRefresh(ctx, time.Hour))
+
+	biasTool, err := New(store, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	consensusTool, err := NewConsensus(store)
+	if err != nil {
+		t.Fatalf("NewConsensus: %v", err)
+	}
+	if out, err := biasTool.(runnable).Run(fakeContext{}, map[string]any{"symbol": "SPY"}); err != nil || out["score"] != 0.4 {
+		t.Errorf("Expected the shared store's snapshot, got %v (%v)", out, err)
+	}
+	if out, err := consensusTool.(runnable).Run(fakeContext{}, map[string]any{}); err != nil || out["bullish"] != 1.0 {
+		t.Errorf("Expected the shared store's consensus, got %v (%v)", out, err)
+	}
+	if got := loads.Load(); got != 1 {
+		t.Errorf("Expected both tools served by one load of the store, got %d", got)
+	}
+}
@@ -0,0 +1,176 @@
+package correlation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+type Input struct {
+	Symbols []string `json:"symbols"`
+	Window  int      `json:"window,omitempty"`
+}
+
+type Output struct {
+	Symbols      []string                      `json:"symbols"`
+	Matrix       map[string]map[string]float64 `json:"matrix"`
+	Observations int                           `json:"observations"`
+	Skipped      []string                      `json:"skipped,omitempty"`
+	Error        string                        `json:"error,omitempty"`
+}
+
+// validate rejects an empty basket or a negative window; zero keeps the default.
+func (in Input) validate() error {
+	if len(in.Symbols) == 0 {
+		return errors.New("symbols are required")
+	}
+	if in.Window < 0 {
+		return fmt.Errorf("window must not be negative, got %d", in.Window)
+	}
+	return nil
+}
+
+// New returns a tool that correlates daily returns across a basket of symbols.
+func New(loader *marketdata.Loader) (tool.Tool, error) {
+	if loader == nil {
+		return nil, errors.New("market data loader is required")
+	}
+	handler := func(ctx tool.Context, input Input) Output {
+		return Compute(ctx, loader, input)
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "get_correlation_matrix",
+		Description: "Correlate daily returns for a basket of symbols over their common trading dates; symbols without data are listed as skipped.",
+	}, handler)
+}
+
+// Compute correlates daily returns across input.Symbols with loader, as the
+// get_correlation_matrix tool does, for callers that need the matrix directly.
+func Compute(ctx context.Context, loader *marketdata.Loader, input Input) Output {
+	if err := input.validate(); err != nil {
+		return Output{Symbols: []string{}, Matrix: map[string]map[string]float64{}, Error: err.Error()}
+	}
+	window := input.Window
+	if window <= 0 {
+		window = 60
+	}
+	series := make(map[string][]marketdata.Row, len(input.Symbols))
+	var skipped []string
+	for _, raw := range input.Symbols {
+		symbol := strings.ToUpper(strings.TrimSpace(raw))
+		if symbol == "" {
+			continue
+		}
+		if _, seen := series[symbol]; seen {
+			continue
+		}
+		rows, err := loader.Load(ctx, symbol, window)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return Output{Symbols: []string{}, Matrix: map[string]map[string]float64{}, Error: ctxErr.Error()}
+		}
+		if err != nil || len(rows) < 2 {
+			skipped = append(skipped, symbol)
+			continue
+		}
+		series[symbol] = rows
+	}
+	out := compute(series)
+	out.Skipped = skipped
+	return out
+}
+
+// compute aligns every series on the dates they all share and returns the pairwise
+// Pearson correlation of the returns derived from those aligned closes.
+func compute(series map[string][]marketdata.Row) Output {
+	symbols := make([]string, 0, len(series))
+	for symbol := range series {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	out := Output{Symbols: symbols, Matrix: map[string]map[string]float64{}}
+	if len(symbols) == 0 {
+		out.Error = "no symbols with data"
+		return out
+	}
+
+	dates := commonDates(series, symbols)
+	if len(dates) < 3 {
+		out.Error = fmt.Sprintf("only %d common trading dates across the basket", len(dates))
+		return out
+	}
+
+	returns := make(map[string][]float64, len(symbols))
+	for _, symbol := range symbols {
+		byDate := make(map[string]marketdata.Row, len(series[symbol]))
+		for _, row := range series[symbol] {
+			byDate[row.Date] = row
+		}
+		aligned := make([]marketdata.Row, 0, len(dates))
+		for _, date := range dates {
+			aligned = append(aligned, byDate[date])
+		}
+		returns[symbol] = marketdata.ComputeStats(aligned, marketdata.StatsOptions{}).Returns
+	}
+	out.Observations = len(dates) - 1
+
+	for _, a := range symbols {
+		out.Matrix[a] = make(map[string]float64, len(symbols))
+		for _, b := range symbols {
+			out.Matrix[a][b] = pearson(returns[a], returns[b])
+		}
+	}
+	return out
+}
+
+func commonDates(series map[string][]marketdata.Row, symbols []string) []string {
+	counts := map[string]int{}
+	for _, symbol := range symbols {
+		seen := map[string]bool{}
+		for _, row := range series[symbol] {
+			if !seen[row.Date] {
+				seen[row.Date] = true
+				counts[row.Date]++
+			}
+		}
+	}
+	dates := make([]string, 0, len(counts))
+	for date, count := range counts {
+		if count == len(symbols) {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+func pearson(a, b []float64) float64 {
+	n := len(a)
+	if n != len(b) || n < 2 {
+		return 0
+	}
+	var meanA, meanB float64
+	for i := 0; i < n; i++ {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(n)
+	meanB /= float64(n)
+	var cov, varA, varB float64
+	for i := 0; i < n; i++ {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
@@ -0,0 +1,79 @@
+package correlation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+)
+
+func rowsFor(dates []string, closes []float64) []marketdata.Row {
+	rows := make([]marketdata.Row, len(dates))
+	for i := range dates {
+		rows[i] = marketdata.Row{Date: dates[i], Close: closes[i]}
+	}
+	return rows
+}
+
+func TestCompute_AlignsOnCommonDates(t *testing.T) {
+	series := map[string][]marketdata.Row{
+		// SPY has an extra 01-03 bar that QQQ lacks; it must be dropped, not zipped by index.
+		"SPY": rowsFor(
+			[]string{"2025-01-01", "2025-01-02", "2025-01-03", "2025-01-06", "2025-01-07"},
+			[]float64{100, 101, 500, 102, 104},
+		),
+		"QQQ": rowsFor(
+			[]string{"2025-01-01", "2025-01-02", "2025-01-06", "2025-01-07"},
+			[]float64{200, 202, 204, 208},
+		),
+	}
+
+	out := compute(series)
+
+	if out.Error != "" {
+		t.Fatalf("Unexpected error: %s", out.Error)
+	}
+	if out.Observations != 3 {
+		t.Errorf("Expected 3 aligned return observations, got %d", out.Observations)
+	}
+	if got := out.Matrix["SPY"]["QQQ"]; math.Abs(got-1) > 1e-9 {
+		t.Errorf("Expected perfectly correlated aligned returns, got %f", got)
+	}
+	if out.Matrix["SPY"]["QQQ"] != out.Matrix["QQQ"]["SPY"] {
+		t.Error("Expected a symmetric matrix")
+	}
+}
+
+func TestCompute_InsufficientOverlap(t *testing.T) {
+	series := map[string][]marketdata.Row{
+		"SPY": rowsFor([]string{"2025-01-01", "2025-01-02"}, []float64{100, 101}),
+		"QQQ": rowsFor([]string{"2025-01-03", "2025-01-06"}, []float64{200, 202}),
+	}
+
+	out := compute(series)
+
+	if out.Error == "" {
+		t.Error("Expected an error when the basket shares too few dates")
+	}
+}
+
+func TestPearson(t *testing.T) {
+	if got := pearson([]float64{1, 2, 3}, []float64{3, 2, 1}); math.Abs(got+1) > 1e-9 {
+		t.Errorf("Expected -1 for inversely related series, got %f", got)
+	}
+	if got := pearson([]float64{1, 1, 1}, []float64{1, 2, 3}); got != 0 {
+		t.Errorf("Expected 0 for a constant series, got %f", got)
+	}
+}
+
+func TestInput_Validate(t *testing.T) {
+	if err := (Input{}).validate(); err == nil {
+		t.Error("Expected an error without symbols")
+	}
+	if err := (Input{Symbols: []string{"SPY", "QQQ"}, Window: -5}).validate(); err == nil {
+		t.Error("Expected an error for a negative window")
+	}
+	if err := (Input{Symbols: []string{"SPY", "QQQ"}}).validate(); err != nil {
+		t.Errorf("Expected a default window to be valid, got %v", err)
+	}
+}
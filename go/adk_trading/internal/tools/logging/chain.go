@@ -0,0 +1,224 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WithHashChain makes the sink chain its entries for tamper evidence: each entry
+// carries "prev_hash", the hash of the entry before it, and "hash", the SHA-256
+// of its own content including prev_hash. The first entry of a chain links to
+// genesisHash. The chain is seeded from the last entry already in the log and
+// carries on across rotations: each file the sink starts opens with a link
+// record naming the last hash of the file before it. VerifyChain checks it.
+func WithHashChain() FileSinkOption {
+	return func(s *FileSink) {
+		s.chain = true
+	}
+}
+
+// genesisHash is the prev_hash of the first entry of a hash chain.
+const genesisHash = ""
+
+// chainLinkKey marks the link record a chained FileSink writes at the top of each
+// new file, carrying the chain over from the file before it. Readers skip it.
+const chainLinkKey = "chain_link"
+
+// ChainError reports the first entry of an audit log that breaks its hash chain.
+type ChainError struct {
+	// File is the log file holding the offending entry; empty for a SQLite log.
+	File string
+	// Line is the 1-based line number of the offending entry, or its row id in a
+	// SQLite log.
+	Line   int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("hash chain broken at %s line %d: %s", e.File, e.Line, e.Reason)
+	}
+	return fmt.Sprintf("hash chain broken at line %d: %s", e.Line, e.Reason)
+}
+
+// chainEntry returns a copy of entry linked to prevHash, with its hash set, and
+// that hash.
+func chainEntry(entry map[string]any, prevHash string) (map[string]any, string, error) {
+	chained := make(map[string]any, len(entry)+2)
+	for k, v := range entry {
+		chained[k] = v
+	}
+	delete(chained, "hash")
+	chained["prev_hash"] = prevHash
+	hash, err := entryHash(chained)
+	if err != nil {
+		return nil, "", err
+	}
+	chained["hash"] = hash
+	return chained, hash, nil
+}
+
+// entryHash hashes the canonical JSON of entry, which must not hold "hash":
+// objects with sorted keys and numbers as written, so the hash of an entry read
+// back from the log matches the one computed when it was written.
+func entryHash(entry map[string]any) (string, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	var canonical any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&canonical); err != nil {
+		return "", err
+	}
+	if data, err = json.Marshal(canonical); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// chainLink returns the link record opening a new file of a chain whose last
+// entry hashed to prevHash, and the record's hash.
+func chainLink(prevHash string) (map[string]any, string, error) {
+	return chainEntry(map[string]any{chainLinkKey: true}, prevHash)
+}
+
+// isChainLink reports whether raw is a link record rather than an entry.
+func isChainLink(raw map[string]any) bool {
+	link, _ := raw[chainLinkKey].(bool)
+	return link
+}
+
+// decodeLine parses one log line, keeping numbers as written.
+func decodeLine(line []byte) (map[string]any, error) {
+	var raw map[string]any
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, errors.New("not a JSON object")
+	}
+	return raw, nil
+}
+
+// lastHash returns the hash of the last chained entry in the log at path, or ""
+// when the log is missing or holds none. A torn final line is skipped.
+func lastHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("seed hash chain: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("seed hash chain: %w", err)
+	}
+	// The last whole entry fits in the final maxEntrySize bytes plus its newline.
+	offset := max(info.Size()-maxEntrySize-1, 0)
+	tail := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return "", fmt.Errorf("seed hash chain: %w", err)
+	}
+	lines := bytes.Split(bytes.TrimRight(tail, "\n"), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		raw, err := decodeLine(lines[i])
+		if err != nil {
+			continue
+		}
+		hash, _ := raw["hash"].(string)
+		return hash, nil
+	}
+	return "", nil
+}
+
+// VerifyChain walks the audit log at path, its rotations oldest first and then
+// the live log, and returns a *ChainError for the first entry whose hash does not
+// match its content or whose prev_hash does not match the entry before it, nil
+// when the chain is intact, or any error reading the log. Entries written before
+// chaining was enabled are allowed ahead of the first chained one, which must
+// link to genesisHash unless it is a link record, whose predecessor was rotated
+// out of the log. A log without any chained entry fails.
+func VerifyChain(path string) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	var prev string
+	chained := false
+	for _, file := range logFiles(path) {
+		file = compressedRotation(path, file)
+		var err error
+		if prev, chained, err = verifyChainFile(file, prev, chained); err != nil {
+			return err
+		}
+	}
+	if !chained {
+		return &ChainError{File: path, Reason: "log has no chained entries"}
+	}
+	return nil
+}
+
+// verifyChainFile carries the chain VerifyChain is walking through one log file,
+// given the hash of the last chained entry so far and whether there was one, and
+// returns them as of the end of the file. The caller holds fileMu.
+func verifyChainFile(file, prev string, chained bool) (string, bool, error) {
+	r, closeLog, err := openLogFile(file)
+	if err != nil {
+		return "", false, err
+	}
+	defer closeLog()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxEntrySize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		raw, err := decodeLine(scanner.Bytes())
+		if err != nil {
+			return "", false, &ChainError{File: file, Line: line, Reason: "entry is not a JSON object"}
+		}
+		hash, hasHash := raw["hash"].(string)
+		if !hasHash {
+			if chained {
+				return "", false, &ChainError{File: file, Line: line, Reason: "entry has no hash"}
+			}
+			continue
+		}
+		prevHash, _ := raw["prev_hash"].(string)
+		switch {
+		case chained && prevHash != prev:
+			return "", false, &ChainError{File: file, Line: line, Reason: fmt.Sprintf("prev_hash %q does not match the previous entry's hash %q", prevHash, prev)}
+		case !chained && prevHash != genesisHash && !isChainLink(raw):
+			return "", false, &ChainError{File: file, Line: line, Reason: fmt.Sprintf("first chained entry has prev_hash %q rather than the start of a chain", prevHash)}
+		}
+		delete(raw, "hash")
+		want, err := entryHash(raw)
+		if err != nil {
+			return "", false, fmt.Errorf("hash %s line %d: %w", file, line, err)
+		}
+		if hash != want {
+			return "", false, &ChainError{File: file, Line: line, Reason: "hash does not match the entry's content"}
+		}
+		prev, chained = hash, true
+	}
+	if err := scanner.Err(); err != nil {
+		return "", false, fmt.Errorf("read log file: %w", err)
+	}
+	return prev, chained, nil
+}
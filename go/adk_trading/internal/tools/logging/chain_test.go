@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashChain_VerifiesAndSeedsFromFile(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	// Entries written before chaining was enabled are skipped by VerifyChain.
+	plain, _ := NewFileSink(logPath)
+	writeN(t, plain, 1)
+
+	sink, err := NewFileSink(logPath, WithHashChain())
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	writeN(t, sink, 2)
+	sink.Close()
+	// A new sink picks up the chain from the last line.
+	restarted, _ := NewFileSink(logPath, WithHashChain())
+	if err := restarted.Write(map[string]any{"symbol": "QQQ", "metadata": map[string]any{"z": 1, "a": 0.25}}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if err := VerifyChain(logPath); err != nil {
+		t.Fatalf("VerifyChain: %v", err)
+	}
+	raw, err := decodeLine(lastLine(t, logPath))
+	if err != nil {
+		t.Fatalf("decode last line: %v", err)
+	}
+	if raw["hash"] != restarted.prevHash {
+		t.Errorf("Expected the last line to carry the sink's hash, got %v", raw)
+	}
+	if raw["prev_hash"] == "" {
+		t.Error("Expected the restarted sink to seed prev_hash from the file")
+	}
+}
+
+func TestVerifyChain_ReportsFirstBrokenLink(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	sink, _ := NewFileSink(logPath, WithHashChain())
+	writeN(t, sink, 3)
+	sink.Close()
+
+	data, _ := os.ReadFile(logPath)
+	lines := strings.Split(string(data), "\n")
+	lines[1] = strings.Replace(lines[1], `"BUY"`, `"SELL"`, 1)
+	if err := os.WriteFile(logPath, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var chainErr *ChainError
+	if err := VerifyChain(logPath); !errors.As(err, &chainErr) || chainErr.Line != 2 {
+		t.Fatalf("Expected a broken chain at line 2, got %v", err)
+	}
+
+	// Dropping an entry breaks the link of the one after it.
+	lines = strings.Split(string(data), "\n")
+	lines = append(lines[:1], lines[2:]...)
+	os.WriteFile(logPath, []byte(strings.Join(lines, "\n")), 0o644)
+	if err := VerifyChain(logPath); !errors.As(err, &chainErr) || !strings.Contains(chainErr.Reason, "prev_hash") {
+		t.Fatalf("Expected a prev_hash mismatch, got %v", err)
+	}
+}
+
+func TestVerifyChain_AnchorsAtGenesis(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	sink, _ := NewFileSink(logPath, WithHashChain())
+	writeN(t, sink, 3)
+	sink.Close()
+	data, _ := os.ReadFile(logPath)
+	lines := strings.Split(string(data), "\n")
+
+	// Deleting the oldest entries leaves a chain that starts mid-way.
+	os.WriteFile(logPath, []byte(strings.Join(lines[1:], "\n")), 0o644)
+	var chainErr *ChainError
+	if err := VerifyChain(logPath); !errors.As(err, &chainErr) || chainErr.Line != 1 || !strings.Contains(chainErr.Reason, "first chained entry") {
+		t.Fatalf("Expected the truncated chain reported at line 1, got %v", err)
+	}
+
+	// Stripping the chain from every entry leaves nothing to verify.
+	var stripped []string
+	for _, line := range lines[:3] {
+		raw, err := decodeLine([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		delete(raw, "hash")
+		delete(raw, "prev_hash")
+		raw["action"] = "SELL"
+		out, _ := json.Marshal(raw)
+		stripped = append(stripped, string(out))
+	}
+	os.WriteFile(logPath, []byte(strings.Join(stripped, "\n")+"\n"), 0o644)
+	if err := VerifyChain(logPath); !errors.As(err, &chainErr) || !strings.Contains(chainErr.Reason, "no chained entries") {
+		t.Fatalf("Expected an unchained log reported, got %v", err)
+	}
+}
+
+func TestVerifyChain_AcrossRotations(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	sink, err := NewFileSink(logPath, WithHashChain(), WithRotation(400, 3), WithCompressedRotations())
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	writeN(t, sink, 4)
+	sink.Close()
+	sink.compressing.Wait()
+	if err := VerifyChain(logPath); err != nil {
+		t.Fatalf("Expected the chain intact across rotations, got %v", err)
+	}
+	entries, err := ReadRotatedEntries(logPath)
+	if err != nil {
+		t.Fatalf("ReadRotatedEntries: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("Expected the link records left out of the entries, got %+v", entries)
+	}
+
+	// Once the first file is pruned, the oldest kept one opens with its link.
+	restarted, _ := NewFileSink(logPath, WithHashChain(), WithRotation(400, 3), WithCompressedRotations())
+	writeN(t, restarted, 4)
+	restarted.Close()
+	restarted.compressing.Wait()
+	if err := VerifyChain(logPath); err != nil {
+		t.Fatalf("Expected the pruned chain to verify from its link record, got %v", err)
+	}
+
+	// Dropping the live log's link record breaks the chain between the files.
+	data, _ := os.ReadFile(logPath)
+	lines := strings.SplitN(string(data), "\n", 2)
+	if !strings.Contains(lines[0], chainLinkKey) {
+		t.Fatalf("Expected the live log to open with a link record, got %s", lines[0])
+	}
+	os.WriteFile(logPath, []byte(lines[1]), 0o644)
+	var chainErr *ChainError
+	if err := VerifyChain(logPath); !errors.As(err, &chainErr) || chainErr.File != logPath || chainErr.Line != 1 {
+		t.Fatalf("Expected a broken link at the top of the live log, got %v", err)
+	}
+}
+
+func lastLine(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return []byte(lines[len(lines)-1])
+}
@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// explainTolerance is how far a logged timestamp may be from the requested one and
+// still match.
+const explainTolerance = 5 * time.Minute
+
+type ExplainInput struct {
+	// Invocation matches entries by ADK invocation ID and takes precedence.
+	Invocation string `json:"invocation,omitempty"`
+	// Symbol and Timestamp (RFC 3339) match the entry for the symbol logged closest to
+	// Timestamp, within five minutes.
+	Symbol    string `json:"symbol,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// validate rejects a query that names neither an invocation nor a symbol and timestamp.
+func (in ExplainInput) validate() error {
+	if strings.TrimSpace(in.Invocation) != "" {
+		return nil
+	}
+	if strings.TrimSpace(in.Symbol) == "" || strings.TrimSpace(in.Timestamp) == "" {
+		return errors.New("invocation, or symbol and timestamp, are required")
+	}
+	return nil
+}
+
+type ExplainOutput struct {
+	Found        bool    `json:"found"`
+	Entry        *Entry  `json:"entry,omitempty"`
+	RiskDecision string  `json:"riskDecision,omitempty"`
+	PositionSize float64 `json:"positionSize,omitempty"`
+	// Matches counts entries that satisfied the query; Entry is the latest of them
+	// for invocation lookups and the closest for timestamp lookups.
+	Matches int    `json:"matches"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewExplain returns a tool that reconstructs a logged decision from the audit log
+// at logPath and its rotations.
+func NewExplain(logPath string) (tool.Tool, error) {
+	if logPath == "" {
+		return nil, errors.New("log path is required")
+	}
+	return newExplain(func() ([]Entry, error) { return ReadRotatedEntries(logPath) })
+}
+
+// NewSQLiteExplain returns a tool that reconstructs a logged decision from the
+// decisions the SQLite sink has written, for deployments that log only to it.
+func NewSQLiteExplain(sink *SQLiteSink) (tool.Tool, error) {
+	if sink == nil {
+		return nil, errors.New("sqlite sink is required")
+	}
+	return newExplain(sink.Entries)
+}
+
+// newExplain returns the explain tool over the entries read returns.
+func newExplain(read func() ([]Entry, error)) (tool.Tool, error) {
+	handler := func(ctx tool.Context, input ExplainInput) ExplainOutput {
+		if err := input.validate(); err != nil {
+			return ExplainOutput{Error: err.Error()}
+		}
+		if err := ctx.Err(); err != nil {
+			return ExplainOutput{Error: err.Error()}
+		}
+		entries, err := read()
+		if err != nil {
+			return ExplainOutput{Error: err.Error()}
+		}
+		return explain(entries, input)
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "explain_trade_decision",
+		Description: "Find a logged trade decision by invocation ID, or by symbol and timestamp, and return its action, confidence, risk decision and metadata.",
+	}, handler)
+}
+
+func explain(entries []Entry, input ExplainInput) ExplainOutput {
+	var match *Entry
+	matches := 0
+	if invocation := strings.TrimSpace(input.Invocation); invocation != "" {
+		for i := range entries {
+			if entries[i].Invocation == invocation {
+				match = &entries[i]
+				matches++
+			}
+		}
+	} else {
+		symbol := strings.ToUpper(strings.TrimSpace(input.Symbol))
+		at, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(input.Timestamp))
+		if err != nil {
+			return ExplainOutput{Error: fmt.Sprintf("timestamp must be RFC 3339: %v", err)}
+		}
+		best := explainTolerance + 1
+		for i := range entries {
+			if strings.ToUpper(entries[i].Symbol) != symbol || entries[i].Timestamp.IsZero() {
+				continue
+			}
+			gap := entries[i].Timestamp.Sub(at).Abs()
+			if gap > explainTolerance {
+				continue
+			}
+			matches++
+			if gap < best {
+				best, match = gap, &entries[i]
+			}
+		}
+	}
+	if match == nil {
+		return ExplainOutput{}
+	}
+	decision, size := riskFields(match.Metadata)
+	return ExplainOutput{
+		Found:        true,
+		Entry:        match,
+		RiskDecision: decision,
+		PositionSize: size,
+		Matches:      matches,
+	}
+}
@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const mixedLog = `{"timestamp":"2025-01-02T14:30:00Z","symbol":"SPY","action":"BUY","confidence":"0.6","invocation":"inv-old"}
+not json
+{"schema_version":2,"timestamp":"2025-01-02T15:00:00Z","mode":"paper","symbol":"SPY","action":"SELL","confidence":0.7,"metadata":{"risk":{"decision":"REVIEW","position_size":25000}},"agent":"execution_agent","invocation":"inv-1"}
+
+{"schema_version":2,"timestamp":"2025-01-02T15:02:00Z","mode":"paper","symbol":"QQQ","action":"BUY","confidence":0.8,"metadata":{"risk":"APPROVE"},"invocation":"inv-1"}
+`
+
+func writeLog(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestReadEntries_ToleratesOlderSchemas(t *testing.T) {
+	entries, err := ReadEntries(writeLog(t, mixedLog))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries around the malformed and blank lines, got %d", len(entries))
+	}
+	old := entries[0]
+	if old.SchemaVersion != 1 || old.Confidence != 0.6 || old.Mode != "" || old.Line != 1 {
+		t.Errorf("Expected a version 1 entry with string confidence parsed, got %+v", old)
+	}
+	if entries[1].SchemaVersion != 2 || entries[1].Line != 3 || entries[1].Mode != "paper" {
+		t.Errorf("Unexpected version 2 entry: %+v", entries[1])
+	}
+}
+
+func TestExplain_BySymbolAndTimestamp(t *testing.T) {
+	entries, err := ReadEntries(writeLog(t, mixedLog))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+
+	out := explain(entries, ExplainInput{Symbol: "spy", Timestamp: "2025-01-02T15:01:00Z"})
+	if !out.Found || out.Entry.Action != "SELL" || out.RiskDecision != "REVIEW" || out.PositionSize != 25000 {
+		t.Errorf("Expected the 15:00 SPY SELL reviewed at 25000, got %+v", out)
+	}
+
+	if out := explain(entries, ExplainInput{Symbol: "SPY", Timestamp: "2025-01-02T16:00:00Z"}); out.Found || out.Error != "" {
+		t.Errorf("Expected a clean not-found outside the tolerance, got %+v", out)
+	}
+	if out := explain(entries, ExplainInput{Symbol: "SPY", Timestamp: "yesterday"}); out.Error == "" {
+		t.Error("Expected an error for an unparseable timestamp")
+	}
+}
+
+func TestExplain_ByInvocation(t *testing.T) {
+	entries, err := ReadEntries(writeLog(t, mixedLog))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+
+	out := explain(entries, ExplainInput{Invocation: "inv-1"})
+	if !out.Found || out.Matches != 2 || out.Entry.Symbol != "QQQ" || out.RiskDecision != "APPROVE" {
+		t.Errorf("Expected the latest of two inv-1 entries, got %+v", out)
+	}
+	if out := explain(entries, ExplainInput{Invocation: "inv-missing"}); out.Found {
+		t.Errorf("Expected no match for an unknown invocation, got %+v", out)
+	}
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Defaults for the idempotency key set kept by log_trade_decision.
+const (
+	DefaultIdempotencyCapacity = 1024
+	DefaultIdempotencyWindow   = 24 * time.Hour
+)
+
+type seenKey struct {
+	Key string    `json:"key"`
+	At  time.Time `json:"at"`
+}
+
+// keySet remembers the most recent idempotency keys, oldest first, bounded by
+// capacity and window. When path is set it is mirrored to a JSON sidecar file so
+// retries after a restart are still recognised.
+type keySet struct {
+	path     string
+	capacity int
+	window   time.Duration
+	keys     []seenKey
+}
+
+// loadKeySet restores the sidecar at path, dropping keys older than window. A
+// missing sidecar is an empty set.
+func loadKeySet(path string, capacity int, window time.Duration, now time.Time) (*keySet, error) {
+	s := &keySet{path: path, capacity: capacity, window: window}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("read idempotency keys: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.keys); err != nil {
+		return s, fmt.Errorf("parse idempotency keys %s: %w", path, err)
+	}
+	s.prune(now)
+	return s, nil
+}
+
+// seen reports whether key was added within the window.
+func (s *keySet) seen(key string, now time.Time) bool {
+	for _, k := range s.keys {
+		if k.Key == key && now.Sub(k.At) <= s.window {
+			return true
+		}
+	}
+	return false
+}
+
+// add records key and rewrites the sidecar.
+func (s *keySet) add(key string, now time.Time) error {
+	s.keys = append(s.keys, seenKey{Key: key, At: now})
+	s.prune(now)
+	return s.save()
+}
+
+// prune drops expired keys and then the oldest keys beyond capacity.
+func (s *keySet) prune(now time.Time) {
+	kept := s.keys[:0]
+	for _, k := range s.keys {
+		if now.Sub(k.At) <= s.window {
+			kept = append(kept, k)
+		}
+	}
+	if len(kept) > s.capacity {
+		kept = kept[len(kept)-s.capacity:]
+	}
+	s.keys = kept
+}
+
+// save replaces the sidecar atomically so a crash never leaves it half-written.
+func (s *keySet) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.keys)
+	if err != nil {
+		return fmt.Errorf("marshal idempotency keys: %w", err)
+	}
+	ensureDir(s.path)
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("write idempotency keys: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write idempotency keys: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write idempotency keys: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("write idempotency keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
+)
+
+func TestLoggingTool_IdempotencyKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	tl, err := New(fileSinks(t, path), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	first := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7, "idempotencyKey": "run-1/SPY"})
+	retry := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.71, "idempotencyKey": "run-1/SPY"})
+	other := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7, "idempotencyKey": "run-2/SPY"})
+
+	if first["status"] != "logged" || retry["status"] != "duplicate" || other["status"] != "logged" {
+		t.Errorf("Expected logged/duplicate/logged, got %v/%v/%v", first["status"], retry["status"], other["status"])
+	}
+	entries, err := ReadEntries(path)
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].IdempotencyKey != "run-1/SPY" {
+		t.Errorf("Expected two entries carrying their keys, got %+v", entries)
+	}
+}
+
+func TestLoggingTool_IdempotencyKeysSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	opts := func() []Option {
+		return []Option{WithClock(clock.Fixed(now)), WithIdempotency(filepath.Join(dir, "keys.json"), 2, time.Hour)}
+	}
+	tl, err := New(fileSinks(t, path), nil, opts()...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		runTool(t, tl, map[string]any{"symbol": key, "action": "BUY", "confidence": 0.5, "idempotencyKey": key})
+	}
+
+	restarted, err := New(fileSinks(t, path), nil, opts()...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if out := runTool(t, restarted, map[string]any{"symbol": "c", "action": "BUY", "confidence": 0.5, "idempotencyKey": "c"}); out["status"] != "duplicate" {
+		t.Errorf("Expected a remembered key to be a duplicate after restart, got %v", out["status"])
+	}
+	if out := runTool(t, restarted, map[string]any{"symbol": "a", "action": "BUY", "confidence": 0.5, "idempotencyKey": "a"}); out["status"] != "logged" {
+		t.Errorf("Expected the oldest key to be evicted beyond capacity, got %v", out["status"])
+	}
+
+	now = now.Add(2 * time.Hour)
+	expired, err := New(fileSinks(t, path), nil, opts()...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if out := runTool(t, expired, map[string]any{"symbol": "c", "action": "BUY", "confidence": 0.5, "idempotencyKey": "c"}); out["status"] != "logged" {
+		t.Errorf("Expected keys older than the window to be forgotten, got %v", out["status"])
+	}
+}
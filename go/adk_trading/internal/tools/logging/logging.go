@@ -1,16 +1,19 @@
 package logging
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"log/slog"
+	"math"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
 	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
 )
@@ -21,128 +24,373 @@ type Input struct {
 	Confidence float64        `json:"confidence"`
 	Notes      string         `json:"notes,omitempty"`
 	Metadata   map[string]any `json:"metadata,omitempty"`
+	// IdempotencyKey identifies a logical decision across retries: a key already
+	// logged within the idempotency window is reported as "duplicate" and not rewritten.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
+// validate rejects a decision that cannot be audited meaningfully: one without a
+// symbol or action, or with a confidence outside [0, 1].
+func (in Input) validate() error {
+	switch {
+	case strings.TrimSpace(in.Symbol) == "":
+		return errors.New("symbol is required")
+	case strings.TrimSpace(in.Action) == "":
+		return errors.New("action is required")
+	case math.IsNaN(in.Confidence) || in.Confidence < 0 || in.Confidence > 1:
+		return fmt.Errorf("confidence must be between 0 and 1, got %g", in.Confidence)
+	}
+	return nil
+}
+
+// DefaultSizeTolerance is the relative difference between the executed size and the
+// risk-approved size tolerated before an entry is flagged as a size mismatch.
+const DefaultSizeTolerance = 0.01
+
+// Output reports status "logged" when every sink took the entry, "partial" when
+// some failed, "error" when none did, "duplicate" when deduplicated by content
+// or idempotency key and "skipped" when below the minimum confidence to log.
+// Path is the first file sink's path, if any. SizeMismatch is set when the
+// metadata's executed size diverges from metadata.risk.position_size. Error
+// explains an "error" status caused by invalid input, which is never written.
 type Output struct {
-	Status    string    `json:"status"`
-	Path      string    `json:"path"`
-	Timestamp time.Time `json:"timestamp"`
+	Status       string    `json:"status"`
+	Path         string    `json:"path"`
+	Timestamp    time.Time `json:"timestamp"`
+	Errors       []string  `json:"errors,omitempty"`
+	SizeMismatch bool      `json:"sizeMismatch,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+type lastWrite struct {
+	hash [sha256.Size]byte
+	at   time.Time
 }
 
-var fileMu sync.Mutex
+type config struct {
+	mode         string
+	dedupWindow  time.Duration
+	clock        clock.Clock
+	keysPath     string
+	keysCapacity int
+	keysWindow   time.Duration
+	sizeTol      float64
+	minConf      float64
+}
+
+// Option customises the logging tool.
+type Option func(*config)
 
-func New(logPath string, recorder *observability.Recorder) (tool.Tool, error) {
-	if logPath == "" {
-		return nil, errors.New("log path is required")
+// WithMode tags every entry and recorder event with the trading mode (e.g. "paper" or "live").
+func WithMode(mode string) Option {
+	return func(c *config) {
+		c.mode = mode
 	}
-	handler := func(ctx tool.Context, input Input) Output {
-		timestamp := time.Now().UTC()
-		entry := map[string]any{
-			"timestamp":  timestamp.Format(time.RFC3339Nano),
-			"symbol":     input.Symbol,
-			"action":     input.Action,
-			"confidence": input.Confidence,
-			"notes":      input.Notes,
-			"metadata":   input.Metadata,
-			"agent":      ctx.AgentName(),
-			"invocation": ctx.InvocationID(),
-		}
-		ensureDir(logPath)
-		fileMu.Lock()
-		defer fileMu.Unlock()
-		f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-		if err != nil {
-			if recorder != nil {
-				recorder.Record(observability.DecisionEvent{
-					Timestamp:  timestamp,
-					Symbol:     input.Symbol,
-					Action:     input.Action,
-					Confidence: input.Confidence,
-					Error:      fmt.Sprintf("open log file: %v", err),
-					Metadata:   input.Metadata,
-				})
+}
+
+// WithClock sets the clock entries are timestamped with.
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) {
+		if c != nil {
+			cfg.clock = c
+		}
+	}
+}
+
+// WithDedupWindow skips an entry whose symbol, action, confidence and notes match the
+// previous entry this tool wrote within window, reporting status "duplicate".
+// A zero window disables deduplication.
+func WithDedupWindow(window time.Duration) Option {
+	return func(c *config) {
+		c.dedupWindow = window
+	}
+}
+
+// WithIdempotency sets where idempotency keys are persisted and how many, and for
+// how long, they are remembered. By default keys are kept in a ".keys.json" sidecar
+// beside the first file sink; an empty path keeps them in memory only. Zero capacity
+// or window keep DefaultIdempotencyCapacity and DefaultIdempotencyWindow.
+func WithIdempotency(path string, capacity int, window time.Duration) Option {
+	return func(c *config) {
+		c.keysPath = path
+		if capacity > 0 {
+			c.keysCapacity = capacity
+		}
+		if window > 0 {
+			c.keysWindow = window
+		}
+	}
+}
+
+// WithSizeTolerance sets the relative difference between the executed size and the
+// risk-approved size tolerated before an entry is flagged as a size mismatch.
+// Zero flags any difference.
+func WithSizeTolerance(tolerance float64) Option {
+	return func(c *config) {
+		c.sizeTol = tolerance
+	}
+}
+
+// WithMinConfidenceToLog skips, with status "skipped", decisions whose confidence
+// is below min, and every HOLD once min is above zero, so they stay out of the
+// audit log. Skips are still counted on the recorder. Zero logs everything.
+func WithMinConfidenceToLog(min float64) Option {
+	return func(c *config) {
+		c.minConf = min
+	}
+}
+
+// New returns a tool that writes each decision entry to every sink. A failing sink
+// does not stop the others; each failure is counted on recorder when it is non-nil.
+func New(sinks []Sink, recorder *observability.Recorder, opts ...Option) (tool.Tool, error) {
+	if len(sinks) == 0 {
+		return nil, errors.New("at least one log sink is required")
+	}
+	var logPath string
+	for _, sink := range sinks {
+		if sink == nil {
+			return nil, errors.New("log sinks must not be nil")
+		}
+		if fs, ok := sink.(*FileSink); ok && logPath == "" {
+			logPath = fs.Path()
+		}
+	}
+	cfg := config{
+		clock:        clock.System,
+		keysCapacity: DefaultIdempotencyCapacity,
+		keysWindow:   DefaultIdempotencyWindow,
+		sizeTol:      DefaultSizeTolerance,
+	}
+	if logPath != "" {
+		cfg.keysPath = logPath + ".keys.json"
+	} else {
+		// Without a file sink the keys live beside the first SQLite database.
+		for _, sink := range sinks {
+			if db, ok := sink.(*SQLiteSink); ok {
+				cfg.keysPath = db.Path() + ".keys.json"
+				break
 			}
-			return Output{Status: "error", Path: logPath, Timestamp: timestamp}
 		}
-		defer f.Close()
-		data, err := json.Marshal(entry)
-		if err != nil {
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.sizeTol < 0 {
+		return nil, fmt.Errorf("size tolerance must not be negative, got %g", cfg.sizeTol)
+	}
+	if math.IsNaN(cfg.minConf) || cfg.minConf < 0 || cfg.minConf > 1 {
+		return nil, fmt.Errorf("minimum confidence to log must be between 0 and 1, got %g", cfg.minConf)
+	}
+	keys, err := loadKeySet(cfg.keysPath, cfg.keysCapacity, cfg.keysWindow, cfg.clock.Now().UTC())
+	if err != nil {
+		slog.Warn("starting with no remembered idempotency keys", "error", err)
+	}
+
+	// mu serialises writes so the dedup check and the fan-out happen atomically.
+	var mu sync.Mutex
+	var last *lastWrite
+	logDecision := func(ctx tool.Context, input Input) Output {
+		timestamp := cfg.clock.Now().UTC()
+		if err := input.validate(); err != nil {
+			return Output{Status: "error", Path: logPath, Timestamp: timestamp, Error: err.Error()}
+		}
+		if cfg.minConf > 0 && (input.Confidence < cfg.minConf || strings.EqualFold(strings.TrimSpace(input.Action), "HOLD")) {
 			if recorder != nil {
-				recorder.Record(observability.DecisionEvent{
-					Timestamp:  timestamp,
-					Symbol:     input.Symbol,
-					Action:     input.Action,
-					Confidence: input.Confidence,
-					Error:      fmt.Sprintf("marshal log entry: %v", err),
-					Metadata:   input.Metadata,
-				})
+				recorder.RecordSkip(input.Symbol, input.Action, ctx.InvocationID(), input.Confidence)
 			}
-			return Output{Status: "error", Path: logPath, Timestamp: timestamp}
+			return Output{Status: "skipped", Path: logPath, Timestamp: timestamp}
 		}
-		if _, err := f.Write(append(data, '\n')); err != nil {
+		entry := map[string]any{
+			"schema_version": observability.SchemaVersion,
+			"timestamp":      timestamp.Format(time.RFC3339Nano),
+			"mode":           cfg.mode,
+			"symbol":         input.Symbol,
+			"action":         input.Action,
+			"confidence":     input.Confidence,
+			"notes":          input.Notes,
+			"metadata":       input.Metadata,
+			"agent":          ctx.AgentName(),
+			"invocation":     ctx.InvocationID(),
+		}
+		fail := func(err error, errs []string) Output {
 			if recorder != nil {
 				recorder.Record(observability.DecisionEvent{
 					Timestamp:  timestamp,
+					Mode:       cfg.mode,
 					Symbol:     input.Symbol,
 					Action:     input.Action,
 					Confidence: input.Confidence,
-					Error:      fmt.Sprintf("write log entry: %v", err),
+					Error:      err.Error(),
 					Metadata:   input.Metadata,
+					TraceID:    traceID(ctx),
+					Invocation: ctx.InvocationID(),
 				})
 			}
-			return Output{Status: "error", Path: logPath, Timestamp: timestamp}
+			return Output{Status: "error", Path: logPath, Timestamp: timestamp, Errors: errs}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return fail(fmt.Errorf("log entry not written: %w", err), nil)
+		}
+		key := strings.TrimSpace(input.IdempotencyKey)
+		if key != "" && keys.seen(key, timestamp) {
+			return Output{Status: "duplicate", Path: logPath, Timestamp: timestamp}
+		}
+		if key != "" {
+			entry["idempotency_key"] = key
+		}
+		approved, executed, mismatch := sizeMismatch(input.Metadata, cfg.sizeTol)
+		if mismatch {
+			entry["size_mismatch"] = true
+		}
+		hash := dedupHash(input)
+		if cfg.dedupWindow > 0 && last != nil && last.hash == hash && timestamp.Sub(last.at) <= cfg.dedupWindow {
+			return Output{Status: "duplicate", Path: logPath, Timestamp: timestamp}
+		}
+
+		var errs []string
+		for _, sink := range sinks {
+			if err := sink.Write(entry); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", sink.Name(), err))
+				if recorder != nil {
+					recorder.RecordSinkFailure(sink.Name(), ctx.InvocationID(), err)
+				}
+			}
+		}
+		if len(errs) == len(sinks) {
+			return fail(fmt.Errorf("log entry not written: %s", strings.Join(errs, "; ")), errs)
+		}
+		last = &lastWrite{hash: hash, at: timestamp}
+		if key != "" {
+			if err := keys.add(key, timestamp); err != nil {
+				slog.Warn("idempotency key not persisted", "key", key, "error", err)
+			}
 		}
 		if recorder != nil {
-			recorder.Record(buildDecisionEvent(timestamp, input))
+			event := buildDecisionEvent(timestamp, input)
+			event.Mode = cfg.mode
+			event.TraceID = traceID(ctx)
+			event.Invocation = ctx.InvocationID()
+			event.SizeMismatch = mismatch
+			recorder.Record(event)
+		}
+		if mismatch {
+			if recorder != nil {
+				recorder.RecordSizeMismatch(input.Symbol, ctx.InvocationID(), approved, executed)
+			} else {
+				slog.Warn("executed size differs from risk-approved size",
+					"symbol", input.Symbol,
+					"invocation", ctx.InvocationID(),
+					"approved_size", approved,
+					"executed_size", executed,
+				)
+			}
+		}
+		status := "logged"
+		if len(errs) > 0 {
+			status = "partial"
 		}
 		return Output{
-			Status:    "logged",
-			Path:      logPath,
-			Timestamp: timestamp,
+			Status:       status,
+			Path:         logPath,
+			Timestamp:    timestamp,
+			Errors:       errs,
+			SizeMismatch: mismatch,
 		}
 	}
 	return functiontool.New(functiontool.Config{
 		Name:        "log_trade_decision",
-		Description: "Append the proposed trade decision into the orchestrator JSONL audit log.",
-	}, handler)
+		Description: "Record the proposed trade decision in the orchestrator audit log sinks (JSONL file, SQLite, syslog).",
+	}, logDecision)
 }
 
-func ensureDir(path string) {
-	dir := filepath.Dir(path)
-	if dir == "" || dir == "." {
-		return
+// traceID returns the active OpenTelemetry trace ID, or "" when the call is not
+// traced; the invocation ID is recorded separately and is no trace ID.
+func traceID(ctx tool.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
 	}
-	_ = os.MkdirAll(dir, 0o755)
+	return ""
+}
+
+// dedupHash identifies an entry by the fields a retried orchestration repeats verbatim.
+func dedupHash(input Input) [sha256.Size]byte {
+	data, _ := json.Marshal([]any{input.Symbol, input.Action, input.Confidence, input.Notes})
+	return sha256.Sum256(data)
 }
 
 func buildDecisionEvent(ts time.Time, input Input) observability.DecisionEvent {
 	event := observability.DecisionEvent{
-		Timestamp:  ts,
-		Symbol:     input.Symbol,
-		Action:     strings.ToUpper(input.Action),
-		Confidence: input.Confidence,
-		Metadata:   input.Metadata,
+		SchemaVersion: observability.SchemaVersion,
+		Timestamp:     ts,
+		Symbol:        input.Symbol,
+		Action:        strings.ToUpper(input.Action),
+		Confidence:    input.Confidence,
+		Metadata:      input.Metadata,
 	}
 
-	if input.Metadata == nil {
-		return event
-	}
+	event.RiskDecision, event.PositionSize = riskFields(input.Metadata)
+	return event
+}
 
-	if riskVal, ok := input.Metadata["risk"]; ok {
-		switch risk := riskVal.(type) {
-		case map[string]any:
-			if dec, ok := risk["decision"]; ok {
-				event.RiskDecision = fmt.Sprintf("%v", dec)
-			}
-			if ps, ok := risk["position_size"].(float64); ok {
-				event.PositionSize = ps
-			} else if ps, ok := risk["positionSize"].(float64); ok {
-				event.PositionSize = ps
-			}
-		default:
-			event.RiskDecision = fmt.Sprintf("%v", risk)
+// riskFields extracts the risk decision and position size the execution agent
+// nests under metadata["risk"], either as an object or a bare decision string.
+func riskFields(metadata map[string]any) (string, float64) {
+	riskVal, ok := metadata["risk"]
+	if !ok {
+		return "", 0
+	}
+	var decision string
+	var size float64
+	switch risk := riskVal.(type) {
+	case map[string]any:
+		if dec, ok := risk["decision"]; ok {
+			decision = fmt.Sprintf("%v", dec)
+		}
+		if ps, ok := risk["position_size"].(float64); ok {
+			size = ps
+		} else if ps, ok := risk["positionSize"].(float64); ok {
+			size = ps
 		}
+	default:
+		decision = fmt.Sprintf("%v", risk)
 	}
+	return decision, size
+}
 
-	return event
+// sizeMismatch compares the position size risk approved with the size the execution
+// agent logged as metadata["executed_size"] (or a top-level position_size). It
+// reports a mismatch only when both are present and differ by more than tolerance
+// relative to the approved size.
+func sizeMismatch(metadata map[string]any, tolerance float64) (approved, executed float64, mismatch bool) {
+	approved, ok := ApprovedSize(metadata)
+	if !ok {
+		return 0, 0, false
+	}
+	executed, ok = sizeField(metadata, "executed_size", "executedSize", "position_size", "positionSize")
+	if !ok {
+		return 0, 0, false
+	}
+	return approved, executed, math.Abs(executed-approved) > tolerance*math.Abs(approved)
+}
+
+// ApprovedSize returns the position size risk approved for a decision, as the
+// execution agent nests it under metadata["risk"]; ok is false when there is none.
+func ApprovedSize(metadata map[string]any) (float64, bool) {
+	risk, ok := metadata["risk"].(map[string]any)
+	if !ok {
+		return 0, false
+	}
+	return sizeField(risk, "position_size", "positionSize")
+}
+
+// sizeField returns the first of keys holding a number.
+func sizeField(fields map[string]any, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		if size, ok := fields[key].(float64); ok {
+			return size, true
+		}
+	}
+	return 0, false
 }
@@ -0,0 +1,321 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/adk/tool"
+)
+
+var fixedTime = time.Date(2025, 1, 2, 15, 30, 0, 0, time.UTC)
+
+// fakeContext satisfies tool.Context for the handful of methods the handler calls.
+// Context methods delegate to ctx, or to context.Background() when it is nil.
+type fakeContext struct {
+	tool.Context
+	ctx        context.Context
+	agent      string
+	invocation string
+}
+
+func (f fakeContext) AgentName() string    { return f.agent }
+func (f fakeContext) InvocationID() string { return f.invocation }
+
+func (f fakeContext) base() context.Context {
+	if f.ctx != nil {
+		return f.ctx
+	}
+	return context.Background()
+}
+
+func (f fakeContext) Deadline() (time.Time, bool) { return f.base().Deadline() }
+func (f fakeContext) Done() <-chan struct{}       { return f.base().Done() }
+func (f fakeContext) Err() error                  { return f.base().Err() }
+func (f fakeContext) Value(key any) any           { return f.base().Value(key) }
+
+type runnable interface {
+	Run(tool.Context, any) (map[string]any, error)
+}
+
+func fileSinks(t *testing.T, path string) []Sink {
+	t.Helper()
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	return []Sink{sink}
+}
+
+func runTool(t *testing.T, tl tool.Tool, args map[string]any) map[string]any {
+	t.Helper()
+	r, ok := tl.(runnable)
+	if !ok {
+		t.Fatalf("tool %T is not runnable", tl)
+	}
+	out, err := r.Run(fakeContext{agent: "execution_agent", invocation: "inv-1"}, args)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return out
+}
+
+func readEntries(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Unmarshal %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLogTool_WritesSchemaVersion(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	tl, err := New(fileSinks(t, logPath), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	out := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7})
+
+	if out["status"] != "logged" {
+		t.Fatalf("Expected status logged, got %v", out["status"])
+	}
+	entries := readEntries(t, logPath)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if got := entries[0]["schema_version"]; got != float64(observability.SchemaVersion) {
+		t.Errorf("Expected schema_version %d, got %v", observability.SchemaVersion, got)
+	}
+}
+
+func TestBuildDecisionEvent_SchemaVersion(t *testing.T) {
+	event := buildDecisionEvent(fixedTime, Input{Symbol: "SPY", Action: "buy"})
+	if event.SchemaVersion != observability.SchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", observability.SchemaVersion, event.SchemaVersion)
+	}
+	if event.Action != "BUY" {
+		t.Errorf("Expected action to be upper-cased, got %q", event.Action)
+	}
+}
+
+func TestLogTool_TagsMode(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	recorder := observability.NewRecorder(":0")
+	tl, err := New(fileSinks(t, logPath), recorder, WithMode("paper"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7})
+
+	entries := readEntries(t, logPath)
+	if entries[0]["mode"] != "paper" {
+		t.Errorf("Expected mode paper on the log entry, got %v", entries[0]["mode"])
+	}
+	// Without an active span there is no trace ID; the invocation is kept apart.
+	if recent := recorder.Recent(1); len(recent) != 1 || recent[0].TraceID != "" || recent[0].Invocation != "inv-1" {
+		t.Errorf("Expected the recorded event to carry invocation inv-1 and no trace ID, got %+v", recent)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	traced := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	if _, err := tl.(runnable).Run(fakeContext{ctx: traced, agent: "execution_agent", invocation: "inv-2"}, map[string]any{"symbol": "QQQ", "action": "BUY", "confidence": 0.7}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if recent := recorder.Recent(1); len(recent) != 1 || recent[0].TraceID != traceID.String() || recent[0].Invocation != "inv-2" {
+		t.Errorf("Expected the active span's trace ID on the event, got %+v", recent)
+	}
+}
+
+func TestLogTool_DedupsConsecutiveIdenticalEntries(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	tl, err := New(fileSinks(t, logPath), nil, WithDedupWindow(time.Minute))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	args := map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7, "notes": "breakout"}
+
+	first := runTool(t, tl, args)
+	second := runTool(t, tl, args)
+	third := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7, "notes": "retest"})
+
+	if first["status"] != "logged" || second["status"] != "duplicate" || third["status"] != "logged" {
+		t.Errorf("Expected logged/duplicate/logged, got %v/%v/%v", first["status"], second["status"], third["status"])
+	}
+	if entries := readEntries(t, logPath); len(entries) != 2 {
+		t.Errorf("Expected 2 entries on disk, got %d", len(entries))
+	}
+}
+
+func TestLogTool_DedupDisabledByDefault(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	tl, err := New(fileSinks(t, logPath), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	args := map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7}
+
+	runTool(t, tl, args)
+	out := runTool(t, tl, args)
+
+	if out["status"] != "logged" {
+		t.Errorf("Expected identical entries to be logged without a dedup window, got %v", out["status"])
+	}
+	if entries := readEntries(t, logPath); len(entries) != 2 {
+		t.Errorf("Expected 2 entries on disk, got %d", len(entries))
+	}
+}
+
+func TestLogTool_SkipsWriteWhenContextDone(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	tl, err := New(fileSinks(t, logPath), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out, err := tl.(runnable).Run(fakeContext{ctx: ctx}, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if out["status"] != "error" {
+		t.Errorf("Expected status error for a cancelled context, got %v", out["status"])
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no log file to be written, got %v", err)
+	}
+}
+
+func TestLogTool_TimestampsFromClock(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	at := time.Date(2025, 1, 2, 14, 30, 0, 123, time.FixedZone("EST", -5*3600))
+	tl, err := New(fileSinks(t, logPath), nil, WithClock(clock.Fixed(at)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	out := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7})
+
+	want := at.UTC().Format(time.RFC3339Nano)
+	if entries := readEntries(t, logPath); entries[0]["timestamp"] != want {
+		t.Errorf("Expected entry timestamp %s, got %v", want, entries[0]["timestamp"])
+	}
+	if out["timestamp"] != want {
+		t.Errorf("Expected output timestamp %s, got %v", want, out["timestamp"])
+	}
+}
+
+func TestLogTool_FlagsSizeMismatch(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	recorder := observability.NewRecorder(":0")
+	tl, err := New(fileSinks(t, logPath), recorder, WithSizeTolerance(0.05))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logged := func(executed float64) map[string]any {
+		return runTool(t, tl, map[string]any{
+			"symbol": "SPY", "action": "BUY", "confidence": 0.7,
+			"metadata": map[string]any{
+				"risk":          map[string]any{"decision": "APPROVE", "position_size": 10000.0},
+				"executed_size": executed,
+			},
+		})
+	}
+
+	if out := logged(10400); out["sizeMismatch"] != nil {
+		t.Errorf("Expected a 4%% difference to be tolerated, got %v", out)
+	}
+	if out := logged(15000); out["sizeMismatch"] != true {
+		t.Errorf("Expected a 50%% difference to be flagged, got %v", out)
+	}
+	if out := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7, "metadata": map[string]any{"risk": "APPROVE", "executed_size": 1.0}}); out["sizeMismatch"] != nil {
+		t.Errorf("Expected no flag without an approved size, got %v", out)
+	}
+
+	entries := readEntries(t, logPath)
+	if _, ok := entries[0]["size_mismatch"]; ok || entries[1]["size_mismatch"] != true {
+		t.Errorf("Expected only the second entry flagged, got %v and %v", entries[0]["size_mismatch"], entries[1]["size_mismatch"])
+	}
+	if recent := recorder.Recent(2); !recent[1].SizeMismatch || recent[0].SizeMismatch {
+		t.Errorf("Expected only the mismatched event flagged, got %+v", recent)
+	}
+}
+
+func TestNew_RejectsNegativeSizeTolerance(t *testing.T) {
+	if _, err := New(fileSinks(t, filepath.Join(t.TempDir(), "decisions.jsonl")), nil, WithSizeTolerance(-0.1)); err == nil {
+		t.Error("Expected an error for a negative size tolerance")
+	}
+}
+
+func TestLogTool_SkipsBelowMinConfidence(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	recorder := observability.NewRecorder(":0")
+	tl, err := New(fileSinks(t, logPath), recorder, WithMinConfidenceToLog(0.5))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	weak := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.3})
+	hold := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "hold", "confidence": 0.9})
+	strong := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.5})
+
+	if weak["status"] != "skipped" || hold["status"] != "skipped" || strong["status"] != "logged" {
+		t.Errorf("Expected skipped/skipped/logged, got %v/%v/%v", weak["status"], hold["status"], strong["status"])
+	}
+	if entries := readEntries(t, logPath); len(entries) != 1 || entries[0]["confidence"] != 0.5 {
+		t.Errorf("Expected only the 0.5 BUY on disk, got %v", entries)
+	}
+	if recent := recorder.Recent(10); len(recent) != 1 {
+		t.Errorf("Expected only the logged decision recorded as an event, got %+v", recent)
+	}
+}
+
+func TestNew_RejectsInvalidMinConfidence(t *testing.T) {
+	for _, min := range []float64{-0.1, 1.5} {
+		if _, err := New(fileSinks(t, filepath.Join(t.TempDir(), "decisions.jsonl")), nil, WithMinConfidenceToLog(min)); err == nil {
+			t.Errorf("Expected an error for a minimum confidence of %g", min)
+		}
+	}
+}
+
+func TestLogTool_RejectsInvalidInput(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	tl, err := New(fileSinks(t, logPath), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	for _, args := range []map[string]any{
+		{"symbol": " ", "action": "BUY", "confidence": 0.7},
+		{"symbol": "SPY", "action": "", "confidence": 0.7},
+		{"symbol": "SPY", "action": "BUY", "confidence": 1.5},
+	} {
+		out := runTool(t, tl, args)
+		if out["status"] != "error" || out["error"] == nil {
+			t.Errorf("Expected %v to be reported as an error, got %v", args, out)
+		}
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written for invalid entries, got %v", err)
+	}
+}
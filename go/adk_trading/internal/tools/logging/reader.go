@@ -0,0 +1,203 @@
+package logging
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEntrySize bounds a single JSONL line; metadata blobs can be large.
+const maxEntrySize = 4 << 20
+
+// Entry is a decision read back from the JSONL audit log. Entries written before
+// the log carried a schema_version are reported as version 1.
+type Entry struct {
+	SchemaVersion  int            `json:"schemaVersion"`
+	Timestamp      time.Time      `json:"timestamp"`
+	Mode           string         `json:"mode,omitempty"`
+	Symbol         string         `json:"symbol"`
+	Action         string         `json:"action"`
+	Confidence     float64        `json:"confidence"`
+	Notes          string         `json:"notes,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+	Agent          string         `json:"agent,omitempty"`
+	Invocation     string         `json:"invocation,omitempty"`
+	IdempotencyKey string         `json:"idempotencyKey,omitempty"`
+	SizeMismatch   bool           `json:"sizeMismatch,omitempty"`
+	// Line is the 1-based line number of the entry in the log file, or its row id
+	// when read from a SQLite log.
+	Line int `json:"line"`
+	// File is the rotation the entry was read from; empty for the live log.
+	File string `json:"file,omitempty"`
+}
+
+// ReadEntries parses every decision in the audit log at path, oldest first. Lines
+// that are blank or not JSON objects are skipped so one torn write does not hide
+// the rest of the log.
+// A path ending in ".gz" is decompressed.
+func ReadEntries(path string) ([]Entry, error) {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	return readEntryFile(path)
+}
+
+// ReadRotatedEntries reads the rotations of the audit log at path, oldest first,
+// followed by the live log, decompressing gzipped rotations. Each rotated entry
+// records the file it came from. A missing live log is not an error when
+// rotations exist.
+func ReadRotatedEntries(path string) ([]Entry, error) {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	var entries []Entry
+	for _, file := range logFiles(path) {
+		file = compressedRotation(path, file)
+		read, err := readEntryFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if file != path {
+			for j := range read {
+				read[j].File = file
+			}
+		}
+		entries = append(entries, read...)
+	}
+	return entries, nil
+}
+
+// logFiles lists the rotations of the log at path, oldest first and by their
+// uncompressed names, followed by the live log, which is left out only when it is
+// missing and rotations exist. The caller holds fileMu.
+func logFiles(path string) []string {
+	var rotations []string
+	for n := 1; ; n++ {
+		rotated := rotationPath(path, n)
+		if !exists(rotated) && !exists(rotated+".gz") {
+			break
+		}
+		rotations = append(rotations, rotated)
+	}
+	var files []string
+	for i := len(rotations) - 1; i >= 0; i-- {
+		files = append(files, rotations[i])
+	}
+	if len(rotations) == 0 || exists(path) {
+		files = append(files, path)
+	}
+	return files
+}
+
+// compressedRotation returns file, a rotation listed by logFiles, with ".gz"
+// added once a background compression has replaced the plain rotation.
+func compressedRotation(path, file string) string {
+	if file != path && !exists(file) {
+		return file + ".gz"
+	}
+	return file
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// readEntryFile parses one log file; the caller holds fileMu.
+func readEntryFile(path string) ([]Entry, error) {
+	r, closeLog, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeLog()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxEntrySize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var raw map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil || raw == nil {
+			continue
+		}
+		if isChainLink(raw) {
+			continue
+		}
+		entry := parseEntry(raw)
+		entry.Line = line
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read log file: %w", err)
+	}
+	return entries, nil
+}
+
+// openLogFile opens the log at path for reading, decompressing a ".gz" rotation.
+// The caller calls the returned func to close it.
+func openLogFile(path string) (io.Reader, func(), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open log file: %w", err)
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, func() { f.Close() }, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("open compressed log file: %w", err)
+	}
+	return zr, func() { zr.Close(); f.Close() }, nil
+}
+
+// parseEntry reads fields leniently: older writers omitted schema_version and mode
+// and some emitted numbers as strings.
+func parseEntry(raw map[string]any) Entry {
+	entry := Entry{
+		SchemaVersion:  int(number(raw["schema_version"])),
+		Mode:           text(raw["mode"]),
+		Symbol:         text(raw["symbol"]),
+		Action:         text(raw["action"]),
+		Confidence:     number(raw["confidence"]),
+		Notes:          text(raw["notes"]),
+		Agent:          text(raw["agent"]),
+		Invocation:     text(raw["invocation"]),
+		IdempotencyKey: text(raw["idempotency_key"]),
+	}
+	entry.SizeMismatch, _ = raw["size_mismatch"].(bool)
+	if entry.SchemaVersion == 0 {
+		entry.SchemaVersion = 1
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, text(raw["timestamp"])); err == nil {
+		entry.Timestamp = ts.UTC()
+	}
+	if metadata, ok := raw["metadata"].(map[string]any); ok {
+		entry.Metadata = metadata
+	}
+	return entry
+}
+
+func text(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+func number(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
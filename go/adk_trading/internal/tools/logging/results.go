@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ResultInput is the root agent's final structured decision. Fields are optional in
+// the schema so a malformed blob gets a descriptive error instead of a bare
+// validation failure.
+type ResultInput struct {
+	Symbol       string `json:"symbol,omitempty"`
+	TradeSummary any    `json:"trade_summary,omitempty"`
+	Risk         any    `json:"risk,omitempty"`
+	Execution    any    `json:"execution,omitempty"`
+	NextSteps    any    `json:"next_steps,omitempty"`
+}
+
+type ResultOutput struct {
+	Status    string    `json:"status"`
+	Path      string    `json:"path,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Missing lists required keys that were absent or empty.
+	Missing []string `json:"missing,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// ResultsFile returns the per-day results file in dir for day.
+func ResultsFile(dir string, day time.Time) string {
+	return filepath.Join(dir, "orchestration_results_"+day.UTC().Format("2006-01-02")+".jsonl")
+}
+
+// NewResults returns a tool that appends the orchestrator's final decision as one
+// JSON line to a per-day file in dir, separate from the trade-decision log.
+func NewResults(dir string, opts ...Option) (tool.Tool, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, errors.New("results directory is required")
+	}
+	cfg := config{clock: clock.System}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	handler := func(ctx tool.Context, input ResultInput) ResultOutput {
+		timestamp := cfg.clock.Now().UTC()
+		if err := ctx.Err(); err != nil {
+			return ResultOutput{Status: "error", Timestamp: timestamp, Error: err.Error()}
+		}
+		if missing := input.missing(); len(missing) > 0 {
+			return ResultOutput{
+				Status:    "error",
+				Timestamp: timestamp,
+				Missing:   missing,
+				Error:     fmt.Sprintf("result is missing required keys: %s", strings.Join(missing, ", ")),
+			}
+		}
+		sink, err := NewFileSink(ResultsFile(dir, timestamp))
+		if err != nil {
+			return ResultOutput{Status: "error", Timestamp: timestamp, Error: err.Error()}
+		}
+		defer sink.Close()
+		err = sink.Write(map[string]any{
+			"schema_version": observability.SchemaVersion,
+			"timestamp":      timestamp.Format(time.RFC3339Nano),
+			"mode":           cfg.mode,
+			"agent":          ctx.AgentName(),
+			"invocation":     ctx.InvocationID(),
+			"symbol":         strings.ToUpper(strings.TrimSpace(input.Symbol)),
+			"trade_summary":  input.TradeSummary,
+			"risk":           input.Risk,
+			"execution":      input.Execution,
+			"next_steps":     input.NextSteps,
+		})
+		if err != nil {
+			return ResultOutput{Status: "error", Path: sink.Path(), Timestamp: timestamp, Error: err.Error()}
+		}
+		return ResultOutput{Status: "persisted", Path: sink.Path(), Timestamp: timestamp}
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "persist_orchestration_result",
+		Description: "Persist the final orchestration decision (symbol, trade_summary, risk, execution, next_steps) to the daily results file.",
+	}, handler)
+}
+
+// missing returns the required keys that are absent, null or empty.
+func (in ResultInput) missing() []string {
+	var missing []string
+	if strings.TrimSpace(in.Symbol) == "" {
+		missing = append(missing, "symbol")
+	}
+	for _, field := range []struct {
+		key   string
+		value any
+	}{
+		{"trade_summary", in.TradeSummary},
+		{"risk", in.Risk},
+		{"execution", in.Execution},
+		{"next_steps", in.NextSteps},
+	} {
+		if isEmpty(field.value) {
+			missing = append(missing, field.key)
+		}
+	}
+	return missing
+}
+
+func isEmpty(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(val) == ""
+	case map[string]any:
+		return len(val) == 0
+	case []any:
+		return len(val) == 0
+	}
+	return false
+}
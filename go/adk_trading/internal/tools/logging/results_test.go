@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/clock"
+)
+
+func TestResultsTool_PersistsPerDay(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2025, 1, 2, 21, 0, 0, 0, time.UTC)
+	tl, err := NewResults(dir, WithMode("paper"), WithClock(clock.Fixed(day)))
+	if err != nil {
+		t.Fatalf("NewResults: %v", err)
+	}
+
+	out := runTool(t, tl, map[string]any{
+		"symbol":        "spy",
+		"trade_summary": "Buy SPY on breadth confirmation",
+		"risk":          map[string]any{"decision": "APPROVE", "positionSize": 25000.0},
+		"execution":     map[string]any{"order_type": "limit"},
+		"next_steps":    []any{"monitor the close"},
+	})
+
+	if out["status"] != "persisted" || out["path"] != ResultsFile(dir, day) {
+		t.Fatalf("Expected the result persisted to the daily file, got %v", out)
+	}
+	data, err := os.ReadFile(ResultsFile(dir, day))
+	if err != nil {
+		t.Fatalf("read results: %v", err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("results line is not JSON: %v", err)
+	}
+	if entry["symbol"] != "SPY" || entry["mode"] != "paper" || entry["invocation"] != "inv-1" {
+		t.Errorf("Unexpected result entry %v", entry)
+	}
+	if !strings.HasSuffix(ResultsFile(dir, day), "orchestration_results_2025-01-02.jsonl") {
+		t.Errorf("Unexpected results file name %s", ResultsFile(dir, day))
+	}
+}
+
+func TestResultsTool_ReportsMissingKeys(t *testing.T) {
+	dir := t.TempDir()
+	tl, err := NewResults(dir)
+	if err != nil {
+		t.Fatalf("NewResults: %v", err)
+	}
+
+	out := runTool(t, tl, map[string]any{"symbol": "SPY", "trade_summary": "", "risk": map[string]any{}})
+
+	if out["status"] != "error" || !strings.Contains(out["error"].(string), "trade_summary, risk, execution, next_steps") {
+		t.Errorf("Expected every missing key named, got %v", out)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected nothing written for a malformed result, found %d files", len(entries))
+	}
+}
@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// DefaultRotationKeep is how many rotations a rotating FileSink keeps when the
+// option does not say.
+const DefaultRotationKeep = 5
+
+// FileSinkOption customises a FileSink.
+type FileSinkOption func(*FileSink)
+
+// WithRotation rotates the log to "<path>.1" before a write would grow it past
+// maxBytes, shifting older rotations up and deleting any beyond keep. A zero
+// keep uses DefaultRotationKeep.
+func WithRotation(maxBytes int64, keep int) FileSinkOption {
+	return func(s *FileSink) {
+		s.maxBytes = maxBytes
+		s.keep = keep
+	}
+}
+
+// WithCompressedRotations gzips rotations older than "<path>.1" to "<path>.N.gz".
+// Compression runs in the background after a rotation, so only a write that has
+// to rotate while an earlier compression is still running ever waits for it.
+func WithCompressedRotations() FileSinkOption {
+	return func(s *FileSink) {
+		s.compress = true
+	}
+}
+
+// rotationPath returns the path of the n-th rotation of path, uncompressed.
+func rotationPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// rotate closes the live log, shifts every rotation up by one and moves the live
+// log to "<path>.1". The caller holds fileMu and reopens the log afterwards.
+func (s *FileSink) rotate() error {
+	if err := s.close(); err != nil {
+		return err
+	}
+	s.rotateMu.Lock()
+	defer s.rotateMu.Unlock()
+	for n := s.keep; n >= 1; n-- {
+		for _, ext := range []string{"", ".gz"} {
+			from := rotationPath(s.path, n) + ext
+			var err error
+			if n == s.keep {
+				err = os.Remove(from)
+			} else {
+				err = os.Rename(from, rotationPath(s.path, n+1)+ext)
+			}
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("rotate log file: %w", err)
+			}
+		}
+	}
+	if err := os.Rename(s.path, rotationPath(s.path, 1)); err != nil {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+	if s.compress && s.keep > 1 {
+		s.compressing.Add(1)
+		go func() {
+			defer s.compressing.Done()
+			s.compressRotations()
+		}()
+	}
+	return nil
+}
+
+// compressRotations gzips every uncompressed rotation past the first. Failures
+// are logged and retried after the next rotation.
+func (s *FileSink) compressRotations() {
+	s.rotateMu.Lock()
+	defer s.rotateMu.Unlock()
+	for n := 2; n <= s.keep; n++ {
+		path := rotationPath(s.path, n)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := gzipFile(path); err != nil {
+			slog.Warn("log rotation not compressed", "path", path, "error", err)
+		}
+	}
+}
+
+// gzipFile replaces path with path+".gz", writing through a temporary file so a
+// reader never sees a partial archive.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeN(t *testing.T, sink *FileSink, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := sink.Write(map[string]any{"symbol": "SPY", "action": "BUY", "invocation": strings.Repeat("x", 40), "seq": i}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+}
+
+func TestFileSink_RotatesAndCompresses(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	sink, err := NewFileSink(logPath, WithRotation(100, 3), WithCompressedRotations())
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+
+	// Each entry exceeds half the limit, so every write after the first rotates.
+	writeN(t, sink, 5)
+	sink.compressing.Wait()
+
+	for _, name := range []string{"decisions.jsonl", "decisions.jsonl.1", "decisions.jsonl.2.gz", "decisions.jsonl.3.gz"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(logPath), name)); err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+		}
+	}
+	for _, name := range []string{"decisions.jsonl.2", "decisions.jsonl.3", "decisions.jsonl.4", "decisions.jsonl.4.gz"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(logPath), name)); err == nil {
+			t.Errorf("Expected %s to be gone", name)
+		}
+	}
+
+	entries, err := ReadRotatedEntries(logPath)
+	if err != nil {
+		t.Fatalf("ReadRotatedEntries: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected the 4 kept entries, got %d", len(entries))
+	}
+	if entries[0].File != logPath+".3.gz" || entries[3].File != "" {
+		t.Errorf("Expected entries oldest first with their files, got %q first and %q last", entries[0].File, entries[3].File)
+	}
+}
+
+func TestFileSink_RotatesWithoutCompression(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	sink, err := NewFileSink(logPath, WithRotation(100, 2))
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+
+	writeN(t, sink, 3)
+
+	if _, err := os.Stat(logPath + ".2"); err != nil {
+		t.Errorf("Expected an uncompressed second rotation: %v", err)
+	}
+	if _, err := os.Stat(logPath + ".2.gz"); err == nil {
+		t.Error("Expected no compressed rotation without the option")
+	}
+}
+
+func TestReadRotatedEntries_NoRotations(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	if _, err := ReadRotatedEntries(logPath); err == nil {
+		t.Error("Expected an error for a missing log without rotations")
+	}
+	sink, _ := NewFileSink(logPath)
+	writeN(t, sink, 2)
+	if entries, err := ReadRotatedEntries(logPath); err != nil || len(entries) != 2 {
+		t.Errorf("Expected the live entries, got %d (%v)", len(entries), err)
+	}
+}
+
+func TestNewFileSink_RejectsNegativeRotation(t *testing.T) {
+	if _, err := NewFileSink("x.jsonl", WithRotation(-1, 0)); err == nil {
+		t.Error("Expected an error for a negative rotation size")
+	}
+	if _, err := NewFileSink("x.jsonl", WithRotation(100, -1)); err == nil {
+		t.Error("Expected an error for a negative rotation count")
+	}
+}
@@ -0,0 +1,240 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Sink receives every audit log entry written by log_trade_decision.
+type Sink interface {
+	// Name identifies the sink in errors and in the sink label of
+	// adk_log_sink_failures_total, e.g. "file:/var/log/decisions.jsonl".
+	Name() string
+	Write(entry map[string]any) error
+}
+
+// fileMu serialises appends to, and reads of, JSONL audit logs across tools.
+var fileMu sync.Mutex
+
+// FileSink appends entries as JSON lines to a local file, creating its directory
+// on first write. The file stays open between writes; Reopen makes the next write
+// open the path afresh after an external tool has moved the file away.
+type FileSink struct {
+	path     string
+	maxBytes int64
+	keep     int
+	compress bool
+	// chain enables hash chaining; prevHash is the hash of the last entry written,
+	// guarded by fileMu and seeded from the file on first open.
+	chain    bool
+	prevHash string
+	seeded   bool
+	// file and size are the open log and its length, guarded by fileMu; file is
+	// nil until the next write opens the path.
+	file *os.File
+	size int64
+	// rotateMu serialises rotation with background compression of old rotations.
+	rotateMu    sync.Mutex
+	compressing sync.WaitGroup
+}
+
+// NewFileSink returns a sink appending to the JSONL file at path.
+func NewFileSink(path string, opts ...FileSinkOption) (*FileSink, error) {
+	if path == "" {
+		return nil, errors.New("log path is required")
+	}
+	s := &FileSink{path: path}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.maxBytes < 0 {
+		return nil, fmt.Errorf("log rotation size must not be negative, got %d", s.maxBytes)
+	}
+	if s.keep < 0 {
+		return nil, fmt.Errorf("log rotations kept must not be negative, got %d", s.keep)
+	}
+	if s.keep == 0 {
+		s.keep = DefaultRotationKeep
+	}
+	return s, nil
+}
+
+// Path returns the file the sink appends to.
+func (s *FileSink) Path() string { return s.path }
+
+func (s *FileSink) Name() string { return "file:" + s.path }
+
+func (s *FileSink) Write(entry map[string]any) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	if err := s.open(); err != nil {
+		return err
+	}
+	var hash string
+	if s.chain {
+		var err error
+		if entry, hash, err = chainEntry(entry, s.prevHash); err != nil {
+			return fmt.Errorf("hash log entry: %w", err)
+		}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal log entry: %w", err)
+	}
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(data))+1 > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+		if err := s.open(); err != nil {
+			return err
+		}
+		if s.chain {
+			// The new file's link record is now the entry's predecessor.
+			if entry, hash, err = chainEntry(entry, s.prevHash); err != nil {
+				return fmt.Errorf("hash log entry: %w", err)
+			}
+			if data, err = json.Marshal(entry); err != nil {
+				return fmt.Errorf("marshal log entry: %w", err)
+			}
+		}
+	}
+	n, err := s.file.Write(append(data, '\n'))
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write log entry: %w", err)
+	}
+	if s.chain {
+		s.prevHash = hash
+	}
+	return nil
+}
+
+// Reopen closes the log file so the next write opens the path again, picking up
+// the new file after logrotate (without copytruncate) has renamed the old one.
+func (s *FileSink) Reopen() error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	return s.close()
+}
+
+// Close closes the log file; a later write reopens it.
+func (s *FileSink) Close() error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	return s.close()
+}
+
+// open opens the log for appending unless it already is. The caller holds fileMu.
+func (s *FileSink) open() error {
+	if s.file != nil {
+		return nil
+	}
+	if s.chain && !s.seeded {
+		hash, err := lastHash(s.path)
+		if err == nil && hash == genesisHash {
+			// The live log may have just been rotated away.
+			hash, err = lastHash(rotationPath(s.path, 1))
+		}
+		if err != nil {
+			return err
+		}
+		s.prevHash, s.seeded = hash, true
+	}
+	ensureDir(s.path)
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("open log file: %w", err)
+	}
+	s.file, s.size = f, info.Size()
+	if s.chain && s.size == 0 && s.prevHash != genesisHash {
+		if err := s.writeChainLink(); err != nil {
+			s.close()
+			return err
+		}
+	}
+	return nil
+}
+
+// writeChainLink starts a new, empty log file with the link record carrying the
+// chain over from the file before it. The caller holds fileMu.
+func (s *FileSink) writeChainLink() error {
+	link, hash, err := chainLink(s.prevHash)
+	if err != nil {
+		return fmt.Errorf("hash chain link: %w", err)
+	}
+	data, err := json.Marshal(link)
+	if err != nil {
+		return fmt.Errorf("marshal chain link: %w", err)
+	}
+	n, err := s.file.Write(append(data, '\n'))
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write chain link: %w", err)
+	}
+	s.prevHash = hash
+	return nil
+}
+
+// close closes the log file if it is open. The caller holds fileMu.
+func (s *FileSink) close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file, s.size = nil, 0
+	if err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+	return nil
+}
+
+// Reopener is implemented by sinks that hold a file open between writes.
+type Reopener interface {
+	Reopen() error
+}
+
+// ReopenSinks reopens every sink that implements Reopener, as on SIGHUP after
+// external log rotation, and returns every failure joined.
+func ReopenSinks(sinks []Sink) error {
+	var errs []error
+	for _, sink := range sinks {
+		if r, ok := sink.(Reopener); ok {
+			if err := r.Reopen(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CloseSinks closes every sink that implements io.Closer, as on shutdown, and
+// returns every failure joined.
+func CloseSinks(sinks []Sink) error {
+	var errs []error
+	for _, sink := range sinks {
+		if c, ok := sink.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func ensureDir(path string) {
+	dir := filepath.Dir(path)
+	if dir == "" || dir == "." {
+		return
+	}
+	_ = os.MkdirAll(dir, 0o755)
+}
@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+)
+
+type failingSink struct{}
+
+func (failingSink) Name() string                     { return "broken" }
+func (failingSink) Write(entry map[string]any) error { return errors.New("disk on fire") }
+
+func TestLogTool_FailingSinkDoesNotBlockOthers(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	recorder := observability.NewRecorder("127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := recorder.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	tl, err := New(append([]Sink{failingSink{}}, fileSinks(t, logPath)...), recorder)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	out := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7})
+
+	if out["status"] != "partial" {
+		t.Errorf("Expected status partial, got %v", out["status"])
+	}
+	if entries := readEntries(t, logPath); len(entries) != 1 {
+		t.Errorf("Expected the file sink to still receive the entry, got %d entries", len(entries))
+	}
+	resp, err := http.Get("http://" + recorder.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `adk_log_sink_failures_total{sink="broken"} 1`) {
+		t.Errorf("Expected the sink failure on /metrics, got:\n%s", body)
+	}
+	if !strings.Contains(string(body), "adk_decisions_total 1\n") {
+		t.Errorf("Expected a partial write to count as a logged decision, got:\n%s", body)
+	}
+}
+
+func TestLogTool_AllSinksFailing(t *testing.T) {
+	tl, err := New([]Sink{failingSink{}}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if out := runTool(t, tl, map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7}); out["status"] != "error" {
+		t.Errorf("Expected status error when no sink took the entry, got %v", out["status"])
+	}
+}
+
+func TestNew_RequiresSinks(t *testing.T) {
+	if _, err := New(nil, nil); err == nil {
+		t.Error("Expected an error without sinks")
+	}
+	if _, err := NewFileSink(""); err == nil {
+		t.Error("Expected an error for an empty file sink path")
+	}
+}
+
+func TestSyslogSink_SendsJSON(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), "trading_orchestrator")
+	if err != nil {
+		t.Fatalf("NewSyslogSink: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Write(map[string]any{"symbol": "SPY", "action": "BUY"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	msg := string(buf[:n])
+	if !strings.Contains(msg, "trading_orchestrator") || !strings.Contains(msg, `{"action":"BUY","symbol":"SPY"}`) {
+		t.Errorf("Expected a tagged JSON syslog message, got %q", msg)
+	}
+}
+
+func TestFileSink_ReopenFollowsExternalRotation(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "decisions.jsonl")
+	sink, err := NewFileSink(logPath)
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	defer sink.Close()
+	write := func(seq int) {
+		t.Helper()
+		if err := sink.Write(map[string]any{"symbol": "SPY", "seq": seq}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	write(1)
+	// logrotate without copytruncate renames the file out from under the open handle.
+	if err := os.Rename(logPath, logPath+".1"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	write(2)
+	if entries := readEntries(t, logPath+".1"); len(entries) != 2 {
+		t.Fatalf("Expected writes before reopen to follow the renamed file, got %d entries", len(entries))
+	}
+
+	if err := ReopenSinks([]Sink{failingSink{}, sink}); err != nil {
+		t.Fatalf("ReopenSinks: %v", err)
+	}
+	write(3)
+	entries := readEntries(t, logPath)
+	if len(entries) != 1 || entries[0]["seq"] != 3.0 {
+		t.Errorf("Expected the write after reopen in a fresh file, got %v", entries)
+	}
+}
@@ -0,0 +1,227 @@
+package logging
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	// Registers the pure Go "sqlite" driver, so the sink builds without cgo.
+	_ "modernc.org/sqlite"
+)
+
+const createDecisionsTable = `CREATE TABLE IF NOT EXISTS decisions (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp       TEXT NOT NULL,
+	schema_version  INTEGER NOT NULL,
+	mode            TEXT,
+	symbol          TEXT NOT NULL,
+	action          TEXT NOT NULL,
+	confidence      REAL NOT NULL,
+	risk_decision   TEXT,
+	position_size   REAL,
+	notes           TEXT,
+	agent           TEXT,
+	invocation      TEXT,
+	idempotency_key TEXT,
+	metadata        TEXT,
+	entry           TEXT NOT NULL,
+	prev_hash       TEXT NOT NULL,
+	hash            TEXT NOT NULL
+)`
+
+const insertDecision = `INSERT INTO decisions
+	(timestamp, schema_version, mode, symbol, action, confidence, risk_decision, position_size,
+	 notes, agent, invocation, idempotency_key, metadata, entry, prev_hash, hash)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// SQLiteSink inserts entries as rows of a decisions table in a SQLite database,
+// for analytics that want SQL rather than JSONL. The common fields, and the risk
+// decision and position size lifted out of metadata, get their own columns; the
+// entry column keeps the whole entry as JSON, hash chained as WithHashChain chains
+// a file, so the database is a complete audit record on its own.
+type SQLiteSink struct {
+	path string
+	db   *sql.DB
+	// mu serialises inserts, which SQLite allows one at a time, and guards
+	// prevHash, the hash of the last row written.
+	mu       sync.Mutex
+	stmt     *sql.Stmt
+	prevHash string
+}
+
+// NewSQLiteSink opens, or creates, the SQLite database at path and its decisions
+// table.
+func NewSQLiteSink(path string) (*SQLiteSink, error) {
+	if path == "" {
+		return nil, errors.New("database path is required")
+	}
+	ensureDir(path)
+	db, err := sql.Open("sqlite", "file:"+url.PathEscape(path)+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open decision database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(createDecisionsTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create decisions table: %w", err)
+	}
+	var prevHash string
+	err = db.QueryRow(`SELECT hash FROM decisions ORDER BY id DESC LIMIT 1`).Scan(&prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		db.Close()
+		return nil, fmt.Errorf("seed hash chain: %w", err)
+	}
+	stmt, err := db.Prepare(insertDecision)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("prepare decision insert: %w", err)
+	}
+	return &SQLiteSink{path: path, db: db, stmt: stmt, prevHash: prevHash}, nil
+}
+
+// Path returns the database the sink inserts into.
+func (s *SQLiteSink) Path() string { return s.path }
+
+func (s *SQLiteSink) Name() string { return "sqlite:" + s.path }
+
+func (s *SQLiteSink) Write(entry map[string]any) error {
+	metadata, _ := entry["metadata"].(map[string]any)
+	decision, _ := riskFields(metadata)
+	size, hasSize := ApprovedSize(metadata)
+	var metadataJSON sql.NullString
+	if metadata != nil {
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("marshal log metadata: %w", err)
+		}
+		metadataJSON = sql.NullString{String: string(data), Valid: true}
+	}
+	confidence, _ := entry["confidence"].(float64)
+	schemaVersion, _ := entry["schema_version"].(int)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chained, hash, err := chainEntry(entry, s.prevHash)
+	if err != nil {
+		return fmt.Errorf("hash log entry: %w", err)
+	}
+	data, err := json.Marshal(chained)
+	if err != nil {
+		return fmt.Errorf("marshal log entry: %w", err)
+	}
+	_, err = s.stmt.Exec(
+		text(entry["timestamp"]),
+		schemaVersion,
+		nullText(entry["mode"]),
+		text(entry["symbol"]),
+		text(entry["action"]),
+		confidence,
+		sql.NullString{String: decision, Valid: decision != ""},
+		sql.NullFloat64{Float64: size, Valid: hasSize},
+		nullText(entry["notes"]),
+		nullText(entry["agent"]),
+		nullText(entry["invocation"]),
+		nullText(entry["idempotency_key"]),
+		metadataJSON,
+		string(data),
+		s.prevHash,
+		hash,
+	)
+	if err != nil {
+		return fmt.Errorf("insert log entry: %w", err)
+	}
+	s.prevHash = hash
+	return nil
+}
+
+// nullText is v as a nullable column: NULL unless it is a non-empty string.
+func nullText(v any) sql.NullString {
+	s := text(v)
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// Entries reads every decision back from the database, oldest first. Each entry's
+// Line is its row id.
+func (s *SQLiteSink) Entries() ([]Entry, error) {
+	rows, err := s.db.Query(`SELECT id, entry FROM decisions ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("read decisions: %w", err)
+	}
+	defer rows.Close()
+	var entries []Entry
+	for rows.Next() {
+		var id int
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("read decisions: %w", err)
+		}
+		var raw map[string]any
+		if err := json.Unmarshal([]byte(data), &raw); err != nil || raw == nil {
+			continue
+		}
+		entry := parseEntry(raw)
+		entry.Line = id
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read decisions: %w", err)
+	}
+	return entries, nil
+}
+
+// VerifyChain checks the hash chain of the database as VerifyChain checks a file:
+// it returns a *ChainError, whose Line is the row id, for the first row whose hash
+// does not match its entry or whose prev_hash does not match the row before it.
+// The first row must link to genesisHash, so deleting the oldest rows shows up.
+func (s *SQLiteSink) VerifyChain() error {
+	rows, err := s.db.Query(`SELECT id, entry, prev_hash, hash FROM decisions ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("read decisions: %w", err)
+	}
+	defer rows.Close()
+	var prev string
+	first := true
+	for rows.Next() {
+		var id int
+		var data, prevHash, hash string
+		if err := rows.Scan(&id, &data, &prevHash, &hash); err != nil {
+			return fmt.Errorf("read decisions: %w", err)
+		}
+		raw, err := decodeLine([]byte(data))
+		if err != nil {
+			return &ChainError{Line: id, Reason: "entry is not a JSON object"}
+		}
+		switch {
+		case !first && prevHash != prev:
+			return &ChainError{Line: id, Reason: fmt.Sprintf("prev_hash %q does not match the previous row's hash %q", prevHash, prev)}
+		case first && prevHash != genesisHash:
+			return &ChainError{Line: id, Reason: fmt.Sprintf("first row has prev_hash %q rather than the start of a chain", prevHash)}
+		}
+		if text(raw["prev_hash"]) != prevHash || text(raw["hash"]) != hash {
+			return &ChainError{Line: id, Reason: "hash columns do not match the entry"}
+		}
+		delete(raw, "hash")
+		want, err := entryHash(raw)
+		if err != nil {
+			return fmt.Errorf("hash row %d: %w", id, err)
+		}
+		if hash != want {
+			return &ChainError{Line: id, Reason: "hash does not match the entry's content"}
+		}
+		prev, first = hash, false
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read decisions: %w", err)
+	}
+	return nil
+}
+
+// Close releases the prepared statement and the database.
+func (s *SQLiteSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.stmt.Close(), s.db.Close())
+}
@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/observability"
+)
+
+func newSQLiteSink(t *testing.T, path string) *SQLiteSink {
+	t.Helper()
+	sink, err := NewSQLiteSink(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteSink: %v", err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	return sink
+}
+
+func TestSQLiteSink_InsertsTypedRows(t *testing.T) {
+	// Spaces and a question mark in the path must not be read as DSN syntax.
+	dbPath := filepath.Join(t.TempDir(), "db dir", "decisions?.sqlite")
+	sink, err := NewSQLiteSink(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteSink: %v", err)
+	}
+	tl, err := New([]Sink{sink}, nil, WithMode("paper"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	runTool(t, tl, map[string]any{
+		"symbol":         "SPY",
+		"action":         "BUY",
+		"confidence":     0.7,
+		"notes":          "breakout",
+		"idempotencyKey": "run-1/SPY",
+		"metadata":       map[string]any{"risk": map[string]any{"decision": "APPROVE", "position_size": 2500.0}},
+	})
+	runTool(t, tl, map[string]any{
+		"symbol":     "IWM",
+		"action":     "BUY",
+		"confidence": 0.6,
+		"metadata":   map[string]any{"risk": map[string]any{"decision": "REJECT", "position_size": 0.0}},
+	})
+	if out := runTool(t, tl, map[string]any{"symbol": "QQQ", "action": "HOLD", "confidence": 0.4}); out["status"] != "logged" {
+		t.Fatalf("Expected the third decision logged, got %v", out)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Fatalf("Expected the database at the literal path: %v", err)
+	}
+
+	// Reopening finds the existing table rather than failing to create it.
+	reopened := newSQLiteSink(t, dbPath)
+	rows, err := reopened.db.Query(`SELECT timestamp, schema_version, mode, symbol, action, confidence, risk_decision, position_size,
+		notes, agent, invocation, idempotency_key, metadata FROM decisions ORDER BY id`)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+
+	type row struct {
+		timestamp, symbol, action string
+		schemaVersion             int
+		confidence                float64
+		mode, decision            sql.NullString
+		size                      sql.NullFloat64
+		notes, agent, invocation  sql.NullString
+		key, metadata             sql.NullString
+	}
+	var got []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.timestamp, &r.schemaVersion, &r.mode, &r.symbol, &r.action, &r.confidence, &r.decision, &r.size,
+			&r.notes, &r.agent, &r.invocation, &r.key, &r.metadata); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		got = append(got, r)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(got))
+	}
+	spy := got[0]
+	if spy.symbol != "SPY" || spy.action != "BUY" || spy.confidence != 0.7 || spy.timestamp == "" {
+		t.Errorf("Unexpected SPY row: %+v", spy)
+	}
+	if spy.decision.String != "APPROVE" || spy.size.Float64 != 2500 {
+		t.Errorf("Expected the risk decision and size in their own columns, got %+v", spy)
+	}
+	if spy.schemaVersion != observability.SchemaVersion || spy.mode.String != "paper" || spy.notes.String != "breakout" ||
+		spy.agent.String != "execution_agent" || spy.invocation.String != "inv-1" || spy.key.String != "run-1/SPY" {
+		t.Errorf("Expected the full audit record in columns, got %+v", spy)
+	}
+	var metadata map[string]any
+	if err := json.Unmarshal([]byte(spy.metadata.String), &metadata); err != nil || metadata["risk"] == nil {
+		t.Errorf("Expected metadata stored as JSON, got %q (%v)", spy.metadata.String, err)
+	}
+	if iwm := got[1]; !iwm.size.Valid || iwm.size.Float64 != 0 {
+		t.Errorf("Expected a zero position size stored as 0, got %+v", iwm.size)
+	}
+	if qqq := got[2]; qqq.decision.Valid || qqq.size.Valid || qqq.metadata.Valid || qqq.notes.Valid {
+		t.Errorf("Expected NULL optional columns without them, got %+v", qqq)
+	}
+}
+
+func TestSQLiteSink_EntriesAndChain(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "decisions.sqlite")
+	sink := newSQLiteSink(t, dbPath)
+	for _, symbol := range []string{"SPY", "QQQ"} {
+		if err := sink.Write(map[string]any{"schema_version": observability.SchemaVersion, "timestamp": "2025-01-02T15:00:00Z", "symbol": symbol, "action": "BUY", "confidence": 0.6, "invocation": "inv-" + symbol}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	sink.Close()
+
+	// The chain carries on across a reopen.
+	sink = newSQLiteSink(t, dbPath)
+	if err := sink.Write(map[string]any{"timestamp": "2025-01-02T16:00:00Z", "symbol": "IWM", "action": "SELL", "confidence": 0.5}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := sink.VerifyChain(); err != nil {
+		t.Fatalf("Expected an intact chain, got %v", err)
+	}
+	entries, err := sink.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(entries) != 3 || entries[1].Invocation != "inv-QQQ" || entries[1].Line != 2 || entries[2].Symbol != "IWM" {
+		t.Errorf("Expected the three decisions read back in order, got %+v", entries)
+	}
+
+	if _, err := sink.db.Exec(`UPDATE decisions SET entry = replace(entry, '"QQQ"', '"TSLA"') WHERE id = 2`); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	var chainErr *ChainError
+	if err := sink.VerifyChain(); !errors.As(err, &chainErr) || chainErr.Line != 2 {
+		t.Errorf("Expected the edited row reported, got %v", err)
+	}
+
+	// Deleting the oldest rows leaves a chain that starts mid-way.
+	if _, err := sink.db.Exec(`DELETE FROM decisions WHERE id <= 2`); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if err := sink.VerifyChain(); !errors.As(err, &chainErr) || chainErr.Line != 3 {
+		t.Errorf("Expected the truncated chain reported at row 3, got %v", err)
+	}
+}
+
+func TestLoggingTool_DatabaseOnly(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "decisions.sqlite")
+	sink := newSQLiteSink(t, dbPath)
+	tl, err := New([]Sink{sink}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	args := map[string]any{"symbol": "SPY", "action": "BUY", "confidence": 0.7, "idempotencyKey": "run-1/SPY"}
+	runTool(t, tl, args)
+	if _, err := os.Stat(dbPath + ".keys.json"); err != nil {
+		t.Errorf("Expected idempotency keys persisted beside the database: %v", err)
+	}
+
+	// A restarted tool still recognises the key.
+	restarted, err := New([]Sink{sink}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if out := runTool(t, restarted, args); out["status"] != "duplicate" {
+		t.Errorf("Expected the retried key reported as duplicate, got %v", out)
+	}
+
+	explainTool, err := NewSQLiteExplain(sink)
+	if err != nil {
+		t.Fatalf("NewSQLiteExplain: %v", err)
+	}
+	out := runTool(t, explainTool, map[string]any{"invocation": "inv-1"})
+	if out["found"] != true || out["matches"] != 1.0 {
+		t.Errorf("Expected the decision explained from the database, got %v", out)
+	}
+}
+
+func TestNewSQLiteSink_RequiresPath(t *testing.T) {
+	if _, err := NewSQLiteSink(""); err == nil {
+		t.Error("Expected an error without a database path")
+	}
+}
@@ -0,0 +1,45 @@
+//go:build !windows && !plan9
+
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+)
+
+// SyslogSink forwards entries as JSON messages at LOG_INFO to a syslog daemon or
+// SIEM collector.
+type SyslogSink struct {
+	addr   string
+	writer *syslog.Writer
+}
+
+// NewSyslogSink dials syslog over network ("udp", "tcp" or "unix") at raddr, tagging
+// messages with tag. An empty network and raddr use the local syslog daemon.
+func NewSyslogSink(network, raddr, tag string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_LOCAL0, tag)
+	if err != nil {
+		return nil, fmt.Errorf("dial syslog: %w", err)
+	}
+	addr := "local"
+	if raddr != "" {
+		addr = network + "://" + raddr
+	}
+	return &SyslogSink{addr: addr, writer: w}, nil
+}
+
+func (s *SyslogSink) Name() string { return "syslog:" + s.addr }
+
+func (s *SyslogSink) Write(entry map[string]any) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal log entry: %w", err)
+	}
+	return s.writer.Info(string(data))
+}
+
+// Close releases the syslog connection.
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,22 @@
+//go:build windows || plan9
+
+package logging
+
+import "errors"
+
+// SyslogSink is unavailable on this platform.
+type SyslogSink struct{}
+
+// NewSyslogSink always fails: log/syslog is not supported on this platform.
+func NewSyslogSink(network, raddr, tag string) (*SyslogSink, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
+
+func (s *SyslogSink) Name() string { return "syslog" }
+
+func (s *SyslogSink) Write(entry map[string]any) error {
+	return errors.New("syslog is not supported on this platform")
+}
+
+// Close is a no-op.
+func (s *SyslogSink) Close() error { return nil }
@@ -0,0 +1,33 @@
+package marketdata
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// asOfDate parses Input.AsOfDate; pinned is false when it is empty.
+func (in Input) asOfDate() (asOf time.Time, pinned bool, err error) {
+	value := strings.TrimSpace(in.AsOfDate)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	asOf, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("asOfDate must be YYYY-MM-DD, got %q", in.AsOfDate)
+	}
+	return asOf, true, nil
+}
+
+// truncateAsOf drops the rows dated after asOf from the end of rows, which are in
+// date order, along with trailing rows whose date does not parse.
+func truncateAsOf(rows []Row, asOf time.Time) []Row {
+	n := len(rows)
+	for n > 0 {
+		if date, err := parseRowDate(rows[n-1].Date); err == nil && !date.After(asOf) {
+			break
+		}
+		n--
+	}
+	return rows[:n]
+}
@@ -0,0 +1,39 @@
+package marketdata
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLoader_SnapshotAsOfDate(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "100.00", "101.00", "102.00", "103.00", "104.00", "200.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	loader.cfg.now = func() time.Time { return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", Window: 3, AsOfDate: "2025-01-04", IncludeRaw: true})
+	if !out.HasData || out.Error != "" {
+		t.Fatalf("Expected a pinned snapshot, got %+v", out)
+	}
+	if out.Close != 103 || !out.AsOf.Equal(time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)) || out.AsOfDate != "2025-01-04" {
+		t.Errorf("Expected the 2025-01-04 bar as the last, got close %f as of %v (%q)", out.Close, out.AsOf, out.AsOfDate)
+	}
+	if len(out.RawRows) != 3 || out.RawRows[0].Date != "2025-01-02" {
+		t.Errorf("Expected the window to count back from the as-of date, got %+v", out.RawRows)
+	}
+	if out.Stale || out.DataAgeDays != 0 {
+		t.Errorf("Expected staleness judged at the as-of date, got %f days (stale=%v)", out.DataAgeDays, out.Stale)
+	}
+
+	// A date before any bar leaves too few rows; a malformed one is rejected.
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", AsOfDate: "2024-12-31"}); out.HasData {
+		t.Errorf("Expected no data before the first bar, got %+v", out)
+	}
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", AsOfDate: "01/04/2025"}); out.Error == "" {
+		t.Error("Expected an error for a malformed asOfDate")
+	}
+}
@@ -0,0 +1,97 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// benchmarkRows loads benchmark the way Snapshot loads its symbol: the same price
+// field, resampling and window.
+func (l *Loader) benchmarkRows(ctx context.Context, benchmark string, input Input, period string, loadWindow, window int) ([]Row, error) {
+	rows, err := l.Load(ctx, benchmark, loadWindow)
+	if err != nil {
+		return nil, err
+	}
+	if asOf, pinned, _ := input.asOfDate(); pinned {
+		rows = truncateAsOf(rows, asOf)
+	}
+	rows, err = selectPriceField(rows, input.PriceField)
+	if err == nil {
+		rows, err = resample(rows, period)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > window {
+		rows = rows[len(rows)-window:]
+	}
+	return rows, nil
+}
+
+// benchmarkBeta returns the beta of rows against bench and the benchmark's
+// annualised volatility, from returns between the dates both share so a missing
+// bar on either side is skipped rather than misaligning the series.
+func benchmarkBeta(rows, bench []Row, opts StatsOptions) (beta, benchVol float64, err error) {
+	closes := make(map[string]float64, len(bench))
+	for _, row := range bench {
+		closes[row.Date] = row.Close
+	}
+	ret := func(prev, cur float64) float64 {
+		if opts.ReturnType == ReturnTypeLog {
+			return math.Log(cur / prev)
+		}
+		return cur/prev - 1
+	}
+	var own, market []float64
+	var prevOwn, prevMarket float64
+	for _, row := range rows {
+		benchClose, ok := closes[row.Date]
+		if !ok || benchClose <= 0 || row.Close <= 0 {
+			continue
+		}
+		if prevOwn > 0 {
+			own = append(own, ret(prevOwn, row.Close))
+			market = append(market, ret(prevMarket, benchClose))
+		}
+		prevOwn, prevMarket = row.Close, benchClose
+	}
+	if len(own) < 2 {
+		return 0, 0, fmt.Errorf("only %d common returns with the benchmark; at least 2 are needed", len(own))
+	}
+	var meanOwn, meanMarket float64
+	for i := range own {
+		meanOwn += own[i]
+		meanMarket += market[i]
+	}
+	meanOwn /= float64(len(own))
+	meanMarket /= float64(len(own))
+	var cov, variance float64
+	for i := range own {
+		cov += (own[i] - meanOwn) * (market[i] - meanMarket)
+		variance += (market[i] - meanMarket) * (market[i] - meanMarket)
+	}
+	if variance == 0 {
+		return 0, 0, errors.New("benchmark returns do not vary")
+	}
+	n := float64(len(own))
+	return cov / variance, math.Sqrt(variance/n) * math.Sqrt(opts.PeriodsPerYear), nil
+}
+
+// setBenchmark fills out's benchmark fields for input.Benchmark, reporting a
+// failure in BenchmarkError rather than failing the snapshot.
+func (l *Loader) setBenchmark(ctx context.Context, out *Output, rows []Row, input Input, opts StatsOptions, period string, loadWindow, window int) {
+	benchmark := l.cfg.aliases.Canonical(input.Benchmark)
+	if benchmark == "" || benchmark == out.Symbol {
+		return
+	}
+	out.Benchmark = benchmark
+	bench, err := l.benchmarkRows(ctx, benchmark, input, period, loadWindow, window)
+	if err == nil {
+		out.Beta, out.BenchmarkVolatility, err = benchmarkBeta(rows, bench, opts)
+	}
+	if err != nil {
+		out.BenchmarkError = err.Error()
+	}
+}
@@ -0,0 +1,55 @@
+package marketdata
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestBenchmarkBeta(t *testing.T) {
+	dates := []string{"2025-01-01", "2025-01-02", "2025-01-03", "2025-01-06", "2025-01-07"}
+	benchReturns := []float64{0.01, -0.02, 0.015, 0.005}
+	bench := []Row{{Date: dates[0], Close: 100}}
+	own := []Row{{Date: dates[0], Close: 50}}
+	for i, r := range benchReturns {
+		bench = append(bench, Row{Date: dates[i+1], Close: bench[i].Close * (1 + r)})
+		own = append(own, Row{Date: dates[i+1], Close: own[i].Close * (1 + 2*r)})
+	}
+	opts, _ := StatsOptions{}.normalize()
+
+	beta, benchVol, err := benchmarkBeta(own, bench, opts)
+	if err != nil {
+		t.Fatalf("benchmarkBeta: %v", err)
+	}
+	if math.Abs(beta-2) > 1e-9 || benchVol <= 0 {
+		t.Errorf("Expected beta 2 and a benchmark volatility, got %f %f", beta, benchVol)
+	}
+
+	// A bar missing from the benchmark is skipped, not zipped against the wrong date.
+	gappy := append(append([]Row{}, bench[:2]...), bench[3:]...)
+	if _, _, err := benchmarkBeta(own, gappy, opts); err != nil {
+		t.Errorf("Expected a beta across the gap, got %v", err)
+	}
+	if _, _, err := benchmarkBeta(own, bench[:2], opts); err == nil {
+		t.Error("Expected an error with a single common return")
+	}
+}
+
+func TestLoader_SnapshotBenchmark(t *testing.T) {
+	dir := t.TempDir()
+	writeHistoricalCSV(t, dir, "SPY_2025-01-01.csv", "100.00", "101.00", "99.00", "102.00")
+	writeHistoricalCSV(t, dir, "QQQ_2025-01-01.csv", "200.00", "203.00", "197.00", "206.00")
+	loader, err := NewLoader(dir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "QQQ", Benchmark: "spy"})
+	if out.Benchmark != "SPY" || out.Beta <= 1 || out.BenchmarkVolatility <= 0 || out.BenchmarkError != "" {
+		t.Errorf("Expected a beta above 1 against SPY, got %+v", out)
+	}
+	missing := loader.Snapshot(context.Background(), Input{Symbol: "QQQ", Benchmark: "IWM"})
+	if !missing.HasData || missing.BenchmarkError == "" || missing.Beta != 0 {
+		t.Errorf("Expected the snapshot to survive a missing benchmark, got %+v", missing)
+	}
+}
@@ -0,0 +1,179 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// breadthWindow is the number of bars loaded per symbol, enough for the 50-day MA.
+const breadthWindow = 60
+
+type BreadthInput struct {
+	// Symbols is an ad-hoc basket reported as the "custom" universe.
+	Symbols []string `json:"symbols,omitempty"`
+	// Universes names files under <dataDir>/universes/<name>.json holding a JSON
+	// array of symbols or an object with a "symbols" array.
+	Universes []string `json:"universes,omitempty"`
+}
+
+type BreadthOutput struct {
+	Universes []UniverseBreadth `json:"universes"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// UniverseBreadth summarises the latest bar across a universe. AdvanceDeclineRatio
+// divides advancers by decliners, counting at least one decliner so an all-up
+// session stays finite.
+type UniverseBreadth struct {
+	Name                string   `json:"name"`
+	Symbols             int      `json:"symbols"`
+	Priced              int      `json:"priced"`
+	AboveMA50           int      `json:"aboveMa50"`
+	FractionAboveMA50   float64  `json:"fractionAboveMa50"`
+	Advancers           int      `json:"advancers"`
+	Decliners           int      `json:"decliners"`
+	Unchanged           int      `json:"unchanged"`
+	AdvanceDeclineRatio float64  `json:"advanceDeclineRatio"`
+	MedianVolatility    float64  `json:"medianVolatility"`
+	Skipped             []string `json:"skipped,omitempty"`
+	Error               string   `json:"error,omitempty"`
+}
+
+// NewBreadthTool returns a tool that measures market breadth across symbol universes.
+func NewBreadthTool(loader *Loader) (tool.Tool, error) {
+	if loader == nil {
+		return nil, errors.New("loader is required")
+	}
+	handler := func(ctx tool.Context, input BreadthInput) BreadthOutput {
+		return loader.Breadth(ctx, input)
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "get_market_breadth",
+		Description: "Measure breadth across a list of symbols or named universes: fraction above the 50-day MA, advance/decline ratio on the latest bar and median volatility.",
+	}, handler)
+}
+
+// validate rejects a request naming neither symbols nor universes.
+func (in BreadthInput) validate() error {
+	if len(in.Symbols) == 0 && len(in.Universes) == 0 {
+		return errors.New("symbols or universes are required")
+	}
+	return nil
+}
+
+// Breadth computes one UniverseBreadth for the ad-hoc symbol list, if any, and one
+// per named universe.
+func (l *Loader) Breadth(ctx context.Context, input BreadthInput) BreadthOutput {
+	out := BreadthOutput{Universes: []UniverseBreadth{}}
+	if err := input.validate(); err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	if len(input.Symbols) > 0 {
+		out.Universes = append(out.Universes, l.universeBreadth(ctx, "custom", input.Symbols))
+	}
+	for _, name := range input.Universes {
+		name = strings.TrimSpace(name)
+		symbols, err := l.readUniverse(name)
+		if err != nil {
+			out.Universes = append(out.Universes, UniverseBreadth{Name: name, Error: err.Error()})
+			continue
+		}
+		out.Universes = append(out.Universes, l.universeBreadth(ctx, name, symbols))
+	}
+	if err := ctx.Err(); err != nil {
+		return BreadthOutput{Universes: []UniverseBreadth{}, Error: err.Error()}
+	}
+	return out
+}
+
+func (l *Loader) universeBreadth(ctx context.Context, name string, symbols []string) UniverseBreadth {
+	b := UniverseBreadth{Name: name}
+	seen := map[string]bool{}
+	var vols []float64
+	for _, raw := range symbols {
+		symbol := l.cfg.aliases.Canonical(raw)
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		b.Symbols++
+		rows, err := l.Load(ctx, symbol, breadthWindow)
+		if err != nil || len(rows) < 2 {
+			b.Skipped = append(b.Skipped, symbol)
+			continue
+		}
+		stats := ComputeStats(rows, StatsOptions{})
+		b.Priced++
+		if stats.Close > stats.MovingAverages["ma50"] {
+			b.AboveMA50++
+		}
+		switch last, _ := stats.lastReturn(); {
+		case last > 0:
+			b.Advancers++
+		case last < 0:
+			b.Decliners++
+		default:
+			b.Unchanged++
+		}
+		vols = append(vols, stats.Volatility)
+	}
+	if b.Priced == 0 {
+		b.Error = "no symbols with data"
+		return b
+	}
+	b.FractionAboveMA50 = float64(b.AboveMA50) / float64(b.Priced)
+	b.AdvanceDeclineRatio = float64(b.Advancers) / float64(max(b.Decliners, 1))
+	b.MedianVolatility = median(vols)
+	return b
+}
+
+// readUniverse loads a named universe from the first data directory that has it.
+func (l *Loader) readUniverse(name string) ([]string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return nil, fmt.Errorf("invalid universe name %q", name)
+	}
+	for _, root := range l.roots {
+		data, err := fs.ReadFile(root.fsys, path.Join("universes", name+".json"))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read universe %s: %w", name, err)
+		}
+		var symbols []string
+		if err := json.Unmarshal(data, &symbols); err == nil {
+			return symbols, nil
+		}
+		var wrapped struct {
+			Symbols []string `json:"symbols"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("parse universe %s: %w", name, err)
+		}
+		return wrapped.Symbols, nil
+	}
+	return nil, fmt.Errorf("universe %s not found", name)
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,86 @@
+package marketdata
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func writeTrend(t *testing.T, dataDir, symbol string, start, step float64, bars int) {
+	t.Helper()
+	closes := make([]string, bars)
+	for i := range closes {
+		closes[i] = strconv.FormatFloat(start+step*float64(i), 'f', 2, 64)
+	}
+	writeHistoricalCSV(t, dataDir, symbol+"_2025-01-01.csv", closes...)
+}
+
+func TestLoader_Breadth(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTrend(t, tempDir, "UP", 100, 1, 28)
+	writeTrend(t, tempDir, "DOWN", 200, -1, 28)
+	writeTrend(t, tempDir, "RISE", 50, 0.5, 28)
+	if err := os.MkdirAll(filepath.Join(tempDir, "universes"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "universes", "core.json"), []byte(`{"symbols": ["UP", "DOWN", "MISSING"]}`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	out := loader.Breadth(context.Background(), BreadthInput{
+		Symbols:   []string{"up", "rise", "down", "UP"},
+		Universes: []string{"core", "nope"},
+	})
+
+	if len(out.Universes) != 3 {
+		t.Fatalf("Expected custom, core and nope universes, got %+v", out.Universes)
+	}
+	custom := out.Universes[0]
+	if custom.Name != "custom" || custom.Symbols != 3 || custom.Priced != 3 {
+		t.Fatalf("Expected 3 distinct priced symbols in the custom basket, got %+v", custom)
+	}
+	if custom.AboveMA50 != 2 || math.Abs(custom.FractionAboveMA50-2.0/3) > 1e-9 {
+		t.Errorf("Expected 2 of 3 above the 50-day MA, got %+v", custom)
+	}
+	if custom.Advancers != 2 || custom.Decliners != 1 || custom.AdvanceDeclineRatio != 2 {
+		t.Errorf("Expected 2 advancers to 1 decliner, got %+v", custom)
+	}
+	if custom.MedianVolatility <= 0 {
+		t.Errorf("Expected a positive median volatility, got %f", custom.MedianVolatility)
+	}
+
+	core := out.Universes[1]
+	if core.Priced != 2 || len(core.Skipped) != 1 || core.Skipped[0] != "MISSING" {
+		t.Errorf("Expected MISSING to be skipped from core, got %+v", core)
+	}
+	if out.Universes[2].Error == "" {
+		t.Error("Expected an error for an unknown universe")
+	}
+}
+
+func TestLoader_BreadthRejectsUniversePaths(t *testing.T) {
+	loader, err := NewLoader(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	out := loader.Breadth(context.Background(), BreadthInput{Universes: []string{"../secrets"}})
+	if len(out.Universes) != 1 || out.Universes[0].Error == "" {
+		t.Errorf("Expected a path-like universe name to be refused, got %+v", out.Universes)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	if got := median([]float64{3, 1, 2}); got != 2 {
+		t.Errorf("Expected odd median 2, got %f", got)
+	}
+	if got := median([]float64{4, 1, 3, 2}); got != 2.5 {
+		t.Errorf("Expected even median 2.5, got %f", got)
+	}
+}
@@ -0,0 +1,85 @@
+package marketdata
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCacheSize is the number of parsed files a Loader keeps in memory when no size is configured.
+const DefaultCacheSize = 64
+
+// rowCache holds parsed rows keyed by file path, valid only while the file's
+// modification time is unchanged. Eviction drops the least recently used entry.
+type rowCache struct {
+	mu       sync.RWMutex
+	maxSize  int
+	entries  map[string]*cacheEntry
+	useClock atomic.Int64
+}
+
+type cacheEntry struct {
+	modTime  time.Time
+	rows     []Row
+	lastUsed atomic.Int64
+}
+
+func newRowCache(maxSize int) *rowCache {
+	if maxSize <= 0 {
+		return nil
+	}
+	return &rowCache{
+		maxSize: maxSize,
+		entries: make(map[string]*cacheEntry, maxSize),
+	}
+}
+
+// get returns the cached rows for path when they were parsed from the same modTime.
+// The returned slice is shared and must not be modified.
+func (c *rowCache) get(path string, modTime time.Time) ([]Row, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[path]
+	if !ok || !entry.modTime.Equal(modTime) {
+		return nil, false
+	}
+	entry.lastUsed.Store(c.useClock.Add(1))
+	return entry.rows, true
+}
+
+func (c *rowCache) put(path string, modTime time.Time, rows []Row) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[path]; !ok && len(c.entries) >= c.maxSize {
+		c.evictOldest()
+	}
+	entry := &cacheEntry{modTime: modTime, rows: rows}
+	entry.lastUsed.Store(c.useClock.Add(1))
+	c.entries[path] = entry
+}
+
+func (c *rowCache) evictOldest() {
+	var oldestPath string
+	var oldest int64
+	for path, entry := range c.entries {
+		if used := entry.lastUsed.Load(); oldestPath == "" || used < oldest {
+			oldestPath, oldest = path, used
+		}
+	}
+	delete(c.entries, oldestPath)
+}
+
+func (c *rowCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
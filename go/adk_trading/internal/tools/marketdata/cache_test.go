@@ -0,0 +1,165 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeHistoricalCSV(t *testing.T, dataDir, name string, closes ...string) string {
+	t.Helper()
+	historicalDir := filepath.Join(dataDir, "historical")
+	if err := os.MkdirAll(historicalDir, 0755); err != nil {
+		t.Fatalf("Failed to create historical directory: %v", err)
+	}
+	content := "# Metadata\n# Metadata\n# Metadata\nDate,Close,High,Low,Open,Volume\n"
+	for i, c := range closes {
+		content += fmt.Sprintf("2025-01-%02d,%s,452.00,448.00,449.00,1000000\n", i+1, c)
+	}
+	path := filepath.Join(historicalDir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write CSV file: %v", err)
+	}
+	return path
+}
+
+func TestLoader_CachesUntilModTimeChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	path := writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00")
+
+	loader, err := NewLoader(tempDir, WithCacheSize(4))
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	rows, err := loader.Load(context.Background(), "SPY", 0)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(rows) != 2 || loader.cache.len() != 1 {
+		t.Fatalf("Expected 2 rows and 1 cache entry, got %d rows and %d entries", len(rows), loader.cache.len())
+	}
+
+	// Rewrite with more rows and bump the modtime so the cache must be invalidated.
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "452.00")
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	rows, err = loader.Load(context.Background(), "SPY", 0)
+	if err != nil {
+		t.Fatalf("Load after rewrite: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Errorf("Expected 3 rows after modtime change, got %d", len(rows))
+	}
+}
+
+func TestRowCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newRowCache(2)
+	now := time.Now()
+	cache.put("a", now, []Row{{Close: 1}})
+	cache.put("b", now, []Row{{Close: 2}})
+	if _, ok := cache.get("a", now); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	cache.put("c", now, []Row{{Close: 3}})
+
+	if _, ok := cache.get("b", now); ok {
+		t.Error("Expected b to be evicted as least recently used")
+	}
+	if _, ok := cache.get("a", now); !ok {
+		t.Error("Expected a to survive eviction")
+	}
+	if _, ok := cache.get("c", now); !ok {
+		t.Error("Expected c to be cached")
+	}
+}
+
+func TestRowCache_Disabled(t *testing.T) {
+	cache := newRowCache(0)
+	cache.put("a", time.Now(), []Row{{Close: 1}})
+	if _, ok := cache.get("a", time.Now()); ok {
+		t.Error("Expected disabled cache to never hit")
+	}
+}
+
+func TestLoader_ConcurrentLoads(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "452.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := loader.Load(context.Background(), "SPY", 2); err != nil {
+				t.Errorf("Load: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLoader_FallsThroughDataDirs(t *testing.T) {
+	fastDir := t.TempDir()
+	archiveDir := t.TempDir()
+	writeHistoricalCSV(t, fastDir, "SPY_2025-01-01.csv", "450.00", "451.00")
+	writeHistoricalCSV(t, archiveDir, "SPY_2024-01-01.csv", "400.00")
+	writeHistoricalCSV(t, archiveDir, "QQQ_2024-01-01.csv", "380.00", "381.00", "382.00")
+
+	loader, err := NewLoader(fastDir + string(filepath.ListSeparator) + archiveDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	rows, err := loader.Load(context.Background(), "QQQ", 0)
+	if err != nil {
+		t.Fatalf("Expected QQQ to load from the second directory: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Errorf("Expected 3 QQQ rows, got %d", len(rows))
+	}
+	rows, err = loader.Load(context.Background(), "SPY", 0)
+	if err != nil {
+		t.Fatalf("Load SPY: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Errorf("Expected SPY from the first directory (2 rows), got %d", len(rows))
+	}
+	if _, err := loader.Load(context.Background(), "IWM", 0); err == nil {
+		t.Error("Expected an error for a symbol missing from every directory")
+	}
+}
+
+func TestLoader_LoadHonoursCancelledContext(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := loader.Load(ctx, "SPY", 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if loader.cache.len() != 0 {
+		t.Error("Expected nothing cached from an abandoned read")
+	}
+	if _, err := readRows(ctx, loader.roots[0].file("historical/SPY_2025-01-01.csv"), nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected readRows to stop on a cancelled context, got %v", err)
+	}
+	if out := loader.Snapshot(ctx, Input{Symbol: "SPY"}); out.HasData || out.Error == "" {
+		t.Errorf("Expected an empty snapshot with an error note, got %+v", out)
+	}
+}
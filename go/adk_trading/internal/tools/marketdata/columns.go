@@ -0,0 +1,158 @@
+package marketdata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// columns maps each OHLCV field to its index within a CSV record. An index of -1
+// marks a field the file does not provide.
+type columns struct {
+	date     int
+	close    int
+	high     int
+	low      int
+	open     int
+	volume   int
+	adjClose int
+}
+
+// positionalColumns is the legacy Date, Close, High, Low, Open, Volume layout used
+// when a file carries no recognisable header row.
+var positionalColumns = columns{date: 0, close: 1, high: 2, low: 3, open: 4, volume: 5, adjClose: -1}
+
+// maxHeaderScan bounds how many leading records are inspected for a header row.
+const maxHeaderScan = 10
+
+// headerAliases maps normalised header labels to the field they describe.
+var headerAliases = map[string]string{
+	"date":          "date",
+	"datetime":      "date",
+	"timestamp":     "date",
+	"time":          "date",
+	"open":          "open",
+	"high":          "high",
+	"low":           "low",
+	"close":         "close",
+	"adjclose":      "adjClose",
+	"adjustedclose": "adjClose",
+	"volume":        "volume",
+	"vol":           "volume",
+}
+
+// normalizeLabel lowercases a header label and drops whitespace, underscores, dashes
+// and dots so that "Adj Close", "adj_close" and " ADJ-CLOSE " compare equal.
+func normalizeLabel(label string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '_', '-', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(label)))
+}
+
+// yfinanceIndexLabels are the labels yfinance gives the date index column of its
+// multi-row header: "Price" above the fields, or nothing in older exports.
+var yfinanceIndexLabels = map[string]bool{"price": true, "": true}
+
+// yfinanceMetaRows are the first cells of the rows yfinance writes between its
+// field labels and the data: the ticker of each column and the index name.
+var yfinanceMetaRows = map[string]bool{"ticker": true, "date": true}
+
+// detectColumns finds the header row among the leading records and returns the
+// column layout it describes together with the index of the first data record.
+// The yfinance layout, a Price,Close,... label row followed by Ticker and Date
+// rows, reads the date from the first column and skips the two metadata rows.
+func detectColumns(records [][]string) (columns, int, bool) {
+	limit := len(records)
+	if limit > maxHeaderScan {
+		limit = maxHeaderScan
+	}
+	for i := 0; i < limit; i++ {
+		cols := columns{date: -1, close: -1, high: -1, low: -1, open: -1, volume: -1, adjClose: -1}
+		for idx, label := range records[i] {
+			switch headerAliases[normalizeLabel(label)] {
+			case "date":
+				cols.date = idx
+			case "close":
+				cols.close = idx
+			case "adjClose":
+				cols.adjClose = idx
+			case "high":
+				cols.high = idx
+			case "low":
+				cols.low = idx
+			case "open":
+				cols.open = idx
+			case "volume":
+				cols.volume = idx
+			}
+		}
+		start := i + 1
+		if cols.date < 0 && len(records[i]) > 0 && yfinanceIndexLabels[normalizeLabel(records[i][0])] {
+			cols.date = 0
+			for start < len(records) && len(records[start]) > 0 && yfinanceMetaRows[normalizeLabel(records[start][0])] {
+				start++
+			}
+		}
+		if cols.date >= 0 && cols.close >= 0 && cols.high >= 0 && cols.low >= 0 && cols.open >= 0 {
+			return cols, start, true
+		}
+	}
+	return columns{}, 0, false
+}
+
+func (c columns) parse(rec []string) (Row, error) {
+	need := 0
+	for _, idx := range []int{c.date, c.close, c.high, c.low, c.open, c.volume, c.adjClose} {
+		if idx+1 > need {
+			need = idx + 1
+		}
+	}
+	if len(rec) < need {
+		return Row{}, fmt.Errorf("insufficient fields: need %d, got %d", need, len(rec))
+	}
+	closeVal, err := c.float(rec, c.close)
+	if err != nil {
+		return Row{}, err
+	}
+	highVal, err := c.float(rec, c.high)
+	if err != nil {
+		return Row{}, err
+	}
+	lowVal, err := c.float(rec, c.low)
+	if err != nil {
+		return Row{}, err
+	}
+	openVal, err := c.float(rec, c.open)
+	if err != nil {
+		return Row{}, err
+	}
+	volumeVal, err := c.float(rec, c.volume)
+	if err != nil {
+		return Row{}, err
+	}
+	adjCloseVal, err := c.float(rec, c.adjClose)
+	if err != nil {
+		return Row{}, err
+	}
+	return Row{
+		Date:     strings.TrimSpace(rec[c.date]),
+		Close:    closeVal,
+		High:     highVal,
+		Low:      lowVal,
+		Open:     openVal,
+		Volume:   volumeVal,
+		AdjClose: adjCloseVal,
+	}, nil
+}
+
+// float parses the field at idx, treating a column the file does not provide as zero.
+func (c columns) float(rec []string, idx int) (float64, error) {
+	if idx < 0 {
+		return 0, nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(rec[idx]), 64)
+}
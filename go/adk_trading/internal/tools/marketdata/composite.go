@@ -0,0 +1,101 @@
+package marketdata
+
+import (
+	"fmt"
+	"math"
+)
+
+// RSIPeriod is the lookback of the relative strength index in snapshots.
+const RSIPeriod = 14
+
+// Default composite score weights for trend, RSI and volume.
+const (
+	DefaultTrendWeight  = 0.5
+	DefaultRSIWeight    = 0.3
+	DefaultVolumeWeight = 0.2
+)
+
+// compositeTrendScale is the trend strength (short over long moving average,
+// minus one) that saturates the trend component: a 5% spread reads as ±1.
+const compositeTrendScale = 0.05
+
+// CompositeWeights weights the components of the composite score. Only their
+// ratios matter; the blend is divided by their sum.
+type CompositeWeights struct {
+	Trend  float64
+	RSI    float64
+	Volume float64
+}
+
+func (w CompositeWeights) validate() error {
+	if w.Trend < 0 || w.RSI < 0 || w.Volume < 0 || w.Trend+w.RSI+w.Volume == 0 {
+		return fmt.Errorf("composite weights must be non-negative and not all zero, got %+v", w)
+	}
+	return nil
+}
+
+// WithCompositeWeights sets the weights of the trend, RSI and volume components
+// of the snapshot's composite score.
+func WithCompositeWeights(trend, rsi, volume float64) Option {
+	return func(c *config) {
+		c.compositeWeights = CompositeWeights{Trend: trend, RSI: rsi, Volume: volume}
+	}
+}
+
+// relativeStrength returns Wilder's RSI over period on the closes of rows, or false
+// when there are not period+1 rows.
+func relativeStrength(rows []Row, period int) (float64, bool) {
+	if period <= 0 || len(rows) <= period {
+		return 0, false
+	}
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		change := rows[i].Close - rows[i-1].Close
+		gain += math.Max(change, 0)
+		loss += math.Max(-change, 0)
+	}
+	gain /= float64(period)
+	loss /= float64(period)
+	for i := period + 1; i < len(rows); i++ {
+		change := rows[i].Close - rows[i-1].Close
+		gain = (gain*float64(period-1) + math.Max(change, 0)) / float64(period)
+		loss = (loss*float64(period-1) + math.Max(-change, 0)) / float64(period)
+	}
+	if loss == 0 {
+		if gain == 0 {
+			return 50, true
+		}
+		return 100, true
+	}
+	return 100 - 100/(1+gain/loss), true
+}
+
+// compositeScore blends three components, each in [-1, 1], into a score in
+// [-1, 1] that is positive when they lean bullish:
+//
+//   - trend: trend strength divided by 5%, clamped;
+//   - RSI: (RSI - 50) / 50, or 0 without enough bars for an RSI;
+//   - volume: the volume ratio's excess over 1, clamped, signed by the last
+//     return, so heavy volume confirms the day's move and light volume fades it.
+//
+// The weighted average is clamped again to guard against rounding.
+func compositeScore(s Summary, rsiOK bool, w CompositeWeights) float64 {
+	trend := clamp(s.TrendStrength/compositeTrendScale, -1, 1)
+	var momentum float64
+	if rsiOK {
+		momentum = (s.RSI - 50) / 50
+	}
+	var volume float64
+	if last, ok := s.lastReturn(); ok && last != 0 {
+		volume = clamp(s.VolumeRatio-1, -1, 1) * math.Copysign(1, last)
+	}
+	total := w.Trend + w.RSI + w.Volume
+	if total == 0 {
+		return 0
+	}
+	return clamp((w.Trend*trend+w.RSI*momentum+w.Volume*volume)/total, -1, 1)
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Min(math.Max(v, min), max)
+}
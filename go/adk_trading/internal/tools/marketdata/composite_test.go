@@ -0,0 +1,74 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func closesRows(closes ...float64) []Row {
+	rows := make([]Row, len(closes))
+	for i, c := range closes {
+		rows[i] = Row{Close: c}
+	}
+	return rows
+}
+
+func TestRelativeStrength(t *testing.T) {
+	if _, ok := relativeStrength(closesRows(1, 2, 3), RSIPeriod); ok {
+		t.Error("Expected no RSI without period+1 rows")
+	}
+	rising := make([]float64, 20)
+	for i := range rising {
+		rising[i] = 100 + float64(i)
+	}
+	if rsi, ok := relativeStrength(closesRows(rising...), RSIPeriod); !ok || rsi != 100 {
+		t.Errorf("Expected RSI 100 for a steady rise, got %f", rsi)
+	}
+	// Equal average gains and losses over the seed period put RSI at 50.
+	if rsi, _ := relativeStrength(closesRows(10, 11, 10, 11, 10), 4); math.Abs(rsi-50) > 1e-9 {
+		t.Errorf("Expected RSI 50 for balanced moves, got %f", rsi)
+	}
+}
+
+func TestCompositeScore(t *testing.T) {
+	w := CompositeWeights{Trend: DefaultTrendWeight, RSI: DefaultRSIWeight, Volume: DefaultVolumeWeight}
+	bullish := Summary{TrendStrength: 0.1, RSI: 75, VolumeRatio: 1.5, Returns: []float64{0.01}}
+	// trend clamps to 1, RSI reads 0.5 and volume confirms the up day at 0.5.
+	if got := compositeScore(bullish, true, w); math.Abs(got-(0.5+0.15+0.1)) > 1e-9 {
+		t.Errorf("Expected 0.75, got %f", got)
+	}
+	bearish := Summary{TrendStrength: -0.2, RSI: 0, VolumeRatio: 5, Returns: []float64{-0.03}}
+	if got := compositeScore(bearish, true, w); got != -1 {
+		t.Errorf("Expected a fully bearish -1, got %f", got)
+	}
+	if got := compositeScore(Summary{TrendStrength: 0.025}, false, CompositeWeights{Trend: 1, RSI: 1}); got != 0.25 {
+		t.Errorf("Expected a missing RSI to count as neutral, got %f", got)
+	}
+}
+
+func TestLoader_SnapshotCompositeScore(t *testing.T) {
+	dir := t.TempDir()
+	closes := make([]string, 20)
+	for i := range closes {
+		closes[i] = fmt.Sprintf("%.2f", 100+float64(i))
+	}
+	writeHistoricalCSV(t, dir, "SPY_2025-01-01.csv", closes...)
+	loader, err := NewLoader(dir, WithCompositeWeights(0, 1, 0))
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"})
+
+	if out.RSI != 100 || out.CompositeScore != 1 {
+		t.Errorf("Expected an RSI-only score of 1 for a steady rise, got RSI %f score %f", out.RSI, out.CompositeScore)
+	}
+	if _, err := NewLoader(dir, WithCompositeWeights(0, 0, 0)); err == nil {
+		t.Error("Expected an error for all-zero composite weights")
+	}
+	if _, err := NewLoader(dir, WithCompositeWeights(1, -1, 0)); err == nil {
+		t.Error("Expected an error for a negative composite weight")
+	}
+}
@@ -0,0 +1,117 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// defaultEntryWindow is the number of bars loaded for the entry check: 14 true
+// ranges for the ATR.
+const defaultEntryWindow = 15
+
+// Entry window positions reported in EntryWindowOutput.Position.
+const (
+	EntryInside = "inside"
+	EntryAbove  = "above"
+	EntryBelow  = "below"
+)
+
+// EntryWindowInput is the entry_window price range a signal proposed for Symbol.
+type EntryWindowInput struct {
+	Symbol string  `json:"symbol"`
+	Low    float64 `json:"low"`
+	High   float64 `json:"high"`
+	// Window is the number of bars the ATR is averaged over, plus one; zero keeps 15.
+	Window int `json:"window,omitempty"`
+}
+
+// EntryWindowOutput places the latest close relative to the window. DistanceATR
+// is how far the close lies outside the window, in average true ranges; it is
+// zero inside the window or when the ATR is zero.
+type EntryWindowOutput struct {
+	Symbol           string    `json:"symbol"`
+	Close            float64   `json:"close"`
+	AsOf             time.Time `json:"asOf"`
+	Stale            bool      `json:"stale"`
+	Low              float64   `json:"low"`
+	High             float64   `json:"high"`
+	Position         string    `json:"position,omitempty"`
+	DistanceATR      float64   `json:"distanceAtr"`
+	AverageTrueRange float64   `json:"averageTrueRange"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// validate rejects a window that is not a positive, ordered price range.
+func (in EntryWindowInput) validate() error {
+	switch {
+	case in.Symbol == "":
+		return errors.New("symbol is required")
+	case !(in.Low > 0) || math.IsInf(in.Low, 0):
+		return fmt.Errorf("low must be a positive price, got %g", in.Low)
+	case !(in.High >= in.Low) || math.IsInf(in.High, 0):
+		return fmt.Errorf("high must be at least low (%g), got %g", in.Low, in.High)
+	case in.Window < 0:
+		return fmt.Errorf("window must not be negative, got %d", in.Window)
+	}
+	return nil
+}
+
+// NewEntryWindowTool returns a tool that checks the latest close against a
+// signal's entry window.
+func NewEntryWindowTool(loader *Loader) (tool.Tool, error) {
+	if loader == nil {
+		return nil, errors.New("loader is required")
+	}
+	handler := func(ctx tool.Context, input EntryWindowInput) EntryWindowOutput {
+		return loader.EntryWindow(ctx, input)
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "check_entry_window",
+		Description: "Check whether a symbol's latest close is inside, above or below an entry window (low to high) and how far outside it is in average true ranges.",
+	}, handler)
+}
+
+// EntryWindow loads the latest bars for input.Symbol and places the last close
+// relative to the input window.
+func (l *Loader) EntryWindow(ctx context.Context, input EntryWindowInput) EntryWindowOutput {
+	input.Symbol = l.cfg.aliases.Canonical(input.Symbol)
+	out := EntryWindowOutput{Symbol: input.Symbol, Low: input.Low, High: input.High}
+	if err := input.validate(); err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	window := input.Window
+	if window == 0 {
+		window = defaultEntryWindow
+	}
+	rows, err := l.Load(ctx, input.Symbol, window)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	last := rows[len(rows)-1]
+	out.Close = last.Close
+	out.AsOf, _ = parseRowDate(last.Date)
+	_, out.Stale = l.cfg.dataAge(out.AsOf)
+	out.AverageTrueRange = averageTrueRange(rows, nil)
+
+	var gap float64
+	switch {
+	case last.Close > input.High:
+		out.Position, gap = EntryAbove, last.Close-input.High
+	case last.Close < input.Low:
+		out.Position, gap = EntryBelow, input.Low-last.Close
+	default:
+		out.Position = EntryInside
+	}
+	if out.AverageTrueRange > 0 {
+		out.DistanceATR = gap / out.AverageTrueRange
+	}
+	return out
+}
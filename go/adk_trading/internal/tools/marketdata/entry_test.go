@@ -0,0 +1,57 @@
+package marketdata
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestLoader_EntryWindow(t *testing.T) {
+	tempDir := t.TempDir()
+	// Every bar spans 448-452, so the ATR is 4 whatever the closes.
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "450.00", "450.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		low, high    float64
+		wantPosition string
+		wantDistance float64
+	}{
+		{"inside", 445, 455, EntryInside, 0},
+		{"on the edge", 450, 455, EntryInside, 0},
+		{"above", 440, 442, EntryAbove, 2},
+		{"below", 456, 460, EntryBelow, 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := loader.EntryWindow(context.Background(), EntryWindowInput{Symbol: "spy", Low: tt.low, High: tt.high})
+			if out.Error != "" {
+				t.Fatalf("Unexpected error: %s", out.Error)
+			}
+			if out.Symbol != "SPY" || out.Close != 450 || out.AverageTrueRange != 4 {
+				t.Errorf("Expected SPY at 450 with an ATR of 4, got %+v", out)
+			}
+			if out.Position != tt.wantPosition || math.Abs(out.DistanceATR-tt.wantDistance) > 1e-9 {
+				t.Errorf("Expected %s at %.2f ATR, got %s at %.2f", tt.wantPosition, tt.wantDistance, out.Position, out.DistanceATR)
+			}
+		})
+	}
+
+	for _, input := range []EntryWindowInput{
+		{Symbol: "SPY", Low: 0, High: 10},
+		{Symbol: "SPY", Low: 10, High: 5},
+		{Symbol: "SPY", Low: 10, High: math.NaN()},
+		{Low: 1, High: 2},
+	} {
+		if out := loader.EntryWindow(context.Background(), input); out.Error == "" || out.Position != "" {
+			t.Errorf("Expected %+v to be rejected, got %+v", input, out)
+		}
+	}
+	if out := loader.EntryWindow(context.Background(), EntryWindowInput{Symbol: "QQQ", Low: 1, High: 2}); out.Error == "" {
+		t.Error("Expected an error for a symbol without data")
+	}
+}
@@ -0,0 +1,83 @@
+package marketdata
+
+import "math"
+
+// DefaultReturnHistogramBins is how many bins a return histogram uses when the
+// input does not say.
+const DefaultReturnHistogramBins = 10
+
+// maxReturnHistogramBins bounds the response size.
+const maxReturnHistogramBins = 100
+
+// minReturnHistogramSamples is the fewest returns a histogram and its moments are
+// computed from; fewer say nothing about the shape of the distribution.
+const minReturnHistogramSamples = 4
+
+// ReturnHistogram is the distribution of the window's returns: equal-width bins
+// spanning the smallest to the largest return, with the sample's skewness and
+// excess kurtosis (zero for a normal distribution) for judging tail risk.
+type ReturnHistogram struct {
+	Bins           []HistogramBin `json:"bins"`
+	Samples        int            `json:"samples"`
+	Skewness       float64        `json:"skewness"`
+	ExcessKurtosis float64        `json:"excessKurtosis"`
+}
+
+type HistogramBin struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Count int     `json:"count"`
+}
+
+// returnHistogram buckets returns into bins equal-width bins; non-positive bins use
+// DefaultReturnHistogramBins. With fewer than minReturnHistogramSamples returns the
+// histogram has no bins and zero moments.
+func returnHistogram(returns []float64, bins int) *ReturnHistogram {
+	hist := &ReturnHistogram{Bins: []HistogramBin{}, Samples: len(returns)}
+	if len(returns) < minReturnHistogramSamples {
+		return hist
+	}
+	if bins <= 0 {
+		bins = DefaultReturnHistogramBins
+	}
+	bins = min(bins, maxReturnHistogramBins)
+	low, high := math.Inf(1), math.Inf(-1)
+	var sum float64
+	for _, ret := range returns {
+		low = math.Min(low, ret)
+		high = math.Max(high, ret)
+		sum += ret
+	}
+	// Identical returns have nothing to bucket: one bin holds everything.
+	if high <= low {
+		bins = 1
+	}
+	width := (high - low) / float64(bins)
+
+	hist.Bins = make([]HistogramBin, bins)
+	for i := range hist.Bins {
+		hist.Bins[i].Low = low + float64(i)*width
+		hist.Bins[i].High = low + float64(i+1)*width
+	}
+	hist.Bins[bins-1].High = high
+	mean := sum / float64(len(returns))
+	var m2, m3, m4 float64
+	for _, ret := range returns {
+		i := 0
+		if width > 0 {
+			i = min(max(int((ret-low)/width), 0), bins-1)
+		}
+		hist.Bins[i].Count++
+		d := ret - mean
+		m2 += d * d
+		m3 += d * d * d
+		m4 += d * d * d * d
+	}
+	n := float64(len(returns))
+	m2, m3, m4 = m2/n, m3/n, m4/n
+	if m2 > 0 {
+		hist.Skewness = m3 / math.Pow(m2, 1.5)
+		hist.ExcessKurtosis = m4/(m2*m2) - 3
+	}
+	return hist
+}
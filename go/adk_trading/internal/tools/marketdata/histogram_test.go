@@ -0,0 +1,57 @@
+package marketdata
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestReturnHistogram(t *testing.T) {
+	// Four flat returns and one jump: a right tail.
+	hist := returnHistogram([]float64{0, 0, 0.01, 0, 0}, 2)
+
+	if len(hist.Bins) != 2 || hist.Bins[0].Low != 0 || hist.Bins[1].High != 0.01 {
+		t.Fatalf("Expected 2 bins spanning 0-0.01, got %+v", hist.Bins)
+	}
+	if hist.Bins[0].Count != 4 || hist.Bins[1].Count != 1 || hist.Samples != 5 {
+		t.Errorf("Expected counts 4 and 1 of 5, got %+v", hist)
+	}
+	if math.Abs(hist.Skewness-1.5) > 1e-9 || math.Abs(hist.ExcessKurtosis-0.25) > 1e-9 {
+		t.Errorf("Expected skewness 1.5 and excess kurtosis 0.25, got %f and %f", hist.Skewness, hist.ExcessKurtosis)
+	}
+}
+
+func TestReturnHistogram_TinyAndFlat(t *testing.T) {
+	tiny := returnHistogram([]float64{0.01, -0.02}, 0)
+	if tiny.Bins == nil || len(tiny.Bins) != 0 || tiny.Skewness != 0 || tiny.ExcessKurtosis != 0 {
+		t.Errorf("Expected an empty histogram and zero moments for two returns, got %+v", tiny)
+	}
+	flat := returnHistogram([]float64{0.01, 0.01, 0.01, 0.01}, 0)
+	if len(flat.Bins) != 1 || flat.Bins[0].Count != 4 || flat.Skewness != 0 || flat.ExcessKurtosis != 0 {
+		t.Errorf("Expected a single bin and zero moments for identical returns, got %+v", flat)
+	}
+}
+
+func TestLoader_SnapshotReturnHistogram(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "100.00", "101.00", "99.00", "102.00", "103.00", "101.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"}); out.ReturnHistogram != nil {
+		t.Errorf("Expected no histogram unless requested, got %+v", out.ReturnHistogram)
+	}
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", IncludeReturnHistogram: true, ReturnHistogramBins: 3})
+	if out.ReturnHistogram == nil || len(out.ReturnHistogram.Bins) != 3 || out.ReturnHistogram.Samples != len(out.Returns) {
+		t.Fatalf("Expected 3 bins over the snapshot's returns, got %+v", out.ReturnHistogram)
+	}
+	var total int
+	for _, bin := range out.ReturnHistogram.Bins {
+		total += bin.Count
+	}
+	if total != len(out.Returns) {
+		t.Errorf("Expected every return counted once, got %d of %d", total, len(out.Returns))
+	}
+}
@@ -0,0 +1,252 @@
+package marketdata
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/symbols"
+)
+
+// globFiles is fs.Glob, swapped out in tests to count directory scans.
+var globFiles = fs.Glob
+
+// historicalDir is the folder of each data root holding the per-symbol CSVs.
+const historicalDir = "historical"
+
+// dataRoot is one searched data directory: the filesystem its files are read from
+// and the name they are reported under, the directory itself for os.DirFS roots.
+type dataRoot struct {
+	name string
+	fsys fs.FS
+}
+
+// file returns the file at the slash-separated name within the root.
+func (r dataRoot) file(name string) dataFile {
+	display := name
+	if r.name != "" {
+		display = filepath.Join(r.name, filepath.FromSlash(name))
+	}
+	return dataFile{fsys: r.fsys, name: name, path: display}
+}
+
+// dataFile is a file within a data root: name is its path in the root's
+// filesystem, path the same file as errors and the row cache refer to it.
+type dataFile struct {
+	fsys fs.FS
+	name string
+	path string
+}
+
+// symbolIndex maps each symbol to its newest historical file so that lookups do not
+// glob the historical directory on every call. dirTimes holds the modification
+// time of each root's historical folder when it was scanned, so a file dropped in
+// later is noticed.
+type symbolIndex struct {
+	mu       sync.RWMutex
+	paths    map[string]dataFile
+	dirTimes []time.Time
+}
+
+func (i *symbolIndex) get(symbol string) (dataFile, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	file, ok := i.paths[symbol]
+	return file, ok
+}
+
+// symbols lists every indexed symbol; it doubles as the cached universe used for suggestions.
+func (i *symbolIndex) symbols() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	symbols := make([]string, 0, len(i.paths))
+	for symbol := range i.paths {
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// set indexes file for symbol; a zero file removes the symbol.
+func (i *symbolIndex) set(symbol string, file dataFile) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.paths == nil {
+		i.paths = map[string]dataFile{}
+	}
+	if file.name == "" {
+		delete(i.paths, symbol)
+		return
+	}
+	i.paths[symbol] = file
+}
+
+func (i *symbolIndex) replace(paths map[string]dataFile, dirTimes []time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.paths = paths
+	i.dirTimes = dirTimes
+}
+
+// current reports whether dirTimes match the folders the index was built from.
+func (i *symbolIndex) current(dirTimes []time.Time) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if len(i.dirTimes) != len(dirTimes) {
+		return false
+	}
+	for n, t := range dirTimes {
+		if !t.Equal(i.dirTimes[n]) {
+			return false
+		}
+	}
+	return true
+}
+
+// RefreshIndex rescans the historical directories and rebuilds the symbol index,
+// picking up files added since the Loader was created. Directories without a
+// historical folder are skipped. Lookups call it themselves once a folder changes.
+func (l *Loader) RefreshIndex() error {
+	return l.refreshIndex(historicalModTimes(l.roots))
+}
+
+// refreshIndex rebuilds the index, recording dirTimes as the folder times it
+// reflects. They are taken before scanning, so a file added mid-scan triggers
+// another rescan.
+func (l *Loader) refreshIndex(dirTimes []time.Time) error {
+	paths, err := scanHistorical(l.roots)
+	l.index.replace(paths, dirTimes)
+	return err
+}
+
+// historicalModTimes returns the modification time of each root's historical
+// folder, zero for a root without one.
+func historicalModTimes(roots []dataRoot) []time.Time {
+	times := make([]time.Time, len(roots))
+	for i, root := range roots {
+		if info, err := fs.Stat(root.fsys, historicalDir); err == nil {
+			times[i] = info.ModTime()
+		}
+	}
+	return times
+}
+
+// scanHistorical lists SYMBOL_<suffix>.csv and consolidated SYMBOL.csv files in each
+// directory's historical folder. As with globbing, the lexically greatest file per symbol is the newest and
+// earlier directories take precedence over later ones.
+func scanHistorical(roots []dataRoot) (map[string]dataFile, error) {
+	paths := map[string]dataFile{}
+	var errs []error
+	for _, root := range roots {
+		entries, err := fs.ReadDir(root.fsys, historicalDir)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("scan %s: %w", root.file(historicalDir).path, err))
+			}
+			continue
+		}
+		newest := map[string]string{}
+		bare := map[string]string{}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".csv") {
+				continue
+			}
+			// Tickers hold no "_", so the first one ends the symbol.
+			sep := strings.Index(name, "_")
+			if sep < 0 {
+				bare[strings.TrimSuffix(name, ".csv")] = name
+				continue
+			}
+			if sep == 0 {
+				continue
+			}
+			symbol := name[:sep]
+			if name > newest[symbol] {
+				newest[symbol] = name
+			}
+		}
+		// Dated files win; a consolidated SYMBOL.csv is used only when none exist.
+		for symbol, name := range bare {
+			if _, dated := newest[symbol]; !dated {
+				newest[symbol] = name
+			}
+		}
+		for symbol, name := range newest {
+			if _, seen := paths[symbol]; !seen {
+				paths[symbol] = root.file(path.Join(historicalDir, name))
+			}
+		}
+	}
+	return paths, errors.Join(errs...)
+}
+
+// resolve returns the newest historical file for symbol and its file info, using the
+// index when possible and globbing each data root in order otherwise. Only tickers
+// are resolved, so a glob pattern never reaches the fallback or the index.
+func (l *Loader) resolve(symbol string) (dataFile, fs.FileInfo, error) {
+	if symbol == "" {
+		return dataFile{}, nil, errors.New("symbol is required")
+	}
+	if !symbols.Valid(symbol) {
+		return dataFile{}, nil, fmt.Errorf("symbol %q is not a valid ticker", symbol)
+	}
+	symbol = l.cfg.aliases.Canonical(symbol)
+	// Adding a file changes its folder's time; rescan before trusting the index.
+	if dirTimes := historicalModTimes(l.roots); !l.index.current(dirTimes) {
+		if err := l.refreshIndex(dirTimes); err != nil {
+			slog.Warn("rescanning historical data failed", "error", err)
+		}
+	}
+	if file, ok := l.index.get(symbol); ok {
+		if info, err := fs.Stat(file.fsys, file.name); err == nil {
+			return file, info, nil
+		}
+		l.index.set(symbol, dataFile{})
+	}
+	if !symbols.Valid(symbol) {
+		return dataFile{}, nil, fmt.Errorf("symbol alias %q is not a valid ticker", symbol)
+	}
+	var file dataFile
+	var err error
+	for _, root := range l.roots {
+		if file, err = findHistoricalFile(root, symbol); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return dataFile{}, nil, err
+	}
+	info, err := fs.Stat(file.fsys, file.name)
+	if err != nil {
+		return dataFile{}, nil, fmt.Errorf("stat historical data: %w", err)
+	}
+	l.index.set(symbol, file)
+	return file, info, nil
+}
+
+// findHistoricalFile returns the lexically greatest SYMBOL_*.csv in root's
+// historical folder, or a consolidated SYMBOL.csv when there is none.
+func findHistoricalFile(root dataRoot, symbol string) (dataFile, error) {
+	if symbol == "" {
+		return dataFile{}, errors.New("symbol is required")
+	}
+	symbol = strings.ToUpper(symbol)
+	matches, err := globFiles(root.fsys, path.Join(historicalDir, symbol+"_*.csv"))
+	if err != nil || len(matches) == 0 {
+		// Fall back to a single consolidated SYMBOL.csv without a date suffix.
+		bare := path.Join(historicalDir, symbol+".csv")
+		if info, statErr := fs.Stat(root.fsys, bare); statErr == nil && !info.IsDir() {
+			return root.file(bare), nil
+		}
+		return dataFile{}, fmt.Errorf("no historical data for %s", symbol)
+	}
+	sort.Strings(matches)
+	return root.file(matches[len(matches)-1]), nil
+}
@@ -0,0 +1,221 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/symbols"
+)
+
+func countGlobs(t *testing.T) *int {
+	t.Helper()
+	calls := 0
+	original := globFiles
+	t.Cleanup(func() { globFiles = original })
+	globFiles = func(fsys fs.FS, pattern string) ([]string, error) {
+		calls++
+		return original(fsys, pattern)
+	}
+	return &calls
+}
+
+func TestLoader_IndexAvoidsGlobbing(t *testing.T) {
+	tempDir := t.TempDir()
+	historicalDir := filepath.Join(tempDir, "historical")
+	if err := os.MkdirAll(historicalDir, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	const symbols = 5000
+	content := []byte("Date,Close,High,Low,Open,Volume\n2025-01-01,10,11,9,10,1000\n2025-01-02,11,12,10,10,1000\n")
+	for i := 0; i < symbols; i++ {
+		for _, date := range []string{"2024-12-31", "2025-01-02"} {
+			name := fmt.Sprintf("S%04d_%s.csv", i, date)
+			if err := os.WriteFile(filepath.Join(historicalDir, name), content, 0644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+		}
+	}
+
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	globs := countGlobs(t)
+
+	for i := 0; i < symbols; i += 97 {
+		if _, err := loader.Load(context.Background(), fmt.Sprintf("S%04d", i), 0); err != nil {
+			t.Fatalf("Load S%04d: %v", i, err)
+		}
+	}
+	if *globs != 0 {
+		t.Errorf("Expected indexed lookups to avoid globbing, got %d globs", *globs)
+	}
+	if file, _ := loader.index.get("S0042"); file.name != "historical/S0042_2025-01-02.csv" {
+		t.Errorf("Expected the index to point at the newest file, got %s", file.name)
+	}
+
+	// A file added after the scan changes the folder, whose rescan indexes it.
+	writeHistoricalCSV(t, tempDir, "NEW_2025-01-01.csv", "10.00", "11.00")
+	for i := 0; i < 3; i++ {
+		if _, err := loader.Load(context.Background(), "NEW", 0); err != nil {
+			t.Fatalf("Load NEW: %v", err)
+		}
+	}
+	if *globs != 0 {
+		t.Errorf("Expected the rescan to index the new symbol without globbing, got %d globs", *globs)
+	}
+}
+
+func TestLoader_RefreshIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	writeHistoricalCSV(t, tempDir, "SPY_2025-02-01.csv", "460.00", "461.00")
+
+	if err := loader.RefreshIndex(); err != nil {
+		t.Fatalf("RefreshIndex: %v", err)
+	}
+
+	rows, err := loader.Load(context.Background(), "SPY", 0)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Errorf("Expected the refreshed index to serve the newer file (2 rows), got %d", len(rows))
+	}
+}
+
+func TestLoader_IndexSplitsOnFirstUnderscore(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025_01_01.csv", "450.00")
+	writeHistoricalCSV(t, tempDir, "SPY_2025_02_01.csv", "460.00", "461.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	if got := loader.index.symbols(); len(got) != 1 || got[0] != "SPY" {
+		t.Errorf("Expected underscore-dated files indexed as SPY, got %v", got)
+	}
+	if file, _ := loader.index.get("SPY"); file.name != "historical/SPY_2025_02_01.csv" {
+		t.Errorf("Expected the index to point at the newest file, got %s", file.name)
+	}
+}
+
+func TestLoader_RejectsGlobSymbols(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	globs := countGlobs(t)
+
+	for _, symbol := range []string{"S*", "SP?", "[S]PY", "../SPY"} {
+		if rows, err := loader.Load(context.Background(), symbol, 0); err == nil {
+			t.Errorf("Expected %q rejected, got %d rows", symbol, len(rows))
+		}
+	}
+	if *globs != 0 {
+		t.Errorf("Expected rejected symbols never globbed, got %d globs", *globs)
+	}
+	if got := loader.suggest("SPX"); len(got) != 1 || got[0] != "SPY" {
+		t.Errorf("Expected only real symbols suggested, got %v", got)
+	}
+}
+
+func TestLoader_IndexPicksUpNewerFile(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	if rows, err := loader.Load(context.Background(), "SPY", 0); err != nil || len(rows) != 1 {
+		t.Fatalf("Expected the indexed file's single row, got %d (%v)", len(rows), err)
+	}
+
+	writeHistoricalCSV(t, tempDir, "SPY_2025-02-01.csv", "460.00", "461.00")
+	// Coarse filesystem clocks could leave the folder time unchanged; force a tick.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(tempDir, "historical"), later, later); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	rows, err := loader.Load(context.Background(), "SPY", 0)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Errorf("Expected the newer file (2 rows) without an explicit refresh, got %d", len(rows))
+	}
+}
+
+func TestLoader_BareSymbolFile(t *testing.T) {
+	for _, indexed := range []bool{true, false} {
+		tempDir := t.TempDir()
+		historicalDir := filepath.Join(tempDir, "historical")
+		if err := os.MkdirAll(historicalDir, 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		loader, err := NewLoader(tempDir)
+		if err != nil {
+			t.Fatalf("NewLoader: %v", err)
+		}
+		consolidated := "# Consolidated history\nDate,Open,High,Low,Close,Volume\n2025-01-02,449,452,448,450,1000000\n2025-01-03,450,453,449,451,1000000\n"
+		if err := os.WriteFile(filepath.Join(historicalDir, "QQQ.csv"), []byte(consolidated), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00")
+		if err := os.WriteFile(filepath.Join(historicalDir, "SPY.csv"), []byte(consolidated), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if indexed {
+			if err := loader.RefreshIndex(); err != nil {
+				t.Fatalf("RefreshIndex: %v", err)
+			}
+		}
+
+		rows, err := loader.Load(context.Background(), "QQQ", 0)
+		if err != nil {
+			t.Fatalf("indexed=%v: expected the bare QQQ.csv to load: %v", indexed, err)
+		}
+		if len(rows) != 2 || rows[1].Close != 451 || rows[1].Open != 450 {
+			t.Errorf("indexed=%v: expected 2 header-mapped rows, got %+v", indexed, rows)
+		}
+		if rows, err := loader.Load(context.Background(), "SPY", 0); err != nil || len(rows) != 1 {
+			t.Errorf("indexed=%v: expected the dated SPY file to win over SPY.csv, got %d rows (%v)", indexed, len(rows), err)
+		}
+	}
+}
+
+func TestLoader_SymbolAliases(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "BRK-B_2025-01-01.csv", "450.00", "451.00")
+	aliases, err := symbols.NewAliases(map[string]string{"BRK.B": "BRK-B", "BRKB": "BRK-B"})
+	if err != nil {
+		t.Fatalf("NewAliases: %v", err)
+	}
+	loader, err := NewLoader(tempDir, WithSymbolAliases(aliases))
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	for _, variant := range []string{"BRK.B", "brk-b", "BRKB", " brkb "} {
+		out := loader.Snapshot(context.Background(), Input{Symbol: variant})
+		if !out.HasData || out.Symbol != "BRK-B" || out.Close != 451 {
+			t.Errorf("Expected %q to resolve to the BRK-B file, got %+v", variant, out)
+		}
+	}
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "BRK"}); out.HasData {
+		t.Errorf("Expected an unmapped symbol to pass through unresolved, got %+v", out)
+	}
+}
@@ -0,0 +1,44 @@
+package marketdata
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+)
+
+// DefaultMaxConcurrentOpens bounds concurrent CSV reads when WithMaxConcurrentOpens
+// is not given.
+var DefaultMaxConcurrentOpens = runtime.GOMAXPROCS(0) * 2
+
+// fileLimiter bounds the CSV files a Loader reads concurrently, so fanning out
+// over a large basket cannot exhaust file descriptors.
+type fileLimiter struct {
+	slots chan struct{}
+
+	inFlight atomic.Int64
+	peak     atomic.Int64
+}
+
+func newFileLimiter(n int) *fileLimiter {
+	return &fileLimiter{slots: make(chan struct{}, max(n, 1))}
+}
+
+// acquire blocks until a slot is free or ctx is done, and returns the release func.
+func (l *fileLimiter) acquire(ctx context.Context) (func(), error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	n := l.inFlight.Add(1)
+	for {
+		peak := l.peak.Load()
+		if n <= peak || l.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	return func() {
+		l.inFlight.Add(-1)
+		<-l.slots
+	}, nil
+}
@@ -0,0 +1,68 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestLoader_ConcurrentOpensAreBounded(t *testing.T) {
+	tempDir := t.TempDir()
+	const symbols = 40
+	for i := 0; i < symbols; i++ {
+		writeHistoricalCSV(t, tempDir, fmt.Sprintf("S%02d_2025-01-01.csv", i), "1.00", "2.00", "3.00")
+	}
+	loader, err := NewLoader(tempDir, WithCacheSize(0), WithMaxConcurrentOpens(3))
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for round := 0; round < 5; round++ {
+		for i := 0; i < symbols; i++ {
+			wg.Add(1)
+			go func(symbol string) {
+				defer wg.Done()
+				if _, err := loader.Load(context.Background(), symbol, 0); err != nil {
+					t.Errorf("Load %s: %v", symbol, err)
+				}
+			}(fmt.Sprintf("S%02d", i))
+		}
+	}
+	wg.Wait()
+
+	if peak := loader.opens.peak.Load(); peak > 3 || peak < 1 {
+		t.Errorf("Expected at most 3 concurrent opens, peaked at %d", peak)
+	}
+
+	// Another Loader's limit is its own and leaves the first one's alone.
+	other, err := NewLoader(tempDir, WithMaxConcurrentOpens(8))
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	if cap(loader.opens.slots) != 3 || cap(other.opens.slots) != 8 {
+		t.Errorf("Expected per-Loader limits of 3 and 8, got %d and %d", cap(loader.opens.slots), cap(other.opens.slots))
+	}
+}
+
+func TestLoader_RejectsNegativeOpenLimit(t *testing.T) {
+	if _, err := NewLoader(t.TempDir(), WithMaxConcurrentOpens(-1)); err == nil {
+		t.Error("Expected an error for a negative open limit")
+	}
+}
+
+func TestFileLimiter_AcquireHonoursContext(t *testing.T) {
+	l := newFileLimiter(1)
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer release()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := l.acquire(ctx); err == nil {
+		t.Error("Expected a cancelled context to abandon the wait")
+	}
+}
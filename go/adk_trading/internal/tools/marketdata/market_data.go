@@ -1,132 +1,884 @@
 package marketdata
 
 import (
+	"bufio"
+	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"math"
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/igorganapolsky/trading/adk_trading/internal/symbols"
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
 )
 
 type Input struct {
-	Symbol     string `json:"symbol"`
-	Window     int    `json:"window,omitempty"`
-	IncludeRaw bool   `json:"includeRaw,omitempty"`
+	Symbol      string `json:"symbol"`
+	Window      int    `json:"window,omitempty"`
+	IncludeRaw  bool   `json:"includeRaw,omitempty"`
+	PriceField  string `json:"priceField,omitempty"`
+	TrendMethod string `json:"trendMethod,omitempty"`
+	Action      string `json:"action,omitempty"`
+	// PeriodsPerYear annualises volatility: 252 for equity daily bars (default),
+	// 365 for crypto, 52 for weekly bars.
+	PeriodsPerYear float64 `json:"periodsPerYear,omitempty"`
+	// Resample aggregates daily bars into "weekly" or "monthly" bars before computing
+	// stats; Window then counts resampled bars and PeriodsPerYear defaults to 52 or 12.
+	Resample string `json:"resample,omitempty"`
+	// ReturnType is "simple" (default) for close/prevClose-1 or "log" for
+	// ln(close/prevClose); it applies to Returns and the volatility derived from them.
+	ReturnType string `json:"returnType,omitempty"`
+	// VolatilityModel is "stddev" (default) for the equal-weighted standard deviation
+	// of returns or "ewma" for a RiskMetrics-style exponentially weighted one that
+	// reacts faster to regime shifts. EWMALambda is its decay (default 0.94).
+	VolatilityModel string  `json:"volatilityModel,omitempty"`
+	EWMALambda      float64 `json:"ewmaLambda,omitempty"`
+	// RiskFreeRate is the annualised risk-free rate (e.g. 0.05 for 5%) subtracted,
+	// per period, from returns before computing SharpeRatio. It defaults to zero.
+	RiskFreeRate float64 `json:"riskFreeRate,omitempty"`
+	// IncludeVolumeProfile adds a volume-by-price profile of the window split into
+	// VolumeProfileBins bins (default 20).
+	IncludeVolumeProfile bool `json:"includeVolumeProfile,omitempty"`
+	VolumeProfileBins    int  `json:"volumeProfileBins,omitempty"`
+	// IncludeReturnHistogram adds the distribution of Returns split into
+	// ReturnHistogramBins bins (default 10), with its skewness and excess kurtosis.
+	IncludeReturnHistogram bool `json:"includeReturnHistogram,omitempty"`
+	ReturnHistogramBins    int  `json:"returnHistogramBins,omitempty"`
+	// IncludeSeasonality adds the average return by weekday over the window. It is
+	// ignored for resampled bars.
+	IncludeSeasonality bool `json:"includeSeasonality,omitempty"`
+	// IncludeSeries adds per-bar indicator arrays (closes, returns, moving averages)
+	// aligned to the loaded rows, for charting.
+	IncludeSeries bool `json:"includeSeries,omitempty"`
+	// Benchmark (e.g. "SPY") adds the symbol's beta to it and its volatility over
+	// the same window, for judging how much of a move is market-driven.
+	Benchmark string `json:"benchmark,omitempty"`
+	// VolumeBaseline is the number of bars before the last that VolumeRatio averages
+	// volume over (default 20); the window must hold one more bar than that.
+	VolumeBaseline int `json:"volumeBaseline,omitempty"`
+	// DetectSplits flags bars whose overnight move exceeds SplitThreshold (default
+	// 0.4, i.e. ±40%) as probable splits and leaves their returns out of Returns and
+	// everything derived from them, and their true ranges out of the ATR, for
+	// unadjusted series.
+	DetectSplits   bool    `json:"detectSplits,omitempty"`
+	SplitThreshold float64 `json:"splitThreshold,omitempty"`
+	// AsOfDate (YYYY-MM-DD) reproduces the snapshot as it stood on that day: rows
+	// after it are ignored, the window counts back from it and staleness is judged
+	// against it instead of today.
+	AsOfDate string `json:"asOfDate,omitempty"`
 }
 
 type Output struct {
-	Symbol           string             `json:"symbol"`
-	AsOf             time.Time          `json:"asOf"`
-	Close            float64            `json:"close"`
-	High             float64            `json:"high"`
-	Low              float64            `json:"low"`
-	Open             float64            `json:"open"`
-	Volume           float64            `json:"volume"`
-	Volatility       float64            `json:"volatility"`
-	AverageTrueRange float64            `json:"averageTrueRange"`
-	Returns          []float64          `json:"returns"`
-	MovingAverages   map[string]float64 `json:"movingAverages"`
-	VolumeRatio      float64            `json:"volumeRatio"`
-	TrendStrength    float64            `json:"trendStrength"`
-	RawRows          []Row              `json:"rawRows,omitempty"`
+	Symbol      string    `json:"symbol"`
+	HasData     bool      `json:"hasData"`
+	Error       string    `json:"error,omitempty"`
+	Suggestions []string  `json:"suggestions,omitempty"`
+	AsOf        time.Time `json:"asOf"`
+	// DataAgeDays is the calendar age of AsOf; Stale is set when its weekday age
+	// exceeds the loader's max data age or AsOf could not be parsed.
+	DataAgeDays        float64            `json:"dataAgeDays"`
+	Stale              bool               `json:"stale"`
+	Close              float64            `json:"close"`
+	High               float64            `json:"high"`
+	Low                float64            `json:"low"`
+	Open               float64            `json:"open"`
+	Volume             float64            `json:"volume"`
+	Volatility         float64            `json:"volatility"`
+	VolatilityModel    string             `json:"volatilityModel"`
+	EWMALambda         float64            `json:"ewmaLambda,omitempty"`
+	PeriodsPerYear     float64            `json:"periodsPerYear"`
+	RiskFreeRate       float64            `json:"riskFreeRate"`
+	SharpeRatio        float64            `json:"sharpeRatio"`
+	VolatilityRegime   string             `json:"volatilityRegime"`
+	VolatilityNote     string             `json:"volatilityNote,omitempty"`
+	AverageTrueRange   float64            `json:"averageTrueRange"`
+	OvernightReturn    float64            `json:"overnightReturn"`
+	IntradayReturn     float64            `json:"intradayReturn"`
+	GapPercent         float64            `json:"gapPercent"`
+	SuggestedStops     SuggestedStops     `json:"suggestedStops"`
+	Pivots             Pivots             `json:"pivots"`
+	Returns            []float64          `json:"returns"`
+	ReturnType         string             `json:"returnType"`
+	MovingAverages     map[string]float64 `json:"movingAverages"`
+	VolumeRatio        float64            `json:"volumeRatio"`
+	AverageDailyVolume float64            `json:"averageDailyVolume"`
+	Illiquid           bool               `json:"illiquid"`
+	TrendStrength      float64            `json:"trendStrength"`
+	TrendMethod        string             `json:"trendMethod"`
+	Resample           string             `json:"resample"`
+	RawRows            []Row              `json:"rawRows,omitempty"`
+	// RSI is the 14-bar relative strength index, zero with fewer than 15 bars.
+	RSI float64 `json:"rsi"`
+	// VolumeBaseline is the number of preceding bars VolumeRatio compares the last
+	// bar's volume with; zero when the window was too short and the ratio is a
+	// neutral 1.
+	VolumeBaseline int `json:"volumeBaseline"`
+	// PossibleCorporateActions lists bars flagged as probable splits when
+	// Input.DetectSplits is set; their returns are dropped from Returns, which is
+	// then no longer aligned with the rows, and so from the statistics and
+	// distributions built on them, and their true ranges from AverageTrueRange.
+	PossibleCorporateActions []CorporateAction `json:"possibleCorporateActions,omitempty"`
+	// CompositeScore blends trend, RSI and volume into a deterministic score from
+	// -1 (bearish) to 1 (bullish); see compositeScore for the weighting.
+	CompositeScore float64 `json:"compositeScore"`
+	// Benchmark, Beta and BenchmarkVolatility are populated only when
+	// Input.Benchmark is set; BenchmarkError explains a beta that could not be computed.
+	Benchmark           string  `json:"benchmark,omitempty"`
+	Beta                float64 `json:"beta,omitempty"`
+	BenchmarkVolatility float64 `json:"benchmarkVolatility,omitempty"`
+	BenchmarkError      string  `json:"benchmarkError,omitempty"`
+	// AsOfDate echoes Input.AsOfDate when the snapshot was pinned to that day.
+	AsOfDate string `json:"asOfDate,omitempty"`
+	// VolumeProfile is populated only when Input.IncludeVolumeProfile is set.
+	VolumeProfile *VolumeProfile `json:"volumeProfile,omitempty"`
+	// ReturnHistogram is populated only when Input.IncludeReturnHistogram is set;
+	// it has no bins and zero moments when the window holds too few returns.
+	ReturnHistogram *ReturnHistogram `json:"returnHistogram,omitempty"`
+	// SeasonalityByWeekday maps weekday names ("Monday") to the average return of
+	// bars ending on them; weekdays with too few samples are omitted. It is
+	// populated only when Input.IncludeSeasonality is set.
+	SeasonalityByWeekday map[string]float64 `json:"seasonalityByWeekday,omitempty"`
+	// Series is populated only when Input.IncludeSeries is set. Every array has one
+	// value per row; values are zero until an indicator's window is full.
+	Series map[string][]float64 `json:"series,omitempty"`
+}
+
+// SuggestedStops are data-derived stop levels around the last close: below it for
+// longs, above it for shorts. All levels are zero when ATR is unavailable.
+type SuggestedStops struct {
+	Side  string  `json:"side,omitempty"`
+	ATR1x float64 `json:"atr1x"`
+	ATR2x float64 `json:"atr2x"`
+	Pct2  float64 `json:"pct2"`
+}
+
+// Pivots are classic floor-trader levels from the most recent bar: Pivot is the
+// average of high, low and close, with resistance (R1, R2) above and support
+// (S1, S2) below. All levels are zero without data.
+type Pivots struct {
+	Pivot float64 `json:"pivot"`
+	R1    float64 `json:"r1"`
+	R2    float64 `json:"r2"`
+	S1    float64 `json:"s1"`
+	S2    float64 `json:"s2"`
 }
 
 type Row struct {
-	Date   string  `json:"date"`
-	Close  float64 `json:"close"`
-	High   float64 `json:"high"`
-	Low    float64 `json:"low"`
-	Open   float64 `json:"open"`
-	Volume float64 `json:"volume"`
+	Date     string  `json:"date"`
+	Close    float64 `json:"close"`
+	High     float64 `json:"high"`
+	Low      float64 `json:"low"`
+	Open     float64 `json:"open"`
+	Volume   float64 `json:"volume"`
+	AdjClose float64 `json:"adjClose,omitempty"`
+}
+
+// Trend methods accepted by Input.TrendMethod.
+const (
+	TrendMethodSMA = "sma"
+	TrendMethodEMA = "ema"
+)
+
+// Return types accepted by Input.ReturnType.
+const (
+	ReturnTypeSimple = "simple"
+	ReturnTypeLog    = "log"
+)
+
+// Volatility models accepted by Input.VolatilityModel.
+const (
+	VolatilityModelStdDev = "stddev"
+	VolatilityModelEWMA   = "ewma"
+)
+
+// DefaultEWMALambda is the RiskMetrics daily decay factor.
+const DefaultEWMALambda = 0.94
+
+// MaxRiskFreeRate bounds the annualised risk-free rate; anything higher is
+// almost certainly a percentage passed as a fraction (5 instead of 0.05).
+const MaxRiskFreeRate = 0.25
+
+// StatsOptions selects how ComputeStats derives its analytics. The zero value
+// reproduces the default snapshot behaviour.
+type StatsOptions struct {
+	// TrendMethod is TrendMethodSMA (default) or TrendMethodEMA.
+	TrendMethod string
+	// PeriodsPerYear annualises volatility; non-positive values use DefaultPeriodsPerYear.
+	PeriodsPerYear float64
+	// ReturnType is ReturnTypeSimple (default) or ReturnTypeLog.
+	ReturnType string
+	// VolatilityModel is VolatilityModelStdDev (default) or VolatilityModelEWMA.
+	VolatilityModel string
+	// EWMALambda is the EWMA decay in (0, 1); zero uses DefaultEWMALambda.
+	EWMALambda float64
+	// RiskFreeRate is the annualised risk-free rate in [0, MaxRiskFreeRate]
+	// subtracted from returns, per period, before computing the Sharpe ratio.
+	RiskFreeRate float64
+	// VolumeBaseline is the number of preceding bars the volume ratio averages;
+	// zero uses DefaultVolumeBaseline.
+	VolumeBaseline int
+	// DetectSplits drops returns, and true ranges, across probable splits, bars
+	// whose overnight move exceeds SplitThreshold (zero uses DefaultSplitThreshold).
+	DetectSplits   bool
+	SplitThreshold float64
+}
+
+// DefaultVolumeBaseline is the number of preceding bars the volume ratio averages
+// volume over by default.
+const DefaultVolumeBaseline = 20
+
+// DefaultPeriodsPerYear is the number of equity trading days used to annualise volatility.
+const DefaultPeriodsPerYear = 252
+
+func (o StatsOptions) normalize() (StatsOptions, error) {
+	if o.PeriodsPerYear <= 0 || math.IsNaN(o.PeriodsPerYear) || math.IsInf(o.PeriodsPerYear, 0) {
+		o.PeriodsPerYear = DefaultPeriodsPerYear
+	}
+	switch strings.ToLower(strings.TrimSpace(o.TrendMethod)) {
+	case "", TrendMethodSMA:
+		o.TrendMethod = TrendMethodSMA
+	case TrendMethodEMA:
+		o.TrendMethod = TrendMethodEMA
+	default:
+		return o, fmt.Errorf("unsupported trend method %q: use %q or %q", o.TrendMethod, TrendMethodSMA, TrendMethodEMA)
+	}
+	switch strings.ToLower(strings.TrimSpace(o.ReturnType)) {
+	case "", ReturnTypeSimple:
+		o.ReturnType = ReturnTypeSimple
+	case ReturnTypeLog:
+		o.ReturnType = ReturnTypeLog
+	default:
+		return o, fmt.Errorf("unsupported return type %q: use %q or %q", o.ReturnType, ReturnTypeSimple, ReturnTypeLog)
+	}
+	switch strings.ToLower(strings.TrimSpace(o.VolatilityModel)) {
+	case "", VolatilityModelStdDev:
+		o.VolatilityModel = VolatilityModelStdDev
+		o.EWMALambda = 0
+	case VolatilityModelEWMA:
+		o.VolatilityModel = VolatilityModelEWMA
+		if o.EWMALambda == 0 {
+			o.EWMALambda = DefaultEWMALambda
+		}
+		if !(o.EWMALambda > 0 && o.EWMALambda < 1) {
+			lambda := o.EWMALambda
+			o.EWMALambda = DefaultEWMALambda
+			return o, fmt.Errorf("ewma lambda must be between 0 and 1, got %g", lambda)
+		}
+	default:
+		return o, fmt.Errorf("unsupported volatility model %q: use %q or %q", o.VolatilityModel, VolatilityModelStdDev, VolatilityModelEWMA)
+	}
+	if o.VolumeBaseline < 0 {
+		baseline := o.VolumeBaseline
+		o.VolumeBaseline = DefaultVolumeBaseline
+		return o, fmt.Errorf("volume baseline must not be negative, got %d", baseline)
+	}
+	if o.VolumeBaseline == 0 {
+		o.VolumeBaseline = DefaultVolumeBaseline
+	}
+	if !(o.SplitThreshold >= 0) || math.IsInf(o.SplitThreshold, 0) {
+		threshold := o.SplitThreshold
+		o.SplitThreshold = DefaultSplitThreshold
+		return o, fmt.Errorf("split threshold must be a non-negative fraction, got %g", threshold)
+	}
+	if o.SplitThreshold == 0 {
+		o.SplitThreshold = DefaultSplitThreshold
+	}
+	if !(o.RiskFreeRate >= 0 && o.RiskFreeRate <= MaxRiskFreeRate) {
+		rate := o.RiskFreeRate
+		o.RiskFreeRate = 0
+		return o, fmt.Errorf("risk-free rate must be an annualised fraction between 0 and %g, got %g", MaxRiskFreeRate, rate)
+	}
+	return o, nil
 }
 
-func New(dataDir string) (tool.Tool, error) {
-	if dataDir == "" {
+// periodRiskFreeRate converts the annualised risk-free rate into the rate for one
+// period, compounding for simple returns and dividing evenly for log returns.
+func (o StatsOptions) periodRiskFreeRate() float64 {
+	if o.ReturnType == ReturnTypeLog {
+		return math.Log1p(o.RiskFreeRate) / o.PeriodsPerYear
+	}
+	return math.Pow(1+o.RiskFreeRate, 1/o.PeriodsPerYear) - 1
+}
+
+// Price fields accepted by Input.PriceField.
+const (
+	PriceFieldClose    = "close"
+	PriceFieldAdjClose = "adjClose"
+)
+
+// Loader reads historical OHLCV rows from the trading dataset so that several
+// tools can share one view of the data. Data directories are searched in order
+// and the first one holding a file for the symbol wins.
+type Loader struct {
+	roots []dataRoot
+	cfg   config
+	cache *rowCache
+	index symbolIndex
+	opens *fileLimiter
+}
+
+// DefaultMinAverageDailyVolume is the share volume below which a symbol is flagged illiquid.
+const DefaultMinAverageDailyVolume = 100_000
+
+// DefaultMinWindow is the fewest rows a snapshot is computed from; fewer give
+// meaningless returns and volatility.
+const DefaultMinWindow = 2
+
+// DefaultMaxDataAge is how old the latest bar may be, counting weekdays only,
+// before a snapshot is flagged stale: the bar's own session plus two more, so
+// Friday's close is fresh through a Monday holiday.
+const DefaultMaxDataAge = 3 * 24 * time.Hour
+
+// Default annualised volatility thresholds separating the low/normal, normal/elevated
+// and elevated/extreme regimes.
+const (
+	DefaultNormalVolatility   = 0.15
+	DefaultElevatedVolatility = 0.30
+	DefaultExtremeVolatility  = 0.50
+)
+
+// Volatility regimes reported in Output.VolatilityRegime.
+const (
+	VolatilityLow      = "low"
+	VolatilityNormal   = "normal"
+	VolatilityElevated = "elevated"
+	VolatilityExtreme  = "extreme"
+)
+
+type config struct {
+	cacheSize             int
+	minAverageDailyVolume float64
+	volThresholds         [3]float64
+	maxDataAge            time.Duration
+	maxOpens              int
+	minWindow             int
+	minSeasonalitySamples int
+	compositeWeights      CompositeWeights
+	schema                *Schema
+	aliases               symbols.Aliases
+	now                   func() time.Time
+}
+
+// Option customises a Loader.
+type Option func(*config)
+
+// WithCacheSize bounds how many parsed files are kept in memory. Zero or a negative size disables caching.
+func WithCacheSize(n int) Option {
+	return func(c *config) {
+		c.cacheSize = n
+	}
+}
+
+// WithMinAverageDailyVolume sets the average daily share volume below which snapshots are flagged illiquid.
+func WithMinAverageDailyVolume(v float64) Option {
+	return func(c *config) {
+		c.minAverageDailyVolume = v
+	}
+}
+
+// WithVolatilityThresholds sets the annualised volatility at which the regime moves
+// from low to normal, normal to elevated and elevated to extreme.
+func WithVolatilityThresholds(normal, elevated, extreme float64) Option {
+	return func(c *config) {
+		c.volThresholds = [3]float64{normal, elevated, extreme}
+	}
+}
+
+// WithSymbolAliases maps the variants users type, e.g. BRK.B and BRKB, to the
+// canonical symbol the data is filed under before any file is looked up.
+// Unmapped symbols are only upper-cased.
+func WithSymbolAliases(aliases symbols.Aliases) Option {
+	return func(c *config) {
+		c.aliases = aliases
+	}
+}
+
+// WithMinWindow sets the fewest rows, after price-field selection and resampling,
+// a snapshot needs; with fewer it reports HasData=false instead of statistics.
+func WithMinWindow(n int) Option {
+	return func(c *config) {
+		c.minWindow = n
+	}
+}
+
+// WithMaxDataAge sets how old the latest bar may be before a snapshot is flagged
+// stale. The age counts weekdays only, as weekends have no sessions to miss.
+func WithMaxDataAge(d time.Duration) Option {
+	return func(c *config) {
+		c.maxDataAge = d
+	}
+}
+
+// WithMaxConcurrentOpens bounds how many historical CSV files the Loader reads at
+// once; the default is DefaultMaxConcurrentOpens.
+func WithMaxConcurrentOpens(n int) Option {
+	return func(c *config) {
+		c.maxOpens = n
+	}
+}
+
+// NewLoader returns a Loader rooted at dataDir, which may list several directories
+// separated by the OS path list separator (":" on Unix), e.g. "/fast/data:/mnt/archive".
+func NewLoader(dataDir string, opts ...Option) (*Loader, error) {
+	return NewMultiLoader(filepath.SplitList(dataDir), opts...)
+}
+
+// NewMultiLoader returns a Loader that searches dataDirs in order.
+func NewMultiLoader(dataDirs []string, opts ...Option) (*Loader, error) {
+	var roots []dataRoot
+	for _, dir := range dataDirs {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			roots = append(roots, dataRoot{name: dir, fsys: os.DirFS(dir)})
+		}
+	}
+	if len(roots) == 0 {
 		return nil, errors.New("data directory not provided")
 	}
-	handler := func(ctx tool.Context, input Input) Output {
-		window := input.Window
-		if window <= 0 {
-			window = 60
+	return newLoader(roots, opts)
+}
+
+// NewFSLoader returns a Loader reading from fsys, whose root holds the historical
+// and universes folders a data directory would, such as an embedded fixture tree
+// or an object-store backed filesystem.
+func NewFSLoader(fsys fs.FS, opts ...Option) (*Loader, error) {
+	if fsys == nil {
+		return nil, errors.New("data filesystem not provided")
+	}
+	return newLoader([]dataRoot{{fsys: fsys}}, opts)
+}
+
+// newLoader validates opts and returns a Loader searching roots in order.
+func newLoader(roots []dataRoot, opts []Option) (*Loader, error) {
+	cfg := config{
+		cacheSize:             DefaultCacheSize,
+		minAverageDailyVolume: DefaultMinAverageDailyVolume,
+		volThresholds:         [3]float64{DefaultNormalVolatility, DefaultElevatedVolatility, DefaultExtremeVolatility},
+		maxDataAge:            DefaultMaxDataAge,
+		minWindow:             DefaultMinWindow,
+		minSeasonalitySamples: DefaultMinSeasonalitySamples,
+		compositeWeights:      CompositeWeights{Trend: DefaultTrendWeight, RSI: DefaultRSIWeight, Volume: DefaultVolumeWeight},
+		now:                   time.Now,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.maxDataAge <= 0 {
+		return nil, fmt.Errorf("max data age must be positive, got %s", cfg.maxDataAge)
+	}
+	if cfg.minWindow < 1 {
+		return nil, fmt.Errorf("min window must be at least 1, got %d", cfg.minWindow)
+	}
+	if cfg.minSeasonalitySamples < 1 {
+		return nil, fmt.Errorf("min seasonality samples must be at least 1, got %d", cfg.minSeasonalitySamples)
+	}
+	if err := cfg.compositeWeights.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.schema != nil {
+		if err := cfg.schema.validate(); err != nil {
+			return nil, err
 		}
-		rows, err := loadRows(dataDir, input.Symbol, window)
+	}
+	if cfg.maxOpens < 0 {
+		return nil, fmt.Errorf("max concurrent opens must not be negative, got %d", cfg.maxOpens)
+	}
+	if cfg.maxOpens == 0 {
+		cfg.maxOpens = DefaultMaxConcurrentOpens
+	}
+	t := cfg.volThresholds
+	if t[0] <= 0 || t[0] >= t[1] || t[1] >= t[2] {
+		return nil, fmt.Errorf("volatility thresholds must be positive and increasing, got %v/%v/%v", t[0], t[1], t[2])
+	}
+	loader := &Loader{
+		roots: roots,
+		cfg:   cfg,
+		cache: newRowCache(cfg.cacheSize),
+		opens: newFileLimiter(cfg.maxOpens),
+	}
+	// A failed scan is not fatal: unindexed symbols fall back to globbing.
+	_ = loader.RefreshIndex()
+	return loader, nil
+}
+
+// Load returns the most recent window rows for symbol. A non-positive window loads the full history.
+// Parsed files are cached until their modification time changes; the returned rows must not be modified.
+// A read is abandoned, and nothing cached, once ctx is done.
+func (l *Loader) Load(ctx context.Context, symbol string, window int) ([]Row, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("load %s: %w", symbol, err)
+	}
+	file, info, err := l.resolve(symbol)
+	if err != nil {
+		return nil, err
+	}
+	rows, ok := l.cache.get(file.path, info.ModTime())
+	if !ok {
+		rows, err = l.read(ctx, file)
 		if err != nil {
-			return Output{Symbol: strings.ToUpper(input.Symbol)}
+			return nil, err
+		}
+		l.cache.put(file.path, info.ModTime(), rows)
+	}
+	if window > 0 && len(rows) > window {
+		rows = rows[len(rows)-window:]
+	}
+	return rows, nil
+}
+
+// validate rejects inputs that cannot describe a snapshot. Zero values and
+// unrecognised option names are left to their defaults and normalize.
+func (in Input) validate() error {
+	switch {
+	case strings.TrimSpace(in.Symbol) == "":
+		return errors.New("symbol is required")
+	case !symbols.Valid(in.Symbol):
+		return fmt.Errorf("symbol %q is not a valid ticker", in.Symbol)
+	case in.Window < 0:
+		return fmt.Errorf("window must not be negative, got %d", in.Window)
+	case in.PeriodsPerYear < 0:
+		return fmt.Errorf("periodsPerYear must not be negative, got %g", in.PeriodsPerYear)
+	case in.VolumeProfileBins < 0:
+		return fmt.Errorf("volumeProfileBins must not be negative, got %d", in.VolumeProfileBins)
+	}
+	if _, _, err := in.asOfDate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Snapshot loads the requested window for input.Symbol and derives the snapshot analytics.
+func (l *Loader) Snapshot(ctx context.Context, input Input) Output {
+	if err := input.validate(); err != nil {
+		return Output{Symbol: strings.ToUpper(strings.TrimSpace(input.Symbol)), Error: err.Error()}
+	}
+	input.Symbol = l.cfg.aliases.Canonical(input.Symbol)
+	window := input.Window
+	if window == 0 {
+		window = 60
+	}
+	period, err := normalizeResample(input.Resample)
+	if err != nil {
+		return Output{Symbol: strings.ToUpper(input.Symbol), Error: err.Error()}
+	}
+	periods := input.PeriodsPerYear
+	if periods <= 0 {
+		periods = periodsPerYear[period]
+	}
+	statsOpts, err := StatsOptions{
+		TrendMethod:     input.TrendMethod,
+		PeriodsPerYear:  periods,
+		ReturnType:      input.ReturnType,
+		VolatilityModel: input.VolatilityModel,
+		EWMALambda:      input.EWMALambda,
+		RiskFreeRate:    input.RiskFreeRate,
+		VolumeBaseline:  input.VolumeBaseline,
+		DetectSplits:    input.DetectSplits,
+		SplitThreshold:  input.SplitThreshold,
+	}.normalize()
+	if err != nil {
+		return Output{Symbol: strings.ToUpper(input.Symbol), Error: err.Error()}
+	}
+	asOf, pinned, _ := input.asOfDate()
+	loadWindow := window
+	if period != ResampleDaily || pinned {
+		loadWindow = 0
+	}
+	rows, err := l.Load(ctx, input.Symbol, loadWindow)
+	if err != nil {
+		return Output{Symbol: strings.ToUpper(input.Symbol), Error: err.Error(), Suggestions: l.suggest(input.Symbol)}
+	}
+	if pinned {
+		rows = truncateAsOf(rows, asOf)
+	}
+	rows, err = selectPriceField(rows, input.PriceField)
+	daily := rows
+	if err == nil {
+		rows, err = resample(rows, period)
+	}
+	if err != nil {
+		return Output{Symbol: strings.ToUpper(input.Symbol), Error: err.Error()}
+	}
+	if len(rows) > window {
+		daily = rowsAfter(daily, rows[len(rows)-window-1].Date)
+		rows = rows[len(rows)-window:]
+	}
+	if len(rows) < l.cfg.minWindow {
+		return Output{
+			Symbol: strings.ToUpper(input.Symbol),
+			Error:  fmt.Sprintf("only %d rows available for %s; at least %d are needed for meaningful statistics", len(rows), strings.ToUpper(input.Symbol), l.cfg.minWindow),
 		}
-		stats := computeStats(rows)
-		out := Output{
-			Symbol:           strings.ToUpper(input.Symbol),
-			AsOf:             stats.AsOf,
-			Close:            stats.Close,
-			High:             stats.High,
-			Low:              stats.Low,
-			Open:             stats.Open,
-			Volume:           stats.Volume,
-			Volatility:       stats.Volatility,
-			AverageTrueRange: stats.AverageTrueRange,
-			Returns:          stats.Returns,
-			MovingAverages:   stats.MovingAverages,
-			VolumeRatio:      stats.VolumeRatio,
-			TrendStrength:    stats.TrendStrength,
+	}
+	stats := ComputeStats(rows, statsOpts)
+	// A resampled bar's volume is the sum of its days; liquidity is per day.
+	stats.AverageDailyVolume = averageDailyVolume(daily)
+	now := l.cfg.now()
+	if pinned {
+		now = asOf
+	}
+	age, stale := dataAgeAt(stats.AsOf, now, l.cfg.maxDataAge)
+	out := Output{
+		Symbol:             strings.ToUpper(input.Symbol),
+		HasData:            true,
+		AsOf:               stats.AsOf,
+		DataAgeDays:        age,
+		Stale:              stale,
+		Close:              stats.Close,
+		High:               stats.High,
+		Low:                stats.Low,
+		Open:               stats.Open,
+		Volume:             stats.Volume,
+		Volatility:         stats.Volatility,
+		VolatilityModel:    statsOpts.VolatilityModel,
+		EWMALambda:         statsOpts.EWMALambda,
+		PeriodsPerYear:     statsOpts.PeriodsPerYear,
+		RiskFreeRate:       statsOpts.RiskFreeRate,
+		SharpeRatio:        stats.SharpeRatio,
+		VolatilityRegime:   l.cfg.volatilityRegime(stats.Volatility),
+		VolatilityNote:     l.cfg.volatilityNote(),
+		AverageTrueRange:   stats.AverageTrueRange,
+		OvernightReturn:    stats.OvernightReturn,
+		IntradayReturn:     stats.IntradayReturn,
+		GapPercent:         stats.OvernightReturn * 100,
+		SuggestedStops:     suggestStops(stats.Close, stats.AverageTrueRange, input.Action),
+		Pivots:             floorPivots(stats.High, stats.Low, stats.Close),
+		Returns:            stats.Returns,
+		ReturnType:         statsOpts.ReturnType,
+		MovingAverages:     stats.MovingAverages,
+		VolumeRatio:        stats.VolumeRatio,
+		VolumeBaseline:     stats.VolumeBaseline,
+		AverageDailyVolume: stats.AverageDailyVolume,
+		Illiquid:           stats.AverageDailyVolume < l.cfg.minAverageDailyVolume,
+		TrendStrength:      stats.TrendStrength,
+		TrendMethod:        statsOpts.TrendMethod,
+		RSI:                stats.RSI,
+		CompositeScore:     compositeScore(stats, len(rows) > RSIPeriod, l.cfg.compositeWeights),
+		Resample:           period,
+	}
+	if pinned {
+		out.AsOfDate = asOf.Format("2006-01-02")
+	}
+	out.PossibleCorporateActions = stats.PossibleCorporateActions
+	if input.IncludeRaw {
+		out.RawRows = rows
+	}
+	if input.IncludeVolumeProfile {
+		out.VolumeProfile = volumeProfile(rows, input.VolumeProfileBins)
+	}
+	if input.IncludeReturnHistogram {
+		out.ReturnHistogram = returnHistogram(stats.Returns, input.ReturnHistogramBins)
+	}
+	if input.IncludeSeasonality && period == ResampleDaily {
+		out.SeasonalityByWeekday = seasonalityByWeekday(stats.ReturnDates, stats.Returns, l.cfg.minSeasonalitySamples)
+	}
+	if input.IncludeSeries {
+		out.Series = indicatorSeries(rows, statsOpts)
+	}
+	l.setBenchmark(ctx, &out, rows, input, statsOpts, period, loadWindow, window)
+	return out
+}
+
+// dataAge returns the calendar age of asOf in days and whether its weekday age
+// exceeds the max data age.
+// An unparsed (zero) asOf has no age and is always stale.
+func (c config) dataAge(asOf time.Time) (float64, bool) {
+	return dataAgeAt(asOf, c.now(), c.maxDataAge)
+}
+
+// dataAgeAt is dataAge measured at now rather than the loader's clock.
+func dataAgeAt(asOf, now time.Time, maxAge time.Duration) (float64, bool) {
+	if asOf.IsZero() {
+		return 0, true
+	}
+	return now.Sub(asOf).Hours() / 24, weekdayAge(asOf, now) > maxAge
+}
+
+// weekdayAge is the time from asOf to now, in UTC, leaving out Saturdays and
+// Sundays.
+func weekdayAge(asOf, now time.Time) time.Duration {
+	asOf, now = asOf.UTC(), now.UTC()
+	if !now.After(asOf) {
+		return now.Sub(asOf)
+	}
+	var age time.Duration
+	for day := asOf.Truncate(24 * time.Hour); day.Before(now); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		start, end := day, day.AddDate(0, 0, 1)
+		if start.Before(asOf) {
+			start = asOf
 		}
-		if input.IncludeRaw {
-			out.RawRows = rows
+		if end.After(now) {
+			end = now
 		}
+		age += end.Sub(start)
+	}
+	return age
+}
+
+// suggestStops places stops one and two ATRs and two percent away from close, on the
+// far side of the position implied by action ("SELL" for shorts, anything else long).
+func suggestStops(close, atr float64, action string) SuggestedStops {
+	if atr <= 0 || close <= 0 {
+		return SuggestedStops{}
+	}
+	if strings.EqualFold(strings.TrimSpace(action), "SELL") {
+		return SuggestedStops{Side: "short", ATR1x: close + atr, ATR2x: close + 2*atr, Pct2: close * 1.02}
+	}
+	return SuggestedStops{Side: "long", ATR1x: close - atr, ATR2x: close - 2*atr, Pct2: close * 0.98}
+}
+
+// floorPivots computes the standard floor-trader pivot levels for one bar.
+func floorPivots(high, low, close float64) Pivots {
+	if high == 0 && low == 0 && close == 0 {
+		return Pivots{}
+	}
+	pivot := (high + low + close) / 3
+	return Pivots{
+		Pivot: pivot,
+		R1:    2*pivot - low,
+		R2:    pivot + (high - low),
+		S1:    2*pivot - high,
+		S2:    pivot - (high - low),
+	}
+}
+
+// volatilityRegime classifies annualised volatility against the configured thresholds.
+func (c config) volatilityRegime(vol float64) string {
+	switch {
+	case vol < c.volThresholds[0]:
+		return VolatilityLow
+	case vol < c.volThresholds[1]:
+		return VolatilityNormal
+	case vol < c.volThresholds[2]:
+		return VolatilityElevated
+	default:
+		return VolatilityExtreme
+	}
+}
+
+func (c config) volatilityNote() string {
+	t := c.volThresholds
+	return fmt.Sprintf("annualised volatility regime: low <%.2f, normal <%.2f, elevated <%.2f, extreme >=%.2f", t[0], t[1], t[2], t[2])
+}
+
+func New(dataDir string, opts ...Option) (tool.Tool, error) {
+	loader, err := NewLoader(dataDir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTool(loader)
+}
+
+// NewWithFS is New reading the dataset from fsys instead of a directory on disk.
+func NewWithFS(fsys fs.FS, opts ...Option) (tool.Tool, error) {
+	loader, err := NewFSLoader(fsys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTool(loader)
+}
+
+// NewTool returns the get_market_snapshot tool backed by an existing Loader.
+func NewTool(loader *Loader) (tool.Tool, error) {
+	if loader == nil {
+		return nil, errors.New("loader is required")
+	}
+	handler := func(ctx tool.Context, input Input) Output {
+		out := loader.Snapshot(ctx, input)
+		slog.Debug("market snapshot served", "invocation", ctx.InvocationID(), "symbol", out.Symbol,
+			"has_data", out.HasData, "stale", out.Stale, "error", out.Error)
 		return out
 	}
 	return functiontool.New(functiontool.Config{
 		Name:        "get_market_snapshot",
-		Description: "Load recent OHLCV data and derived analytics for a symbol from the trading dataset.",
+		Description: "Load recent OHLCV data and derived analytics for a symbol from the trading dataset. sharpeRatio is annualised and uses riskFreeRate, an annualised fraction (0.05 for 5%, default 0). compositeScore blends trend, RSI and volume into a deterministic -1 (bearish) to 1 (bullish) score.",
 	}, handler)
 }
 
-type summary struct {
-	AsOf             time.Time
-	Close            float64
-	High             float64
-	Low              float64
-	Open             float64
-	Volume           float64
-	Volatility       float64
-	AverageTrueRange float64
-	Returns          []float64
-	MovingAverages   map[string]float64
-	VolumeRatio      float64
-	TrendStrength    float64
+// Summary holds the analytics derived from a window of rows.
+type Summary struct {
+	AsOf               time.Time
+	Close              float64
+	High               float64
+	Low                float64
+	Open               float64
+	Volume             float64
+	Volatility         float64
+	SharpeRatio        float64
+	AverageTrueRange   float64
+	Returns            []float64
+	ReturnDates        []string // ReturnDates[i] is the date of the bar Returns[i] ends on
+	MovingAverages     map[string]float64
+	VolumeRatio        float64
+	VolumeBaseline     int // bars VolumeRatio averaged, zero when there were too few
+	AverageDailyVolume float64
+	TrendStrength      float64
+	RSI                float64
+	OvernightReturn    float64
+	IntradayReturn     float64
+	// PossibleCorporateActions lists the probable splits whose returns and true
+	// ranges were dropped when StatsOptions.DetectSplits is set.
+	PossibleCorporateActions []CorporateAction
 }
 
-func loadRows(dataDir, symbol string, window int) ([]Row, error) {
-	if symbol == "" {
-		return nil, errors.New("symbol is required")
+// lastReturn is the return into the last bar; false when the window has none or
+// the last bar was dropped as a probable split, whose return is not a trade.
+func (s Summary) lastReturn() (float64, bool) {
+	n := len(s.Returns)
+	if n == 0 {
+		return 0, false
 	}
-	symbol = strings.ToUpper(symbol)
-	glob := filepath.Join(dataDir, "historical", fmt.Sprintf("%s_*.csv", symbol))
-	matches, err := filepath.Glob(glob)
-	if err != nil || len(matches) == 0 {
-		return nil, fmt.Errorf("no historical data for %s", symbol)
+	// Any bar after the last kept return was dropped, and corporate actions are in
+	// date order, so a later action means the last bar is one.
+	if actions := s.PossibleCorporateActions; len(actions) > 0 && n <= len(s.ReturnDates) &&
+		actions[len(actions)-1].Date > s.ReturnDates[n-1] {
+		return 0, false
 	}
-	sort.Strings(matches)
-	path := matches[len(matches)-1]
+	return s.Returns[n-1], true
+}
+
+// ctxCheckInterval is how many CSV records readRows reads between context checks.
+const ctxCheckInterval = 1024
+
+// read parses file with readRows once one of the Loader's open slots is free.
+func (l *Loader) read(ctx context.Context, file dataFile) ([]Row, error) {
+	release, err := l.opens.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", file.path, err)
+	}
+	defer release()
+	return readRows(ctx, file, l.cfg.schema)
+}
 
-	file, err := os.Open(path)
+// readRows parses the CSV file with schema, or, when schema is nil, with the
+// columns its header names.
+func readRows(ctx context.Context, file dataFile, schema *Schema) ([]Row, error) {
+	path := file.path
+	f, err := file.fsys.Open(file.name)
 	if err != nil {
 		return nil, fmt.Errorf("open historical data: %w", err)
 	}
-	defer file.Close()
+	defer f.Close()
 
-	reader := csv.NewReader(file)
+	reader := csv.NewReader(normalizeText(f))
 	reader.FieldsPerRecord = -1
 	var records [][]string
 	for {
+		if len(records)%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("read %s: %w", path, err)
+			}
+		}
 		record, err := reader.Read()
 		if errors.Is(err, io.EOF) {
 			break
@@ -136,130 +888,259 @@ func loadRows(dataDir, symbol string, window int) ([]Row, error) {
 		}
 		records = append(records, record)
 	}
-	if len(records) <= 3 {
-		return nil, fmt.Errorf("insufficient data in %s", path)
+	// Without a schema or a recognisable header fall back to the legacy positional
+	// layout; metadata rows simply fail to parse and are skipped.
+	var (
+		cols  columns
+		start int
+		ok    bool
+	)
+	if schema != nil {
+		if cols, start, err = schema.columns(records); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if cols, start, ok = detectColumns(records); !ok {
+		cols, start = positionalColumns, 0
 	}
-	records = records[3:] // skip metadata rows
+	records = records[start:]
 	if len(records) == 0 {
 		return nil, fmt.Errorf("no price rows in %s", path)
 	}
-	if window > 0 && len(records) > window {
-		records = records[len(records)-window:]
-	}
 	rows := make([]Row, 0, len(records))
 	for _, rec := range records {
-		if len(rec) < 6 {
-			continue
-		}
-		row, err := parseRow(rec)
+		row, err := cols.parse(rec)
 		if err != nil {
 			continue
 		}
 		rows = append(rows, row)
 	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no parseable price rows in %s", path)
+	}
 	return rows, nil
 }
 
-func parseRow(rec []string) (Row, error) {
-	if len(rec) < 6 {
-		return Row{}, fmt.Errorf("insufficient fields: need 6, got %d", len(rec))
+// utf8BOM is the byte order mark spreadsheet exports often prepend.
+const utf8BOM = "\xef\xbb\xbf"
+
+// normalizeText strips a leading UTF-8 BOM and turns CRLF and bare CR line endings
+// into LF, so files exported from spreadsheets parse like clean ones.
+func normalizeText(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if prefix, err := br.Peek(len(utf8BOM)); err == nil && string(prefix) == utf8BOM {
+		_, _ = br.Discard(len(utf8BOM))
 	}
-	closeVal, err := strconv.ParseFloat(rec[1], 64)
-	if err != nil {
-		return Row{}, err
+	return &lineEndingReader{r: br}
+}
+
+// lineEndingReader rewrites CRLF and bare CR as LF.
+type lineEndingReader struct {
+	r      io.Reader
+	prevCR bool
+}
+
+func (l *lineEndingReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	out := 0
+	for _, b := range p[:n] {
+		if l.prevCR {
+			l.prevCR = false
+			if b == '\n' {
+				continue
+			}
+		}
+		if b == '\r' {
+			l.prevCR, b = true, '\n'
+		}
+		p[out] = b
+		out++
 	}
-	highVal, err := strconv.ParseFloat(rec[2], 64)
-	if err != nil {
-		return Row{}, err
+	return out, err
+}
+
+func parseRow(rec []string) (Row, error) {
+	return positionalColumns.parse(rec)
+}
+
+// selectPriceField returns rows priced on the requested field. Adjusted mode rescales
+// open, high and low by each bar's adjustment factor so ranges stay consistent with the
+// adjusted close.
+func selectPriceField(rows []Row, field string) ([]Row, error) {
+	switch strings.ToLower(strings.TrimSpace(field)) {
+	case "", strings.ToLower(PriceFieldClose):
+		return rows, nil
+	case strings.ToLower(PriceFieldAdjClose):
+	default:
+		return nil, fmt.Errorf("unsupported price field %q: use %q or %q", field, PriceFieldClose, PriceFieldAdjClose)
 	}
-	lowVal, err := strconv.ParseFloat(rec[3], 64)
-	if err != nil {
-		return Row{}, err
+	adjusted := make([]Row, len(rows))
+	for i, row := range rows {
+		if row.AdjClose == 0 || row.Close == 0 {
+			return nil, fmt.Errorf("adjusted close not available for %s", row.Date)
+		}
+		factor := row.AdjClose / row.Close
+		row.Open *= factor
+		row.High *= factor
+		row.Low *= factor
+		row.Close = row.AdjClose
+		adjusted[i] = row
 	}
-	openVal, err := strconv.ParseFloat(rec[4], 64)
-	if err != nil {
-		return Row{}, err
+	return adjusted, nil
+}
+
+// ewmaVolatility annualises the exponentially weighted volatility of returns:
+// variance starts at the first squared return and each later return updates it
+// as lambda*variance + (1-lambda)*r², assuming a zero mean as RiskMetrics does.
+func ewmaVolatility(returns []float64, lambda, periodsPerYear float64) float64 {
+	if len(returns) < 2 {
+		return 0
 	}
-	volumeVal, err := strconv.ParseFloat(rec[5], 64)
-	if err != nil {
-		return Row{}, err
+	variance := returns[0] * returns[0]
+	for _, ret := range returns[1:] {
+		variance = lambda*variance + (1-lambda)*ret*ret
 	}
-	return Row{
-		Date:   rec[0],
-		Close:  closeVal,
-		High:   highVal,
-		Low:    lowVal,
-		Open:   openVal,
-		Volume: volumeVal,
-	}, nil
+	return math.Sqrt(variance) * math.Sqrt(periodsPerYear)
 }
 
-func computeStats(rows []Row) summary {
+// ComputeStats derives the snapshot analytics from rows ordered oldest to newest.
+// Unrecognised options fall back to their defaults.
+func ComputeStats(rows []Row, opts StatsOptions) Summary {
+	opts, _ = opts.normalize()
 	n := len(rows)
 	if n == 0 {
-		return summary{MovingAverages: map[string]float64{}}
+		return Summary{MovingAverages: map[string]float64{}}
 	}
 	last := rows[n-1]
 
+	var actions []CorporateAction
+	var flagged []bool
+	if opts.DetectSplits {
+		actions, flagged = detectSplits(rows, opts.SplitThreshold)
+	}
+	// Returns across a probable split are dropped rather than zeroed, so nothing
+	// built on them sees a move that never traded.
 	returns := make([]float64, 0, n-1)
+	returnDates := make([]string, 0, n-1)
 	var sumReturn, sumReturnSq float64
 	for i := 1; i < n; i++ {
+		if flagged != nil && flagged[i-1] {
+			continue
+		}
 		ret := (rows[i].Close / rows[i-1].Close) - 1.0
+		if opts.ReturnType == ReturnTypeLog {
+			ret = math.Log(rows[i].Close / rows[i-1].Close)
+		}
 		returns = append(returns, ret)
+		returnDates = append(returnDates, rows[i].Date)
 		sumReturn += ret
 		sumReturnSq += ret * ret
 	}
-	var volatility float64
+	var volatility, sharpe float64
 	if len(returns) > 1 {
 		mean := sumReturn / float64(len(returns))
 		variance := (sumReturnSq / float64(len(returns))) - (mean * mean)
 		if variance < 0 {
 			variance = 0
 		}
-		volatility = math.Sqrt(variance) * math.Sqrt(252.0)
+		volatility = math.Sqrt(variance) * math.Sqrt(opts.PeriodsPerYear)
+		// Sharpe always uses the equal-weighted deviation, whatever the volatility model.
+		if stdDev := math.Sqrt(variance); stdDev > 0 {
+			sharpe = (mean - opts.periodRiskFreeRate()) / stdDev * math.Sqrt(opts.PeriodsPerYear)
+		}
+	}
+	if opts.VolatilityModel == VolatilityModelEWMA {
+		volatility = ewmaVolatility(returns, opts.EWMALambda, opts.PeriodsPerYear)
 	}
 
-	atr := averageTrueRange(rows)
+	atr := averageTrueRange(rows, flagged)
 	movingAverages := map[string]float64{
 		"ma20":  movingAverage(rows, 20),
 		"ma50":  movingAverage(rows, 50),
 		"ma100": movingAverage(rows, 100),
 	}
 
+	// Without a full baseline before the last bar the ratio stays a neutral 1.
 	var volumeRatio float64 = 1.0
-	if n >= 21 {
+	var volumeBaseline int
+	if baseline := opts.VolumeBaseline; n > baseline {
 		var sumVolume float64
-		for i := n - 21; i < n-1; i++ {
+		for i := n - 1 - baseline; i < n-1; i++ {
 			sumVolume += rows[i].Volume
 		}
-		avgVolume := sumVolume / 20
-		if avgVolume > 0 {
+		volumeBaseline = baseline
+		if avgVolume := sumVolume / float64(baseline); avgVolume > 0 {
 			volumeRatio = rows[n-1].Volume / avgVolume
 		}
 	}
 
 	maShort := movingAverages["ma20"]
 	maLong := movingAverages["ma50"]
+	if opts.TrendMethod == TrendMethodEMA {
+		maShort = ema(rows, 20)
+		maLong = ema(rows, 50)
+		movingAverages["ema20"] = maShort
+		movingAverages["ema50"] = maLong
+	}
 	var trendStrength float64
 	if maLong != 0 {
 		trendStrength = (maShort - maLong) / maLong
 	}
 
+	// Gap metrics compare the last bar with the prior close; a single row has none.
+	var overnight, intraday float64
+	if n > 1 {
+		if prevClose := rows[n-2].Close; prevClose != 0 {
+			overnight = last.Open/prevClose - 1
+		}
+		if last.Open != 0 {
+			intraday = last.Close/last.Open - 1
+		}
+	}
+
 	asOf, _ := time.Parse("2006-01-02", last.Date)
-	return summary{
-		AsOf:             asOf,
-		Close:            last.Close,
-		High:             last.High,
-		Low:              last.Low,
-		Open:             last.Open,
-		Volume:           last.Volume,
-		Volatility:       volatility,
-		AverageTrueRange: atr,
-		Returns:          returns,
-		MovingAverages:   movingAverages,
-		VolumeRatio:      volumeRatio,
-		TrendStrength:    trendStrength,
+	rsi, _ := relativeStrength(rows, RSIPeriod)
+	return Summary{
+		AsOf:               asOf,
+		Close:              last.Close,
+		High:               last.High,
+		Low:                last.Low,
+		Open:               last.Open,
+		Volume:             last.Volume,
+		Volatility:         volatility,
+		SharpeRatio:        sharpe,
+		AverageTrueRange:   atr,
+		Returns:            returns,
+		ReturnDates:        returnDates,
+		MovingAverages:     movingAverages,
+		VolumeRatio:        volumeRatio,
+		VolumeBaseline:     volumeBaseline,
+		AverageDailyVolume: averageDailyVolume(rows),
+		TrendStrength:      trendStrength,
+		RSI:                rsi,
+		OvernightReturn:    overnight,
+		IntradayReturn:     intraday,
+
+		PossibleCorporateActions: actions,
+	}
+}
+
+// averageDailyVolume is the mean volume of rows, which are daily bars.
+func averageDailyVolume(rows []Row) float64 {
+	if len(rows) == 0 {
+		return 0
 	}
+	var total float64
+	for _, row := range rows {
+		total += row.Volume
+	}
+	return total / float64(len(rows))
+}
+
+// rowsAfter returns the rows, ordered oldest to newest, dated after date: the
+// days making up the resampled bars that follow the bar dated date.
+func rowsAfter(rows []Row, date string) []Row {
+	i := sort.Search(len(rows), func(i int) bool { return rows[i].Date > date })
+	return rows[i:]
 }
 
 func movingAverage(rows []Row, period int) float64 {
@@ -279,12 +1160,31 @@ func movingAverage(rows []Row, period int) float64 {
 	return sum / float64(period)
 }
 
-func averageTrueRange(rows []Row) float64 {
+// ema returns the exponential moving average of closes with smoothing 2/(period+1),
+// seeded from the first close so short histories still yield a value.
+func ema(rows []Row, period int) float64 {
+	if period <= 0 || len(rows) == 0 {
+		return 0
+	}
+	alpha := 2.0 / float64(period+1)
+	value := rows[0].Close
+	for _, row := range rows[1:] {
+		value = alpha*row.Close + (1-alpha)*value
+	}
+	return value
+}
+
+// averageTrueRange averages the true range of each bar after the first, skipping
+// rows[i] when skip[i-1] is set; skip may be nil.
+func averageTrueRange(rows []Row, skip []bool) float64 {
 	if len(rows) < 2 {
 		return 0
 	}
 	var trs []float64
 	for i := 1; i < len(rows); i++ {
+		if skip != nil && skip[i-1] {
+			continue
+		}
 		high := rows[i].High
 		low := rows[i].Low
 		prevClose := rows[i-1].Close
@@ -292,6 +1192,9 @@ func averageTrueRange(rows []Row) float64 {
 		tr := math.Max(high-low, math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
 		trs = append(trs, tr)
 	}
+	if len(trs) == 0 {
+		return 0
+	}
 	var sum float64
 	for _, tr := range trs {
 		sum += tr
@@ -1,43 +1,37 @@
 package marketdata
 
 import (
-	"encoding/csv"
+	"context"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
-func TestMarketDataTool_LoadRows(t *testing.T) {
-	// Create a temporary data directory structure
-	tempDir := t.TempDir()
-	historicalDir := filepath.Join(tempDir, "historical")
-	if err := os.MkdirAll(historicalDir, 0755); err != nil {
-		t.Fatalf("Failed to create historical directory: %v", err)
-	}
-
-	// Create a sample CSV file
-	csvPath := filepath.Join(historicalDir, "SPY_2025-01-01.csv")
-	file, err := os.Create(csvPath)
+// loadFS loads symbol's rows from an in-memory data directory.
+func loadFS(t *testing.T, fsys fstest.MapFS, symbol string, window int) ([]Row, error) {
+	t.Helper()
+	loader, err := NewFSLoader(fsys)
 	if err != nil {
-		t.Fatalf("Failed to create CSV file: %v", err)
+		t.Fatalf("NewFSLoader: %v", err)
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
+	return loader.Load(context.Background(), symbol, window)
+}
 
-	// Write CSV with metadata rows and data rows
-	writer.Write([]string{"# Metadata row 1"})
-	writer.Write([]string{"# Metadata row 2"})
-	writer.Write([]string{"# Metadata row 3"})
-	writer.Write([]string{"Date", "Close", "High", "Low", "Open", "Volume"})
-	writer.Write([]string{"2025-01-01", "450.00", "452.00", "448.00", "449.00", "1000000"})
-	writer.Write([]string{"2025-01-02", "451.00", "453.00", "449.00", "450.00", "1100000"})
-	writer.Write([]string{"2025-01-03", "452.00", "454.00", "450.00", "451.00", "1200000"})
-	writer.Flush()
-	file.Close()
+func TestMarketDataTool_LoadRows(t *testing.T) {
+	// Metadata rows precede the header and data rows.
+	fsys := fstest.MapFS{"historical/SPY_2025-01-01.csv": {Data: []byte(
+		"# Metadata row 1\n# Metadata row 2\n# Metadata row 3\n" +
+			"Date,Close,High,Low,Open,Volume\n" +
+			"2025-01-01,450.00,452.00,448.00,449.00,1000000\n" +
+			"2025-01-02,451.00,453.00,449.00,450.00,1100000\n" +
+			"2025-01-03,452.00,454.00,450.00,451.00,1200000\n")}}
 
-	// Test loading rows
-	rows, err := loadRows(tempDir, "SPY", 0)
+	rows, err := loadFS(t, fsys, "SPY", 0)
 	if err != nil {
 		t.Fatalf("Failed to load rows: %v", err)
 	}
@@ -52,41 +46,16 @@ func TestMarketDataTool_LoadRows(t *testing.T) {
 }
 
 func TestMarketDataTool_LoadRowsWithWindow(t *testing.T) {
-	tempDir := t.TempDir()
-	historicalDir := filepath.Join(tempDir, "historical")
-	if err := os.MkdirAll(historicalDir, 0755); err != nil {
-		t.Fatalf("Failed to create historical directory: %v", err)
-	}
-
-	csvPath := filepath.Join(historicalDir, "SPY_2025-01-01.csv")
-	file, err := os.Create(csvPath)
-	if err != nil {
-		t.Fatalf("Failed to create CSV file: %v", err)
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-
-	writer.Write([]string{"# Metadata"})
-	writer.Write([]string{"# Metadata"})
-	writer.Write([]string{"# Metadata"})
-	writer.Write([]string{"Date", "Close", "High", "Low", "Open", "Volume"})
+	var b strings.Builder
+	b.WriteString("# Metadata\n# Metadata\n# Metadata\nDate,Close,High,Low,Open,Volume\n")
 	// Write 10 rows
 	for i := 1; i <= 10; i++ {
-		writer.Write([]string{
-			"2025-01-01",
-			"450.00",
-			"452.00",
-			"448.00",
-			"449.00",
-			"1000000",
-		})
+		b.WriteString("2025-01-01,450.00,452.00,448.00,449.00,1000000\n")
 	}
-	writer.Flush()
-	file.Close()
+	fsys := fstest.MapFS{"historical/SPY_2025-01-01.csv": {Data: []byte(b.String())}}
 
 	// Test with window of 5
-	rows, err := loadRows(tempDir, "SPY", 5)
+	rows, err := loadFS(t, fsys, "SPY", 5)
 	if err != nil {
 		t.Fatalf("Failed to load rows: %v", err)
 	}
@@ -97,14 +66,31 @@ func TestMarketDataTool_LoadRowsWithWindow(t *testing.T) {
 }
 
 func TestMarketDataTool_LoadRowsNoData(t *testing.T) {
-	tempDir := t.TempDir()
-
-	_, err := loadRows(tempDir, "NONEXISTENT", 0)
+	_, err := loadFS(t, fstest.MapFS{}, "NONEXISTENT", 0)
 	if err == nil {
 		t.Error("Expected error for non-existent symbol")
 	}
 }
 
+func TestNewFSLoader_ReadsFromDisk(t *testing.T) {
+	// os.DirFS behaves like NewLoader on the same directory, bare SYMBOL.csv included.
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "QQQ.csv", "500.00", "501.00")
+	loader, err := NewFSLoader(os.DirFS(tempDir))
+	if err != nil {
+		t.Fatalf("NewFSLoader: %v", err)
+	}
+	if rows, err := loader.Load(context.Background(), "qqq", 0); err != nil || len(rows) != 2 {
+		t.Errorf("Expected 2 rows from the bare file, got %v, %v", rows, err)
+	}
+	if _, err := NewFSLoader(nil); err == nil {
+		t.Error("Expected an error without a filesystem")
+	}
+	if _, err := NewWithFS(os.DirFS(tempDir)); err != nil {
+		t.Errorf("NewWithFS: %v", err)
+	}
+}
+
 func TestMarketDataTool_ParseRow(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -174,7 +160,7 @@ func TestMarketDataTool_AverageTrueRange(t *testing.T) {
 		{Date: "2025-01-03", High: 122.0, Low: 118.0, Close: 120.0},
 	}
 
-	result := averageTrueRange(rows)
+	result := averageTrueRange(rows, nil)
 	if result <= 0 {
 		t.Errorf("Expected positive ATR, got %f", result)
 	}
@@ -185,7 +171,7 @@ func TestMarketDataTool_AverageTrueRangeInsufficientData(t *testing.T) {
 		{Date: "2025-01-01", High: 102.0, Low: 98.0, Close: 100.0},
 	}
 
-	result := averageTrueRange(rows)
+	result := averageTrueRange(rows, nil)
 	if result != 0 {
 		t.Errorf("Expected 0 ATR for insufficient data, got %f", result)
 	}
@@ -205,3 +191,699 @@ func TestMarketDataTool_New(t *testing.T) {
 		t.Error("Expected error for empty data directory")
 	}
 }
+
+func TestLoader_SnapshotReportsMissingData(t *testing.T) {
+	loader, err := NewLoader(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "spy"})
+
+	if out.HasData {
+		t.Error("Expected HasData=false when no file exists")
+	}
+	if out.Error == "" {
+		t.Error("Expected a descriptive error when no file exists")
+	}
+	if out.Symbol != "SPY" {
+		t.Errorf("Expected symbol to stay populated as SPY, got %q", out.Symbol)
+	}
+}
+
+func TestLoader_SnapshotHasData(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "452.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"})
+
+	if !out.HasData || out.Error != "" {
+		t.Errorf("Expected HasData=true with no error, got HasData=%v error=%q", out.HasData, out.Error)
+	}
+	if out.Close != 452.00 {
+		t.Errorf("Expected Close 452.00, got %f", out.Close)
+	}
+}
+
+func TestLoader_SnapshotPriceField(t *testing.T) {
+	tempDir := t.TempDir()
+	historicalDir := filepath.Join(tempDir, "historical")
+	if err := os.MkdirAll(historicalDir, 0755); err != nil {
+		t.Fatalf("Failed to create historical directory: %v", err)
+	}
+	content := "Date,Open,High,Low,Close,Adj Close,Volume\n" +
+		"2025-01-01,100.00,102.00,98.00,100.00,50.00,1000000\n" +
+		"2025-01-02,100.00,104.00,99.00,102.00,51.00,1100000\n"
+	if err := os.WriteFile(filepath.Join(historicalDir, "SPY_2025-01-02.csv"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write CSV file: %v", err)
+	}
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	raw := loader.Snapshot(context.Background(), Input{Symbol: "SPY"})
+	if raw.Close != 102.00 || raw.High != 104.00 {
+		t.Errorf("Expected raw close 102 and high 104, got close %f high %f", raw.Close, raw.High)
+	}
+
+	adjusted := loader.Snapshot(context.Background(), Input{Symbol: "SPY", PriceField: PriceFieldAdjClose})
+	if adjusted.Error != "" {
+		t.Fatalf("Unexpected error: %s", adjusted.Error)
+	}
+	if adjusted.Close != 51.00 || adjusted.High != 52.00 {
+		t.Errorf("Expected adjusted close 51 and high 52, got close %f high %f", adjusted.Close, adjusted.High)
+	}
+
+	invalid := loader.Snapshot(context.Background(), Input{Symbol: "SPY", PriceField: "vwap"})
+	if invalid.HasData || invalid.Error == "" {
+		t.Error("Expected an error for an unsupported price field")
+	}
+}
+
+func TestLoader_SnapshotAdjCloseYFinanceLayout(t *testing.T) {
+	loader, err := NewFSLoader(checkedInFS(t, "AMZN_2025-11-29.csv"))
+	if err != nil {
+		t.Fatalf("NewFSLoader: %v", err)
+	}
+	rows, err := loader.Load(context.Background(), "AMZN", 0)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	last := rows[len(rows)-1]
+	if last.AdjClose == 0 {
+		t.Fatalf("Expected the Adj Close column read, got %+v", last)
+	}
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "AMZN", PriceField: PriceFieldAdjClose})
+	if out.Error != "" || !out.HasData {
+		t.Fatalf("Expected an adjusted snapshot, got error %q", out.Error)
+	}
+	factor := last.AdjClose / last.Close
+	if out.Close != last.AdjClose || math.Abs(out.High-last.High*factor) > 1e-9 || out.Low > out.High {
+		t.Errorf("Expected the last bar scaled to its adjusted close %f, got close %f high %f low %f", last.AdjClose, out.Close, out.High, out.Low)
+	}
+}
+
+func TestLoader_SnapshotAdjCloseMissing(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", PriceField: PriceFieldAdjClose})
+
+	if out.HasData || out.Error == "" {
+		t.Error("Expected an error when the file has no adjusted close column")
+	}
+}
+
+func TestMarketDataTool_LoadRowsOHLCVOrder(t *testing.T) {
+	content := "Date,Open,High,Low,Close,Volume\n" +
+		"2025-02-03,592.67,600.29,590.49,597.77,65857248.0\n" +
+		"2025-02-04,597.83,602.30,597.28,601.78,33457815.0\n"
+	fsys := fstest.MapFS{"historical/SPY_2025-02-04.csv": {Data: []byte(content)}}
+
+	rows, err := loadFS(t, fsys, "SPY", 0)
+	if err != nil {
+		t.Fatalf("Failed to load rows: %v", err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+	want := Row{Date: "2025-02-03", Open: 592.67, High: 600.29, Low: 590.49, Close: 597.77, Volume: 65857248}
+	if rows[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, rows[0])
+	}
+}
+
+func TestDetectColumns(t *testing.T) {
+	tests := []struct {
+		name      string
+		records   [][]string
+		wantOK    bool
+		wantStart int
+		wantCols  columns
+	}{
+		{
+			name:      "ohlcv order",
+			records:   [][]string{{"Date", "Open", "High", "Low", "Close", "Volume"}},
+			wantOK:    true,
+			wantStart: 1,
+			wantCols:  columns{date: 0, open: 1, high: 2, low: 3, close: 4, volume: 5, adjClose: -1},
+		},
+		{
+			name:      "case, whitespace and extra columns",
+			records:   [][]string{{"# vendor export"}, {" TIMESTAMP ", "Dividends", "close", "HIGH", "low", "Open", "Adj_Close", "vol"}},
+			wantOK:    true,
+			wantStart: 2,
+			wantCols:  columns{date: 0, close: 2, high: 3, low: 4, open: 5, adjClose: 6, volume: 7},
+		},
+		{
+			name: "yfinance multi-row header",
+			records: [][]string{
+				{"Price", "Adj Close", "Close", "High", "Low", "Open", "Volume"},
+				{"Ticker", "AMZN", "AMZN", "AMZN", "AMZN", "AMZN", "AMZN"},
+				{"Date", "", "", "", "", "", ""},
+			},
+			wantOK:    true,
+			wantStart: 3,
+			wantCols:  columns{date: 0, adjClose: 1, close: 2, high: 3, low: 4, open: 5, volume: 6},
+		},
+		{
+			name: "yfinance header without an index label",
+			records: [][]string{
+				{"", "Close", "High", "Low", "Open", "Volume", "('Close', 'QQQ')"},
+				{"Ticker", "QQQ", "QQQ", "QQQ", "QQQ", "QQQ", ""},
+				{"Date", "", "", "", "", "", ""},
+			},
+			wantOK:    true,
+			wantStart: 3,
+			wantCols:  columns{date: 0, close: 1, high: 2, low: 3, open: 4, volume: 5, adjClose: -1},
+		},
+		{
+			name:    "no header",
+			records: [][]string{{"2025-01-01", "450.00", "452.00", "448.00", "449.00", "1000000"}},
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols, start, ok := detectColumns(tt.records)
+			if ok != tt.wantOK {
+				t.Fatalf("detectColumns() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if start != tt.wantStart || cols != tt.wantCols {
+				t.Errorf("detectColumns() = %+v at %d, want %+v at %d", cols, start, tt.wantCols, tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestMarketDataTool_LoadRowsPositionalFallback(t *testing.T) {
+	content := "Price,Close,High,Low,Open,Volume\n" +
+		"Ticker,SPY,SPY,SPY,SPY,SPY\n" +
+		"2025-01-01,450.00,452.00,448.00,449.00,1000000\n"
+	fsys := fstest.MapFS{"historical/SPY_2025-01-01.csv": {Data: []byte(content)}}
+
+	rows, err := loadFS(t, fsys, "SPY", 0)
+	if err != nil {
+		t.Fatalf("Failed to load rows: %v", err)
+	}
+
+	if len(rows) != 1 || rows[0].Close != 450.00 || rows[0].Open != 449.00 {
+		t.Errorf("Expected one positional row with close 450 and open 449, got %+v", rows)
+	}
+}
+
+// checkedInFS serves the named files of the repository's data/historical folder,
+// so tests exercise the layouts real downloads arrive in.
+func checkedInFS(t *testing.T, names ...string) fstest.MapFS {
+	t.Helper()
+	fsys := fstest.MapFS{}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join("..", "..", "..", "..", "..", "data", "historical", name))
+		if err != nil {
+			t.Fatalf("read checked-in data: %v", err)
+		}
+		fsys["historical/"+name] = &fstest.MapFile{Data: data}
+	}
+	return fsys
+}
+
+func TestMarketDataTool_LoadRowsYFinanceLayout(t *testing.T) {
+	for _, name := range []string{"AMZN_2025-11-29.csv", "GOOGL_2025-11-02.csv", "QQQ_2025-11-02.csv"} {
+		symbol := name[:strings.Index(name, "_")]
+		rows, err := loadFS(t, checkedInFS(t, name), symbol, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(rows) == 0 {
+			t.Fatalf("%s: expected rows", name)
+		}
+		for _, row := range rows {
+			if _, err := time.Parse("2006-01-02", row.Date); err != nil {
+				t.Fatalf("%s: expected a date in the first column, got %+v", name, row)
+			}
+			if row.Low > row.High || row.Close < row.Low || row.Close > row.High {
+				t.Fatalf("%s: expected low <= close <= high, got %+v", name, row)
+			}
+			// Volumes are in shares, far above any price the open column would hold.
+			if row.Volume < 100000 {
+				t.Fatalf("%s: expected a share volume, got %+v", name, row)
+			}
+		}
+	}
+}
+
+func TestMarketDataTool_EMA(t *testing.T) {
+	rows := []Row{{Close: 100.0}, {Close: 110.0}, {Close: 120.0}}
+
+	result := ema(rows, 3)
+	// alpha = 0.5: 100 -> 105 -> 112.5
+	if result != 112.5 {
+		t.Errorf("Expected EMA 112.5, got %f", result)
+	}
+	if ema(nil, 3) != 0 {
+		t.Error("Expected EMA of no rows to be 0")
+	}
+}
+
+func TestComputeStats_TrendMethod(t *testing.T) {
+	rows := make([]Row, 0, 60)
+	for i := 0; i < 60; i++ {
+		rows = append(rows, Row{Date: "2025-01-01", Close: 100 + float64(i)})
+	}
+
+	sma := ComputeStats(rows, StatsOptions{})
+	emaStats := ComputeStats(rows, StatsOptions{TrendMethod: TrendMethodEMA})
+
+	if sma.TrendStrength <= 0 || emaStats.TrendStrength <= 0 {
+		t.Fatalf("Expected positive trend strength for an uptrend, got sma=%f ema=%f", sma.TrendStrength, emaStats.TrendStrength)
+	}
+	if sma.TrendStrength == emaStats.TrendStrength {
+		t.Error("Expected EMA trend strength to differ from SMA")
+	}
+	if _, ok := sma.MovingAverages["ema20"]; ok {
+		t.Error("Expected SMA mode to leave EMA values out of movingAverages")
+	}
+	want := (emaStats.MovingAverages["ema20"] - emaStats.MovingAverages["ema50"]) / emaStats.MovingAverages["ema50"]
+	if emaStats.TrendStrength != want {
+		t.Errorf("Expected EMA trend strength %f, got %f", want, emaStats.TrendStrength)
+	}
+}
+
+func TestLoader_SnapshotLiquidity(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00")
+
+	liquid, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	out := liquid.Snapshot(context.Background(), Input{Symbol: "SPY"})
+	if out.AverageDailyVolume != 1_000_000 {
+		t.Errorf("Expected ADV 1,000,000, got %f", out.AverageDailyVolume)
+	}
+	if out.Illiquid {
+		t.Error("Expected SPY to be liquid under the default threshold")
+	}
+
+	strict, err := NewLoader(tempDir, WithMinAverageDailyVolume(5_000_000))
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	if out := strict.Snapshot(context.Background(), Input{Symbol: "SPY"}); !out.Illiquid {
+		t.Error("Expected SPY to be flagged illiquid under a 5M share threshold")
+	}
+}
+
+func TestLoader_SnapshotStaleness(t *testing.T) {
+	tempDir := t.TempDir()
+	// The last bar is dated 2025-01-02.
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00")
+
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	loader.cfg.now = func() time.Time { return time.Date(2025, 1, 4, 12, 0, 0, 0, time.UTC) }
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"})
+	if out.DataAgeDays != 2.5 || out.Stale {
+		t.Errorf("Expected fresh data 2.5 days old, got %f (stale=%v)", out.DataAgeDays, out.Stale)
+	}
+
+	loader.cfg.now = func() time.Time { return time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC) }
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"}); out.DataAgeDays != 7 || !out.Stale {
+		t.Errorf("Expected stale data 7 days old, got %f (stale=%v)", out.DataAgeDays, out.Stale)
+	}
+
+	if _, err := NewLoader(tempDir, WithMaxDataAge(0)); err == nil {
+		t.Error("Expected an error for a non-positive max data age")
+	}
+}
+
+func TestDataAge_UnparsedAsOfIsStale(t *testing.T) {
+	cfg := config{maxDataAge: DefaultMaxDataAge, now: time.Now}
+	if _, stale := cfg.dataAge(time.Time{}); !stale {
+		t.Error("Expected an unparsed AsOf to be stale")
+	}
+}
+
+func TestDataAge_SkipsWeekends(t *testing.T) {
+	friday := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		now   time.Time
+		stale bool
+	}{
+		{"monday morning", time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC), false},
+		{"tuesday after a monday holiday", time.Date(2025, 1, 7, 12, 0, 0, 0, time.UTC), false},
+		{"wednesday", time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC), true},
+		{"a week later", time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			days, stale := dataAgeAt(friday, tc.now, DefaultMaxDataAge)
+			if stale != tc.stale {
+				t.Errorf("Expected stale=%v for Friday's bar %.1f calendar days later", tc.stale, days)
+			}
+		})
+	}
+}
+
+func TestVolatilityRegime(t *testing.T) {
+	cfg := config{volThresholds: [3]float64{DefaultNormalVolatility, DefaultElevatedVolatility, DefaultExtremeVolatility}}
+	cases := map[float64]string{
+		0.10: VolatilityLow,
+		0.15: VolatilityNormal,
+		0.29: VolatilityNormal,
+		0.45: VolatilityElevated,
+		0.50: VolatilityExtreme,
+		1.20: VolatilityExtreme,
+	}
+	for vol, want := range cases {
+		if got := cfg.volatilityRegime(vol); got != want {
+			t.Errorf("volatilityRegime(%.2f) = %q, want %q", vol, got, want)
+		}
+	}
+}
+
+func TestLoader_VolatilityThresholds(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "449.00")
+
+	if _, err := NewLoader(tempDir, WithVolatilityThresholds(0.3, 0.2, 0.5)); err == nil {
+		t.Error("Expected out-of-order thresholds to be rejected")
+	}
+
+	loader, err := NewLoader(tempDir, WithVolatilityThresholds(0.01, 0.02, 0.03))
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"})
+	if out.VolatilityRegime != VolatilityExtreme {
+		t.Errorf("Expected extreme regime under tight thresholds, got %q (vol %f)", out.VolatilityRegime, out.Volatility)
+	}
+	if !strings.Contains(out.VolatilityNote, "0.03") {
+		t.Errorf("Expected the note to quote the thresholds, got %q", out.VolatilityNote)
+	}
+}
+
+func TestSuggestStops(t *testing.T) {
+	long := suggestStops(100, 2, "")
+	if long.Side != "long" || long.ATR1x != 98 || long.ATR2x != 96 || long.Pct2 != 98 {
+		t.Errorf("Unexpected long stops: %+v", long)
+	}
+	short := suggestStops(100, 2, "sell")
+	if short.Side != "short" || short.ATR1x != 102 || short.ATR2x != 104 || short.Pct2 != 102 {
+		t.Errorf("Unexpected short stops: %+v", short)
+	}
+	if got := suggestStops(100, 0, "BUY"); got != (SuggestedStops{}) {
+		t.Errorf("Expected zero stops without ATR, got %+v", got)
+	}
+}
+
+func TestComputeStats_PeriodsPerYear(t *testing.T) {
+	rows := []Row{{Close: 100}, {Close: 102}, {Close: 99}, {Close: 101}}
+
+	equity := ComputeStats(rows, StatsOptions{})
+	crypto := ComputeStats(rows, StatsOptions{PeriodsPerYear: 365})
+	invalid := ComputeStats(rows, StatsOptions{PeriodsPerYear: -1})
+
+	want := equity.Volatility * math.Sqrt(365.0/252.0)
+	if math.Abs(crypto.Volatility-want) > 1e-12 {
+		t.Errorf("Expected 365-period volatility %f, got %f", want, crypto.Volatility)
+	}
+	if invalid.Volatility != equity.Volatility {
+		t.Errorf("Expected a non-positive PeriodsPerYear to fall back to 252, got %f vs %f", invalid.Volatility, equity.Volatility)
+	}
+}
+
+func TestComputeStats_VolumeBaseline(t *testing.T) {
+	// 25 bars: 20 at 1,000, then four at 3,000 and a last bar of 3,000.
+	rows := make([]Row, 25)
+	for i := range rows {
+		rows[i] = Row{Close: 100, Volume: 1000}
+		if i >= 20 {
+			rows[i].Volume = 3000
+		}
+	}
+
+	standard := ComputeStats(rows, StatsOptions{})
+	short := ComputeStats(rows, StatsOptions{VolumeBaseline: 4})
+	long := ComputeStats(rows, StatsOptions{VolumeBaseline: 30})
+
+	// The default 20 bars before the last hold 16 at 1,000 and 4 at 3,000.
+	if want := 3000 / ((16*1000 + 4*3000) / 20.0); standard.VolumeBaseline != 20 || math.Abs(standard.VolumeRatio-want) > 1e-12 {
+		t.Errorf("Expected a ratio of %f over 20 bars, got %f over %d", want, standard.VolumeRatio, standard.VolumeBaseline)
+	}
+	if short.VolumeBaseline != 4 || short.VolumeRatio != 1 {
+		t.Errorf("Expected a ratio of 1 over the last 4 bars, got %f over %d", short.VolumeRatio, short.VolumeBaseline)
+	}
+	if long.VolumeBaseline != 0 || long.VolumeRatio != 1 {
+		t.Errorf("Expected a neutral ratio without a full baseline, got %f over %d", long.VolumeRatio, long.VolumeBaseline)
+	}
+}
+
+func TestComputeStats_GapMetrics(t *testing.T) {
+	rows := []Row{
+		{Date: "2025-01-02", Open: 99, Close: 100},
+		{Date: "2025-01-03", Open: 102, Close: 99.96},
+	}
+
+	stats := ComputeStats(rows, StatsOptions{})
+
+	if math.Abs(stats.OvernightReturn-0.02) > 1e-12 {
+		t.Errorf("Expected a 2%% overnight gap, got %f", stats.OvernightReturn)
+	}
+	if math.Abs(stats.IntradayReturn+0.02) > 1e-12 {
+		t.Errorf("Expected a -2%% intraday move, got %f", stats.IntradayReturn)
+	}
+
+	single := ComputeStats(rows[:1], StatsOptions{})
+	if single.OvernightReturn != 0 || single.IntradayReturn != 0 {
+		t.Errorf("Expected zero gap metrics for a single row, got %f and %f", single.OvernightReturn, single.IntradayReturn)
+	}
+}
+
+func TestComputeStats_ReturnType(t *testing.T) {
+	rows := []Row{{Date: "2025-01-01", Close: 100}, {Date: "2025-01-02", Close: 110}, {Date: "2025-01-03", Close: 99}}
+
+	simple := ComputeStats(rows, StatsOptions{})
+	logStats := ComputeStats(rows, StatsOptions{ReturnType: ReturnTypeLog})
+
+	if math.Abs(simple.Returns[0]-0.1) > 1e-12 {
+		t.Errorf("Expected a simple return of 0.1, got %f", simple.Returns[0])
+	}
+	if math.Abs(logStats.Returns[0]-math.Log(1.1)) > 1e-12 || math.Abs(logStats.Returns[1]-math.Log(0.9)) > 1e-12 {
+		t.Errorf("Expected log returns, got %v", logStats.Returns)
+	}
+	if logStats.Volatility == simple.Volatility || logStats.Volatility == 0 {
+		t.Errorf("Expected log-return volatility to differ from simple, got %f vs %f", logStats.Volatility, simple.Volatility)
+	}
+}
+
+func TestLoader_SnapshotReturnType(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "452.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"}); out.ReturnType != ReturnTypeSimple {
+		t.Errorf("Expected simple returns by default, got %q", out.ReturnType)
+	}
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", ReturnType: "LOG"}); out.ReturnType != ReturnTypeLog || math.Abs(out.Returns[0]-math.Log(451.0/450.0)) > 1e-12 {
+		t.Errorf("Expected log returns to be reported, got %q %v", out.ReturnType, out.Returns)
+	}
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", ReturnType: "geometric"}); out.Error == "" {
+		t.Error("Expected an error for an unsupported return type")
+	}
+}
+
+func TestFloorPivots(t *testing.T) {
+	got := floorPivots(110, 90, 100)
+
+	want := Pivots{Pivot: 100, R1: 110, R2: 120, S1: 90, S2: 80}
+	if got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if empty := floorPivots(0, 0, 0); empty != (Pivots{}) {
+		t.Errorf("Expected zero pivots without data, got %+v", empty)
+	}
+}
+
+func TestReadRows_BOMAndCRLF(t *testing.T) {
+	lines := []string{
+		"Date,Open,High,Low,Close,Volume",
+		"2025-01-02,100,105,99,104,1000",
+		"2025-01-03,104,106,101,102,1200",
+	}
+	root := dataRoot{fsys: fstest.MapFS{
+		"clean.csv":    {Data: []byte(strings.Join(lines, "\n") + "\n")},
+		"exported.csv": {Data: []byte("\xef\xbb\xbf" + strings.Join(lines, "\r\n") + "\r\n")},
+	}}
+
+	want, err := readRows(context.Background(), root.file("clean.csv"), nil)
+	if err != nil {
+		t.Fatalf("readRows clean: %v", err)
+	}
+	got, err := readRows(context.Background(), root.file("exported.csv"), nil)
+	if err != nil {
+		t.Fatalf("readRows exported: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the BOM/CRLF file to load like the clean one:\n got %+v\nwant %+v", got, want)
+	}
+	if want[0].Close != 104 {
+		t.Errorf("Expected header-mapped closes, got %+v", want[0])
+	}
+}
+
+func TestReadRows_BareCarriageReturns(t *testing.T) {
+	body := "Date,Open,High,Low,Close,Volume\r2025-01-02,100,105,99,104,1000\r2025-01-03,104,106,101,102,1200\r"
+	root := dataRoot{fsys: fstest.MapFS{"mac.csv": {Data: []byte(body)}}}
+
+	rows, err := readRows(context.Background(), root.file("mac.csv"), nil)
+	if err != nil {
+		t.Fatalf("readRows: %v", err)
+	}
+	if len(rows) != 2 || rows[1].Close != 102 {
+		t.Errorf("Expected two rows split on bare CRs, got %+v", rows)
+	}
+}
+
+func TestComputeStats_EWMAVolatility(t *testing.T) {
+	rows := []Row{{Close: 100}, {Close: 101}, {Close: 100}, {Close: 110}}
+	returns := []float64{0.01, 100.0/101 - 1, 0.1}
+
+	stats := ComputeStats(rows, StatsOptions{VolatilityModel: VolatilityModelEWMA, EWMALambda: 0.5})
+
+	variance := returns[0] * returns[0]
+	for _, r := range returns[1:] {
+		variance = 0.5*variance + 0.5*r*r
+	}
+	if want := math.Sqrt(variance) * math.Sqrt(DefaultPeriodsPerYear); math.Abs(stats.Volatility-want) > 1e-12 {
+		t.Errorf("Expected EWMA volatility %f, got %f", want, stats.Volatility)
+	}
+	// The last, largest return dominates the EWMA but not the equal-weighted estimate.
+	if equal := ComputeStats(rows, StatsOptions{}); stats.Volatility <= equal.Volatility {
+		t.Errorf("Expected EWMA to react more to the recent jump, got %f vs %f", stats.Volatility, equal.Volatility)
+	}
+}
+
+func TestLoader_SnapshotVolatilityModel(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "449.00", "452.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"}); out.VolatilityModel != VolatilityModelStdDev || out.EWMALambda != 0 {
+		t.Errorf("Expected the stddev model by default, got %q (%f)", out.VolatilityModel, out.EWMALambda)
+	}
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", VolatilityModel: "EWMA"}); out.VolatilityModel != VolatilityModelEWMA || out.EWMALambda != DefaultEWMALambda || out.Volatility == 0 {
+		t.Errorf("Expected EWMA with the default lambda, got %q (%f) vol %f", out.VolatilityModel, out.EWMALambda, out.Volatility)
+	}
+	for _, in := range []Input{{Symbol: "SPY", VolatilityModel: "garch"}, {Symbol: "SPY", VolatilityModel: "ewma", EWMALambda: 1.5}} {
+		if out := loader.Snapshot(context.Background(), in); out.Error == "" {
+			t.Errorf("Expected an error for %+v", in)
+		}
+	}
+}
+
+func TestComputeStats_SharpeRatioNetOfRiskFreeRate(t *testing.T) {
+	rows := []Row{{Close: 100}, {Close: 101}, {Close: 100}, {Close: 102}}
+	returns := []float64{0.01, 100.0/101 - 1, 0.02}
+	var mean, sumSq float64
+	for _, r := range returns {
+		mean += r / 3
+		sumSq += r * r / 3
+	}
+	stdDev := math.Sqrt(sumSq - mean*mean)
+
+	gross := ComputeStats(rows, StatsOptions{})
+	if want := mean / stdDev * math.Sqrt(DefaultPeriodsPerYear); math.Abs(gross.SharpeRatio-want) > 1e-12 {
+		t.Errorf("Expected Sharpe %f with no risk-free rate, got %f", want, gross.SharpeRatio)
+	}
+	net := ComputeStats(rows, StatsOptions{RiskFreeRate: 0.05})
+	perPeriod := math.Pow(1.05, 1.0/DefaultPeriodsPerYear) - 1
+	if want := (mean - perPeriod) / stdDev * math.Sqrt(DefaultPeriodsPerYear); math.Abs(net.SharpeRatio-want) > 1e-12 {
+		t.Errorf("Expected Sharpe %f net of 5%%, got %f", want, net.SharpeRatio)
+	}
+	if flat := ComputeStats([]Row{{Close: 100}, {Close: 100}, {Close: 100}}, StatsOptions{RiskFreeRate: 0.05}); flat.SharpeRatio != 0 {
+		t.Errorf("Expected no Sharpe without variance, got %f", flat.SharpeRatio)
+	}
+}
+
+func TestLoader_SnapshotRiskFreeRate(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "449.00", "452.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	gross := loader.Snapshot(context.Background(), Input{Symbol: "SPY"})
+	net := loader.Snapshot(context.Background(), Input{Symbol: "SPY", RiskFreeRate: 0.05})
+	if net.RiskFreeRate != 0.05 || net.SharpeRatio >= gross.SharpeRatio {
+		t.Errorf("Expected the risk-free rate to lower Sharpe, got %f (rate %f) vs %f", net.SharpeRatio, net.RiskFreeRate, gross.SharpeRatio)
+	}
+	for _, rate := range []float64{-0.01, 5, math.NaN()} {
+		if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", RiskFreeRate: rate}); out.Error == "" {
+			t.Errorf("Expected an error for risk-free rate %v", rate)
+		}
+	}
+}
+
+func TestLoader_SnapshotMinWindow(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "452.00", "453.00")
+	loader, err := NewLoader(tempDir, WithMinWindow(3))
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", Window: 2}); out.HasData || !strings.Contains(out.Error, "at least 3") {
+		t.Errorf("Expected a too-short window to report no data, got HasData=%v error %q", out.HasData, out.Error)
+	}
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", Window: 3}); !out.HasData {
+		t.Errorf("Expected a window at the minimum to succeed, got %q", out.Error)
+	}
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", Window: -5}); out.HasData || !strings.Contains(out.Error, "negative") {
+		t.Errorf("Expected a negative window to be rejected, got HasData=%v error %q", out.HasData, out.Error)
+	}
+	if _, err := NewLoader(tempDir, WithMinWindow(0)); err == nil {
+		t.Error("Expected an error for a zero min window")
+	}
+}
+
+func TestLoader_SnapshotRejectsInvalidInput(t *testing.T) {
+	dir := t.TempDir()
+	writeHistoricalCSV(t, dir, "SPY_2025-01-01.csv", "100.00", "101.00", "102.00")
+	loader, err := NewLoader(dir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	for _, input := range []Input{{Symbol: " "}, {Symbol: "../SPY"}, {Symbol: "SPY", Window: -1}, {Symbol: "SPY", PeriodsPerYear: -252}, {Symbol: "SPY", VolumeBaseline: -1}, {Symbol: "SPY", DetectSplits: true, SplitThreshold: -0.4}} {
+		if out := loader.Snapshot(context.Background(), input); out.HasData || out.Error == "" {
+			t.Errorf("Expected %+v to be reported as invalid, got %+v", input, out)
+		}
+	}
+}
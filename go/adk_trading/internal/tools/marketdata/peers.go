@@ -0,0 +1,210 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// defaultPeersWindow is the number of bars loaded per symbol for peer comparisons.
+const defaultPeersWindow = 60
+
+type PeersInput struct {
+	Symbol string   `json:"symbol"`
+	Peers  []string `json:"peers"`
+	Window int      `json:"window,omitempty"`
+}
+
+// PeerStats are one symbol's metrics over the dates shared by the whole group.
+type PeerStats struct {
+	Symbol         string  `json:"symbol"`
+	TrailingReturn float64 `json:"trailingReturn"`
+	Volatility     float64 `json:"volatility"`
+	TrendStrength  float64 `json:"trendStrength"`
+}
+
+// PeersOutput places Symbol within its peer group. ReturnRank is 1 for the best
+// trailing return among the symbol and its priced peers. RelativeVolatility divides
+// the symbol's volatility by the peer median; RelativeTrendStrength subtracts the
+// peer median trend strength.
+type PeersOutput struct {
+	Symbol                string      `json:"symbol"`
+	From                  string      `json:"from,omitempty"`
+	To                    string      `json:"to,omitempty"`
+	Observations          int         `json:"observations"`
+	ReturnRank            int         `json:"returnRank"`
+	GroupSize             int         `json:"groupSize"`
+	RelativeVolatility    float64     `json:"relativeVolatility"`
+	RelativeTrendStrength float64     `json:"relativeTrendStrength"`
+	Members               []PeerStats `json:"members"`
+	Skipped               []string    `json:"skipped,omitempty"`
+	Error                 string      `json:"error,omitempty"`
+}
+
+// validate rejects a comparison without a symbol or peers, or with a negative window.
+func (in PeersInput) validate() error {
+	switch {
+	case strings.TrimSpace(in.Symbol) == "":
+		return errors.New("symbol is required")
+	case len(in.Peers) == 0:
+		return errors.New("at least one peer is required")
+	case in.Window < 0:
+		return fmt.Errorf("window must not be negative, got %d", in.Window)
+	}
+	return nil
+}
+
+// NewPeersTool returns a tool that ranks a symbol against a list of peers.
+func NewPeersTool(loader *Loader) (tool.Tool, error) {
+	if loader == nil {
+		return nil, errors.New("loader is required")
+	}
+	handler := func(ctx tool.Context, input PeersInput) PeersOutput {
+		return loader.Peers(ctx, input)
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "get_peer_relative_strength",
+		Description: "Compare a symbol with a list of peers over their shared trading dates: trailing return rank, volatility relative to the peer median and relative trend strength; peers without data are listed as skipped.",
+	}, handler)
+}
+
+// Peers aligns the symbol and every peer with data on the dates they all share
+// and compares the symbol with the peers over those dates.
+func (l *Loader) Peers(ctx context.Context, input PeersInput) PeersOutput {
+	symbol := l.cfg.aliases.Canonical(input.Symbol)
+	out := PeersOutput{Symbol: symbol, Members: []PeerStats{}}
+	if err := input.validate(); err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	window := input.Window
+	if window == 0 {
+		window = defaultPeersWindow
+	}
+
+	target, err := l.Load(ctx, symbol, window)
+	if err != nil || len(target) < 2 {
+		out.Error = fmt.Sprintf("no data for %s", symbol)
+		if err != nil {
+			out.Error = err.Error()
+		}
+		return out
+	}
+	shared := dateSet(target)
+	group := map[string][]Row{symbol: target}
+	seen := map[string]bool{symbol: true}
+	var peers []string
+	for _, raw := range input.Peers {
+		peer := l.cfg.aliases.Canonical(raw)
+		if peer == "" || seen[peer] {
+			continue
+		}
+		seen[peer] = true
+		rows, err := l.Load(ctx, peer, window)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return PeersOutput{Symbol: symbol, Members: []PeerStats{}, Error: ctxErr.Error()}
+		}
+		// A peer sharing too few dates with the symbol would empty the alignment.
+		if err != nil || len(rows) < 2 || overlap(shared, rows) < 2 {
+			out.Skipped = append(out.Skipped, peer)
+			continue
+		}
+		group[peer] = rows
+		peers = append(peers, peer)
+		shared = intersect(shared, rows)
+	}
+	if len(peers) == 0 {
+		out.Error = "no peers with data"
+		return out
+	}
+	if len(shared) < 2 {
+		out.Error = fmt.Sprintf("only %d trading dates shared by %s and its peers", len(shared), symbol)
+		return out
+	}
+
+	dates := make([]string, 0, len(shared))
+	for date := range shared {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	out.From, out.To, out.Observations = dates[0], dates[len(dates)-1], len(dates)
+
+	self := peerStats(symbol, align(group[symbol], shared))
+	out.Members = append(out.Members, self)
+	var vols, trends []float64
+	out.ReturnRank = 1
+	for _, peer := range peers {
+		stats := peerStats(peer, align(group[peer], shared))
+		out.Members = append(out.Members, stats)
+		vols = append(vols, stats.Volatility)
+		trends = append(trends, stats.TrendStrength)
+		if stats.TrailingReturn > self.TrailingReturn {
+			out.ReturnRank++
+		}
+	}
+	out.GroupSize = len(out.Members)
+	if medianVol := median(vols); medianVol > 0 {
+		out.RelativeVolatility = self.Volatility / medianVol
+	}
+	out.RelativeTrendStrength = self.TrendStrength - median(trends)
+	return out
+}
+
+// peerStats reuses the snapshot analytics for rows already aligned on shared dates.
+func peerStats(symbol string, rows []Row) PeerStats {
+	stats := ComputeStats(rows, StatsOptions{})
+	var trailing float64
+	if first := rows[0].Close; first != 0 {
+		trailing = rows[len(rows)-1].Close/first - 1
+	}
+	return PeerStats{
+		Symbol:         symbol,
+		TrailingReturn: trailing,
+		Volatility:     stats.Volatility,
+		TrendStrength:  stats.TrendStrength,
+	}
+}
+
+func dateSet(rows []Row) map[string]bool {
+	dates := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		dates[row.Date] = true
+	}
+	return dates
+}
+
+func overlap(dates map[string]bool, rows []Row) int {
+	n := 0
+	for _, row := range rows {
+		if dates[row.Date] {
+			n++
+		}
+	}
+	return n
+}
+
+func intersect(dates map[string]bool, rows []Row) map[string]bool {
+	kept := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		if dates[row.Date] {
+			kept[row.Date] = true
+		}
+	}
+	return kept
+}
+
+// align keeps the rows whose date is in dates, preserving order.
+func align(rows []Row, dates map[string]bool) []Row {
+	aligned := make([]Row, 0, len(dates))
+	for _, row := range rows {
+		if dates[row.Date] {
+			aligned = append(aligned, row)
+		}
+	}
+	return aligned
+}
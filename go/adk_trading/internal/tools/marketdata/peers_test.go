@@ -0,0 +1,64 @@
+package marketdata
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestLoader_Peers(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "100", "105", "110")
+	writeHistoricalCSV(t, tempDir, "QQQ_2025-01-01.csv", "100", "101", "102")
+	writeHistoricalCSV(t, tempDir, "IWM_2025-01-01.csv", "100", "120", "130")
+	// DIA has an extra, later bar that the others lack; alignment drops it.
+	writeHistoricalCSV(t, tempDir, "DIA_2025-01-01.csv", "100", "100", "100", "200")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	out := loader.Peers(context.Background(), PeersInput{Symbol: "spy", Peers: []string{"QQQ", "IWM", "DIA", "XYZ", "qqq", "SPY"}})
+
+	if out.Error != "" {
+		t.Fatalf("Peers: %s", out.Error)
+	}
+	if out.Observations != 3 || out.From != "2025-01-01" || out.To != "2025-01-03" {
+		t.Errorf("Expected 3 aligned dates, got %d from %s to %s", out.Observations, out.From, out.To)
+	}
+	if len(out.Skipped) != 1 || out.Skipped[0] != "XYZ" {
+		t.Errorf("Expected XYZ to be skipped, got %v", out.Skipped)
+	}
+	if out.GroupSize != 4 || out.ReturnRank != 2 {
+		t.Errorf("Expected rank 2 of 4, got %d of %d", out.ReturnRank, out.GroupSize)
+	}
+	if self := out.Members[0]; self.Symbol != "SPY" || math.Abs(self.TrailingReturn-0.1) > 1e-12 {
+		t.Errorf("Expected SPY's 10%% trailing return first, got %+v", self)
+	}
+	if dia := out.Members[3]; dia.Symbol != "DIA" || dia.TrailingReturn != 0 {
+		t.Errorf("Expected DIA's aligned return to ignore its extra bar, got %+v", dia)
+	}
+	if out.RelativeVolatility <= 0 {
+		t.Errorf("Expected a relative volatility, got %f", out.RelativeVolatility)
+	}
+}
+
+func TestLoader_PeersErrors(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "100", "105", "110")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	for name, input := range map[string]PeersInput{
+		"no symbol":       {Peers: []string{"QQQ"}},
+		"unknown symbol":  {Symbol: "XYZ", Peers: []string{"SPY"}},
+		"no priced peers": {Symbol: "SPY", Peers: []string{"XYZ"}},
+		"negative window": {Symbol: "SPY", Peers: []string{"SPY"}, Window: -1},
+	} {
+		if out := loader.Peers(context.Background(), input); out.Error == "" {
+			t.Errorf("%s: expected an error, got %+v", name, out)
+		}
+	}
+}
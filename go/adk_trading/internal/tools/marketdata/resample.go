@@ -0,0 +1,77 @@
+package marketdata
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Resample periods accepted by Input.Resample.
+const (
+	ResampleDaily   = "daily"
+	ResampleWeekly  = "weekly"
+	ResampleMonthly = "monthly"
+)
+
+// periodsPerYear is the annualisation factor implied by each resample period.
+var periodsPerYear = map[string]float64{
+	ResampleDaily:   DefaultPeriodsPerYear,
+	ResampleWeekly:  52,
+	ResampleMonthly: 12,
+}
+
+func normalizeResample(period string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(period)); p {
+	case "":
+		return ResampleDaily, nil
+	case ResampleDaily, ResampleWeekly, ResampleMonthly:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unsupported resample period %q: use %q, %q or %q", period, ResampleDaily, ResampleWeekly, ResampleMonthly)
+	}
+}
+
+// resample aggregates daily rows, ordered oldest to newest, into ISO-week or
+// calendar-month bars: open is the first open, high the max, low the min, close the
+// last close and volume the sum. Each bar is dated by its last trading day.
+func resample(rows []Row, period string) ([]Row, error) {
+	if period == ResampleDaily {
+		return rows, nil
+	}
+	var out []Row
+	var current string
+	for _, row := range rows {
+		date, err := parseRowDate(row.Date)
+		if err != nil {
+			return nil, fmt.Errorf("resample %s: %w", period, err)
+		}
+		key := date.Format("2006-01")
+		if period == ResampleWeekly {
+			year, week := date.ISOWeek()
+			key = fmt.Sprintf("%d-W%02d", year, week)
+		}
+		if len(out) == 0 || key != current {
+			current = key
+			out = append(out, row)
+			continue
+		}
+		bar := &out[len(out)-1]
+		bar.Date = row.Date
+		bar.High = max(bar.High, row.High)
+		bar.Low = min(bar.Low, row.Low)
+		bar.Close = row.Close
+		bar.AdjClose = row.AdjClose
+		bar.Volume += row.Volume
+	}
+	return out, nil
+}
+
+// parseRowDate reads the calendar date at the start of a row's Date field, which may
+// carry a time component.
+func parseRowDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) > len("2006-01-02") {
+		value = value[:len("2006-01-02")]
+	}
+	return time.Parse("2006-01-02", value)
+}
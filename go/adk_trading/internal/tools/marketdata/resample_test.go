@@ -0,0 +1,95 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResample_Weekly(t *testing.T) {
+	rows := []Row{
+		// ISO week 2025-W02: Monday 6th to Friday 10th (the 3rd is in W01).
+		{Date: "2025-01-03", Open: 9, High: 10, Low: 8, Close: 9.5, Volume: 50},
+		{Date: "2025-01-06", Open: 10, High: 12, Low: 9, Close: 11, Volume: 100},
+		{Date: "2025-01-08", Open: 11, High: 15, Low: 10, Close: 14, Volume: 200},
+		{Date: "2025-01-10", Open: 14, High: 14, Low: 7, Close: 8, Volume: 300},
+	}
+
+	bars, err := resample(rows, ResampleWeekly)
+	if err != nil {
+		t.Fatalf("resample: %v", err)
+	}
+
+	if len(bars) != 2 {
+		t.Fatalf("Expected 2 weekly bars, got %d", len(bars))
+	}
+	want := Row{Date: "2025-01-10", Open: 10, High: 15, Low: 7, Close: 8, Volume: 600}
+	if bars[1] != want {
+		t.Errorf("Expected %+v, got %+v", want, bars[1])
+	}
+	if rows[1].Close != 11 {
+		t.Error("Expected resampling to leave the input rows untouched")
+	}
+}
+
+func TestResample_MonthlyAndInvalid(t *testing.T) {
+	rows := []Row{
+		{Date: "2025-01-30", Open: 1, High: 2, Low: 1, Close: 2, Volume: 1},
+		{Date: "2025-01-31", Open: 2, High: 3, Low: 1, Close: 3, Volume: 1},
+		{Date: "2025-02-03", Open: 3, High: 4, Low: 2, Close: 4, Volume: 1},
+	}
+	bars, err := resample(rows, ResampleMonthly)
+	if err != nil {
+		t.Fatalf("resample: %v", err)
+	}
+	if len(bars) != 2 || bars[0].Close != 3 || bars[0].Volume != 2 {
+		t.Errorf("Unexpected monthly bars: %+v", bars)
+	}
+
+	if _, err := normalizeResample("hourly"); err == nil {
+		t.Error("Expected an unsupported period to be rejected")
+	}
+	if _, err := resample([]Row{{Date: "not-a-date"}}, ResampleWeekly); err == nil {
+		t.Error("Expected an unparseable date to fail resampling")
+	}
+}
+
+func TestLoader_SnapshotResampleWindowCountsBars(t *testing.T) {
+	tempDir := t.TempDir()
+	historicalDir := filepath.Join(tempDir, "historical")
+	if err := os.MkdirAll(historicalDir, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	content := "Date,Open,High,Low,Close,Volume\n"
+	for day := 1; day <= 31; day++ {
+		content += fmt.Sprintf("2025-01-%02d,100,101,99,%d,%d\n", day, 100+day, 1000*day)
+	}
+	if err := os.WriteFile(filepath.Join(historicalDir, "SPY_2025-01-31.csv"), []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", Window: 3, Resample: "weekly", IncludeRaw: true})
+
+	if out.Error != "" {
+		t.Fatalf("Snapshot: %s", out.Error)
+	}
+	if len(out.RawRows) != 3 {
+		t.Errorf("Expected the window to count 3 weekly bars, got %d", len(out.RawRows))
+	}
+	if out.Resample != ResampleWeekly || out.PeriodsPerYear != 52 {
+		t.Errorf("Expected weekly bars annualised at 52, got %s at %f", out.Resample, out.PeriodsPerYear)
+	}
+	if out.Close != 131 || out.Volume != 145000 {
+		t.Errorf("Expected the last weekly bar (Jan 27-31) close 131 volume 145000, got %f and %f", out.Close, out.Volume)
+	}
+	// Liquidity averages the days of the three bars (Jan 13-31), not their sums.
+	if out.AverageDailyVolume != 22000 || !out.Illiquid {
+		t.Errorf("Expected an illiquid daily average of 22000, got %f (illiquid %v)", out.AverageDailyVolume, out.Illiquid)
+	}
+}
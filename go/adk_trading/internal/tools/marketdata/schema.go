@@ -0,0 +1,169 @@
+package marketdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ColumnRef picks a CSV column by header label or, when Name is empty, by
+// zero-based index. In JSON it is written as a string label or a number.
+type ColumnRef struct {
+	Name  string
+	Index int
+}
+
+func (r *ColumnRef) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var name string
+		if err := json.Unmarshal(data, &name); err != nil {
+			return err
+		}
+		*r = ColumnRef{Name: name}
+		return nil
+	}
+	var index int
+	if err := json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("column must be a header label or an index: %w", err)
+	}
+	*r = ColumnRef{Index: index}
+	return nil
+}
+
+func (r ColumnRef) MarshalJSON() ([]byte, error) {
+	if r.Name != "" {
+		return json.Marshal(r.Name)
+	}
+	return json.Marshal(r.Index)
+}
+
+func (r ColumnRef) String() string {
+	if r.Name != "" {
+		return fmt.Sprintf("%q", r.Name)
+	}
+	return fmt.Sprintf("column %d", r.Index)
+}
+
+// Schema maps the logical OHLCV fields to the columns of a vendor's CSV files, for
+// layouts header detection gets wrong. Date, Open, High, Low and Close are
+// required; a nil Volume or AdjClose reads as zero. For example:
+//
+//	{"date": "Trade Date", "open": "Px Open", "high": 3, "low": 4, "close": "Last"}
+type Schema struct {
+	Date     *ColumnRef `json:"date"`
+	Open     *ColumnRef `json:"open"`
+	High     *ColumnRef `json:"high"`
+	Low      *ColumnRef `json:"low"`
+	Close    *ColumnRef `json:"close"`
+	Volume   *ColumnRef `json:"volume,omitempty"`
+	AdjClose *ColumnRef `json:"adjClose,omitempty"`
+}
+
+// WithSchema makes the loader parse every CSV with schema instead of detecting
+// its header or assuming the positional layout.
+func WithSchema(schema Schema) Option {
+	return func(c *config) {
+		c.schema = &schema
+	}
+}
+
+// ReadSchema reads a JSON schema descriptor from path.
+func ReadSchema(path string) (Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Schema{}, fmt.Errorf("read csv schema: %w", err)
+	}
+	var schema Schema
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&schema); err != nil {
+		return Schema{}, fmt.Errorf("parse csv schema %s: %w", path, err)
+	}
+	if err := schema.validate(); err != nil {
+		return Schema{}, fmt.Errorf("csv schema %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// schemaField is one logical field of a Schema and the column it is mapped to.
+type schemaField struct {
+	name string
+	ref  *ColumnRef
+}
+
+// fields lists the schema's fields in the order of the columns struct.
+func (s Schema) fields() []schemaField {
+	return []schemaField{
+		{"date", s.Date}, {"close", s.Close}, {"high", s.High}, {"low", s.Low},
+		{"open", s.Open}, {"volume", s.Volume}, {"adjClose", s.AdjClose},
+	}
+}
+
+func (s Schema) validate() error {
+	for _, f := range s.fields() {
+		switch {
+		case f.ref == nil:
+			if f.name != "volume" && f.name != "adjClose" {
+				return fmt.Errorf("csv schema must map %s", f.name)
+			}
+		case f.ref.Name == "" && f.ref.Index < 0:
+			return fmt.Errorf("csv schema %s index must not be negative, got %d", f.name, f.ref.Index)
+		case f.ref.Name != "" && normalizeLabel(f.ref.Name) == "":
+			return fmt.Errorf("csv schema %s label must not be blank", f.name)
+		}
+	}
+	return nil
+}
+
+// columns resolves the schema against records. Labels are matched like detected
+// headers, against the first of the leading records holding all of them, and data
+// starts after that row; a schema of indices alone reads every record, skipping
+// those that fail to parse.
+func (s Schema) columns(records [][]string) (columns, int, error) {
+	var labels []string
+	for _, f := range s.fields() {
+		if f.ref != nil && f.ref.Name != "" {
+			labels = append(labels, f.ref.String())
+		}
+	}
+	if len(labels) == 0 {
+		cols, _ := s.resolve(nil)
+		return cols, 0, nil
+	}
+	for i := 0; i < min(len(records), maxHeaderScan); i++ {
+		header := make(map[string]int, len(records[i]))
+		for idx, label := range records[i] {
+			if _, dup := header[normalizeLabel(label)]; !dup {
+				header[normalizeLabel(label)] = idx
+			}
+		}
+		if cols, ok := s.resolve(header); ok {
+			return cols, i + 1, nil
+		}
+	}
+	return columns{}, 0, fmt.Errorf("no header row holds the csv schema columns %s", strings.Join(labels, ", "))
+}
+
+// resolve maps every field through header, which holds the normalised labels of a
+// candidate header row; ok is false when one of the labels is not in it.
+func (s Schema) resolve(header map[string]int) (columns, bool) {
+	idx := make([]int, 0, 7)
+	for _, f := range s.fields() {
+		switch {
+		case f.ref == nil:
+			idx = append(idx, -1)
+		case f.ref.Name == "":
+			idx = append(idx, f.ref.Index)
+		default:
+			i, ok := header[normalizeLabel(f.ref.Name)]
+			if !ok {
+				return columns{}, false
+			}
+			idx = append(idx, i)
+		}
+	}
+	return columns{date: idx[0], close: idx[1], high: idx[2], low: idx[3], open: idx[4], volume: idx[5], adjClose: idx[6]}, true
+}
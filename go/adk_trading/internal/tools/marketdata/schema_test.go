@@ -0,0 +1,73 @@
+package marketdata
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func writeSchema(t *testing.T, dir, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, "schema.json")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadRows_Schema(t *testing.T) {
+	dir := t.TempDir()
+	// Vendor labels detection does not know, plus an unlabelled volume column.
+	body := "Exported by Vendor\nTrade Date,Px Open,Px High,Px Low,Last,\n2025-01-02,100,105,99,104,1000\n2025-01-03,104,106,101,102,1200\n"
+	file := dataRoot{fsys: fstest.MapFS{"vendor.csv": {Data: []byte(body)}}}.file("vendor.csv")
+
+	schema, err := ReadSchema(writeSchema(t, dir, `{"date": "Trade Date", "open": "px_open", "high": "PX HIGH", "low": "Px Low", "close": "Last", "volume": 5}`))
+	if err != nil {
+		t.Fatalf("ReadSchema: %v", err)
+	}
+	rows, err := readRows(context.Background(), file, &schema)
+	if err != nil {
+		t.Fatalf("readRows: %v", err)
+	}
+	want := Row{Date: "2025-01-03", Open: 104, High: 106, Low: 101, Close: 102, Volume: 1200}
+	if len(rows) != 2 || rows[1] != want {
+		t.Errorf("Expected schema-mapped rows ending %+v, got %+v", want, rows)
+	}
+
+	missing := Schema{Date: &ColumnRef{Name: "Date"}, Open: &ColumnRef{Index: 1}, High: &ColumnRef{Index: 2}, Low: &ColumnRef{Index: 3}, Close: &ColumnRef{Name: "Close"}}
+	if _, err := readRows(context.Background(), file, &missing); err == nil || !strings.Contains(err.Error(), `"Close"`) {
+		t.Errorf("Expected an error naming the missing labels, got %v", err)
+	}
+}
+
+func TestReadRows_SchemaIndicesOnly(t *testing.T) {
+	file := dataRoot{fsys: fstest.MapFS{"noheader.csv": {Data: []byte("SPY,2025-01-02,104,105,99,100\nSPY,2025-01-03,102,106,101,104\n")}}}.file("noheader.csv")
+	schema := Schema{Date: &ColumnRef{Index: 1}, Close: &ColumnRef{Index: 2}, High: &ColumnRef{Index: 3}, Low: &ColumnRef{Index: 4}, Open: &ColumnRef{Index: 5}}
+	rows, err := readRows(context.Background(), file, &schema)
+	if err != nil {
+		t.Fatalf("readRows: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Date != "2025-01-02" || rows[0].Open != 100 || rows[0].Volume != 0 {
+		t.Errorf("Expected index-mapped rows without volume, got %+v", rows)
+	}
+}
+
+func TestReadSchema_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"missing close":  `{"date": 0, "open": 1, "high": 2, "low": 3}`,
+		"negative index": `{"date": 0, "open": 1, "high": 2, "low": 3, "close": -1}`,
+		"unknown field":  `{"date": 0, "open": 1, "high": 2, "low": 3, "close": 4, "settle": 5}`,
+		"bad column":     `{"date": 0, "open": 1, "high": 2, "low": 3, "close": true}`,
+	} {
+		if _, err := ReadSchema(writeSchema(t, dir, body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := NewLoader(dir, WithSchema(Schema{Date: &ColumnRef{Index: 0}})); err == nil {
+		t.Error("Expected NewLoader to reject an incomplete schema")
+	}
+}
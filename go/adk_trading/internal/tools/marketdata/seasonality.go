@@ -0,0 +1,41 @@
+package marketdata
+
+// DefaultMinSeasonalitySamples is the fewest returns a weekday needs before its
+// average is reported.
+const DefaultMinSeasonalitySamples = 4
+
+// WithMinSeasonalitySamples sets the fewest returns a weekday needs before
+// seasonality reports its average; sparser weekdays are omitted.
+func WithMinSeasonalitySamples(n int) Option {
+	return func(c *config) {
+		c.minSeasonalitySamples = n
+	}
+}
+
+// seasonalityByWeekday averages returns by the weekday of the bar each one ends
+// on, keyed by weekday name. returns[i] is the return into the bar dated dates[i].
+// Weekdays with fewer than minSamples returns, and bars with unparseable dates,
+// are left out.
+func seasonalityByWeekday(dates []string, returns []float64, minSamples int) map[string]float64 {
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	for i, ret := range returns {
+		if i >= len(dates) {
+			break
+		}
+		date, err := parseRowDate(dates[i])
+		if err != nil {
+			continue
+		}
+		day := date.Weekday().String()
+		sums[day] += ret
+		counts[day]++
+	}
+	averages := make(map[string]float64, len(sums))
+	for day, n := range counts {
+		if n >= minSamples {
+			averages[day] = sums[day] / float64(n)
+		}
+	}
+	return averages
+}
@@ -0,0 +1,57 @@
+package marketdata
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSeasonalityByWeekday(t *testing.T) {
+	// 2025-01-06 is a Monday.
+	rows := []Row{
+		{Date: "2025-01-03", Close: 100},
+		{Date: "2025-01-06", Close: 102},
+		{Date: "2025-01-07", Close: 103},
+		{Date: "2025-01-13", Close: 103},
+		{Date: "bad", Close: 110},
+	}
+	stats := ComputeStats(rows, StatsOptions{})
+
+	got := seasonalityByWeekday(stats.ReturnDates, stats.Returns, 2)
+
+	if len(got) != 1 || math.Abs(got["Monday"]-0.01) > 1e-12 {
+		t.Errorf("Expected only Monday averaging 1%%, got %v", got)
+	}
+	if got := seasonalityByWeekday(stats.ReturnDates, stats.Returns, 1); len(got) != 2 || got["Tuesday"] == 0 {
+		t.Errorf("Expected Monday and Tuesday with one sample allowed, got %v", got)
+	}
+}
+
+func TestLoader_SnapshotSeasonality(t *testing.T) {
+	tempDir := t.TempDir()
+	closes := make([]string, 28)
+	for i := range closes {
+		closes[i] = "450.00"
+	}
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", closes...)
+	loader, err := NewLoader(tempDir, WithMinSeasonalitySamples(4))
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"}); out.SeasonalityByWeekday != nil {
+		t.Errorf("Expected no seasonality unless requested, got %v", out.SeasonalityByWeekday)
+	}
+	// 27 returns from Thursday 2 January: Thursday to Tuesday get four, Wednesday three.
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", Window: 28, IncludeSeasonality: true})
+	if len(out.SeasonalityByWeekday) != 6 {
+		t.Errorf("Expected six weekdays with four samples, got %v", out.SeasonalityByWeekday)
+	}
+	if _, ok := out.SeasonalityByWeekday["Wednesday"]; ok {
+		t.Error("Expected Wednesday, with three samples, to be omitted")
+	}
+
+	if _, err := NewLoader(tempDir, WithMinSeasonalitySamples(0)); err == nil {
+		t.Error("Expected an error for a zero minimum sample count")
+	}
+}
@@ -0,0 +1,68 @@
+package marketdata
+
+import "math"
+
+// indicatorSeries returns the per-bar indicator arrays behind a snapshot, each aligned
+// to rows. Bars before an indicator's window is full are zero; opts must be normalized.
+func indicatorSeries(rows []Row, opts StatsOptions) map[string][]float64 {
+	series := map[string][]float64{
+		"close":  make([]float64, len(rows)),
+		"return": make([]float64, len(rows)),
+		"ma20":   movingAverageSeries(rows, 20),
+		"ma50":   movingAverageSeries(rows, 50),
+		"ma100":  movingAverageSeries(rows, 100),
+	}
+	for i, row := range rows {
+		series["close"][i] = row.Close
+		if i > 0 && rows[i-1].Close != 0 {
+			series["return"][i] = row.Close/rows[i-1].Close - 1
+			if opts.ReturnType == ReturnTypeLog {
+				series["return"][i] = math.Log(row.Close / rows[i-1].Close)
+			}
+		}
+	}
+	if opts.TrendMethod == TrendMethodEMA {
+		series["ema20"] = emaSeries(rows, 20)
+		series["ema50"] = emaSeries(rows, 50)
+	}
+	return series
+}
+
+// movingAverageSeries returns the simple moving average of closes ending at each bar.
+func movingAverageSeries(rows []Row, period int) []float64 {
+	out := make([]float64, len(rows))
+	if period <= 0 {
+		return out
+	}
+	var sum float64
+	for i, row := range rows {
+		sum += row.Close
+		if i >= period {
+			sum -= rows[i-period].Close
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// emaSeries returns the running EMA used by ema at each bar, seeded from the first
+// close and reported once period bars have been seen.
+func emaSeries(rows []Row, period int) []float64 {
+	out := make([]float64, len(rows))
+	if period <= 0 || len(rows) == 0 {
+		return out
+	}
+	alpha := 2.0 / float64(period+1)
+	value := rows[0].Close
+	for i, row := range rows {
+		if i > 0 {
+			value = alpha*row.Close + (1-alpha)*value
+		}
+		if i >= period-1 {
+			out[i] = value
+		}
+	}
+	return out
+}
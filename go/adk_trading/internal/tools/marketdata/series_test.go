@@ -0,0 +1,56 @@
+package marketdata
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestMovingAverageSeries(t *testing.T) {
+	rows := []Row{{Close: 1}, {Close: 2}, {Close: 3}, {Close: 4}}
+
+	got := movingAverageSeries(rows, 3)
+
+	want := []float64{0, 0, 2, 3}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestEMASeriesEndsAtEMA(t *testing.T) {
+	rows := []Row{{Close: 10}, {Close: 11}, {Close: 9}, {Close: 12}, {Close: 13}}
+
+	got := emaSeries(rows, 3)
+
+	if got[0] != 0 || got[1] != 0 || got[2] == 0 {
+		t.Errorf("Expected zeros until the window fills, got %v", got)
+	}
+	if math.Abs(got[len(got)-1]-ema(rows, 3)) > 1e-12 {
+		t.Errorf("Expected the last value %f to match ema %f", got[len(got)-1], ema(rows, 3))
+	}
+}
+
+func TestLoader_SnapshotSeries(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "452.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"}); out.Series != nil {
+		t.Errorf("Expected no series by default, got %v", out.Series)
+	}
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", IncludeSeries: true, TrendMethod: TrendMethodEMA})
+	for _, name := range []string{"close", "return", "ma20", "ma50", "ma100", "ema20", "ema50"} {
+		if len(out.Series[name]) != 3 {
+			t.Errorf("Expected series %q aligned to 3 rows, got %v", name, out.Series[name])
+		}
+	}
+	if out.Series["close"][2] != 452 || out.Series["return"][0] != 0 {
+		t.Errorf("Unexpected close/return series: %v / %v", out.Series["close"], out.Series["return"])
+	}
+}
@@ -0,0 +1,43 @@
+package marketdata
+
+import "math"
+
+// DefaultSplitThreshold is the overnight move, either way, beyond which a bar is
+// flagged as a probable split when split detection is on. A 2:1 split shows up as
+// a -50% gap and a 1:2 reverse split as +100%.
+const DefaultSplitThreshold = 0.4
+
+// CorporateAction is a bar whose overnight move looked like a split or similar
+// event rather than trading.
+type CorporateAction struct {
+	Date string `json:"date"`
+	// Return is the overnight return into the bar, open over the prior close.
+	Return float64 `json:"return"`
+}
+
+// detectSplits flags the bars of rows whose overnight return exceeds threshold in
+// either direction. The overnight return uses the bar's open, or its close when the
+// feed has no opens. flagged[i] reports whether returns[i], the return into
+// rows[i+1], crosses such a bar.
+func detectSplits(rows []Row, threshold float64) (actions []CorporateAction, flagged []bool) {
+	if len(rows) < 2 {
+		return nil, nil
+	}
+	flagged = make([]bool, len(rows)-1)
+	for i := 1; i < len(rows); i++ {
+		prevClose := rows[i-1].Close
+		if prevClose == 0 {
+			continue
+		}
+		price := rows[i].Open
+		if price == 0 {
+			price = rows[i].Close
+		}
+		overnight := price/prevClose - 1
+		if math.Abs(overnight) > threshold {
+			flagged[i-1] = true
+			actions = append(actions, CorporateAction{Date: rows[i].Date, Return: overnight})
+		}
+	}
+	return actions, flagged
+}
@@ -0,0 +1,53 @@
+package marketdata
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestComputeStats_DetectSplits(t *testing.T) {
+	// A 2:1 split on 2025-01-06 halves the price overnight around otherwise small moves.
+	rows := []Row{
+		{Date: "2025-01-02", Open: 100, High: 101, Low: 99, Close: 100},
+		{Date: "2025-01-03", Open: 100, High: 102, Low: 100, Close: 101},
+		{Date: "2025-01-06", Open: 50.5, High: 51.5, Low: 50, Close: 51},
+		{Date: "2025-01-07", Open: 51, High: 51, Low: 49.5, Close: 50},
+		{Date: "2025-01-08", Open: 0, High: 51, Low: 50, Close: 50.5},
+	}
+
+	raw := ComputeStats(rows, StatsOptions{})
+	adjusted := ComputeStats(rows, StatsOptions{DetectSplits: true})
+
+	if raw.PossibleCorporateActions != nil {
+		t.Errorf("Expected no detection unless asked, got %+v", raw.PossibleCorporateActions)
+	}
+	actions := adjusted.PossibleCorporateActions
+	if len(actions) != 1 || actions[0].Date != "2025-01-06" || math.Abs(actions[0].Return+0.5) > 1e-12 {
+		t.Fatalf("Expected the 2025-01-06 split at -50%%, got %+v", actions)
+	}
+	if len(adjusted.Returns) != len(rows)-2 || slices.Contains(adjusted.ReturnDates, "2025-01-06") || slices.Contains(adjusted.Returns, 0) {
+		t.Errorf("Expected the return across the split dropped, got %v on %v", adjusted.Returns, adjusted.ReturnDates)
+	}
+	if adjusted.AverageTrueRange >= 2 {
+		t.Errorf("Expected the split's gap excluded from the ATR, got %f against %f raw", adjusted.AverageTrueRange, raw.AverageTrueRange)
+	}
+	if hist := returnHistogram(adjusted.Returns, 0); hist.Samples != 3 {
+		t.Errorf("Expected the histogram built without the split, got %+v", hist)
+	}
+	if adjusted.Volatility >= raw.Volatility/10 {
+		t.Errorf("Expected the split excluded from volatility, got %f against %f raw", adjusted.Volatility, raw.Volatility)
+	}
+
+	// The bar without an open falls back to its close; a tight threshold flags it too.
+	tight := ComputeStats(rows, StatsOptions{DetectSplits: true, SplitThreshold: 0.005})
+	if got := tight.PossibleCorporateActions; len(got) != 2 || got[1].Date != "2025-01-08" {
+		t.Errorf("Expected the split and the open-less 1%% move flagged, got %+v", got)
+	}
+	if _, ok := tight.lastReturn(); ok {
+		t.Error("Expected no last return when the last bar is flagged")
+	}
+	if last, ok := adjusted.lastReturn(); !ok || math.Abs(last-0.01) > 1e-12 {
+		t.Errorf("Expected the last bar's 1%% return, got %f (%v)", last, ok)
+	}
+}
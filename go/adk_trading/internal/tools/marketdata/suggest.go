@@ -0,0 +1,80 @@
+package marketdata
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions bounds how many alternative symbols a failed lookup returns.
+const maxSuggestions = 5
+
+// suggest returns up to maxSuggestions indexed symbols resembling symbol: names
+// sharing a prefix with it first, then those within a small edit distance.
+func (l *Loader) suggest(symbol string) []string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil
+	}
+	type candidate struct {
+		symbol   string
+		prefix   bool
+		distance int
+	}
+	maxDistance := 2
+	if len(symbol) <= 2 {
+		maxDistance = 1
+	}
+	var candidates []candidate
+	for _, known := range l.index.symbols() {
+		if known == symbol {
+			continue
+		}
+		c := candidate{
+			symbol:   known,
+			prefix:   strings.HasPrefix(known, symbol) || strings.HasPrefix(symbol, known),
+			distance: levenshtein(symbol, known),
+		}
+		if c.prefix || c.distance <= maxDistance {
+			candidates = append(candidates, c)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.prefix != b.prefix {
+			return a.prefix
+		}
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		return a.symbol < b.symbol
+	})
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+	suggestions := make([]string, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = c.symbol
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
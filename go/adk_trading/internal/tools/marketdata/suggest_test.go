@@ -0,0 +1,48 @@
+package marketdata
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestLoader_SnapshotSuggestsSymbols(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"SPY_2025-01-01.csv", "SPYG_2025-01-01.csv", "QQQ_2025-01-01.csv", "XLE_2025-01-01.csv"} {
+		writeHistoricalCSV(t, tempDir, name, "100.00")
+	}
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPX"})
+
+	if out.HasData || out.Error == "" {
+		t.Fatalf("Expected a load failure for SPX, got %+v", out)
+	}
+	if want := []string{"SPY", "SPYG"}; !reflect.DeepEqual(out.Suggestions, want) {
+		t.Errorf("Expected suggestions %v, got %v", want, out.Suggestions)
+	}
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "QQ"}); !reflect.DeepEqual(out.Suggestions, []string{"QQQ"}) {
+		t.Errorf("Expected prefix suggestion QQQ, got %v", out.Suggestions)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"SPY", "SPY", 0},
+		{"SPY", "SPX", 1},
+		{"QQQ", "QQ", 1},
+		{"AAPL", "APPL", 1},
+		{"", "XLE", 3},
+	}
+	for _, tc := range cases {
+		if got := levenshtein(tc.a, tc.b); got != tc.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package marketdata
+
+import "math"
+
+// DefaultVolumeProfileBins is how many price bins a volume profile uses when the
+// input does not say.
+const DefaultVolumeProfileBins = 20
+
+// maxVolumeProfileBins bounds the response size.
+const maxVolumeProfileBins = 200
+
+// VolumeProfile buckets the window's price range into equal bins and sums each
+// bar's volume into the bin holding its typical price, (high+low+close)/3.
+type VolumeProfile struct {
+	Bins []VolumeBin `json:"bins"`
+	// PointOfControl is the midpoint of the bin with the most volume; the lowest
+	// such bin wins a tie.
+	PointOfControl    float64 `json:"pointOfControl"`
+	PointOfControlBin int     `json:"pointOfControlBin"`
+	TotalVolume       float64 `json:"totalVolume"`
+}
+
+type VolumeBin struct {
+	Low    float64 `json:"low"`
+	High   float64 `json:"high"`
+	Volume float64 `json:"volume"`
+}
+
+// volumeProfile builds a profile of rows over bins price bins; non-positive bins
+// use DefaultVolumeProfileBins. It returns nil without rows.
+func volumeProfile(rows []Row, bins int) *VolumeProfile {
+	if len(rows) == 0 {
+		return nil
+	}
+	if bins <= 0 {
+		bins = DefaultVolumeProfileBins
+	}
+	bins = min(bins, maxVolumeProfileBins)
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, row := range rows {
+		low = math.Min(low, math.Min(row.Low, row.Close))
+		high = math.Max(high, math.Max(row.High, row.Close))
+	}
+	// A flat window has nothing to bucket: one bin holds everything.
+	if high <= low {
+		bins = 1
+	}
+	width := (high - low) / float64(bins)
+
+	profile := &VolumeProfile{Bins: make([]VolumeBin, bins)}
+	for i := range profile.Bins {
+		profile.Bins[i].Low = low + float64(i)*width
+		profile.Bins[i].High = low + float64(i+1)*width
+	}
+	profile.Bins[bins-1].High = high
+	for _, row := range rows {
+		i := 0
+		if width > 0 {
+			typical := (row.High + row.Low + row.Close) / 3
+			i = min(max(int((typical-low)/width), 0), bins-1)
+		}
+		profile.Bins[i].Volume += row.Volume
+		profile.TotalVolume += row.Volume
+	}
+	for i, bin := range profile.Bins {
+		if bin.Volume > profile.Bins[profile.PointOfControlBin].Volume {
+			profile.PointOfControlBin = i
+		}
+	}
+	poc := profile.Bins[profile.PointOfControlBin]
+	profile.PointOfControl = (poc.Low + poc.High) / 2
+	return profile
+}
@@ -0,0 +1,57 @@
+package marketdata
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVolumeProfile(t *testing.T) {
+	rows := []Row{
+		{High: 101, Low: 99, Close: 100, Volume: 100},
+		{High: 103, Low: 101, Close: 102, Volume: 500},
+		{High: 110, Low: 108, Close: 109, Volume: 200},
+		{High: 102.5, Low: 101.5, Close: 102, Volume: 300},
+	}
+
+	profile := volumeProfile(rows, 11)
+
+	if len(profile.Bins) != 11 || profile.Bins[0].Low != 99 || profile.Bins[10].High != 110 {
+		t.Fatalf("Expected 11 bins spanning 99-110, got %+v", profile.Bins)
+	}
+	if profile.TotalVolume != 1100 || profile.Bins[3].Volume != 800 {
+		t.Errorf("Expected the 102 bars in bin 3, got %+v", profile.Bins)
+	}
+	if profile.PointOfControlBin != 3 || profile.PointOfControl != 102.5 {
+		t.Errorf("Expected the point of control at 102.5, got bin %d at %f", profile.PointOfControlBin, profile.PointOfControl)
+	}
+	if last := profile.Bins[10]; last.Volume != 200 {
+		t.Errorf("Expected the top bar in the last bin, got %+v", last)
+	}
+}
+
+func TestVolumeProfile_FlatAndEmpty(t *testing.T) {
+	if volumeProfile(nil, 0) != nil {
+		t.Error("Expected no profile without rows")
+	}
+	flat := volumeProfile([]Row{{High: 5, Low: 5, Close: 5, Volume: 10}, {High: 5, Low: 5, Close: 5, Volume: 20}}, 0)
+	if len(flat.Bins) != 1 || flat.Bins[0].Volume != 30 || flat.PointOfControl != 5 {
+		t.Errorf("Expected a single bin for a flat window, got %+v", flat)
+	}
+}
+
+func TestLoader_SnapshotVolumeProfile(t *testing.T) {
+	tempDir := t.TempDir()
+	writeHistoricalCSV(t, tempDir, "SPY_2025-01-01.csv", "450.00", "451.00", "452.00")
+	loader, err := NewLoader(tempDir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	if out := loader.Snapshot(context.Background(), Input{Symbol: "SPY"}); out.VolumeProfile != nil {
+		t.Error("Expected no volume profile by default")
+	}
+	out := loader.Snapshot(context.Background(), Input{Symbol: "SPY", IncludeVolumeProfile: true})
+	if out.VolumeProfile == nil || len(out.VolumeProfile.Bins) != DefaultVolumeProfileBins {
+		t.Errorf("Expected a %d-bin profile, got %+v", DefaultVolumeProfileBins, out.VolumeProfile)
+	}
+}
@@ -0,0 +1,260 @@
+package netting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/correlation"
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/logging"
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// DefaultOffsetCorrelation is the return correlation at which a long in one symbol
+// and a short in another are flagged as offsetting each other.
+const DefaultOffsetCorrelation = 0.8
+
+// Input is a session's proposed decisions, shaped as log_trade_decision takes them,
+// each carrying its size as metadata.risk.position_size.
+type Input struct {
+	Decisions []logging.Input `json:"decisions"`
+	// Window is the number of daily bars correlations are computed over; zero
+	// keeps the correlation default.
+	Window int `json:"window,omitempty"`
+}
+
+// Output is the netted plan: one decision per symbol, in the order symbols first
+// appear, plus the pairs of netted decisions that hedge each other.
+type Output struct {
+	Decisions []logging.Input `json:"decisions"`
+	// Netted counts the proposed decisions folded into combined ones.
+	Netted  int      `json:"netted"`
+	Offsets []Offset `json:"offsets,omitempty"`
+	// CorrelationError explains why offsets could not be checked; the netted
+	// decisions are still returned.
+	CorrelationError string `json:"correlationError,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// Offset is a pair of netted decisions whose exposures largely cancel: opposite
+// actions in positively correlated symbols or the same action in negatively
+// correlated ones.
+type Offset struct {
+	Symbols     [2]string `json:"symbols"`
+	Correlation float64   `json:"correlation"`
+}
+
+// validate rejects a request without decisions or with a decision the log would
+// refuse; sizes are checked when a symbol needs netting.
+func (in Input) validate() error {
+	if len(in.Decisions) == 0 {
+		return errors.New("at least one decision is required")
+	}
+	for i, d := range in.Decisions {
+		if strings.TrimSpace(d.Symbol) == "" {
+			return fmt.Errorf("decision %d: symbol is required", i)
+		}
+		switch strings.ToUpper(strings.TrimSpace(d.Action)) {
+		case "BUY", "SELL", "HOLD":
+		default:
+			return fmt.Errorf("decision %d: action must be BUY, SELL or HOLD, got %q", i, d.Action)
+		}
+	}
+	if in.Window < 0 {
+		return fmt.Errorf("window must not be negative, got %d", in.Window)
+	}
+	return nil
+}
+
+type config struct {
+	offsetCorrelation float64
+}
+
+// Option customises the netting tool.
+type Option func(*config)
+
+// WithOffsetCorrelation sets the absolute return correlation, in (0, 1], at which
+// two netted decisions are flagged as offsetting.
+func WithOffsetCorrelation(threshold float64) Option {
+	return func(c *config) {
+		c.offsetCorrelation = threshold
+	}
+}
+
+// New returns a tool that nets a session's proposed decisions before they are
+// logged, using loader to correlate the symbols left after netting.
+func New(loader *marketdata.Loader, opts ...Option) (tool.Tool, error) {
+	if loader == nil {
+		return nil, errors.New("market data loader is required")
+	}
+	cfg := config{offsetCorrelation: DefaultOffsetCorrelation}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if !(cfg.offsetCorrelation > 0 && cfg.offsetCorrelation <= 1) {
+		return nil, fmt.Errorf("offset correlation must be in (0, 1], got %g", cfg.offsetCorrelation)
+	}
+	handler := func(ctx tool.Context, input Input) Output {
+		return net(ctx, loader, input, cfg.offsetCorrelation)
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "net_trade_decisions",
+		Description: "Net a session's proposed trade decisions before logging: BUY and SELL decisions for the same symbol become one decision for the net size (metadata.risk.position_size), and long/short pairs of highly correlated symbols are flagged as offsets.",
+	}, handler)
+}
+
+// net folds input's decisions into one per symbol and flags offsetting pairs whose
+// absolute correlation reaches threshold.
+func net(ctx context.Context, loader *marketdata.Loader, input Input, threshold float64) Output {
+	if err := input.validate(); err != nil {
+		return Output{Decisions: []logging.Input{}, Error: err.Error()}
+	}
+	var order []string
+	bySymbol := map[string][]logging.Input{}
+	for _, d := range input.Decisions {
+		symbol := strings.ToUpper(strings.TrimSpace(d.Symbol))
+		if _, seen := bySymbol[symbol]; !seen {
+			order = append(order, symbol)
+		}
+		bySymbol[symbol] = append(bySymbol[symbol], d)
+	}
+	out := Output{Decisions: make([]logging.Input, 0, len(order))}
+	for _, symbol := range order {
+		group := bySymbol[symbol]
+		if len(group) == 1 {
+			out.Decisions = append(out.Decisions, group[0])
+			continue
+		}
+		netted, err := netSymbol(symbol, group)
+		if err != nil {
+			return Output{Decisions: []logging.Input{}, Error: err.Error()}
+		}
+		out.Decisions = append(out.Decisions, netted)
+		out.Netted += len(group)
+	}
+	out.Offsets, out.CorrelationError = offsets(ctx, loader, out.Decisions, input.Window, threshold)
+	return out
+}
+
+// netSymbol combines one symbol's decisions into a single decision for the signed
+// sum of their sizes: BUY when positive, SELL when negative, HOLD when they cancel.
+// Its confidence is the size-weighted confidence of the decisions on the winning
+// side, and its metadata that of the largest of them with the net size and the
+// folded decisions, as metadata.netted_from, recorded.
+func netSymbol(symbol string, group []logging.Input) (logging.Input, error) {
+	var total, sideSize, sideConfidence float64
+	var legs []map[string]any
+	var notes []string
+	sizes := make([]float64, len(group))
+	for i, d := range group {
+		action := strings.ToUpper(strings.TrimSpace(d.Action))
+		if action != "HOLD" {
+			size, ok := logging.ApprovedSize(d.Metadata)
+			if !ok || size < 0 || math.IsNaN(size) {
+				return logging.Input{}, fmt.Errorf("%s %s decision needs a non-negative metadata.risk.position_size to be netted", symbol, action)
+			}
+			sizes[i] = size
+			if action == "SELL" {
+				total -= size
+			} else {
+				total += size
+			}
+		}
+		legs = append(legs, map[string]any{"action": action, "size": sizes[i], "confidence": d.Confidence})
+		if note := strings.TrimSpace(d.Notes); note != "" {
+			notes = append(notes, note)
+		}
+	}
+
+	action := "HOLD"
+	switch {
+	case total > 0:
+		action = "BUY"
+	case total < 0:
+		action = "SELL"
+	}
+	// When the sides cancel exactly and nothing was HOLD, the first decision leads.
+	lead, leadSize := group[0], -1.0
+	for i, d := range group {
+		if strings.ToUpper(strings.TrimSpace(d.Action)) != action {
+			continue
+		}
+		if sizes[i] > leadSize {
+			lead, leadSize = d, sizes[i]
+		}
+		sideSize += sizes[i]
+		sideConfidence += sizes[i] * d.Confidence
+	}
+	confidence := lead.Confidence
+	if sideSize > 0 {
+		confidence = sideConfidence / sideSize
+	}
+
+	metadata := make(map[string]any, len(lead.Metadata)+1)
+	for k, v := range lead.Metadata {
+		metadata[k] = v
+	}
+	risk := map[string]any{}
+	if prior, ok := lead.Metadata["risk"].(map[string]any); ok {
+		for k, v := range prior {
+			risk[k] = v
+		}
+	}
+	risk["position_size"] = math.Abs(total)
+	metadata["risk"] = risk
+	metadata["netted_from"] = legs
+
+	summary := fmt.Sprintf("netted %d decisions to %s %.2f", len(group), action, math.Abs(total))
+	return logging.Input{
+		Symbol:     symbol,
+		Action:     action,
+		Confidence: confidence,
+		Notes:      strings.Join(append([]string{summary}, notes...), "; "),
+		Metadata:   metadata,
+	}, nil
+}
+
+// offsets correlates the symbols of the BUY and SELL decisions and returns the
+// pairs that hedge each other, or why the correlations could not be computed.
+func offsets(ctx context.Context, loader *marketdata.Loader, decisions []logging.Input, window int, threshold float64) ([]Offset, string) {
+	sign := map[string]float64{}
+	var symbols []string
+	for _, d := range decisions {
+		symbol := strings.ToUpper(strings.TrimSpace(d.Symbol))
+		switch strings.ToUpper(strings.TrimSpace(d.Action)) {
+		case "BUY":
+			sign[symbol] = 1
+		case "SELL":
+			sign[symbol] = -1
+		default:
+			continue
+		}
+		symbols = append(symbols, symbol)
+	}
+	if len(symbols) < 2 {
+		return nil, ""
+	}
+	matrix := correlation.Compute(ctx, loader, correlation.Input{Symbols: symbols, Window: window})
+	if matrix.Error != "" {
+		return nil, matrix.Error
+	}
+	var pairs []Offset
+	for i, a := range symbols {
+		for _, b := range symbols[i+1:] {
+			corr, ok := matrix.Matrix[a][b]
+			if !ok {
+				continue
+			}
+			// Opposite signs with positive correlation, or equal signs with negative
+			// correlation, make the pair's exposures cancel.
+			if -sign[a]*sign[b]*corr >= threshold {
+				pairs = append(pairs, Offset{Symbols: [2]string{a, b}, Correlation: corr})
+			}
+		}
+	}
+	return pairs, ""
+}
@@ -0,0 +1,148 @@
+package netting
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/logging"
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+)
+
+// newLoader writes one CSV per symbol with the given closes and returns a loader over them.
+func newLoader(t *testing.T, closes map[string][]float64) *marketdata.Loader {
+	t.Helper()
+	dir := t.TempDir()
+	historical := filepath.Join(dir, "historical")
+	if err := os.MkdirAll(historical, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for symbol, series := range closes {
+		var b strings.Builder
+		b.WriteString("Date,Close,High,Low,Open,Volume\n")
+		for i, c := range series {
+			fmt.Fprintf(&b, "2025-01-%02d,%.2f,%.2f,%.2f,%.2f,1000000\n", i+1, c, c+1, c-1, c)
+		}
+		if err := os.WriteFile(filepath.Join(historical, symbol+"_2025-01-01.csv"), []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loader, err := marketdata.NewLoader(dir)
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	return loader
+}
+
+func decision(symbol, action string, size, confidence float64) logging.Input {
+	return logging.Input{
+		Symbol:     symbol,
+		Action:     action,
+		Confidence: confidence,
+		Metadata:   map[string]any{"risk": map[string]any{"decision": "APPROVE", "position_size": size}},
+	}
+}
+
+func TestNet_SameSymbol(t *testing.T) {
+	loader := newLoader(t, nil)
+	out := net(context.Background(), loader, Input{Decisions: []logging.Input{
+		decision("spy", "BUY", 10000, 0.8),
+		decision("QQQ", "HOLD", 0, 0.5),
+		decision("SPY", "SELL", 4000, 0.6),
+		decision("SPY", "BUY", 2000, 0.5),
+	}}, DefaultOffsetCorrelation)
+
+	if out.Error != "" {
+		t.Fatalf("Unexpected error: %s", out.Error)
+	}
+	if len(out.Decisions) != 2 || out.Netted != 3 {
+		t.Fatalf("Expected SPY netted from 3 decisions plus QQQ, got %+v", out)
+	}
+	spy := out.Decisions[0]
+	if spy.Symbol != "SPY" || spy.Action != "BUY" {
+		t.Errorf("Expected a net SPY BUY first, got %s %s", spy.Symbol, spy.Action)
+	}
+	if size, _ := logging.ApprovedSize(spy.Metadata); size != 8000 {
+		t.Errorf("Expected a net size of 8000, got %f", size)
+	}
+	if want := (10000*0.8 + 2000*0.5) / 12000; math.Abs(spy.Confidence-want) > 1e-9 {
+		t.Errorf("Expected the size-weighted BUY confidence %f, got %f", want, spy.Confidence)
+	}
+	if legs, _ := spy.Metadata["netted_from"].([]map[string]any); len(legs) != 3 {
+		t.Errorf("Expected the three folded decisions recorded, got %v", spy.Metadata["netted_from"])
+	}
+	if out.Decisions[1].Symbol != "QQQ" || out.Decisions[1].Action != "HOLD" {
+		t.Errorf("Expected QQQ passed through, got %+v", out.Decisions[1])
+	}
+
+	flat := net(context.Background(), loader, Input{Decisions: []logging.Input{
+		decision("SPY", "BUY", 5000, 0.7),
+		decision("SPY", "SELL", 5000, 0.7),
+	}}, DefaultOffsetCorrelation)
+	if len(flat.Decisions) != 1 || flat.Decisions[0].Action != "HOLD" {
+		t.Errorf("Expected cancelling decisions to net to HOLD, got %+v", flat.Decisions)
+	}
+}
+
+func TestNet_RequiresSizes(t *testing.T) {
+	loader := newLoader(t, nil)
+	unsized := decision("SPY", "SELL", 0, 0.6)
+	unsized.Metadata = nil
+	out := net(context.Background(), loader, Input{Decisions: []logging.Input{decision("SPY", "BUY", 1000, 0.7), unsized}}, DefaultOffsetCorrelation)
+	if out.Error == "" {
+		t.Error("Expected an error netting a decision without a size")
+	}
+	if out := net(context.Background(), loader, Input{}, DefaultOffsetCorrelation); out.Error == "" {
+		t.Error("Expected an error without decisions")
+	}
+	if out := net(context.Background(), loader, Input{Decisions: []logging.Input{{Symbol: "SPY", Action: "SHORT"}}}, DefaultOffsetCorrelation); out.Error == "" {
+		t.Error("Expected an error for an unknown action")
+	}
+}
+
+func TestNet_FlagsOffsets(t *testing.T) {
+	up := []float64{100, 102, 101, 104, 103, 106, 108, 107}
+	twin := make([]float64, len(up))
+	inverse := make([]float64, len(up))
+	for i, c := range up {
+		twin[i] = c * 2
+		inverse[i] = 300 - c
+	}
+	loader := newLoader(t, map[string][]float64{"SPY": up, "VOO": twin, "SH": inverse})
+
+	out := net(context.Background(), loader, Input{Decisions: []logging.Input{
+		decision("SPY", "BUY", 10000, 0.8),
+		decision("VOO", "SELL", 10000, 0.7),
+		decision("SH", "SELL", 5000, 0.6),
+	}}, DefaultOffsetCorrelation)
+
+	if out.Error != "" || out.CorrelationError != "" {
+		t.Fatalf("Unexpected error: %s %s", out.Error, out.CorrelationError)
+	}
+	flagged := map[[2]string]bool{}
+	for _, o := range out.Offsets {
+		flagged[o.Symbols] = true
+	}
+	// Long SPY against short VOO, and short VOO against short SH, cancel out;
+	// long SPY with short SH (its inverse) stacks.
+	if !flagged[[2]string{"SPY", "VOO"}] || !flagged[[2]string{"VOO", "SH"}] || len(out.Offsets) != 2 {
+		t.Errorf("Expected SPY/VOO and VOO/SH offsets, got %+v", out.Offsets)
+	}
+}
+
+func TestNew_ValidatesOptions(t *testing.T) {
+	loader := newLoader(t, nil)
+	if _, err := New(nil); err == nil {
+		t.Error("Expected an error without a loader")
+	}
+	if _, err := New(loader, WithOffsetCorrelation(1.5)); err == nil {
+		t.Error("Expected an error for an offset correlation above 1")
+	}
+	if _, err := New(loader); err != nil {
+		t.Errorf("New: %v", err)
+	}
+}
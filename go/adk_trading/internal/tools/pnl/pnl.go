@@ -0,0 +1,154 @@
+package pnl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+type Input struct {
+	Positions []Position `json:"positions"`
+}
+
+// Position is an open holding. Side is LONG or SHORT (BUY and SELL are accepted as aliases).
+type Position struct {
+	Symbol     string  `json:"symbol"`
+	EntryPrice float64 `json:"entryPrice"`
+	Shares     float64 `json:"shares"`
+	Side       string  `json:"side"`
+}
+
+type Output struct {
+	Positions []Mark `json:"positions"`
+	// Unpriced lists symbols with no market data; their positions are excluded from the totals.
+	Unpriced         []string `json:"unpriced,omitempty"`
+	CostBasis        float64  `json:"costBasis"`
+	MarketValue      float64  `json:"marketValue"`
+	UnrealizedPnL    float64  `json:"unrealizedPnl"`
+	UnrealizedReturn float64  `json:"unrealizedReturn"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// Mark is a position valued at the latest close. MarketValue is the absolute
+// notional; UnrealizedPnL is signed by side.
+type Mark struct {
+	Symbol           string  `json:"symbol"`
+	Side             string  `json:"side"`
+	Shares           float64 `json:"shares"`
+	EntryPrice       float64 `json:"entryPrice"`
+	LastPrice        float64 `json:"lastPrice,omitempty"`
+	AsOf             string  `json:"asOf,omitempty"`
+	MarketValue      float64 `json:"marketValue,omitempty"`
+	UnrealizedPnL    float64 `json:"unrealizedPnl"`
+	UnrealizedReturn float64 `json:"unrealizedReturn"`
+	Priced           bool    `json:"priced"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// validate rejects a request without positions; individual bad positions are
+// reported on their Mark instead.
+func (in Input) validate() error {
+	if len(in.Positions) == 0 {
+		return errors.New("at least one position is required")
+	}
+	return nil
+}
+
+// New returns a tool that marks open positions to the latest close in the market data store.
+func New(loader *marketdata.Loader) (tool.Tool, error) {
+	if loader == nil {
+		return nil, errors.New("market data loader is required")
+	}
+	handler := func(ctx tool.Context, input Input) Output {
+		if err := input.validate(); err != nil {
+			return Output{Positions: []Mark{}, Error: err.Error()}
+		}
+		latest := make(map[string]marketdata.Row, len(input.Positions))
+		for _, p := range input.Positions {
+			symbol := strings.ToUpper(strings.TrimSpace(p.Symbol))
+			if _, seen := latest[symbol]; seen || symbol == "" {
+				continue
+			}
+			rows, err := loader.Load(ctx, symbol, 1)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return Output{Positions: []Mark{}, Error: ctxErr.Error()}
+			}
+			if err != nil || len(rows) == 0 || rows[len(rows)-1].Close <= 0 {
+				continue
+			}
+			latest[symbol] = rows[len(rows)-1]
+		}
+		return mark(input.Positions, latest)
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "mark_positions_to_market",
+		Description: "Mark open positions (symbol, entryPrice, shares, side) to the latest close and return unrealized P&L per position and in aggregate; symbols without data are reported as unpriced.",
+	}, handler)
+}
+
+// mark values each position against the latest row for its symbol. Positions with
+// invalid fields carry an error and, like unpriced ones, are left out of the totals.
+func mark(positions []Position, latest map[string]marketdata.Row) Output {
+	out := Output{Positions: make([]Mark, 0, len(positions))}
+	unpriced := map[string]bool{}
+	for _, p := range positions {
+		symbol := strings.ToUpper(strings.TrimSpace(p.Symbol))
+		side, direction := normalizeSide(p.Side)
+		m := Mark{Symbol: symbol, Side: side, Shares: p.Shares, EntryPrice: p.EntryPrice}
+		switch {
+		case symbol == "":
+			m.Error = "symbol is required"
+		case direction == 0:
+			m.Error = fmt.Sprintf("unknown side %q", p.Side)
+		case p.Shares <= 0:
+			m.Error = "shares must be positive"
+		case p.EntryPrice <= 0:
+			m.Error = "entry price must be positive"
+		}
+		if m.Error != "" {
+			out.Positions = append(out.Positions, m)
+			continue
+		}
+
+		row, ok := latest[symbol]
+		if !ok {
+			if !unpriced[symbol] {
+				unpriced[symbol] = true
+				out.Unpriced = append(out.Unpriced, symbol)
+			}
+			out.Positions = append(out.Positions, m)
+			continue
+		}
+		cost := p.Shares * p.EntryPrice
+		m.Priced = true
+		m.LastPrice = row.Close
+		m.AsOf = row.Date
+		m.MarketValue = p.Shares * row.Close
+		m.UnrealizedPnL = direction * (m.MarketValue - cost)
+		m.UnrealizedReturn = m.UnrealizedPnL / cost
+		out.Positions = append(out.Positions, m)
+
+		out.CostBasis += cost
+		out.MarketValue += m.MarketValue
+		out.UnrealizedPnL += m.UnrealizedPnL
+	}
+	if out.CostBasis > 0 {
+		out.UnrealizedReturn = out.UnrealizedPnL / out.CostBasis
+	}
+	return out
+}
+
+// normalizeSide maps a side to LONG or SHORT with its P&L sign, or 0 when unknown.
+func normalizeSide(side string) (string, float64) {
+	switch strings.ToUpper(strings.TrimSpace(side)) {
+	case "", "LONG", "BUY":
+		return "LONG", 1
+	case "SHORT", "SELL":
+		return "SHORT", -1
+	}
+	return strings.ToUpper(strings.TrimSpace(side)), 0
+}
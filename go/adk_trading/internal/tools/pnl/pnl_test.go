@@ -0,0 +1,83 @@
+package pnl
+
+import (
+	"math"
+	"testing"
+
+	"github.com/igorganapolsky/trading/adk_trading/internal/tools/marketdata"
+)
+
+func TestMark_LongAndShort(t *testing.T) {
+	latest := map[string]marketdata.Row{
+		"SPY": {Date: "2025-01-10", Close: 110},
+		"QQQ": {Date: "2025-01-10", Close: 180},
+	}
+	out := mark([]Position{
+		{Symbol: "spy", EntryPrice: 100, Shares: 10, Side: "long"},
+		{Symbol: "QQQ", EntryPrice: 200, Shares: 5, Side: "SHORT"},
+	}, latest)
+
+	if len(out.Positions) != 2 {
+		t.Fatalf("Expected 2 marks, got %d", len(out.Positions))
+	}
+	spy, qqq := out.Positions[0], out.Positions[1]
+	if spy.UnrealizedPnL != 100 || math.Abs(spy.UnrealizedReturn-0.1) > 1e-9 || spy.AsOf != "2025-01-10" {
+		t.Errorf("Unexpected long mark: %+v", spy)
+	}
+	// A short profits when the price falls: (200-180)*5.
+	if qqq.UnrealizedPnL != 100 || math.Abs(qqq.UnrealizedReturn-0.1) > 1e-9 {
+		t.Errorf("Unexpected short mark: %+v", qqq)
+	}
+	if out.UnrealizedPnL != 200 || out.CostBasis != 2000 || out.MarketValue != 1100+900 {
+		t.Errorf("Unexpected totals: %+v", out)
+	}
+}
+
+func TestMark_UnpricedSymbolsDoNotFailTheCall(t *testing.T) {
+	latest := map[string]marketdata.Row{"SPY": {Date: "2025-01-10", Close: 90}}
+	out := mark([]Position{
+		{Symbol: "SPY", EntryPrice: 100, Shares: 10},
+		{Symbol: "ZZZZ", EntryPrice: 5, Shares: 100},
+		{Symbol: "ZZZZ", EntryPrice: 6, Shares: 50},
+	}, latest)
+
+	if out.Error != "" {
+		t.Fatalf("Unexpected error: %s", out.Error)
+	}
+	if len(out.Unpriced) != 1 || out.Unpriced[0] != "ZZZZ" {
+		t.Errorf("Expected ZZZZ listed once as unpriced, got %v", out.Unpriced)
+	}
+	if out.Positions[1].Priced || out.Positions[2].Priced {
+		t.Error("Expected ZZZZ positions to be marked unpriced")
+	}
+	if out.UnrealizedPnL != -100 || out.CostBasis != 1000 {
+		t.Errorf("Expected totals from SPY alone, got %+v", out)
+	}
+}
+
+func TestMark_InvalidPositions(t *testing.T) {
+	latest := map[string]marketdata.Row{"SPY": {Close: 100}}
+	out := mark([]Position{
+		{Symbol: "SPY", EntryPrice: 100, Shares: 0},
+		{Symbol: "SPY", EntryPrice: 100, Shares: 1, Side: "sideways"},
+		{Symbol: "", EntryPrice: 100, Shares: 1},
+	}, latest)
+
+	for i, m := range out.Positions {
+		if m.Error == "" || m.Priced {
+			t.Errorf("Expected position %d to be rejected, got %+v", i, m)
+		}
+	}
+	if out.CostBasis != 0 {
+		t.Errorf("Expected invalid positions to be left out of totals, got %+v", out)
+	}
+}
+
+func TestInput_Validate(t *testing.T) {
+	if err := (Input{}).validate(); err == nil {
+		t.Error("Expected an error without positions")
+	}
+	if err := (Input{Positions: []Position{{Symbol: "SPY"}}}).validate(); err != nil {
+		t.Errorf("Expected per-position problems to be left to mark, got %v", err)
+	}
+}
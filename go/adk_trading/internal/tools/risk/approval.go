@@ -0,0 +1,136 @@
+package risk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultApprovalTimeout is how long the risk check waits for a human approver
+// when WithApproval does not say.
+const DefaultApprovalTimeout = 2 * time.Minute
+
+// Approval outcomes reported in Output.Approval.
+const (
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalPending  = "pending"
+)
+
+// ApprovalRequest is the proposed decision POSTed to the approval URL.
+type ApprovalRequest struct {
+	Symbol       string         `json:"symbol"`
+	Action       string         `json:"action"`
+	Confidence   float64        `json:"confidence"`
+	PositionSize float64        `json:"positionSize"`
+	Shares       int64          `json:"shares,omitempty"`
+	Threshold    float64        `json:"threshold"`
+	Reasons      []ReasonDetail `json:"reasons"`
+	Invocation   string         `json:"invocation,omitempty"`
+}
+
+// ApprovalResponse is the approver's answer; Reason is quoted back to the agent.
+type ApprovalResponse struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// approvalHook asks a human, through an HTTP service, to approve large positions.
+type approvalHook struct {
+	url       string
+	threshold float64
+	timeout   time.Duration
+}
+
+// WithApproval POSTs every approved decision whose position size exceeds threshold
+// to url as an ApprovalRequest and waits up to timeout (zero keeps
+// DefaultApprovalTimeout) for an ApprovalResponse. A refusal rejects the trade; no
+// answer in time, or a failed request, downgrades it to REVIEW awaiting human
+// approval. Without this option no decision needs approval.
+func WithApproval(url string, threshold float64, timeout time.Duration) Option {
+	return func(c *config) {
+		c.approval = &approvalHook{url: url, threshold: threshold, timeout: timeout}
+	}
+}
+
+// needsApproval reports whether out must be confirmed by a human.
+func (h *approvalHook) needsApproval(out Output) bool {
+	return h != nil && out.Decision == "APPROVE" && out.PositionSize > h.threshold
+}
+
+// request POSTs req and decodes the answer, giving up after the hook's timeout.
+func (h *approvalHook) request(ctx context.Context, req ApprovalRequest) (ApprovalResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	body, err := json.Marshal(req)
+	if err != nil {
+		return ApprovalResponse{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return ApprovalResponse{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return ApprovalResponse{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ApprovalResponse{}, fmt.Errorf("approval service returned %s", resp.Status)
+	}
+	var answer ApprovalResponse
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return ApprovalResponse{}, fmt.Errorf("decode approval response: %w", err)
+	}
+	return answer, nil
+}
+
+// approve asks the approver about out and applies the answer.
+func (h *approvalHook) approve(ctx context.Context, input Input, out Output, invocation string) Output {
+	answer, err := h.request(ctx, ApprovalRequest{
+		Symbol:       strings.ToUpper(strings.TrimSpace(input.Symbol)),
+		Action:       strings.ToUpper(strings.TrimSpace(input.Action)),
+		Confidence:   out.Confidence,
+		PositionSize: out.PositionSize,
+		Shares:       out.Shares,
+		Threshold:    h.threshold,
+		Reasons:      out.Reasons,
+		Invocation:   invocation,
+	})
+	switch {
+	case err != nil:
+		slog.Warn("human approval not received", "invocation", invocation, "symbol", input.Symbol, "error", err)
+		out.Approval = ApprovalPending
+		return out.override("REVIEW", ReasonAwaitingApproval,
+			fmt.Sprintf("awaiting human approval: position %.2f above %.2f threshold", out.PositionSize, h.threshold))
+	case !answer.Approved:
+		out.Approval = ApprovalRejected
+		message := "rejected by human approver"
+		if answer.Reason != "" {
+			message += ": " + answer.Reason
+		}
+		return out.override("REJECT", ReasonHumanRejected, message)
+	}
+	out.Approval = ApprovalApproved
+	return out
+}
+
+// override escalates out to decision for the given reason, which replaces the
+// "within limits" placeholder when that was the only finding.
+func (o Output) override(decision, code, message string) Output {
+	reasons := reasonList(o.Reasons)
+	if len(reasons) == 1 && reasons[0].Code == ReasonWithinLimits {
+		reasons = nil
+	}
+	reasons = reasons.add(code, message)
+	o.Decision = escalate(o.Decision, decision)
+	o.Reasons = reasons
+	o.Reason = reasons.String()
+	return o
+}
@@ -0,0 +1,83 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// approver answers every approval request with answer after delay, recording the last request.
+func approver(t *testing.T, answer string, delay time.Duration, got *ApprovalRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+			t.Errorf("Decode approval request: %v", err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte(answer))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestApprovalHook_AppliesAnswer(t *testing.T) {
+	input := Input{Symbol: "spy", Action: "buy", Confidence: 0.7, Volatility: 0.01, MaxRiskBps: 200}
+	out := testHandler(1_000_000, input)
+	if out.Decision != "APPROVE" || out.PositionSize != 100_000 {
+		t.Fatalf("Expected an approved 100000 position, got %s %f", out.Decision, out.PositionSize)
+	}
+
+	var req ApprovalRequest
+	hook := &approvalHook{url: approver(t, `{"approved": true}`, 0, &req).URL, threshold: 50_000, timeout: time.Second}
+	if !hook.needsApproval(out) {
+		t.Fatal("Expected a position above the threshold to need approval")
+	}
+	approved := hook.approve(context.Background(), input, out, "inv-1")
+	if approved.Decision != "APPROVE" || approved.Approval != ApprovalApproved {
+		t.Errorf("Expected the approval to stand, got %s (%s)", approved.Decision, approved.Approval)
+	}
+	if req.Symbol != "SPY" || req.PositionSize != 100_000 || req.Threshold != 50_000 || req.Invocation != "inv-1" {
+		t.Errorf("Unexpected approval request: %+v", req)
+	}
+
+	hook.url = approver(t, `{"approved": false, "reason": "too big before earnings"}`, 0, &req).URL
+	rejected := hook.approve(context.Background(), input, out, "inv-1")
+	if rejected.Decision != "REJECT" || rejected.Approval != ApprovalRejected || len(rejected.Reasons) != 1 ||
+		rejected.Reasons[0].Code != ReasonHumanRejected || rejected.Reason != "rejected by human approver: too big before earnings" {
+		t.Errorf("Expected a human rejection, got %+v", rejected)
+	}
+}
+
+func TestApprovalHook_TimeoutAwaitsApproval(t *testing.T) {
+	input := Input{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: 0.01, MaxRiskBps: 200}
+	out := testHandler(1_000_000, input)
+	var req ApprovalRequest
+	hook := &approvalHook{url: approver(t, `{"approved": true}`, time.Second, &req).URL, threshold: 50_000, timeout: 20 * time.Millisecond}
+
+	pending := hook.approve(context.Background(), input, out, "inv-1")
+
+	if pending.Decision != "REVIEW" || pending.Approval != ApprovalPending || pending.Reasons[0].Code != ReasonAwaitingApproval {
+		t.Errorf("Expected REVIEW awaiting human approval, got %+v", pending)
+	}
+}
+
+func TestApprovalHook_Disabled(t *testing.T) {
+	var hook *approvalHook
+	if hook.needsApproval(Output{Decision: "APPROVE", PositionSize: 1e9}) {
+		t.Error("Expected no approval without a hook")
+	}
+	hook = &approvalHook{threshold: 50_000}
+	if hook.needsApproval(Output{Decision: "APPROVE", PositionSize: 50_000}) || hook.needsApproval(Output{Decision: "REVIEW", PositionSize: 1e9}) {
+		t.Error("Expected approval only for approved positions above the threshold")
+	}
+	if _, err := New(1_000_000, WithApproval("", 50_000, 0)); err == nil {
+		t.Error("Expected an error for an approval hook without a URL")
+	}
+}
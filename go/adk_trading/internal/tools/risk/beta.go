@@ -0,0 +1,33 @@
+package risk
+
+import "math"
+
+// DefaultBetaThreshold is the beta above which a largely market-driven name is
+// flagged when WithBetaReview does not say otherwise.
+const DefaultBetaThreshold = 1.5
+
+// MarketDrivenShare is the share of a name's variance explained by the benchmark
+// (beta² × benchmark variance ÷ variance) at or above which its move counts as
+// largely market-driven.
+const MarketDrivenShare = 0.5
+
+// WithBetaReview flags trades in names whose beta exceeds threshold and whose
+// volatility is largely market-driven, and downgrades their approvals to REVIEW:
+// the signal may be nothing more than leveraged market exposure. Without this
+// option such trades are only annotated, at DefaultBetaThreshold.
+func WithBetaReview(threshold float64) Option {
+	return func(c *config) {
+		c.betaThreshold = threshold
+		c.betaReview = true
+	}
+}
+
+// marketShare returns the share of variance explained by the benchmark for a name
+// with annualised volatility vol, capped at 1, or false without both a beta and a
+// benchmark volatility.
+func marketShare(beta, benchmarkVol, vol float64) (float64, bool) {
+	if beta == 0 || benchmarkVol <= 0 || vol <= 0 {
+		return 0, false
+	}
+	return math.Min(math.Pow(beta*benchmarkVol/vol, 2), 1), true
+}
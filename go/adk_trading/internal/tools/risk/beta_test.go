@@ -0,0 +1,33 @@
+package risk
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRiskTool_MarketBeta(t *testing.T) {
+	input := Input{Symbol: "TSLA", Action: "BUY", Confidence: 0.8, Volatility: 0.3, Beta: 2, BenchmarkVolatility: 0.2}
+
+	annotated := evaluate(newConfig(1_000_000), input)
+	if annotated.Decision != "APPROVE" || annotated.MarketShare != 1 || annotated.Reasons[0].Code != ReasonMarketBeta {
+		t.Errorf("Expected an approval annotated as market-driven, got %+v", annotated)
+	}
+	reviewed := evaluate(newConfig(1_000_000, WithBetaReview(1.5)), input)
+	if reviewed.Decision != "REVIEW" || reviewed.Reasons[0].Code != ReasonMarketBeta {
+		t.Errorf("Expected a market-driven approval to need review, got %+v", reviewed)
+	}
+
+	// A beta below the threshold, or a move that is mostly the name's own, passes.
+	input.Beta = 1.2
+	if out := evaluate(newConfig(1_000_000, WithBetaReview(1.5)), input); out.Decision != "APPROVE" || math.Abs(out.MarketShare-0.64) > 1e-9 || out.Reasons[0].Code != ReasonWithinLimits {
+		t.Errorf("Expected a low-beta name to pass with a 64%% market share, got %+v", out)
+	}
+	input.Beta, input.BenchmarkVolatility = 2, 0.05
+	if out := evaluate(newConfig(1_000_000, WithBetaReview(1.5)), input); out.Decision != "APPROVE" || out.Reasons[0].Code != ReasonWithinLimits {
+		t.Errorf("Expected an idiosyncratic move to pass, got %+v", out)
+	}
+
+	if _, err := New(1_000_000, WithBetaReview(0)); err == nil {
+		t.Error("Expected an error for a zero beta threshold")
+	}
+}
@@ -0,0 +1,24 @@
+package risk
+
+import "fmt"
+
+// explanation collects the steps of an evaluation, with the numbers used, when
+// Input.Explain is set. A nil explanation ignores every step, so the default
+// output stays lean.
+type explanation []string
+
+func (e *explanation) addf(format string, args ...any) {
+	if e != nil {
+		*e = append(*e, fmt.Sprintf(format, args...))
+	}
+}
+
+// attach returns out carrying the collected steps, closed by the final decision.
+func (e *explanation) attach(out Output) Output {
+	if e == nil {
+		return out
+	}
+	e.addf("decision %s with position %.2f: %s", out.Decision, out.PositionSize, out.Reason)
+	out.Explanation = *e
+	return out
+}
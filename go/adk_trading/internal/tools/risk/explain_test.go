@@ -0,0 +1,60 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluate_Explain(t *testing.T) {
+	input := Input{
+		Symbol:               "SPY",
+		Action:               "BUY",
+		Confidence:           0.3,
+		Volatility:           0.15,
+		PortfolioValue:       1_000_000,
+		MaxRiskBps:           50,
+		CurrentGrossLeverage: 0.999,
+	}
+
+	if lean := testHandler(1_000_000, input); lean.Explanation != nil {
+		t.Fatalf("Expected no explanation by default, got %v", lean.Explanation)
+	}
+
+	input.Explain = true
+	out := testHandler(1_000_000, input)
+	steps := strings.Join(out.Explanation, "\n")
+	for _, want := range []string{
+		"risk budget: portfolio 1000000.00 x 50 bps (requested) = 5000.00",
+		"volatility-adjusted size: budget 5000.00 / (volatility 0.1500 x 10) = 3333.33",
+		"single-position cap 10% of portfolio = 100000.00: not reached",
+		"confidence 0.30 below 0.35: REVIEW",
+		"gross leverage headroom (1.00x - 1.00x) x portfolio = 1000.00: size clipped to it",
+		"decision REVIEW with position 1000.00: confidence weak; gross leverage capped",
+	} {
+		if !strings.Contains(steps, want) {
+			t.Errorf("Expected the explanation to include %q, got:\n%s", want, steps)
+		}
+	}
+	if last := out.Explanation[len(out.Explanation)-1]; !strings.HasPrefix(last, "decision REVIEW") {
+		t.Errorf("Expected the explanation to end with the decision, got %q", last)
+	}
+}
+
+func TestEvaluate_ExplainEarlyExits(t *testing.T) {
+	hold := testHandler(1_000_000, Input{Symbol: "SPY", Action: "HOLD", Explain: true})
+	if len(hold.Explanation) == 0 || !strings.Contains(strings.Join(hold.Explanation, "\n"), "HOLD stays flat") {
+		t.Errorf("Expected a HOLD explanation, got %v", hold.Explanation)
+	}
+
+	cfg := newConfig(1_000_000, WithSymbolRestrictions(nil, []string{"XYZ"}))
+	restricted := evaluate(cfg, Input{Symbol: "xyz", Action: "BUY", Confidence: 0.8, Volatility: 0.2, Explain: true})
+	if last := restricted.Explanation[len(restricted.Explanation)-1]; !strings.Contains(last, "decision REJECT") || !strings.Contains(last, "blocked list") {
+		t.Errorf("Expected the restriction in the closing step, got %v", restricted.Explanation)
+	}
+
+	// Sweep points stay lean even when the primary decision is explained.
+	swept := testHandler(1_000_000, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.2, Explain: true, ConfidenceSweep: []float64{0.2, 0.9}})
+	if len(swept.Explanation) == 0 || len(swept.Sweep) != 2 {
+		t.Errorf("Expected an explained decision with a sweep, got %+v", swept)
+	}
+}
@@ -0,0 +1,100 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Thresholds replaces some of the default risk thresholds. Zero fields keep the
+// default.
+type Thresholds struct {
+	// MaxRiskBps replaces DefaultMaxRiskBps and caps any budget the caller supplies.
+	MaxRiskBps float64 `json:"maxRiskBps,omitempty"`
+	// RejectVolatility replaces DefaultRejectVolatility.
+	RejectVolatility float64 `json:"rejectVolatility,omitempty"`
+	// MaxPositionFraction replaces the configured single-position cap.
+	MaxPositionFraction float64 `json:"maxPositionFraction,omitempty"`
+}
+
+// valid reports whether every threshold is non-negative and the position cap is
+// at most the whole portfolio.
+func (t Thresholds) valid() bool {
+	return t.MaxRiskBps >= 0 && t.RejectVolatility >= 0 && t.MaxPositionFraction >= 0 && t.MaxPositionFraction <= 1
+}
+
+// SymbolOverride replaces the default thresholds for one symbol.
+type SymbolOverride Thresholds
+
+// overrideStore holds the per-symbol overrides read from a JSON file, re-reading it
+// once reload has elapsed since the last read.
+type overrideStore struct {
+	path   string
+	reload time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	bySymbol map[string]SymbolOverride
+}
+
+// WithSymbolOverrides loads per-symbol thresholds from the JSON file at path, an
+// object mapping symbol to SymbolOverride, e.g. {"TQQQ": {"maxRiskBps": 20}}. The
+// file is re-read when a decision arrives more than reload after the last read; zero
+// reads it once. A file that stops parsing leaves the last good overrides in place.
+func WithSymbolOverrides(path string, reload time.Duration) Option {
+	return func(c *config) {
+		c.overrides = &overrideStore{path: path, reload: reload}
+	}
+}
+
+func readOverrides(path string) (map[string]SymbolOverride, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read symbol overrides: %w", err)
+	}
+	var raw map[string]SymbolOverride
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse symbol overrides %s: %w", path, err)
+	}
+	bySymbol := make(map[string]SymbolOverride, len(raw))
+	for symbol, o := range raw {
+		if !Thresholds(o).valid() {
+			return nil, fmt.Errorf("symbol override for %s must be non-negative with maxPositionFraction at most 1", symbol)
+		}
+		bySymbol[strings.ToUpper(strings.TrimSpace(symbol))] = o
+	}
+	return bySymbol, nil
+}
+
+// load reads the file for the first time; New fails if it cannot.
+func (s *overrideStore) load() error {
+	bySymbol, err := readOverrides(s.path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bySymbol, s.loadedAt = bySymbol, s.now()
+	return nil
+}
+
+// lookup returns the override for symbol, refreshing the file first when it is due.
+func (s *overrideStore) lookup(symbol string) (SymbolOverride, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := s.now(); s.reload > 0 && now.Sub(s.loadedAt) >= s.reload {
+		s.loadedAt = now
+		if bySymbol, err := readOverrides(s.path); err != nil {
+			slog.Warn("keeping previous risk symbol overrides", "path", s.path, "error", err)
+		} else {
+			s.bySymbol = bySymbol
+		}
+	}
+	o, ok := s.bySymbol[strings.ToUpper(strings.TrimSpace(symbol))]
+	return o, ok
+}
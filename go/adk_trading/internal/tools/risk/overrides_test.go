@@ -0,0 +1,67 @@
+package risk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeOverrides(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write overrides: %v", err)
+	}
+}
+
+func TestRiskTool_SymbolOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	writeOverrides(t, path, `{"tqqq": {"maxRiskBps": 10, "rejectVolatility": 0.5, "maxPositionFraction": 0.01}}`)
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	cfg := newConfig(1_000_000, WithSymbolOverrides(path, time.Minute))
+	cfg.now = func() time.Time { return now }
+	cfg.overrides.now = cfg.now
+	if err := cfg.overrides.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	leveraged := evaluate(cfg, Input{Symbol: "TQQQ", Action: "BUY", Confidence: 0.8, Volatility: 0.6, PortfolioValue: 1_000_000})
+	if leveraged.Decision != "REJECT" || !leveraged.SymbolOverride {
+		t.Errorf("Expected the overridden volatility ceiling to reject, got %s (%s)", leveraged.Decision, leveraged.Reason)
+	}
+	calm := evaluate(cfg, Input{Symbol: "TQQQ", Action: "BUY", Confidence: 0.8, Volatility: 0.01, MaxRiskBps: 50, PortfolioValue: 1_000_000})
+	if calm.ExpectedRisk != 1_000 || calm.PositionSize != 10_000 {
+		t.Errorf("Expected a 10bps budget capped at 1%%, got risk %f size %f", calm.ExpectedRisk, calm.PositionSize)
+	}
+	blueChip := evaluate(cfg, Input{Symbol: "AAPL", Action: "BUY", Confidence: 0.8, Volatility: 0.6, PortfolioValue: 1_000_000})
+	if blueChip.Decision != "APPROVE" || blueChip.SymbolOverride {
+		t.Errorf("Expected defaults for an unlisted symbol, got %s (%s)", blueChip.Decision, blueChip.Reason)
+	}
+
+	writeOverrides(t, path, `{"AAPL": {"rejectVolatility": 0.5}}`)
+	if out := evaluate(cfg, Input{Symbol: "AAPL", Action: "BUY", Confidence: 0.8, Volatility: 0.6, PortfolioValue: 1_000_000}); out.Decision != "APPROVE" {
+		t.Errorf("Expected the file not to be re-read before the interval, got %s", out.Decision)
+	}
+	now = now.Add(time.Minute)
+	if out := evaluate(cfg, Input{Symbol: "AAPL", Action: "BUY", Confidence: 0.8, Volatility: 0.6, PortfolioValue: 1_000_000}); out.Decision != "REJECT" {
+		t.Errorf("Expected the reloaded override to apply, got %s", out.Decision)
+	}
+
+	writeOverrides(t, path, `not json`)
+	now = now.Add(time.Minute)
+	if out := evaluate(cfg, Input{Symbol: "AAPL", Action: "BUY", Confidence: 0.8, Volatility: 0.6, PortfolioValue: 1_000_000}); out.Decision != "REJECT" {
+		t.Errorf("Expected a broken file to keep the last good overrides, got %s", out.Decision)
+	}
+}
+
+func TestNew_RejectsBadOverridesFile(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(1_000_000, WithSymbolOverrides(filepath.Join(dir, "missing.json"), 0)); err == nil {
+		t.Error("Expected an error for a missing overrides file")
+	}
+	path := filepath.Join(dir, "overrides.json")
+	writeOverrides(t, path, `{"SPY": {"maxPositionFraction": 2}}`)
+	if _, err := New(1_000_000, WithSymbolOverrides(path, 0)); err == nil {
+		t.Error("Expected an error for a position fraction above 1")
+	}
+}
@@ -0,0 +1,58 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RiskProfile is a named set of thresholds for one mandate, selected per call with
+// Input.Profile.
+type RiskProfile Thresholds
+
+// WithProfiles registers named risk profiles, e.g. "aggressive" and "conservative",
+// so one deployment can serve several books. Names are matched case-insensitively.
+// A per-symbol override still takes precedence over the profile for its symbol.
+func WithProfiles(profiles map[string]RiskProfile) Option {
+	return func(c *config) {
+		c.profiles = profiles
+	}
+}
+
+// ReadProfiles reads a JSON file mapping profile name to RiskProfile, e.g.
+// {"conservative": {"maxRiskBps": 25, "maxPositionFraction": 0.05}}.
+func ReadProfiles(path string) (map[string]RiskProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read risk profiles: %w", err)
+	}
+	var profiles map[string]RiskProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("parse risk profiles %s: %w", path, err)
+	}
+	return profiles, nil
+}
+
+// normalizeProfiles validates profiles and keys them by lower-cased name.
+func normalizeProfiles(profiles map[string]RiskProfile) (map[string]RiskProfile, error) {
+	byName := make(map[string]RiskProfile, len(profiles))
+	for name, p := range profiles {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" {
+			return nil, errors.New("risk profile name must not be blank")
+		}
+		if !Thresholds(p).valid() {
+			return nil, fmt.Errorf("risk profile %s must be non-negative with maxPositionFraction at most 1", name)
+		}
+		byName[key] = p
+	}
+	return byName, nil
+}
+
+// profile returns the profile named name; ok is false for an unknown name.
+func (c config) profile(name string) (RiskProfile, bool) {
+	p, ok := c.profiles[strings.ToLower(strings.TrimSpace(name))]
+	return p, ok
+}
@@ -0,0 +1,52 @@
+package risk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRiskTool_Profiles(t *testing.T) {
+	cfg := newConfig(1_000_000, WithProfiles(map[string]RiskProfile{
+		"conservative": {MaxRiskBps: 10, RejectVolatility: 0.4, MaxPositionFraction: 0.005},
+		"aggressive":   {MaxRiskBps: 100},
+	}))
+
+	conservative := evaluate(cfg, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.01, Profile: "Conservative"})
+	if conservative.ExpectedRisk != 1_000 || conservative.PositionSize != 5_000 || conservative.Profile != "conservative" {
+		t.Errorf("Expected a 10bps budget capped at 0.5%%, got risk %f size %f (%q)", conservative.ExpectedRisk, conservative.PositionSize, conservative.Profile)
+	}
+	if out := evaluate(cfg, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.6}); out.Decision != "APPROVE" {
+		t.Errorf("Expected the default volatility ceiling to allow 60%% without a profile, got %s", out.Decision)
+	}
+	if out := evaluate(cfg, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.6, Profile: "conservative"}); out.Decision != "REJECT" {
+		t.Errorf("Expected the conservative profile to reject 60%% volatility, got %s (%s)", out.Decision, out.Reason)
+	}
+	if out := evaluate(cfg, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.01, Profile: "aggressive"}); out.ExpectedRisk != 10_000 || out.PositionSize != 100_000 {
+		t.Errorf("Expected a 100bps budget held to the default 10%% cap, got risk %f size %f", out.ExpectedRisk, out.PositionSize)
+	}
+
+	unknown := evaluate(cfg, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.01, Profile: "yolo"})
+	if unknown.Decision != "APPROVE" || unknown.PositionSize != 50_000 || unknown.Profile != "" ||
+		len(unknown.Reasons) != 1 || unknown.Reasons[0].Code != ReasonUnknownProfile {
+		t.Errorf("Expected defaults with an unknown profile note, got %+v", unknown)
+	}
+}
+
+func TestNew_RejectsBadProfiles(t *testing.T) {
+	if _, err := New(1_000_000, WithProfiles(map[string]RiskProfile{" ": {}})); err == nil {
+		t.Error("Expected an error for a blank profile name")
+	}
+	if _, err := New(1_000_000, WithProfiles(map[string]RiskProfile{"wild": {MaxPositionFraction: 2}})); err == nil {
+		t.Error("Expected an error for a position cap above 1")
+	}
+
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(path, []byte(`{"conservative": {"maxRiskBps": 25}}`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	profiles, err := ReadProfiles(path)
+	if err != nil || profiles["conservative"].MaxRiskBps != 25 {
+		t.Errorf("Expected the profile file to parse, got %v (%v)", profiles, err)
+	}
+}
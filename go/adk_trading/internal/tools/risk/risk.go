@@ -2,13 +2,37 @@ package risk
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
 	"math"
 	"strings"
+	"time"
 
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
 )
 
+const (
+	// DefaultMaxRiskBps is the per-trade risk budget applied when the caller does not supply one.
+	DefaultMaxRiskBps = 50 // 0.5%
+	// MaxPositionFraction is the default cap on a single position as a fraction of portfolio value.
+	MaxPositionFraction = 0.1
+	// DefaultSectorCap caps long exposure to a single sector as a fraction of portfolio value.
+	DefaultSectorCap = 0.3
+	// DefaultRejectVolatility is the annualised volatility above which trades are rejected.
+	DefaultRejectVolatility = 0.8
+	// DefaultMaxGrossLeverage caps gross exposure as a multiple of portfolio value.
+	DefaultMaxGrossLeverage = 1.0
+	// DefaultShortCap caps aggregate short exposure as a fraction of portfolio value.
+	DefaultShortCap = 0.3
+	// DefaultDrawdownFloor is the drawdown from peak at which every new trade is rejected.
+	DefaultDrawdownFloor = 0.20
+	// DefaultReversalCooldown is how long after a trade an opposing trade on the same symbol needs review.
+	DefaultReversalCooldown = 30 * time.Minute
+	// DefaultATRMultiple is the adverse move, in ATRs, that ATR sizing budgets for.
+	DefaultATRMultiple = 2.0
+)
+
 type Input struct {
 	Symbol         string  `json:"symbol"`
 	Action         string  `json:"action"`
@@ -16,78 +40,952 @@ type Input struct {
 	Volatility     float64 `json:"volatility"`
 	PortfolioValue float64 `json:"portfolioValue"`
 	MaxRiskBps     float64 `json:"maxRiskBps,omitempty"`
+	// Sector and SectorExposures (current notional by sector) enable the sector concentration check.
+	Sector          string             `json:"sector,omitempty"`
+	SectorExposures map[string]float64 `json:"sectorExposures,omitempty"`
+	// Leverage and short exposure are expressed as multiples of portfolio value.
+	CurrentGrossLeverage float64 `json:"currentGrossLeverage,omitempty"`
+	MaxGrossLeverage     float64 `json:"maxGrossLeverage,omitempty"`
+	CurrentShortExposure float64 `json:"currentShortExposure,omitempty"`
+	// RecentActions lets the check spot a proposed trade that flips a recent one.
+	RecentActions []RecentAction `json:"recentActions,omitempty"`
+	// Scenarios requests conservative/base/aggressive sizing alongside the primary decision.
+	Scenarios bool `json:"scenarios,omitempty"`
+	// ConfidenceSweep re-runs the check at each listed confidence, everything else
+	// unchanged, for a sensitivity table. The primary decision still uses Confidence.
+	ConfidenceSweep []float64 `json:"confidenceSweep,omitempty"`
+	// SharePrice enables rounding the position down to whole lots of LotSize shares (default 1).
+	SharePrice float64 `json:"sharePrice,omitempty"`
+	LotSize    int64   `json:"lotSize,omitempty"`
+	// SizingUnit is "notional" (default) to size in dollars or "shares" to size in
+	// whole lots, which requires SharePrice.
+	SizingUnit string `json:"sizingUnit,omitempty"`
+	// ConfidenceHaircut (0-1) scales Confidence down by that fraction before any
+	// check uses it, calibrating an overconfident signal. Zero applies no haircut.
+	ConfidenceHaircut float64 `json:"confidenceHaircut,omitempty"`
+	// CurrentDrawdown is the book's decline from its peak as a fraction, e.g. -0.07.
+	// Deeper drawdowns throttle sizing and, past the floor, reject outright.
+	CurrentDrawdown float64 `json:"currentDrawdown,omitempty"`
+	// SizingMethod is "volatility" (default) to size from annualised volatility or
+	// "atr" to size so that an adverse move of ATRMultiple (default 2) ATRs from
+	// EntryPrice loses exactly the risk budget. EntryPrice defaults to SharePrice;
+	// without a positive ATR and entry price the volatility method is used.
+	SizingMethod string  `json:"sizingMethod,omitempty"`
+	EntryPrice   float64 `json:"entryPrice,omitempty"`
+	ATR          float64 `json:"atr,omitempty"`
+	ATRMultiple  float64 `json:"atrMultiple,omitempty"`
+	// Beta and BenchmarkVolatility (annualised), e.g. from a market snapshot with a
+	// benchmark, let the check spot a high-beta name whose move is mostly market.
+	Beta                float64 `json:"beta,omitempty"`
+	BenchmarkVolatility float64 `json:"benchmarkVolatility,omitempty"`
+	// Profile names a risk profile registered with WithProfiles whose thresholds
+	// replace the defaults for this call. An unknown name keeps the defaults.
+	Profile string `json:"profile,omitempty"`
+	// Explain adds Output.Explanation, a step-by-step account of the budget, the
+	// sizing, the constraints that fired and the decision, with the numbers used.
+	Explain bool `json:"explain,omitempty"`
+}
+
+// validate rejects values no caller could mean: an unknown action or a negative
+// amount. Zero and out-of-range confidences are still coerced by evaluate.
+func (in Input) validate() error {
+	switch strings.ToUpper(strings.TrimSpace(in.Action)) {
+	case "BUY", "SELL", "HOLD":
+	default:
+		return fmt.Errorf("unsupported action %q: use BUY, SELL or HOLD", in.Action)
+	}
+	for _, field := range []struct {
+		name  string
+		value float64
+	}{
+		{"portfolioValue", in.PortfolioValue},
+		{"maxRiskBps", in.MaxRiskBps},
+		{"volatility", in.Volatility},
+		{"currentGrossLeverage", in.CurrentGrossLeverage},
+		{"maxGrossLeverage", in.MaxGrossLeverage},
+		{"currentShortExposure", in.CurrentShortExposure},
+		{"sharePrice", in.SharePrice},
+		{"entryPrice", in.EntryPrice},
+		{"atr", in.ATR},
+		{"atrMultiple", in.ATRMultiple},
+		{"benchmarkVolatility", in.BenchmarkVolatility},
+	} {
+		if field.value < 0 || math.IsNaN(field.value) {
+			return fmt.Errorf("%s must not be negative, got %g", field.name, field.value)
+		}
+	}
+	if in.LotSize < 0 {
+		return fmt.Errorf("lotSize must not be negative, got %d", in.LotSize)
+	}
+	return nil
+}
+
+// Sizing units accepted by Input.SizingUnit.
+const (
+	SizingUnitNotional = "notional"
+	SizingUnitShares   = "shares"
+)
+
+// Sizing methods accepted by Input.SizingMethod.
+const (
+	SizingMethodVolatility = "volatility"
+	SizingMethodATR        = "atr"
+)
+
+// ReasonDetail is one finding behind a risk decision: a stable Code for machines
+// and a Message for people.
+type ReasonDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Reason codes reported in Output.Reasons.
+const (
+	ReasonWithinLimits      = "within_limits"
+	ReasonFlatByDesign      = "flat_by_design"
+	ReasonRestricted        = "restricted"
+	ReasonInvalidInput      = "invalid_input"
+	ReasonCircuitOpen       = "circuit_open"
+	ReasonDrawdownFloor     = "drawdown_floor"
+	ReasonDrawdownThrottle  = "drawdown_throttle"
+	ReasonHighVolatility    = "high_volatility"
+	ReasonWeakConfidence    = "weak_confidence"
+	ReasonDownsideRisk      = "downside_risk"
+	ReasonPaperPositionCap  = "paper_position_cap"
+	ReasonGrossLeverageCap  = "gross_leverage_cap"
+	ReasonShortExposureCap  = "short_exposure_cap"
+	ReasonBelowLotSize      = "below_lot_size"
+	ReasonReversalCooldown  = "reversal_cooldown"
+	ReasonSectorAtCap       = "sector_at_cap"
+	ReasonSectorCapExceeded = "sector_cap_exceeded"
+	ReasonAwaitingApproval  = "awaiting_approval"
+	ReasonHumanRejected     = "human_rejected"
+	ReasonUnknownProfile    = "unknown_profile"
+	ReasonMarketBeta        = "market_beta"
+)
+
+type reasonList []ReasonDetail
+
+func (r reasonList) add(code, message string) reasonList {
+	return append(r, ReasonDetail{Code: code, Message: message})
+}
+
+func (r reasonList) String() string {
+	messages := make([]string, len(r))
+	for i, reason := range r {
+		messages[i] = reason.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// RecentAction is a previously decided trade.
+type RecentAction struct {
+	Symbol    string    `json:"symbol"`
+	Action    string    `json:"action"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 type Output struct {
-	Decision      string  `json:"decision"`
-	Reason        string  `json:"reason"`
+	Decision string `json:"decision"`
+	// Reason joins the messages in Reasons with "; "; key off Reasons codes instead.
+	Reason        string         `json:"reason"`
+	Reasons       []ReasonDetail `json:"reasons"`
+	PositionSize  float64        `json:"positionSize"`
+	ExpectedRisk  float64        `json:"expectedRisk"`
+	Confidence    float64        `json:"confidence"`
+	Volatility    float64        `json:"volatility"`
+	ConstraintHit bool           `json:"constraintHit"`
+	// SectorExposure is the sector's notional after this trade; SectorLimit is the cap it was checked against.
+	Sector         string  `json:"sector,omitempty"`
+	SectorExposure float64 `json:"sectorExposure,omitempty"`
+	SectorLimit    float64 `json:"sectorLimit,omitempty"`
+	// GrossLeverage and ShortExposure report the book after this trade as multiples of portfolio value.
+	GrossLeverage float64 `json:"grossLeverage"`
+	ShortExposure float64 `json:"shortExposure,omitempty"`
+	// SizingUnit echoes the unit the trade was sized in.
+	SizingUnit string `json:"sizingUnit"`
+	// SizingMethod is the method the trade was sized with: "atr" only when ATR
+	// sizing was requested and the ATR and entry price were available.
+	SizingMethod string `json:"sizingMethod"`
+	// UnitsOfRisk, in ATR sizing, is the loss the final position takes on an
+	// ATRMultiple-ATR adverse move as a multiple of the risk budget: 1 when the
+	// size was not cut by any cap or throttle.
+	UnitsOfRisk float64 `json:"unitsOfRisk,omitempty"`
+	// Shares and UnroundedPositionSize are reported when Input.SharePrice is set;
+	// PositionSize is then the rounded notional. In shares mode Shares is the size
+	// to submit.
+	Shares                int64   `json:"shares,omitempty"`
+	UnroundedPositionSize float64 `json:"unroundedPositionSize,omitempty"`
+	// SymbolOverride is set when a per-symbol override replaced the default thresholds.
+	SymbolOverride bool `json:"symbolOverride,omitempty"`
+	// MarketShare is the share of the name's variance explained by the benchmark,
+	// reported when Input.Beta and Input.BenchmarkVolatility are set.
+	MarketShare float64 `json:"marketShare,omitempty"`
+	// Profile is the lower-cased name of the risk profile applied, if any.
+	Profile string `json:"profile,omitempty"`
+	// DrawdownScale is the sizing multiplier applied for Input.CurrentDrawdown, when below 1.
+	DrawdownScale float64 `json:"drawdownScale,omitempty"`
+	// Scenarios is populated when Input.Scenarios is set.
+	Scenarios []SizingScenario `json:"scenarios,omitempty"`
+	// Sweep is populated when Input.ConfidenceSweep is set, one point per level.
+	Sweep []SweepPoint `json:"sweep,omitempty"`
+	// Approval is the human approver's verdict ("approved", "rejected" or
+	// "pending") when the position size required one.
+	Approval string `json:"approval,omitempty"`
+	// Explanation walks through the evaluation when Input.Explain is set.
+	Explanation []string `json:"explanation,omitempty"`
+	// Error describes the invalid input behind an invalid_input rejection.
+	Error string `json:"error,omitempty"`
+}
+
+// SweepPoint is the decision the check reaches at one confidence level. Confidence
+// is the level requested; the haircut, if any, still applies to it.
+type SweepPoint struct {
+	Confidence    float64        `json:"confidence"`
+	Decision      string         `json:"decision"`
+	PositionSize  float64        `json:"positionSize"`
+	Shares        int64          `json:"shares,omitempty"`
+	ConstraintHit bool           `json:"constraintHit"`
+	Reasons       []ReasonDetail `json:"reasons"`
+}
+
+// maxSweepPoints bounds Input.ConfidenceSweep; later levels are ignored.
+const maxSweepPoints = 21
+
+// SizingScenario sizes the trade at an alternative risk budget. VaR is the one-day
+// 95% parametric value at risk of the position at the input volatility.
+type SizingScenario struct {
+	Name          string  `json:"name"`
+	MaxRiskBps    float64 `json:"maxRiskBps"`
 	PositionSize  float64 `json:"positionSize"`
-	ExpectedRisk  float64 `json:"expectedRisk"`
-	Confidence    float64 `json:"confidence"`
-	Volatility    float64 `json:"volatility"`
+	VaR           float64 `json:"var"`
 	ConstraintHit bool    `json:"constraintHit"`
+	// Shares is set in shares mode, where PositionSize and VaR use the rounded notional.
+	Shares int64 `json:"shares,omitempty"`
+}
+
+// DrawdownTier scales position size by Scale once the drawdown from peak reaches
+// Drawdown, both as fractions (e.g. {0.05, 0.5} halves sizing at -5%).
+type DrawdownTier struct {
+	Drawdown float64
+	Scale    float64
+}
+
+// DefaultDrawdownTiers halve sizing at a 5% drawdown and quarter it at 10%.
+var DefaultDrawdownTiers = []DrawdownTier{{Drawdown: 0.05, Scale: 0.5}, {Drawdown: 0.10, Scale: 0.25}}
+
+// scenarioBudgets are the risk budgets, in basis points, behind each sizing scenario.
+var scenarioBudgets = []struct {
+	name string
+	bps  float64
+}{
+	{"conservative", 25},
+	{"base", 50},
+	{"aggressive", 100},
+}
+
+// CircuitBreaker reports whether trading has been halted, e.g. after a run of rejections.
+type CircuitBreaker interface {
+	CircuitOpen() bool
+}
+
+type config struct {
+	defaultPortfolioValue float64
+	maxPositionFraction   float64
+	sectorCap             float64
+	shortCap              float64
+	paperPositionCap      float64
+	reversalCooldown      time.Duration
+	drawdownTiers         []DrawdownTier
+	allowed               map[string]bool
+	blocked               map[string]bool
+	drawdownFloor         float64
+	breaker               CircuitBreaker
+	overrides             *overrideStore
+	profiles              map[string]RiskProfile
+	betaThreshold         float64
+	betaReview            bool
+	approval              *approvalHook
+	now                   func() time.Time
+}
+
+// Option customises the risk tool.
+type Option func(*config)
+
+// WithMaxPositionFraction sets the single-position cap as a fraction of portfolio value.
+func WithMaxPositionFraction(fraction float64) Option {
+	return func(c *config) {
+		c.maxPositionFraction = fraction
+	}
+}
+
+// WithSectorCap sets the maximum long exposure to one sector as a fraction of portfolio value.
+func WithSectorCap(fraction float64) Option {
+	return func(c *config) {
+		c.sectorCap = fraction
+	}
+}
+
+// WithShortCap sets the maximum aggregate short exposure as a fraction of portfolio value.
+func WithShortCap(fraction float64) Option {
+	return func(c *config) {
+		c.shortCap = fraction
+	}
+}
+
+// WithPaperPositionCap applies a tighter single-position cap, as a fraction of portfolio
+// value, for orchestrators running in paper mode.
+func WithPaperPositionCap(fraction float64) Option {
+	return func(c *config) {
+		c.paperPositionCap = fraction
+	}
+}
+
+// WithReversalCooldown sets how long after a trade an opposing trade on the same
+// symbol is downgraded to REVIEW. Zero disables the check.
+func WithReversalCooldown(d time.Duration) Option {
+	return func(c *config) {
+		c.reversalCooldown = d
+	}
+}
+
+// WithDrawdownTiers replaces the sizing throttle tiers; the deepest tier reached applies.
+// No tiers disables throttling.
+func WithDrawdownTiers(tiers ...DrawdownTier) Option {
+	return func(c *config) {
+		c.drawdownTiers = tiers
+	}
+}
+
+// WithDrawdownFloor sets the drawdown, as a fraction, at which every trade is rejected.
+func WithDrawdownFloor(fraction float64) Option {
+	return func(c *config) {
+		c.drawdownFloor = fraction
+	}
+}
+
+// WithSymbolRestrictions rejects every trade in a blocked symbol and, when allowed is
+// non-empty, every trade in a symbol not on it. Symbols are matched case-insensitively.
+func WithSymbolRestrictions(allowed, blocked []string) Option {
+	return func(c *config) {
+		c.allowed = symbolSet(allowed)
+		c.blocked = symbolSet(blocked)
+	}
+}
+
+func symbolSet(symbols []string) map[string]bool {
+	set := map[string]bool{}
+	for _, symbol := range symbols {
+		if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+			set[symbol] = true
+		}
+	}
+	return set
+}
+
+// WithCircuitBreaker rejects every trade while breaker reports the circuit open.
+func WithCircuitBreaker(breaker CircuitBreaker) Option {
+	return func(c *config) {
+		c.breaker = breaker
+	}
 }
 
-func New(defaultPortfolioValue float64) (tool.Tool, error) {
+func newConfig(defaultPortfolioValue float64, opts ...Option) config {
+	cfg := config{
+		defaultPortfolioValue: defaultPortfolioValue,
+		maxPositionFraction:   MaxPositionFraction,
+		sectorCap:             DefaultSectorCap,
+		shortCap:              DefaultShortCap,
+		reversalCooldown:      DefaultReversalCooldown,
+		drawdownTiers:         DefaultDrawdownTiers,
+		drawdownFloor:         DefaultDrawdownFloor,
+		betaThreshold:         DefaultBetaThreshold,
+		now:                   time.Now,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+func New(defaultPortfolioValue float64, opts ...Option) (tool.Tool, error) {
 	if defaultPortfolioValue <= 0 {
 		return nil, errors.New("default portfolio value must be positive")
 	}
+	cfg := newConfig(defaultPortfolioValue, opts...)
+	if cfg.maxPositionFraction <= 0 || cfg.maxPositionFraction > 1 {
+		return nil, errors.New("max position fraction must be in (0, 1]")
+	}
+	if cfg.sectorCap <= 0 || cfg.sectorCap > 1 {
+		return nil, errors.New("sector cap must be in (0, 1]")
+	}
+	if cfg.shortCap < 0 {
+		return nil, errors.New("short cap must not be negative")
+	}
+	if cfg.paperPositionCap < 0 || cfg.paperPositionCap > 1 {
+		return nil, errors.New("paper position cap must be in [0, 1]")
+	}
+	if cfg.reversalCooldown < 0 {
+		return nil, errors.New("reversal cooldown must not be negative")
+	}
+	if cfg.betaThreshold <= 0 {
+		return nil, fmt.Errorf("beta threshold must be positive, got %g", cfg.betaThreshold)
+	}
+	if cfg.drawdownFloor <= 0 || cfg.drawdownFloor > 1 {
+		return nil, errors.New("drawdown floor must be in (0, 1]")
+	}
+	for _, tier := range cfg.drawdownTiers {
+		if tier.Drawdown <= 0 || tier.Drawdown >= cfg.drawdownFloor || tier.Scale <= 0 || tier.Scale > 1 {
+			return nil, fmt.Errorf("drawdown tier %+v must sit above the floor with a scale in (0, 1]", tier)
+		}
+	}
+	if cfg.overrides != nil {
+		if cfg.overrides.reload < 0 {
+			return nil, errors.New("symbol override reload interval must not be negative")
+		}
+		cfg.overrides.now = cfg.now
+		if err := cfg.overrides.load(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.profiles != nil {
+		profiles, err := normalizeProfiles(cfg.profiles)
+		if err != nil {
+			return nil, err
+		}
+		cfg.profiles = profiles
+	}
+	if cfg.approval != nil {
+		if strings.TrimSpace(cfg.approval.url) == "" {
+			return nil, errors.New("approval URL is required")
+		}
+		if cfg.approval.threshold < 0 {
+			return nil, errors.New("approval threshold must not be negative")
+		}
+		if cfg.approval.timeout < 0 {
+			return nil, errors.New("approval timeout must not be negative")
+		}
+		if cfg.approval.timeout == 0 {
+			cfg.approval.timeout = DefaultApprovalTimeout
+		}
+	}
 	handler := func(ctx tool.Context, input Input) Output {
-		portfolioValue := input.PortfolioValue
-		if portfolioValue <= 0 {
-			portfolioValue = defaultPortfolioValue
+		out := evaluate(cfg, input)
+		if cfg.approval.needsApproval(out) {
+			out = cfg.approval.approve(ctx, input, out, ctx.InvocationID())
+			if out.Explanation != nil {
+				out.Explanation = append(out.Explanation, fmt.Sprintf("human approval %s: decision %s", out.Approval, out.Decision))
+			}
 		}
+		slog.Debug("risk budget checked", "invocation", ctx.InvocationID(), "symbol", input.Symbol,
+			"decision", out.Decision, "position_size", out.PositionSize)
+		return out
+	}
+	return functiontool.New(functiontool.Config{
+		Name:        "risk_budget_check",
+		Description: "Evaluate the proposed trade against volatility-adjusted (or, with sizingMethod \"atr\", ATR-based) risk budgets and return an approval decision.",
+	}, handler)
+}
 
-		maxRiskBps := input.MaxRiskBps
-		if maxRiskBps <= 0 {
-			maxRiskBps = 50 // 0.5%
-		}
+// PositionSize returns the volatility-adjusted notional for a trade and whether the
+// default single-position cap clipped it. Non-positive maxRiskBps falls back to DefaultMaxRiskBps.
+func PositionSize(portfolioValue, maxRiskBps, volatility float64) (float64, bool) {
+	return positionSize(portfolioValue, maxRiskBps, volatility, MaxPositionFraction)
+}
 
-		riskBudget := portfolioValue * (maxRiskBps / 10000.0)
-		vol := math.Max(input.Volatility, 0.01)
-		confidence := clamp(input.Confidence, 0.0, 1.0)
+// positionSize is PositionSize with an explicit single-position cap.
+func positionSize(portfolioValue, maxRiskBps, volatility, maxFraction float64) (float64, bool) {
+	if maxRiskBps <= 0 {
+		maxRiskBps = DefaultMaxRiskBps
+	}
+	riskBudget := portfolioValue * (maxRiskBps / 10000.0)
+	vol := math.Max(volatility, 0.01)
 
-		positionSize := riskBudget / (vol * 10)
-		constraintHit := false
-		if positionSize > portfolioValue*0.1 {
-			positionSize = portfolioValue * 0.1
-			constraintHit = true
-		}
+	size := riskBudget / (vol * 10)
+	if size > portfolioValue*maxFraction {
+		return portfolioValue * maxFraction, true
+	}
+	return size, false
+}
 
-		decision := "APPROVE"
-		reasonBuilder := []string{}
+// atrPositionSize returns the notional at which an adverse move of multiple ATRs
+// from entryPrice loses the risk budget, and whether maxFraction clipped it.
+func atrPositionSize(portfolioValue, maxRiskBps, entryPrice, atr, multiple, maxFraction float64) (float64, bool) {
+	if maxRiskBps <= 0 {
+		maxRiskBps = DefaultMaxRiskBps
+	}
+	riskBudget := portfolioValue * (maxRiskBps / 10000.0)
+	size := riskBudget / (multiple * atr) * entryPrice
+	if size > portfolioValue*maxFraction {
+		return portfolioValue * maxFraction, true
+	}
+	return size, false
+}
 
-		if vol > 0.8 {
-			decision = "REJECT"
-			reasonBuilder = append(reasonBuilder, "volatility too high ")
+func evaluate(cfg config, input Input) Output {
+	if err := input.validate(); err != nil {
+		return Output{
+			Decision: "REJECT",
+			Reason:   err.Error(),
+			Reasons:  []ReasonDetail{{Code: ReasonInvalidInput, Message: err.Error()}},
+			Error:    err.Error(),
 		}
-		if confidence < 0.35 {
-			decision = "REVIEW"
-			reasonBuilder = append(reasonBuilder, "confidence weak")
+	}
+	if len(input.ConfidenceSweep) > 0 {
+		levels := input.ConfidenceSweep
+		input.ConfidenceSweep = nil
+		out := evaluate(cfg, input)
+		input.Explain = false
+		out.Sweep = confidenceSweep(cfg, input, levels)
+		return out
+	}
+	var steps *explanation
+	if input.Explain {
+		steps = &explanation{}
+	}
+	portfolioValue := input.PortfolioValue
+	if portfolioValue <= 0 {
+		portfolioValue = cfg.defaultPortfolioValue
+	}
+
+	maxRiskBps := input.MaxRiskBps
+	budgetSource := "requested"
+	if maxRiskBps <= 0 {
+		maxRiskBps, budgetSource = DefaultMaxRiskBps, "default"
+	}
+	rejectVolatility := DefaultRejectVolatility
+	var profileName, profileNote string
+	if name := strings.TrimSpace(input.Profile); name != "" {
+		if profile, ok := cfg.profile(name); ok {
+			profileName = strings.ToLower(name)
+			if profile.MaxRiskBps > 0 && (input.MaxRiskBps <= 0 || input.MaxRiskBps > profile.MaxRiskBps) {
+				maxRiskBps, budgetSource = profile.MaxRiskBps, "profile "+profileName
+			}
+			if profile.RejectVolatility > 0 {
+				rejectVolatility = profile.RejectVolatility
+			}
+			if profile.MaxPositionFraction > 0 {
+				cfg.maxPositionFraction = profile.MaxPositionFraction
+			}
+		} else {
+			profileNote = fmt.Sprintf("unknown risk profile %q; default thresholds applied", name)
 		}
-		if strings.ToUpper(input.Action) == "SELL" && confidence >= 0.5 && vol > 0.4 {
-			reasonBuilder = append(reasonBuilder, "elevated downside risk")
+	}
+	var override SymbolOverride
+	var overridden bool
+	if cfg.overrides != nil {
+		override, overridden = cfg.overrides.lookup(input.Symbol)
+	}
+	if overridden {
+		if override.MaxRiskBps > 0 && (input.MaxRiskBps <= 0 || maxRiskBps > override.MaxRiskBps) {
+			maxRiskBps, budgetSource = override.MaxRiskBps, "symbol override"
 		}
-
-		reason := strings.TrimSpace(strings.Join(reasonBuilder, "; "))
-		if reason == "" {
-			reason = "Risk within configured thresholds."
+		if override.RejectVolatility > 0 {
+			rejectVolatility = override.RejectVolatility
+		}
+		if override.MaxPositionFraction > 0 {
+			cfg.maxPositionFraction = override.MaxPositionFraction
 		}
+	}
 
-		return Output{
-			Decision:      decision,
+	riskBudget := portfolioValue * (maxRiskBps / 10000.0)
+	vol := math.Max(input.Volatility, 0.01)
+	confidence := clamp(input.Confidence, 0.0, 1.0) * (1 - clamp(input.ConfidenceHaircut, 0.0, 1.0))
+	steps.addf("risk budget: portfolio %.2f x %g bps (%s) = %.2f", portfolioValue, maxRiskBps, budgetSource, riskBudget)
+	steps.addf("confidence %.2f after clamping to [0, 1] and a %.0f%% haircut; volatility %.4f (floored at 0.01)",
+		confidence, clamp(input.ConfidenceHaircut, 0.0, 1.0)*100, vol)
+
+	// A HOLD is a deliberate decision to stay flat: nothing to size, nothing to reject.
+	if strings.ToUpper(strings.TrimSpace(input.Action)) == "HOLD" {
+		steps.addf("HOLD stays flat: nothing is sized and no limit applies")
+		return steps.attach(Output{
+			Decision:      "APPROVE",
+			Reason:        "flat by design",
+			Reasons:       []ReasonDetail{{Code: ReasonFlatByDesign, Message: "flat by design"}},
+			ExpectedRisk:  riskBudget,
+			Confidence:    confidence,
+			Volatility:    vol,
+			GrossLeverage: input.CurrentGrossLeverage,
+		})
+	}
+
+	unit := strings.ToLower(strings.TrimSpace(input.SizingUnit))
+	if unit == "" {
+		unit = SizingUnitNotional
+	}
+	method := strings.ToLower(strings.TrimSpace(input.SizingMethod))
+	if method == "" {
+		method = SizingMethodVolatility
+	}
+	invalid := sizingUnitError(unit, input.SharePrice)
+	if invalid == "" && method != SizingMethodVolatility && method != SizingMethodATR {
+		invalid = fmt.Sprintf("unsupported sizing method %q: use %q or %q", method, SizingMethodVolatility, SizingMethodATR)
+	}
+	if invalid != "" {
+		return steps.attach(Output{
+			Decision:      "REJECT",
+			Reason:        invalid,
+			Reasons:       []ReasonDetail{{Code: ReasonInvalidInput, Message: invalid}},
+			ExpectedRisk:  riskBudget,
+			Confidence:    confidence,
+			Volatility:    vol,
+			GrossLeverage: input.CurrentGrossLeverage,
+			SizingUnit:    unit,
+			Error:         invalid,
+		})
+	}
+
+	// Restricted symbols are a compliance control: reject before anything is sized.
+	if reason := cfg.restriction(input.Symbol); reason != "" {
+		return steps.attach(Output{
+			Decision:      "REJECT",
+			Reason:        reason,
+			Reasons:       []ReasonDetail{{Code: ReasonRestricted, Message: reason}},
+			ExpectedRisk:  riskBudget,
+			Confidence:    confidence,
+			Volatility:    vol,
+			GrossLeverage: input.CurrentGrossLeverage,
+		})
+	}
+
+	// While the circuit is open nothing is sized: the book stays put until an operator resets it.
+	if cfg.breaker != nil && cfg.breaker.CircuitOpen() {
+		const reason = "circuit open: trading halted after repeated rejections; reset via /reset"
+		return steps.attach(Output{
+			Decision:      "REJECT",
 			Reason:        reason,
-			PositionSize:  positionSize,
+			Reasons:       []ReasonDetail{{Code: ReasonCircuitOpen, Message: reason}},
 			ExpectedRisk:  riskBudget,
 			Confidence:    confidence,
 			Volatility:    vol,
-			ConstraintHit: constraintHit,
+			GrossLeverage: input.CurrentGrossLeverage,
+		})
+	}
+
+	entryPrice := input.EntryPrice
+	if entryPrice <= 0 {
+		entryPrice = input.SharePrice
+	}
+	atrMultiple := input.ATRMultiple
+	if atrMultiple <= 0 {
+		atrMultiple = DefaultATRMultiple
+	}
+	// ATR sizing needs both an ATR and a price to turn it into a notional.
+	if method == SizingMethodATR && (input.ATR <= 0 || entryPrice <= 0) {
+		method = SizingMethodVolatility
+	}
+	size := func(bps float64) (float64, bool) {
+		if method == SizingMethodATR {
+			return atrPositionSize(portfolioValue, bps, entryPrice, input.ATR, atrMultiple, cfg.maxPositionFraction)
 		}
+		return positionSize(portfolioValue, bps, vol, cfg.maxPositionFraction)
 	}
-	return functiontool.New(functiontool.Config{
-		Name:        "risk_budget_check",
-		Description: "Evaluate the proposed trade against volatility-adjusted risk budgets and return an approval decision.",
-	}, handler)
+	positionSize, constraintHit := size(maxRiskBps)
+	positionCap := portfolioValue * cfg.maxPositionFraction
+	if method == SizingMethodATR {
+		steps.addf("ATR size: budget %.2f / (%g x ATR %.4f) x entry %.2f = %.2f",
+			riskBudget, atrMultiple, input.ATR, entryPrice, riskBudget/(atrMultiple*input.ATR)*entryPrice)
+	} else {
+		steps.addf("volatility-adjusted size: budget %.2f / (volatility %.4f x 10) = %.2f", riskBudget, vol, riskBudget/(vol*10))
+	}
+	if constraintHit {
+		steps.addf("single-position cap %.0f%% of portfolio = %.2f: size clipped to it", cfg.maxPositionFraction*100, positionCap)
+	} else {
+		steps.addf("single-position cap %.0f%% of portfolio = %.2f: not reached", cfg.maxPositionFraction*100, positionCap)
+	}
+
+	decision := "APPROVE"
+	var reasons reasonList
+	if profileNote != "" {
+		reasons = reasons.add(ReasonUnknownProfile, profileNote)
+	}
+
+	drawdown := math.Abs(input.CurrentDrawdown)
+	drawdownScale := 1.0
+	if drawdown >= cfg.drawdownFloor {
+		positionSize = 0
+		decision = "REJECT"
+		steps.addf("drawdown %.1f%% at or beyond the %.0f%% floor: size 0, REJECT", drawdown*100, cfg.drawdownFloor*100)
+		reasons = reasons.add(ReasonDrawdownFloor, fmt.Sprintf("drawdown %.1f%% at or beyond the %.0f%% floor", drawdown*100, cfg.drawdownFloor*100))
+	} else if tier, ok := drawdownTier(cfg.drawdownTiers, drawdown); ok {
+		drawdownScale = tier.Scale
+		positionSize *= tier.Scale
+		steps.addf("drawdown %.1f%% reaches the %.0f%% tier: size x %g = %.2f", drawdown*100, tier.Drawdown*100, tier.Scale, positionSize)
+		reasons = reasons.add(ReasonDrawdownThrottle, fmt.Sprintf("drawdown %.1f%% throttles size to %.0f%%", drawdown*100, tier.Scale*100))
+	}
+
+	if vol > rejectVolatility {
+		decision = "REJECT"
+		steps.addf("volatility %.4f above the %g reject threshold: REJECT", vol, rejectVolatility)
+		reasons = reasons.add(ReasonHighVolatility, "volatility too high")
+	}
+	if confidence < 0.35 {
+		decision = "REVIEW"
+		steps.addf("confidence %.2f below 0.35: REVIEW", confidence)
+		reasons = reasons.add(ReasonWeakConfidence, "confidence weak")
+	}
+	if strings.ToUpper(input.Action) == "SELL" && confidence >= 0.5 && vol > 0.4 {
+		reasons = reasons.add(ReasonDownsideRisk, "elevated downside risk")
+	}
+	share, shareOK := marketShare(input.Beta, input.BenchmarkVolatility, vol)
+	if shareOK && input.Beta > cfg.betaThreshold && share >= MarketDrivenShare {
+		reasons = reasons.add(ReasonMarketBeta, fmt.Sprintf("beta %.2f above %.2f: %.0f%% of volatility is market-driven", input.Beta, cfg.betaThreshold, share*100))
+		if cfg.betaReview {
+			decision = escalate(decision, "REVIEW")
+			steps.addf("beta %.2f above %.2f with %.0f%% market share of variance: at least REVIEW", input.Beta, cfg.betaThreshold, share*100)
+		}
+	}
+
+	if cfg.paperPositionCap > 0 && positionSize > portfolioValue*cfg.paperPositionCap {
+		positionSize = portfolioValue * cfg.paperPositionCap
+		constraintHit = true
+		steps.addf("paper mode cap %.0f%% of portfolio: size clipped to %.2f", cfg.paperPositionCap*100, positionSize)
+		reasons = reasons.add(ReasonPaperPositionCap, fmt.Sprintf("paper mode position cap %.0f%%", cfg.paperPositionCap*100))
+	}
+
+	maxGross := input.MaxGrossLeverage
+	if maxGross <= 0 {
+		maxGross = DefaultMaxGrossLeverage
+	}
+	if headroom := math.Max((maxGross-input.CurrentGrossLeverage)*portfolioValue, 0); positionSize > headroom {
+		positionSize = headroom
+		constraintHit = true
+		steps.addf("gross leverage headroom (%.2fx - %.2fx) x portfolio = %.2f: size clipped to it", maxGross, input.CurrentGrossLeverage, headroom)
+		reasons = reasons.add(ReasonGrossLeverageCap, fmt.Sprintf("gross leverage capped at %.2fx (currently %.2fx)", maxGross, input.CurrentGrossLeverage))
+	}
+	if strings.ToUpper(input.Action) == "SELL" {
+		if headroom := math.Max((cfg.shortCap-input.CurrentShortExposure)*portfolioValue, 0); positionSize > headroom {
+			positionSize = headroom
+			constraintHit = true
+			steps.addf("short exposure headroom (%.2fx - %.2fx) x portfolio = %.2f: size clipped to it", cfg.shortCap, input.CurrentShortExposure, headroom)
+			reasons = reasons.add(ReasonShortExposureCap, fmt.Sprintf("short exposure capped at %.2fx (currently %.2fx)", cfg.shortCap, input.CurrentShortExposure))
+		}
+	}
+	var shares int64
+	unrounded := positionSize
+	lot := input.LotSize
+	if lot <= 0 {
+		lot = 1
+	}
+	if input.SharePrice > 0 {
+		shares, positionSize = roundToLots(positionSize, input.SharePrice, lot)
+		steps.addf("rounded %.2f down to %d shares in lots of %d at %.2f = %.2f", unrounded, shares, lot, input.SharePrice, positionSize)
+		if shares == 0 && unrounded > 0 {
+			reasons = reasons.add(ReasonBelowLotSize, fmt.Sprintf("budget %.2f is below one lot of %d shares at %.2f", unrounded, lot, input.SharePrice))
+		}
+	}
+	var shortExposure float64
+	if strings.ToUpper(input.Action) == "SELL" {
+		shortExposure = input.CurrentShortExposure + positionSize/portfolioValue
+	}
+	if positionSize == 0 {
+		decision = escalate(decision, "REJECT")
+		steps.addf("nothing left to trade after sizing: REJECT")
+	}
+
+	if prior, ok := recentReversal(input, cfg.now(), cfg.reversalCooldown); ok {
+		decision = escalate(decision, "REVIEW")
+		steps.addf("reverses a %s within the %s cooldown: at least REVIEW", strings.ToUpper(prior.Action), cfg.reversalCooldown)
+		reasons = reasons.add(ReasonReversalCooldown, fmt.Sprintf("%s reverses %s %s at %s within %s cooldown",
+			strings.ToUpper(input.Action), strings.ToUpper(prior.Action), strings.ToUpper(prior.Symbol),
+			prior.Timestamp.UTC().Format(time.RFC3339), cfg.reversalCooldown))
+	}
+
+	var sector string
+	var sectorExposure, sectorLimit float64
+	if name := strings.TrimSpace(input.Sector); name != "" && input.SectorExposures != nil {
+		sector = name
+		current := input.SectorExposures[sector]
+		sectorExposure = current
+		buy := strings.ToUpper(input.Action) == "BUY"
+		if buy {
+			sectorExposure += positionSize
+		}
+		sectorLimit = portfolioValue * cfg.sectorCap
+		switch {
+		case !buy:
+			// Only buys add to the sector; selling at or over the cap reduces it.
+		case current >= sectorLimit:
+			decision = escalate(decision, "REJECT")
+			steps.addf("sector %s already at %.2f of its %.2f cap: REJECT", sector, current, sectorLimit)
+			reasons = reasons.add(ReasonSectorAtCap, fmt.Sprintf("sector %s already at cap (%.0f of %.0f)", sector, current, sectorLimit))
+		case sectorExposure > sectorLimit:
+			decision = escalate(decision, "REVIEW")
+			steps.addf("sector %s would reach %.2f, above its %.2f cap: at least REVIEW", sector, sectorExposure, sectorLimit)
+			reasons = reasons.add(ReasonSectorCapExceeded, fmt.Sprintf("sector %s exposure would reach %.0f, above cap %.0f", sector, sectorExposure, sectorLimit))
+		}
+	}
+
+	if len(reasons) == 0 {
+		reasons = reasons.add(ReasonWithinLimits, "Risk within configured thresholds.")
+	}
+
+	var scenarios []SizingScenario
+	if input.Scenarios {
+		scenarios = sizingScenarios(size, vol)
+		if unit == SizingUnitShares {
+			for i := range scenarios {
+				scenarios[i].Shares, scenarios[i].PositionSize = roundToLots(scenarios[i].PositionSize, input.SharePrice, lot)
+				scenarios[i].VaR = valueAtRisk(scenarios[i].PositionSize, vol)
+			}
+		}
+	}
+
+	out := Output{
+		Decision:       decision,
+		Reason:         reasons.String(),
+		Reasons:        reasons,
+		PositionSize:   positionSize,
+		ExpectedRisk:   riskBudget,
+		Confidence:     confidence,
+		Volatility:     vol,
+		ConstraintHit:  constraintHit,
+		Sector:         sector,
+		SectorExposure: sectorExposure,
+		SectorLimit:    sectorLimit,
+		GrossLeverage:  input.CurrentGrossLeverage + positionSize/portfolioValue,
+		ShortExposure:  shortExposure,
+		Scenarios:      scenarios,
+		SizingUnit:     unit,
+		SizingMethod:   method,
+	}
+	if method == SizingMethodATR && riskBudget > 0 {
+		out.UnitsOfRisk = positionSize / entryPrice * atrMultiple * input.ATR / riskBudget
+	}
+	if drawdownScale < 1 {
+		out.DrawdownScale = drawdownScale
+	}
+	out.SymbolOverride = overridden
+	out.Profile = profileName
+	out.MarketShare = share
+	if input.SharePrice > 0 {
+		out.Shares = shares
+		out.UnroundedPositionSize = unrounded
+	}
+	return steps.attach(out)
+}
+
+// confidenceSweep evaluates input at each confidence level in levels, without
+// scenarios, which do not depend on confidence.
+func confidenceSweep(cfg config, input Input, levels []float64) []SweepPoint {
+	if len(levels) > maxSweepPoints {
+		levels = levels[:maxSweepPoints]
+	}
+	input.Scenarios = false
+	sweep := make([]SweepPoint, 0, len(levels))
+	for _, level := range levels {
+		input.Confidence = level
+		out := evaluate(cfg, input)
+		sweep = append(sweep, SweepPoint{
+			Confidence:    level,
+			Decision:      out.Decision,
+			PositionSize:  out.PositionSize,
+			Shares:        out.Shares,
+			ConstraintHit: out.ConstraintHit,
+			Reasons:       out.Reasons,
+		})
+	}
+	return sweep
+}
+
+// sizingScenarios sizes the trade at each scenario budget with size, which caps
+// each at the single-position limit on its own.
+func sizingScenarios(size func(bps float64) (float64, bool), vol float64) []SizingScenario {
+	scenarios := make([]SizingScenario, 0, len(scenarioBudgets))
+	for _, budget := range scenarioBudgets {
+		size, capped := size(budget.bps)
+		scenarios = append(scenarios, SizingScenario{
+			Name:          budget.name,
+			MaxRiskBps:    budget.bps,
+			PositionSize:  size,
+			VaR:           valueAtRisk(size, vol),
+			ConstraintHit: capped,
+		})
+	}
+	return scenarios
+}
+
+// valueAtRisk is the one-day 95% parametric VaR of notional at annualised volatility vol.
+func valueAtRisk(notional, vol float64) float64 {
+	return notional * 1.645 * vol / math.Sqrt(252)
+}
+
+// roundToLots rounds notional down to whole lots at price, returning the share
+// count and the notional it buys.
+func roundToLots(notional, price float64, lot int64) (int64, float64) {
+	shares := int64(math.Floor(notional/price/float64(lot))) * lot
+	return shares, float64(shares) * price
+}
+
+// sizingUnitError explains why unit cannot be used, or returns "" when it can.
+func sizingUnitError(unit string, sharePrice float64) string {
+	switch unit {
+	case SizingUnitNotional:
+		return ""
+	case SizingUnitShares:
+		if sharePrice <= 0 {
+			return "sizing in shares requires a positive sharePrice"
+		}
+		return ""
+	}
+	return fmt.Sprintf("unsupported sizing unit %q: use %q or %q", unit, SizingUnitNotional, SizingUnitShares)
+}
+
+// restriction explains why symbol may not be traded, or returns "" when it may.
+func (c config) restriction(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if c.blocked[symbol] {
+		return fmt.Sprintf("restricted: %s is on the blocked list", symbol)
+	}
+	if len(c.allowed) > 0 && !c.allowed[symbol] {
+		return fmt.Sprintf("restricted: %s is not on the allowed list", symbol)
+	}
+	return ""
+}
+
+// drawdownTier returns the deepest tier that drawdown has reached.
+func drawdownTier(tiers []DrawdownTier, drawdown float64) (DrawdownTier, bool) {
+	var deepest DrawdownTier
+	found := false
+	for _, tier := range tiers {
+		if drawdown >= tier.Drawdown && (!found || tier.Drawdown > deepest.Drawdown) {
+			deepest, found = tier, true
+		}
+	}
+	return deepest, found
+}
+
+// recentReversal returns the latest recent action on the same symbol that the proposed
+// action reverses (BUY after SELL or SELL after BUY) within cooldown of now.
+func recentReversal(input Input, now time.Time, cooldown time.Duration) (RecentAction, bool) {
+	opposite := map[string]string{"BUY": "SELL", "SELL": "BUY"}[strings.ToUpper(strings.TrimSpace(input.Action))]
+	if opposite == "" || cooldown <= 0 {
+		return RecentAction{}, false
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(input.Symbol))
+	var latest RecentAction
+	found := false
+	for _, prior := range input.RecentActions {
+		if strings.ToUpper(strings.TrimSpace(prior.Symbol)) != symbol || strings.ToUpper(strings.TrimSpace(prior.Action)) != opposite {
+			continue
+		}
+		if age := now.Sub(prior.Timestamp); age < 0 || age > cooldown {
+			continue
+		}
+		if !found || prior.Timestamp.After(latest.Timestamp) {
+			latest, found = prior, true
+		}
+	}
+	return latest, found
+}
+
+// escalate returns the more severe of two decisions (APPROVE < REVIEW < REJECT).
+func escalate(current, proposed string) string {
+	severity := map[string]int{"APPROVE": 0, "REVIEW": 1, "REJECT": 2}
+	if severity[proposed] > severity[current] {
+		return proposed
+	}
+	return current
 }
 
 func clamp(v, min, max float64) float64 {
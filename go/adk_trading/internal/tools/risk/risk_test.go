@@ -1,69 +1,15 @@
 package risk
 
 import (
+	"math"
 	"strings"
 	"testing"
+	"time"
 )
 
-// testHandler wraps the handler logic for testing
+// testHandler runs the handler logic for testing
 func testHandler(defaultPortfolioValue float64, input Input) Output {
-	portfolioValue := input.PortfolioValue
-	if portfolioValue <= 0 {
-		portfolioValue = defaultPortfolioValue
-	}
-
-	maxRiskBps := input.MaxRiskBps
-	if maxRiskBps <= 0 {
-		maxRiskBps = 50 // 0.5%
-	}
-
-	riskBudget := portfolioValue * (maxRiskBps / 10000.0)
-	vol := max(input.Volatility, 0.01)
-	confidence := clamp(input.Confidence, 0.0, 1.0)
-
-	positionSize := riskBudget / (vol * 10)
-	constraintHit := false
-	if positionSize > portfolioValue*0.1 {
-		positionSize = portfolioValue * 0.1
-		constraintHit = true
-	}
-
-	decision := "APPROVE"
-	reasonBuilder := []string{}
-
-	if vol > 0.8 {
-		decision = "REJECT"
-		reasonBuilder = append(reasonBuilder, "volatility too high ")
-	}
-	if confidence < 0.35 {
-		decision = "REVIEW"
-		reasonBuilder = append(reasonBuilder, "confidence weak")
-	}
-	if strings.ToUpper(input.Action) == "SELL" && confidence >= 0.5 && vol > 0.4 {
-		reasonBuilder = append(reasonBuilder, "elevated downside risk")
-	}
-
-	reason := strings.TrimSpace(strings.Join(reasonBuilder, "; "))
-	if reason == "" {
-		reason = "Risk within configured thresholds."
-	}
-
-	return Output{
-		Decision:      decision,
-		Reason:        reason,
-		PositionSize:  positionSize,
-		ExpectedRisk:  riskBudget,
-		Confidence:    confidence,
-		Volatility:    vol,
-		ConstraintHit: constraintHit,
-	}
-}
-
-func max(a, b float64) float64 {
-	if a > b {
-		return a
-	}
-	return b
+	return evaluate(newConfig(defaultPortfolioValue), input)
 }
 
 func TestRiskTool_Approve(t *testing.T) {
@@ -156,6 +102,26 @@ func TestRiskTool_PositionSizeConstraint(t *testing.T) {
 	if !output.ConstraintHit {
 		t.Error("Expected ConstraintHit to be true when position is capped")
 	}
+
+	// A tighter 5% mandate clamps the same trade at 50,000.
+	output = evaluate(newConfig(1_000_000, WithMaxPositionFraction(0.05)), input)
+	if output.PositionSize != input.PortfolioValue*0.05 {
+		t.Errorf("Expected position clamped at the 5%% cap (%f), got %f", input.PortfolioValue*0.05, output.PositionSize)
+	}
+	if !output.ConstraintHit {
+		t.Error("Expected ConstraintHit to be true under the 5% cap")
+	}
+}
+
+func TestRiskTool_InvalidMaxPositionFraction(t *testing.T) {
+	for _, fraction := range []float64{-0.1, 0, 1.5} {
+		if _, err := New(1_000_000, WithMaxPositionFraction(fraction)); err == nil {
+			t.Errorf("Expected an error for max position fraction %v", fraction)
+		}
+	}
+	if _, err := New(1_000_000, WithMaxPositionFraction(1)); err != nil {
+		t.Errorf("Expected a 100%% cap to be valid, got %v", err)
+	}
 }
 
 func TestRiskTool_SellWithElevatedRisk(t *testing.T) {
@@ -251,3 +217,566 @@ func TestClamp(t *testing.T) {
 		})
 	}
 }
+
+func TestRiskTool_SectorCap(t *testing.T) {
+	base := Input{
+		Symbol:         "XLK",
+		Action:         "BUY",
+		Confidence:     0.75,
+		Volatility:     0.15,
+		PortfolioValue: 1_000_000,
+		MaxRiskBps:     50,
+		Sector:         "tech",
+	}
+
+	tests := []struct {
+		name         string
+		exposure     float64
+		wantDecision string
+	}{
+		{"well below cap", 100_000, "APPROVE"},
+		{"pushed past cap", 298_000, "REVIEW"},
+		{"already at cap", 300_000, "REJECT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := base
+			input.SectorExposures = map[string]float64{"tech": tt.exposure}
+			output := testHandler(1_000_000, input)
+			if output.Decision != tt.wantDecision {
+				t.Errorf("Expected %s, got %s (%s)", tt.wantDecision, output.Decision, output.Reason)
+			}
+			if output.SectorExposure != tt.exposure+output.PositionSize {
+				t.Errorf("Expected sector exposure %f, got %f", tt.exposure+output.PositionSize, output.SectorExposure)
+			}
+			if output.SectorLimit != 300_000 {
+				t.Errorf("Expected default 30%% sector limit of 300000, got %f", output.SectorLimit)
+			}
+		})
+	}
+}
+
+func TestRiskTool_SectorCapAllowsSells(t *testing.T) {
+	for _, exposure := range []float64{300_000, 350_000} {
+		output := testHandler(1_000_000, Input{
+			Symbol:          "XLK",
+			Action:          "SELL",
+			Confidence:      0.75,
+			Volatility:      0.15,
+			PortfolioValue:  1_000_000,
+			MaxRiskBps:      50,
+			Sector:          "tech",
+			SectorExposures: map[string]float64{"tech": exposure},
+		})
+		if output.Decision != "APPROVE" {
+			t.Errorf("Expected a SELL out of a sector at %.0f approved, got %s (%s)", exposure, output.Decision, output.Reason)
+		}
+		if output.SectorExposure != exposure {
+			t.Errorf("Expected a SELL to leave sector exposure at %.0f, got %f", exposure, output.SectorExposure)
+		}
+	}
+}
+
+func TestRiskTool_NoSectorInfoUnchanged(t *testing.T) {
+	input := Input{
+		Symbol:         "SPY",
+		Action:         "BUY",
+		Confidence:     0.75,
+		Volatility:     0.15,
+		PortfolioValue: 1_000_000,
+		Sector:         "tech",
+	}
+
+	output := testHandler(1_000_000, input)
+
+	if output.Decision != "APPROVE" || output.Sector != "" || output.SectorExposure != 0 {
+		t.Errorf("Expected sector check to be skipped without exposures, got %+v", output)
+	}
+}
+
+func TestRiskTool_InvalidSectorCap(t *testing.T) {
+	if _, err := New(1_000_000, WithSectorCap(1.5)); err == nil {
+		t.Error("Expected error for a sector cap above 100%")
+	}
+}
+
+func TestRiskTool_GrossLeverageShrinksPosition(t *testing.T) {
+	input := Input{
+		Symbol:               "SPY",
+		Action:               "BUY",
+		Confidence:           0.75,
+		Volatility:           0.15,
+		PortfolioValue:       1_000_000,
+		MaxRiskBps:           50,
+		CurrentGrossLeverage: 0.999,
+	}
+
+	output := testHandler(1_000_000, input)
+
+	if output.PositionSize > 1_000.01 {
+		t.Errorf("Expected position shrunk to the 1000 of leverage headroom, got %f", output.PositionSize)
+	}
+	if !output.ConstraintHit {
+		t.Error("Expected ConstraintHit when leverage caps the position")
+	}
+	if !strings.Contains(output.Reason, "leverage") {
+		t.Errorf("Expected reason to mention leverage, got %q", output.Reason)
+	}
+	if output.GrossLeverage > 1.0+1e-9 {
+		t.Errorf("Expected resulting gross leverage at most 1.0x, got %f", output.GrossLeverage)
+	}
+}
+
+func TestRiskTool_FullyLeveragedRejects(t *testing.T) {
+	input := Input{
+		Symbol:               "SPY",
+		Action:               "BUY",
+		Confidence:           0.75,
+		Volatility:           0.15,
+		PortfolioValue:       1_000_000,
+		CurrentGrossLeverage: 1.2,
+	}
+
+	output := testHandler(1_000_000, input)
+
+	if output.Decision != "REJECT" || output.PositionSize != 0 {
+		t.Errorf("Expected REJECT with zero size when already over leverage, got %s size %f", output.Decision, output.PositionSize)
+	}
+}
+
+func TestRiskTool_ShortCap(t *testing.T) {
+	input := Input{
+		Symbol:               "SPY",
+		Action:               "SELL",
+		Confidence:           0.75,
+		Volatility:           0.15,
+		PortfolioValue:       1_000_000,
+		CurrentGrossLeverage: 0.5,
+		CurrentShortExposure: 0.299,
+	}
+
+	output := testHandler(1_000_000, input)
+
+	if output.PositionSize > 1_000.01 {
+		t.Errorf("Expected short capped to the 1000 of short headroom, got %f", output.PositionSize)
+	}
+	if !strings.Contains(output.Reason, "short exposure") {
+		t.Errorf("Expected reason to mention short exposure, got %q", output.Reason)
+	}
+	if output.ShortExposure > DefaultShortCap+1e-9 {
+		t.Errorf("Expected resulting short exposure at most %f, got %f", DefaultShortCap, output.ShortExposure)
+	}
+}
+
+func TestRiskTool_PaperPositionCap(t *testing.T) {
+	input := Input{
+		Symbol:         "SPY",
+		Action:         "BUY",
+		Confidence:     0.90,
+		Volatility:     0.01,
+		PortfolioValue: 1_000_000,
+		MaxRiskBps:     500,
+	}
+
+	output := evaluate(newConfig(1_000_000, WithPaperPositionCap(0.02)), input)
+
+	if output.PositionSize != 20_000 {
+		t.Errorf("Expected paper cap to clamp the position to 20000, got %f", output.PositionSize)
+	}
+	if !output.ConstraintHit || !strings.Contains(output.Reason, "paper") {
+		t.Errorf("Expected a paper-mode constraint, got hit=%v reason=%q", output.ConstraintHit, output.Reason)
+	}
+}
+
+func TestRiskTool_ReversalCooldown(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	cfg := newConfig(1_000_000)
+	cfg.now = func() time.Time { return now }
+	input := Input{
+		Symbol:     "SPY",
+		Action:     "SELL",
+		Confidence: 0.7,
+		Volatility: 0.2,
+		RecentActions: []RecentAction{
+			{Symbol: "spy", Action: "BUY", Timestamp: now.Add(-10 * time.Minute)},
+			{Symbol: "QQQ", Action: "BUY", Timestamp: now.Add(-5 * time.Minute)},
+		},
+	}
+
+	out := evaluate(cfg, input)
+
+	if out.Decision != "REVIEW" {
+		t.Errorf("Expected REVIEW for a reversal inside the cooldown, got %s", out.Decision)
+	}
+	if !strings.Contains(out.Reason, "reverses BUY SPY") {
+		t.Errorf("Expected the reason to name the prior BUY, got %q", out.Reason)
+	}
+
+	input.RecentActions[0].Timestamp = now.Add(-2 * time.Hour)
+	if out := evaluate(cfg, input); out.Decision != "APPROVE" {
+		t.Errorf("Expected APPROVE once the cooldown has elapsed, got %s (%s)", out.Decision, out.Reason)
+	}
+
+	input.RecentActions[0] = RecentAction{Symbol: "SPY", Action: "SELL", Timestamp: now.Add(-time.Minute)}
+	if out := evaluate(cfg, input); out.Decision != "APPROVE" {
+		t.Errorf("Expected repeating the same action not to count as a reversal, got %s", out.Decision)
+	}
+}
+
+func TestRiskTool_SizingScenarios(t *testing.T) {
+	input := Input{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: 0.05, Scenarios: true}
+
+	out := testHandler(1_000_000, input)
+
+	if len(out.Scenarios) != 3 {
+		t.Fatalf("Expected 3 scenarios, got %d", len(out.Scenarios))
+	}
+	base, _ := PositionSize(1_000_000, 50, 0.05)
+	if out.PositionSize != base {
+		t.Errorf("Expected the primary size to stay at the base budget, got %f", out.PositionSize)
+	}
+	conservative, aggressive := out.Scenarios[0], out.Scenarios[2]
+	if conservative.Name != "conservative" || conservative.PositionSize != base/2 || conservative.ConstraintHit {
+		t.Errorf("Unexpected conservative scenario: %+v", conservative)
+	}
+	if aggressive.PositionSize != base*2 {
+		t.Errorf("Expected the aggressive scenario at twice the base size, got %+v", aggressive)
+	}
+	if aggressive.VaR <= conservative.VaR {
+		t.Errorf("Expected VaR to grow with size, got %f vs %f", aggressive.VaR, conservative.VaR)
+	}
+
+	if out := testHandler(1_000_000, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: 0.05}); out.Scenarios != nil {
+		t.Errorf("Expected no scenarios unless requested, got %+v", out.Scenarios)
+	}
+}
+
+func TestSizingScenarios_CappedIndependently(t *testing.T) {
+	// At the 1% volatility floor the aggressive budget lands exactly on the 10% cap.
+	scenarios := sizingScenarios(func(bps float64) (float64, bool) {
+		return positionSize(1_000_000, bps, 0.01, MaxPositionFraction)
+	}, 0.01)
+
+	if got := scenarios[2].PositionSize; got != 1_000_000*MaxPositionFraction {
+		t.Errorf("Expected the aggressive scenario at the position cap, got %f", got)
+	}
+	for _, s := range scenarios {
+		if s.PositionSize > 1_000_000*MaxPositionFraction {
+			t.Errorf("Scenario %s exceeds the position cap: %f", s.Name, s.PositionSize)
+		}
+	}
+}
+
+func TestRiskTool_LotRounding(t *testing.T) {
+	input := Input{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: 0.02, SharePrice: 450, LotSize: 10}
+
+	out := testHandler(1_000_000, input)
+
+	// A 5,000 risk budget at 2% vol sizes 25,000: 55.5 shares, rounded down to 50.
+	if out.UnroundedPositionSize != 25_000 {
+		t.Errorf("Expected unrounded size 25000, got %f", out.UnroundedPositionSize)
+	}
+	if out.Shares != 50 || out.PositionSize != 22_500 {
+		t.Errorf("Expected 50 shares worth 22500, got %d shares worth %f", out.Shares, out.PositionSize)
+	}
+
+	input.SharePrice = 30_000
+	input.LotSize = 0
+	if out := testHandler(1_000_000, input); out.Shares != 0 || out.Decision != "REJECT" {
+		t.Errorf("Expected REJECT when the budget buys no whole share, got %d shares and %s", out.Shares, out.Decision)
+	}
+
+	input.SharePrice = 0
+	if out := testHandler(1_000_000, input); out.PositionSize != 25_000 || out.Shares != 0 {
+		t.Errorf("Expected no rounding without a share price, got %f and %d shares", out.PositionSize, out.Shares)
+	}
+}
+
+func TestRiskTool_HoldIsFlatByDesign(t *testing.T) {
+	input := Input{Symbol: "SPY", Action: "hold", Confidence: 0.7, Volatility: 0.95, Scenarios: true}
+
+	out := testHandler(1_000_000, input)
+
+	if out.Decision != "APPROVE" {
+		t.Errorf("Expected HOLD to be approved despite high volatility, got %s (%s)", out.Decision, out.Reason)
+	}
+	if out.PositionSize != 0 || out.Reason != "flat by design" {
+		t.Errorf("Expected a zero position flat by design, got %f (%s)", out.PositionSize, out.Reason)
+	}
+}
+
+func TestRiskTool_ConfidenceHaircut(t *testing.T) {
+	input := Input{
+		Symbol:            "SPY",
+		Action:            "BUY",
+		Confidence:        0.6,
+		Volatility:        0.15,
+		PortfolioValue:    1_000_000,
+		ConfidenceHaircut: 0.5,
+	}
+
+	output := testHandler(1_000_000, input)
+	if output.Decision != "REVIEW" {
+		t.Errorf("Expected REVIEW for 0.6 confidence after a 0.5 haircut, got %s (%s)", output.Decision, output.Reason)
+	}
+	if output.Confidence != 0.3 {
+		t.Errorf("Expected adjusted confidence 0.3, got %f", output.Confidence)
+	}
+
+	input.ConfidenceHaircut = 0
+	if output := testHandler(1_000_000, input); output.Decision != "APPROVE" || output.Confidence != 0.6 {
+		t.Errorf("Expected an unadjusted APPROVE without a haircut, got %s at %f", output.Decision, output.Confidence)
+	}
+}
+
+type stubBreaker bool
+
+func (b stubBreaker) CircuitOpen() bool { return bool(b) }
+
+func TestRiskTool_CircuitOpenRejects(t *testing.T) {
+	input := Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.15, PortfolioValue: 1_000_000}
+
+	output := evaluate(newConfig(1_000_000, WithCircuitBreaker(stubBreaker(true))), input)
+	if output.Decision != "REJECT" || output.PositionSize != 0 || !strings.Contains(output.Reason, "circuit open") {
+		t.Errorf("Expected a circuit-open REJECT with no size, got %s %f (%s)", output.Decision, output.PositionSize, output.Reason)
+	}
+
+	output = evaluate(newConfig(1_000_000, WithCircuitBreaker(stubBreaker(false))), input)
+	if output.Decision != "APPROVE" {
+		t.Errorf("Expected APPROVE with the circuit closed, got %s (%s)", output.Decision, output.Reason)
+	}
+}
+
+func TestRiskTool_DrawdownThrottle(t *testing.T) {
+	input := Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.2, PortfolioValue: 1_000_000}
+	base := testHandler(1_000_000, input)
+
+	tests := []struct {
+		drawdown  float64
+		wantScale float64
+		wantDec   string
+	}{
+		{-0.03, 1, "APPROVE"},
+		{-0.05, 0.5, "APPROVE"},
+		{-0.12, 0.25, "APPROVE"},
+		{0.12, 0.25, "APPROVE"}, // sign-insensitive
+		{-0.20, 0, "REJECT"},
+	}
+	for _, tt := range tests {
+		input.CurrentDrawdown = tt.drawdown
+		out := testHandler(1_000_000, input)
+		if out.Decision != tt.wantDec {
+			t.Errorf("drawdown %v: expected %s, got %s (%s)", tt.drawdown, tt.wantDec, out.Decision, out.Reason)
+		}
+		if want := base.PositionSize * tt.wantScale; math.Abs(out.PositionSize-want) > 1e-6 {
+			t.Errorf("drawdown %v: expected size %f, got %f", tt.drawdown, want, out.PositionSize)
+		}
+		if tt.wantScale > 0 && tt.wantScale < 1 && (out.DrawdownScale != tt.wantScale || !strings.Contains(out.Reason, "drawdown")) {
+			t.Errorf("drawdown %v: expected scale %v noted in the reason, got %v (%s)", tt.drawdown, tt.wantScale, out.DrawdownScale, out.Reason)
+		}
+	}
+}
+
+func TestRiskTool_CustomDrawdownTiers(t *testing.T) {
+	cfg := newConfig(1_000_000, WithDrawdownTiers(DrawdownTier{Drawdown: 0.02, Scale: 0.8}), WithDrawdownFloor(0.06))
+	input := Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.2, PortfolioValue: 1_000_000, CurrentDrawdown: -0.03}
+	if out := evaluate(cfg, input); out.DrawdownScale != 0.8 {
+		t.Errorf("Expected the custom 80%% tier, got %v", out.DrawdownScale)
+	}
+	input.CurrentDrawdown = -0.06
+	if out := evaluate(cfg, input); out.Decision != "REJECT" {
+		t.Errorf("Expected REJECT at the custom floor, got %s", out.Decision)
+	}
+
+	if _, err := New(1_000_000, WithDrawdownFloor(0)); err == nil {
+		t.Error("Expected an error for a zero drawdown floor")
+	}
+	if _, err := New(1_000_000, WithDrawdownTiers(DrawdownTier{Drawdown: 0.25, Scale: 0.5})); err == nil {
+		t.Error("Expected an error for a tier beyond the floor")
+	}
+}
+
+func TestRiskTool_SymbolRestrictions(t *testing.T) {
+	input := Input{Symbol: "spy", Action: "BUY", Confidence: 0.8, Volatility: 0.15, PortfolioValue: 1_000_000}
+
+	blocked := newConfig(1_000_000, WithSymbolRestrictions(nil, []string{" SPY "}))
+	if out := evaluate(blocked, input); out.Decision != "REJECT" || out.PositionSize != 0 || !strings.Contains(out.Reason, "restricted") {
+		t.Errorf("Expected a restricted REJECT for a blocked symbol, got %s %f (%s)", out.Decision, out.PositionSize, out.Reason)
+	}
+
+	allowlist := newConfig(1_000_000, WithSymbolRestrictions([]string{"QQQ"}, nil))
+	if out := evaluate(allowlist, input); out.Decision != "REJECT" || !strings.Contains(out.Reason, "restricted") {
+		t.Errorf("Expected a restricted REJECT for a symbol off the allowlist, got %s (%s)", out.Decision, out.Reason)
+	}
+	input.Symbol = "qqq"
+	if out := evaluate(allowlist, input); out.Decision != "APPROVE" {
+		t.Errorf("Expected an allowlisted symbol to pass, got %s (%s)", out.Decision, out.Reason)
+	}
+
+	both := newConfig(1_000_000, WithSymbolRestrictions([]string{"QQQ"}, []string{"QQQ"}))
+	if out := evaluate(both, input); !strings.Contains(out.Reason, "blocked") {
+		t.Errorf("Expected the blocklist to win over the allowlist, got %s", out.Reason)
+	}
+}
+
+func TestRiskTool_ReasonCodes(t *testing.T) {
+	cfg := newConfig(1_000_000)
+
+	out := evaluate(cfg, Input{Symbol: "SPY", Action: "SELL", Confidence: 0.2, Volatility: 0.9, PortfolioValue: 1_000_000})
+
+	var codes []string
+	for _, r := range out.Reasons {
+		if r.Message == "" {
+			t.Errorf("Expected a message for code %q", r.Code)
+		}
+		codes = append(codes, r.Code)
+	}
+	if strings.Join(codes, ",") != ReasonHighVolatility+","+ReasonWeakConfidence {
+		t.Errorf("Expected high_volatility and weak_confidence codes, got %v", codes)
+	}
+	if out.Reason != "volatility too high; confidence weak" {
+		t.Errorf("Expected the joined reason to keep its messages, got %q", out.Reason)
+	}
+
+	calm := evaluate(cfg, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.15, PortfolioValue: 1_000_000})
+	if len(calm.Reasons) != 1 || calm.Reasons[0].Code != ReasonWithinLimits {
+		t.Errorf("Expected a single within_limits reason, got %+v", calm.Reasons)
+	}
+}
+
+func TestRiskTool_SizingUnits(t *testing.T) {
+	cfg := newConfig(1_000_000)
+	base := Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.2, PortfolioValue: 1_000_000, Scenarios: true}
+
+	notional := evaluate(cfg, base)
+	if notional.SizingUnit != SizingUnitNotional || notional.PositionSize != 2_500 || notional.Shares != 0 {
+		t.Errorf("Expected 2500 dollars in notional mode, got %s %f shares=%d", notional.SizingUnit, notional.PositionSize, notional.Shares)
+	}
+
+	shares := base
+	shares.SizingUnit = "shares"
+	shares.SharePrice = 30
+	shares.LotSize = 10
+	out := evaluate(cfg, shares)
+	if out.SizingUnit != SizingUnitShares || out.Shares != 80 || out.PositionSize != 2_400 {
+		t.Errorf("Expected 80 shares worth 2400, got %s %d %f", out.SizingUnit, out.Shares, out.PositionSize)
+	}
+	for _, sc := range out.Scenarios {
+		if sc.Shares%10 != 0 || sc.PositionSize != float64(sc.Shares)*30 || math.Abs(sc.VaR-valueAtRisk(sc.PositionSize, 0.2)) > 1e-9 {
+			t.Errorf("Expected scenario %s in whole lots with VaR on the rounded notional, got %+v", sc.Name, sc)
+		}
+	}
+
+	capped := shares
+	capped.Volatility = 0.01
+	capped.MaxRiskBps = 500
+	if out := evaluate(cfg, capped); out.Shares != 3_330 || !out.ConstraintHit {
+		t.Errorf("Expected the 10%% position cap to bound shares at 3330, got %d hit=%v", out.Shares, out.ConstraintHit)
+	}
+
+	missingPrice := base
+	missingPrice.SizingUnit = SizingUnitShares
+	if out := evaluate(cfg, missingPrice); out.Decision != "REJECT" || out.Reasons[0].Code != ReasonInvalidInput {
+		t.Errorf("Expected shares mode without a price to be rejected, got %s %+v", out.Decision, out.Reasons)
+	}
+	bogus := base
+	bogus.SizingUnit = "contracts"
+	if out := evaluate(cfg, bogus); out.Decision != "REJECT" || !strings.Contains(out.Reason, "unsupported sizing unit") {
+		t.Errorf("Expected an unsupported unit to be rejected, got %s (%s)", out.Decision, out.Reason)
+	}
+}
+
+func TestEvaluate_ConfidenceSweep(t *testing.T) {
+	input := Input{
+		Symbol:          "SPY",
+		Action:          "BUY",
+		Confidence:      0.8,
+		Volatility:      0.2,
+		PortfolioValue:  1_000_000,
+		Scenarios:       true,
+		ConfidenceSweep: []float64{0.2, 0.5, 0.9},
+	}
+
+	out := testHandler(1_000_000, input)
+
+	if out.Decision != "APPROVE" || out.Confidence != 0.8 {
+		t.Errorf("Expected the primary decision to use confidence 0.8, got %s at %f", out.Decision, out.Confidence)
+	}
+	if len(out.Scenarios) == 0 {
+		t.Error("Expected scenarios on the primary output")
+	}
+	if len(out.Sweep) != 3 {
+		t.Fatalf("Expected one sweep point per level, got %d", len(out.Sweep))
+	}
+	for i, want := range []string{"REVIEW", "APPROVE", "APPROVE"} {
+		point := out.Sweep[i]
+		if point.Confidence != input.ConfidenceSweep[i] || point.Decision != want {
+			t.Errorf("Point %d: expected %s at %f, got %s at %f", i, want, input.ConfidenceSweep[i], point.Decision, point.Confidence)
+		}
+		if point.PositionSize != out.PositionSize {
+			t.Errorf("Point %d: expected size %f held constant, got %f", i, out.PositionSize, point.PositionSize)
+		}
+	}
+	if out.Sweep[0].Reasons[len(out.Sweep[0].Reasons)-1].Code != ReasonWeakConfidence {
+		t.Errorf("Expected the low-confidence point to cite weak confidence, got %+v", out.Sweep[0].Reasons)
+	}
+
+	if plain := testHandler(1_000_000, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.8, Volatility: 0.2}); plain.Sweep != nil {
+		t.Errorf("Expected no sweep without levels, got %+v", plain.Sweep)
+	}
+}
+
+func TestRiskTool_ATRSizing(t *testing.T) {
+	input := Input{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: 0.2, SizingMethod: "ATR", EntryPrice: 500, ATR: 10}
+
+	out := testHandler(1_000_000, input)
+
+	// A 5,000 budget over a 2 ATR (20) stop buys 250 shares at 500: 125,000, capped at 100,000.
+	if out.SizingMethod != SizingMethodATR || out.PositionSize != 100_000 || !out.ConstraintHit {
+		t.Errorf("Expected a capped ATR size of 100000, got %s %f (capped %v)", out.SizingMethod, out.PositionSize, out.ConstraintHit)
+	}
+	if math.Abs(out.UnitsOfRisk-0.8) > 1e-9 {
+		t.Errorf("Expected the cap to leave 0.8 units of risk, got %f", out.UnitsOfRisk)
+	}
+
+	input.ATRMultiple = 4
+	if out := testHandler(1_000_000, input); out.PositionSize != 62_500 || math.Abs(out.UnitsOfRisk-1) > 1e-9 {
+		t.Errorf("Expected a 4 ATR stop to size 62500 at one unit of risk, got %f (%f units)", out.PositionSize, out.UnitsOfRisk)
+	}
+}
+
+func TestRiskTool_ATRSizingFallsBackWithoutATR(t *testing.T) {
+	for _, input := range []Input{
+		{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: 0.2, SizingMethod: "atr", EntryPrice: 500},
+		{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: 0.2, SizingMethod: "atr", ATR: 10},
+	} {
+		out := testHandler(1_000_000, input)
+		if out.SizingMethod != SizingMethodVolatility || out.PositionSize != 2_500 || out.UnitsOfRisk != 0 {
+			t.Errorf("Expected volatility sizing of 2500 for %+v, got %s %f", input, out.SizingMethod, out.PositionSize)
+		}
+	}
+
+	if out := testHandler(1_000_000, Input{Symbol: "SPY", Action: "BUY", Confidence: 0.7, SizingMethod: "kelly"}); out.Decision != "REJECT" || out.Reasons[0].Code != ReasonInvalidInput {
+		t.Errorf("Expected an unsupported sizing method to be rejected, got %+v", out)
+	}
+}
+
+func TestRiskTool_RejectsInvalidInput(t *testing.T) {
+	for _, input := range []Input{
+		{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: 0.2, PortfolioValue: -1_000_000},
+		{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: -0.2},
+		{Symbol: "SPY", Action: "BUY", Confidence: 0.7, Volatility: 0.2, SharePrice: 500, LotSize: -100},
+		{Symbol: "SPY", Action: "BUYY", Confidence: 0.7, Volatility: 0.2},
+	} {
+		out := testHandler(1_000_000, input)
+		if out.Decision != "REJECT" || out.Reasons[0].Code != ReasonInvalidInput || out.Error == "" || out.PositionSize != 0 {
+			t.Errorf("Expected %+v to be rejected as invalid, got %+v", input, out)
+		}
+	}
+
+	// Borderline values are still coerced rather than rejected.
+	if out := testHandler(1_000_000, Input{Symbol: "SPY", Action: "buy", Confidence: 1.4, Volatility: 0.2}); out.Decision == "REJECT" || out.Error != "" {
+		t.Errorf("Expected an out-of-range confidence to be clamped, got %+v", out)
+	}
+}